@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/importer"
+	"github.com/dsemenov12/shorturl/internal/keygen"
+)
+
+// importUsage описывает аргументы подкоманды import.
+const importUsage = "usage: shortener [flags] import [-format csv|jsonl] [-user id] <file>"
+
+// errImportUsage возвращается при неверных аргументах подкоманды import.
+var errImportUsage = errors.New(importUsage)
+
+// runImport выполняет подкоманду import: загружает ссылки из файла CSV или JSON Lines
+// в хранилище, выбранное конфигурацией, и выводит итоги и ошибки записей.
+// Формат определяется флагом -format или расширением файла, владелец ссылок задаётся флагом -user.
+func runImport(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	format := flags.String("format", "", "file format: csv or jsonl")
+	userID := flags.String("user", "", "owner of imported links")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errImportUsage
+	}
+
+	path := flags.Arg(0)
+	importFormat, err := importer.ParseFormat(*format, path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	storage, _, closeStorage, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	if err = storage.Bootstrap(ctx); err != nil {
+		return err
+	}
+
+	keys, err := keygen.New(config.FlagKeyStrategy, config.FlagKeyAlphabet, config.FlagKeyLength, config.FlagKeyNodeID)
+	if err != nil {
+		return err
+	}
+
+	ctx = context.WithValue(ctx, auth.UserIDKey, *userID)
+	result, err := importer.New(storage, keys).Import(ctx, file, importFormat)
+
+	for _, rowErr := range result.Errors {
+		fmt.Printf("line %d\t%s\t%s\n", rowErr.Line, rowErr.ShortURL, rowErr.Error)
+	}
+	fmt.Printf("processed %d record(s): imported %d, failed %d\n", result.Total, result.Imported, result.Failed)
+
+	return err
+}
@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"flag"
 	"fmt"
 	"net/http"
 	_ "net/http/pprof"
@@ -11,16 +11,27 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/dsemenov12/shorturl/internal/analytics"
 	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/deleter"
+	"github.com/dsemenov12/shorturl/internal/expiry"
+	"github.com/dsemenov12/shorturl/internal/filestorage"
 	"github.com/dsemenov12/shorturl/internal/grpcserver"
 	"github.com/dsemenov12/shorturl/internal/handlers"
+	"github.com/dsemenov12/shorturl/internal/health"
+	"github.com/dsemenov12/shorturl/internal/keygen"
+	"github.com/dsemenov12/shorturl/internal/metrics"
 	"github.com/dsemenov12/shorturl/internal/middlewares/authcookiehandler"
 	"github.com/dsemenov12/shorturl/internal/middlewares/authhandler"
 	"github.com/dsemenov12/shorturl/internal/middlewares/gziphandler"
 	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
-	"github.com/dsemenov12/shorturl/internal/storage"
-	"github.com/dsemenov12/shorturl/internal/storage/memory"
-	"github.com/dsemenov12/shorturl/internal/storage/pg"
+	"github.com/dsemenov12/shorturl/internal/middlewares/metricshandler"
+	"github.com/dsemenov12/shorturl/internal/middlewares/requestid"
+	"github.com/dsemenov12/shorturl/internal/middlewares/tracinghandler"
+	"github.com/dsemenov12/shorturl/internal/storage/cache"
+	"github.com/dsemenov12/shorturl/internal/storage/file"
+	"github.com/dsemenov12/shorturl/internal/storage/instrumented"
+	"github.com/dsemenov12/shorturl/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -42,21 +53,37 @@ func main() {
 }
 
 func run() error {
-	var storage storage.Storage
-
 	config.ParseFlags()
 
+	switch flag.Arg(0) {
+	case "migrate":
+		return runMigrate(context.Background(), flag.Args()[1:])
+	case "import":
+		return runImport(context.Background(), flag.Args()[1:])
+	}
+
 	baseURL, err := url.Parse(config.FlagBaseAddr)
 	if err != nil {
 		return err
 	}
 
 	router := chi.NewRouter()
+	router.Use(metricshandler.MetricsHandle)
+	router.Use(tracinghandler.TracingHandle)
+	router.Use(requestid.RequestIDHandle)
 
-	// Контекст с отменой
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	// Контекст, отменяемый сигналом завершения
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	defer stop()
 
+	// Контекст работы фоновых задач; отменяется после остановки серверов
+	ctx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Контекст работы gRPC сервера и grpc-gateway; отменяется после перевода сервиса в состояние «не готов»
+	serversCtx, stopServers := context.WithCancel(context.Background())
+	defer stopServers()
+
 	go func() {
 		logger.Log.Info("Starting pprof", zap.String("address", ":6060"))
 		if err := http.ListenAndServe(":6060", nil); err != nil {
@@ -64,45 +91,125 @@ func run() error {
 		}
 	}()
 
-	storage = memory.NewStorage()
-	if config.FlagDatabaseDSN != "" {
-		conn, err := sql.Open("pgx", config.FlagDatabaseDSN)
-		if err != nil {
-			return err
+	storage, conn, closeStorage, err := openStorage()
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	// Проверки состояния сервиса: готовность требует доступного хранилища и запущенного gRPC сервера
+	checks := health.New()
+	pingStorage := storageChecker(storage)
+	checks.AddReadinessCheck("storage", pingStorage)
+	checks.AddComponent(grpcserver.HealthComponent)
+
+	router.Get("/ping", logger.RequestLogger(func(res http.ResponseWriter, req *http.Request) {
+		if err := pingStorage.Check(req.Context()); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
-		router.Get("/ping", logger.RequestLogger(func(res http.ResponseWriter, req *http.Request) {
-			if err := conn.Ping(); err != nil {
-				http.Error(res, err.Error(), http.StatusInternalServerError)
-			}
+		res.WriteHeader(http.StatusOK)
+	}))
+	router.Get("/healthz", checks.LiveHandler())
+	router.Get("/readyz", checks.ReadyHandler())
 
-			res.WriteHeader(http.StatusOK)
-		}))
+	if err = storage.Bootstrap(context.TODO()); err != nil {
+		return err
+	}
 
-		storage = pg.NewStorage(conn)
+	// Запускаем периодическое сжатие журнала файлового хранилища
+	if fileStorage, ok := storage.(*file.StorageFile); ok {
+		go filestorage.RunCompactor(ctx, config.FlagFileCompactInterval, fileStorage.Compact)
 	}
 
-	if err = storage.Bootstrap(context.TODO()); err != nil {
+	if err = logger.Initialize(config.FlagLogLevel); err != nil {
 		return err
 	}
 
-	app := handlers.NewApp(storage)
+	// Настраиваем трассировку; перед выходом отправляем накопленные спаны
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:    config.FlagTraceExporter,
+		Endpoint:    config.FlagTraceEndpoint,
+		SampleRatio: config.FlagTraceSampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			logger.Log.Error("tracing shutdown failed", zap.Error(err))
+		}
+	}()
 
-	if err = logger.Initialize(config.FlagLogLevel); err != nil {
+	// Записываем метрики операций хранилища и пула соединений
+	metrics.Registry.MustRegister(metrics.NewStatsCollector(storage))
+	if conn != nil {
+		metrics.Registry.MustRegister(metrics.NewDBStatsCollector(conn))
+	}
+	storage = instrumented.NewStorage(storage)
+
+	// Подключаем кеш ссылок перед хранилищем
+	if config.FlagCacheSize > 0 {
+		linkCache := cache.NewStorage(storage, cache.Options{
+			Size:        config.FlagCacheSize,
+			TTL:         config.FlagCacheTTL,
+			NegativeTTL: config.FlagCacheNegativeTTL,
+		})
+		defer func() {
+			stats := linkCache.Stats()
+			logger.Log.Info("link cache stats", zap.Uint64("hits", stats.Hits), zap.Uint64("misses", stats.Misses))
+		}()
+		metrics.Registry.MustRegister(metrics.NewCacheCollector(linkCache))
+		storage = linkCache
+	}
+
+	// Запускаем сбор статистики переходов; до закрытия хранилища дожидаемся сохранения накопленных событий
+	clicks := analytics.NewRecorder(storage)
+	go clicks.Run(ctx)
+	defer func() {
+		stopWorkers()
+		clicks.Wait()
+	}()
+
+	// Запускаем фоновое удаление ссылок; до закрытия хранилища дожидаемся выполнения принятых запросов
+	deletes := deleter.NewDeleter(storage)
+	go deletes.Run(ctx)
+	defer func() {
+		stopWorkers()
+		deletes.Wait()
+	}()
+
+	// Запускаем удаление ссылок с истёкшим сроком действия
+	go expiry.RunReaper(ctx, storage, config.FlagReaperInterval, config.FlagPurgeExpired)
+
+	// Запускаем окончательное удаление ссылок, срок хранения которых после удаления истёк
+	go deleter.RunPurger(ctx, storage, config.FlagPurgeInterval, config.FlagDeletedRetention)
+
+	keys, err := keygen.New(config.FlagKeyStrategy, config.FlagKeyAlphabet, config.FlagKeyLength, config.FlagKeyNodeID)
+	if err != nil {
 		return err
 	}
 
+	app := handlers.NewApp(storage, clicks, keys, deletes)
+
 	// Запускаем gRPC сервер
 	grpcAddr := config.FlagGRPCAddress
+	grpcStopped := make(chan struct{})
 	go func() {
-		if err := grpcserver.RunGRPCServer(ctx, storage, grpcAddr); err != nil {
+		defer close(grpcStopped)
+		if err := grpcserver.RunGRPCServer(serversCtx, storage, clicks, keys, deletes, checks, grpcAddr); err != nil {
 			logger.Log.Fatal("gRPC server error", zap.Error(err))
 		}
 	}()
 
 	// Запускаем grpc-gateway HTTP сервер
+	gatewayStopped := make(chan struct{})
 	go func() {
-		if err := grpcserver.RunGateway(ctx, grpcAddr, config.FlagGRPCGatewayAddr); err != nil {
+		defer close(gatewayStopped)
+		if err := grpcserver.RunGateway(serversCtx, grpcAddr, config.FlagGRPCGatewayAddr); err != nil {
 			logger.Log.Fatal("grpc-gateway server error", zap.Error(err))
 		}
 	}()
@@ -111,9 +218,18 @@ func run() error {
 	router.Post("/api/shorten", logger.RequestLogger(authhandler.AuthHandle(app.ShortenPost)))
 	router.Post("/api/shorten/batch", logger.RequestLogger(authhandler.AuthHandle(app.ShortenBatchPost)))
 	router.Get(baseURL.Path+"/{id}", logger.RequestLogger(app.Redirect))
+	router.Post(baseURL.Path+"/{id}", logger.RequestLogger(app.UnlockLink))
 	router.Get("/api/user/urls", logger.RequestLogger(authhandler.AuthHandle(app.UserUrls)))
+	router.Get("/api/user/urls/export", logger.RequestLogger(authhandler.AuthHandle(app.ExportURLs)))
+	router.Post("/api/user/urls/import", logger.RequestLogger(authhandler.AuthHandle(app.ImportURLs)))
 	router.Delete("/api/user/urls", logger.RequestLogger(authcookiehandler.AuthCookieHandle(app.DeleteUserUrls)))
+	router.Put("/api/user/urls/{id}", logger.RequestLogger(authcookiehandler.AuthCookieHandle(app.UpdateURL)))
+	router.Get("/api/user/urls/{id}/history", logger.RequestLogger(authcookiehandler.AuthCookieHandle(app.URLHistory)))
+	router.Post("/api/user/urls/{id}/rollback", logger.RequestLogger(authcookiehandler.AuthCookieHandle(app.RollbackURL)))
+	router.Post("/api/user/urls/{id}/restore", logger.RequestLogger(authcookiehandler.AuthCookieHandle(app.RestoreURL)))
 	router.Get("/api/internal/stats", logger.RequestLogger(app.InternalStats))
+	router.Get("/api/stats/{id}", logger.RequestLogger(authcookiehandler.AuthCookieHandle(app.LinkStats)))
+	router.Handle("/metrics", metrics.Handler())
 
 	server := &http.Server{
 		Addr:    config.FlagRunAddr,
@@ -138,35 +254,32 @@ func run() error {
 	}()
 
 	// Ожидание сигнала завершения
-	<-ctx.Done()
+	<-sigCtx.Done()
 	logger.Log.Info("Shutting down server...")
 
+	// Сообщаем о неготовности и даём балансировщику время исключить сервис, продолжая обрабатывать запросы
+	checks.Drain()
+	time.Sleep(config.FlagShutdownDelay)
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if config.FlagEnableHTTPS {
-		certFile := "cert.pem"
-		keyFile := "key.pem"
-
-		logger.Log.Info("Starting HTTPS server", zap.String("address", config.FlagRunAddr))
-		return http.ListenAndServeTLS(config.FlagRunAddr, certFile, keyFile, gziphandler.GzipHandle(router))
-	}
-
-	logger.Log.Info("Running server", zap.String("address", config.FlagRunAddr))
-
-	err = http.ListenAndServe(
-		config.FlagRunAddr,
-		gziphandler.GzipHandle(router),
-	)
-	if err != nil {
-		return err
-	}
-
+	// Останавливаем серверы, дожидаясь выполнения начатых запросов. Фоновые задачи останавливаются
+	// после этого, чтобы удаление ссылок и переходы из этих запросов не были потеряны
+	stopServers()
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		logger.Log.Error("Server shutdown failed", zap.Error(err))
 	} else {
 		logger.Log.Info("Server exited properly")
 	}
+	for name, stopped := range map[string]chan struct{}{"gRPC server": grpcStopped, "grpc-gateway server": gatewayStopped} {
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			logger.Log.Error("Server shutdown timed out", zap.String("server", name))
+		}
+	}
+	stopWorkers()
 
 	return nil
 }
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/storage/pg"
+)
+
+// migrateUsage описывает аргументы подкоманды migrate.
+const migrateUsage = "usage: shortener -d <postgres dsn> migrate up | down [steps] | status"
+
+// errMigrateUsage возвращается при неверных аргументах подкоманды migrate.
+var errMigrateUsage = errors.New(migrateUsage)
+
+// runMigrate выполняет подкоманду migrate: применяет, откатывает миграции схемы PostgreSQL
+// или выводит их состояние. По умолчанию down откатывает одну последнюю миграцию.
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+	if config.FlagDatabaseDriver != config.DatabaseDriverPostgres {
+		return errors.New("migrate: PostgreSQL DSN is required")
+	}
+
+	conn, err := pg.Open(config.FlagDatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	storage := pg.NewStorage(conn)
+
+	switch args[0] {
+	case "up":
+		count, err := storage.MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", count)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return errMigrateUsage
+			}
+		}
+		count, err := storage.MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", count)
+	case "status":
+		status, err := storage.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range status {
+			state := "pending"
+			if migration.Applied() {
+				state = "applied " + migration.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", migration.Version, migration.Name, state)
+		}
+	default:
+		return errMigrateUsage
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/filestorage"
+	"github.com/dsemenov12/shorturl/internal/health"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/file"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/dsemenov12/shorturl/internal/storage/pg"
+	"github.com/dsemenov12/shorturl/internal/storage/redis"
+	"github.com/dsemenov12/shorturl/internal/storage/sqlite"
+)
+
+// openStorage открывает хранилище, выбранное конфигурацией: базу данных или Redis при заданном DSN,
+// файловое хранилище при заданном пути к файлу, иначе хранилище в памяти.
+// Для SQL-базы данных возвращает также соединение, nil — для остальных хранилищ.
+// Функция close освобождает ресурсы хранилища.
+func openStorage() (s storage.Storage, conn *sql.DB, close func() error, err error) {
+	switch {
+	case config.FlagDatabaseDriver == config.DatabaseDriverRedis:
+		client, err := redis.Open(config.FlagDatabaseDSN)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return redis.NewStorage(client), nil, client.Close, nil
+	case config.FlagDatabaseDSN != "":
+		if config.FlagDatabaseDriver == config.DatabaseDriverSQLite {
+			conn, err = sqlite.Open(config.FlagDatabaseDSN)
+			s = sqlite.NewStorage(conn)
+		} else {
+			conn, err = pg.Open(config.FlagDatabaseDSN)
+			s = pg.NewStorage(conn)
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return s, conn, conn.Close, nil
+	case config.FlagFileStoragePath != "":
+		journal, err := filestorage.Open(config.FlagFileStoragePath, filestorage.Options{
+			Sync:         filestorage.SyncPolicy(config.FlagFileSync),
+			SyncInterval: config.FlagFileSyncInterval,
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		fileStorage := file.NewStorage(journal)
+		return fileStorage, nil, fileStorage.Close, nil
+	default:
+		return memory.NewStorage(), nil, func() error { return nil }, nil
+	}
+}
+
+// storageChecker возвращает проверку доступности хранилища s, открытого openStorage:
+// соединение с базой данных или Redis, возможность записи в файл файлового хранилища.
+// Хранилище в памяти доступно всегда.
+func storageChecker(s storage.Storage) health.Checker {
+	switch s := s.(type) {
+	case storage.Pinger:
+		return health.CheckerFunc(s.Ping)
+	case *file.StorageFile:
+		return health.Writable(config.FlagFileStoragePath)
+	default:
+		return health.CheckerFunc(func(context.Context) error { return nil })
+	}
+}
@@ -0,0 +1,43 @@
+package alias
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/dsemenov12/shorturl/internal/config"
+)
+
+// Ошибки проверки пользовательского короткого ключа.
+var (
+	// ErrInvalidLength возвращается, если длина алиаса выходит за допустимые границы.
+	ErrInvalidLength = errors.New("alias has invalid length")
+	// ErrInvalidChars возвращается, если алиас содержит недопустимые символы.
+	ErrInvalidChars = errors.New("alias contains invalid characters")
+	// ErrReserved возвращается, если алиас совпадает с зарезервированным словом.
+	ErrReserved = errors.New("alias is reserved")
+)
+
+// Validate проверяет пользовательский короткий ключ (алиас) по правилам из конфигурации:
+// длина в пределах config.FlagAliasMinLength..config.FlagAliasMaxLength,
+// символы только из config.FlagAliasAlphabet и отсутствие в списке config.FlagAliasReserved.
+// Сравнение с зарезервированными словами выполняется без учёта регистра.
+func Validate(alias string) error {
+	length := len([]rune(alias))
+	if length < config.FlagAliasMinLength || length > config.FlagAliasMaxLength {
+		return ErrInvalidLength
+	}
+
+	for _, r := range alias {
+		if !strings.ContainsRune(config.FlagAliasAlphabet, r) {
+			return ErrInvalidChars
+		}
+	}
+
+	for _, reserved := range strings.Split(config.FlagAliasReserved, ",") {
+		if strings.EqualFold(strings.TrimSpace(reserved), alias) {
+			return ErrReserved
+		}
+	}
+
+	return nil
+}
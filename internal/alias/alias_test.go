@@ -0,0 +1,60 @@
+package alias
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   string
+		wantErr error
+	}{
+		{
+			name:  "valid alias",
+			alias: "spring-sale_2024",
+		},
+		{
+			name:    "too short",
+			alias:   "ab",
+			wantErr: ErrInvalidLength,
+		},
+		{
+			name:    "too long",
+			alias:   "abcdefghijklmnopqrstuvwxyz0123456789",
+			wantErr: ErrInvalidLength,
+		},
+		{
+			name:    "invalid characters",
+			alias:   "sale/2024",
+			wantErr: ErrInvalidChars,
+		},
+		{
+			name:    "non-latin characters",
+			alias:   "скидка",
+			wantErr: ErrInvalidChars,
+		},
+		{
+			name:    "reserved word",
+			alias:   "api",
+			wantErr: ErrReserved,
+		},
+		{
+			name:    "reserved word in another case",
+			alias:   "PING",
+			wantErr: ErrReserved,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.alias)
+			if test.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, test.wantErr)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+)
+
+const (
+	// defaultBufferSize — размер очереди событий, ожидающих записи в хранилище.
+	defaultBufferSize = 1024
+	// batchSize — максимальное количество событий, сохраняемых за одно обращение к хранилищу.
+	batchSize = 100
+	// flushInterval — период, с которым накопленные события сбрасываются в хранилище.
+	flushInterval = time.Second
+
+	// DefaultStatsPeriod — период статистики, если границы не указаны в запросе.
+	DefaultStatsPeriod = 7 * 24 * time.Hour
+	// DefaultStatsInterval — длина интервала временного ряда по умолчанию.
+	DefaultStatsInterval = 24 * time.Hour
+	// maxStatsBuckets — максимальное количество интервалов во временном ряду.
+	maxStatsBuckets = 1000
+)
+
+// ErrInvalidRange возвращается, если параметры периода статистики заданы некорректно.
+var ErrInvalidRange = errors.New("invalid stats range")
+
+// Recorder принимает события перехода по коротким ссылкам и асинхронно сохраняет их
+// в хранилище пакетами, не задерживая обработку перенаправления.
+type Recorder struct {
+	storage storage.Storage
+	events  chan models.ClickEvent
+	done    chan struct{}
+}
+
+// NewRecorder создает новый экземпляр Recorder, сохраняющий события в указанное хранилище.
+func NewRecorder(storage storage.Storage) *Recorder {
+	return &Recorder{
+		storage: storage,
+		events:  make(chan models.ClickEvent, defaultBufferSize),
+		done:    make(chan struct{}),
+	}
+}
+
+// Track ставит событие перехода в очередь на сохранение.
+// Метод не блокируется: если очередь переполнена, событие отбрасывается.
+// Вызов на nil-получателе ничего не делает, что позволяет отключить сбор статистики.
+func (r *Recorder) Track(event models.ClickEvent) {
+	if r == nil {
+		return
+	}
+
+	select {
+	case r.events <- event:
+	default:
+		logger.Log.Warn("click event dropped", zap.String("short_key", event.ShortKey))
+	}
+}
+
+// Run читает события из очереди и сохраняет их в хранилище пакетами по batchSize
+// или раз в flushInterval. После отмены контекста оставшиеся в очереди события
+// сохраняются, и метод завершается.
+func (r *Recorder) Run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.ClickEvent, 0, batchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.storage.SaveClicks(ctx, batch); err != nil {
+			logger.Log.Error("failed to save click events", zap.Int("count", len(batch)), zap.Error(err))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event := <-r.events:
+			batch = append(batch, event)
+			if len(batch) >= batchSize {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		case <-ctx.Done():
+			drainCtx := context.WithoutCancel(ctx)
+			for {
+				select {
+				case event := <-r.events:
+					batch = append(batch, event)
+					if len(batch) >= batchSize {
+						flush(drainCtx)
+					}
+				default:
+					flush(drainCtx)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Wait ожидает завершения Run, то есть сохранения всех событий из очереди после отмены контекста.
+func (r *Recorder) Wait() {
+	<-r.done
+}
+
+// ParseRange разбирает параметры запроса статистики: границы периода from и to в формате RFC 3339
+// и длину интервала временного ряда interval в формате time.ParseDuration.
+// Пустые значения заменяются значениями по умолчанию относительно момента now.
+func ParseRange(from, to, interval string, now time.Time) (time.Time, time.Time, time.Duration, error) {
+	var err error
+
+	end := now
+	if to != "" {
+		if end, err = time.Parse(time.RFC3339, to); err != nil {
+			return time.Time{}, time.Time{}, 0, ErrInvalidRange
+		}
+	}
+
+	start := end.Add(-DefaultStatsPeriod)
+	if from != "" {
+		if start, err = time.Parse(time.RFC3339, from); err != nil {
+			return time.Time{}, time.Time{}, 0, ErrInvalidRange
+		}
+	}
+
+	step := DefaultStatsInterval
+	if interval != "" {
+		if step, err = time.ParseDuration(interval); err != nil {
+			return time.Time{}, time.Time{}, 0, ErrInvalidRange
+		}
+	}
+
+	if step < time.Minute || !start.Before(end) || end.Sub(start)/step > maxStatsBuckets {
+		return time.Time{}, time.Time{}, 0, ErrInvalidRange
+	}
+
+	return start, end, step, nil
+}
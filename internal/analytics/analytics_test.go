@@ -0,0 +1,107 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder_Run(t *testing.T) {
+	store := memory.NewStorage()
+	recorder := NewRecorder(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go recorder.Run(ctx)
+
+	now := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		recorder.Track(models.ClickEvent{ShortKey: "short1", Timestamp: now})
+	}
+
+	// После отмены контекста накопленные события должны быть сохранены
+	cancel()
+	recorder.Wait()
+
+	total, _, err := store.GetStats(context.Background(), "short1", now.Add(-time.Hour), now.Add(time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestRecorder_TrackNil(t *testing.T) {
+	var recorder *Recorder
+
+	assert.NotPanics(t, func() {
+		recorder.Track(models.ClickEvent{ShortKey: "short1"})
+	})
+}
+
+func TestParseRange(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		interval string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantStep time.Duration
+		wantErr  bool
+	}{
+		{
+			name:     "defaults",
+			wantFrom: now.Add(-DefaultStatsPeriod),
+			wantTo:   now,
+			wantStep: DefaultStatsInterval,
+		},
+		{
+			name:     "explicit range",
+			from:     "2024-01-01T00:00:00Z",
+			to:       "2024-01-02T00:00:00Z",
+			interval: "1h",
+			wantFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			wantStep: time.Hour,
+		},
+		{
+			name:    "invalid from",
+			from:    "yesterday",
+			wantErr: true,
+		},
+		{
+			name:     "interval too small",
+			interval: "1s",
+			wantErr:  true,
+		},
+		{
+			name:    "from after to",
+			from:    "2024-01-03T00:00:00Z",
+			to:      "2024-01-02T00:00:00Z",
+			wantErr: true,
+		},
+		{
+			name:     "too many buckets",
+			from:     "2023-01-01T00:00:00Z",
+			interval: "1m",
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			from, to, step, err := ParseRange(test.from, test.to, test.interval, now)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidRange)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.wantFrom, from)
+			assert.Equal(t, test.wantTo, to)
+			assert.Equal(t, test.wantStep, step)
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthJWT описывает интерфейс для работы с JWT-токенами.
@@ -36,6 +39,13 @@ type userContextKey string
 // UserIDKey — это ключ для хранения идентификатора пользователя в контексте.
 const UserIDKey userContextKey = "user_id"
 
+// UserIDFromContext возвращает идентификатор пользователя из контекста
+// или пустую строку, если пользователь не определён.
+func UserIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(UserIDKey).(string)
+	return userID
+}
+
 // BuildJWTString создает новый JWT-токен для указанного userID.
 // Этот токен подписан с использованием секретного ключа и включает в себя срок действия.
 // Возвращает строку с токеном или ошибку в случае неудачи.
@@ -70,3 +80,54 @@ func GetUserID(tokenString string) (string, error) {
 
 	return claims.UserID, nil
 }
+
+// LinkTokenExp определяет время жизни токена доступа к защищённой паролем ссылке.
+const LinkTokenExp = 15 * time.Minute
+
+// LinkCookieName — имя cookie с токеном доступа к защищённой паролем ссылке.
+const LinkCookieName = "link_token"
+
+// ErrInvalidLinkToken возвращается, если токен доступа не относится к указанной ссылке.
+var ErrInvalidLinkToken = errors.New("invalid link token")
+
+// BuildLinkToken создает короткоживущий JWT-токен, открывающий доступ к защищённой паролем ссылке shortKey.
+func BuildLinkToken(shortKey string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   shortKey,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(LinkTokenExp)),
+	})
+
+	return token.SignedString([]byte(SecretKey))
+}
+
+// CheckLinkToken проверяет, что токен действителен и выдан для ссылки shortKey.
+func CheckLinkToken(tokenString string, shortKey string) error {
+	claims := &jwt.RegisteredClaims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return []byte(SecretKey), nil
+	})
+	if err != nil {
+		return err
+	}
+	if claims.Subject != shortKey {
+		return ErrInvalidLinkToken
+	}
+
+	return nil
+}
+
+// HashPassword возвращает bcrypt-хеш пароля ссылки.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword сообщает, соответствует ли пароль bcrypt-хешу.
+func CheckPassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
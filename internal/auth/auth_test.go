@@ -100,3 +100,23 @@ func TestTokenExpiry(t *testing.T) {
 		assert.WithinDuration(t, claims.ExpiresAt.Time, time.Now().Add(TokenExp), time.Minute)
 	})
 }
+
+func TestLinkToken(t *testing.T) {
+	token, err := BuildLinkToken("short1")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+
+	// Токен действует только для той ссылки, для которой выдан
+	assert.NoError(t, CheckLinkToken(token, "short1"))
+	assert.ErrorIs(t, CheckLinkToken(token, "short2"), ErrInvalidLinkToken)
+	assert.Error(t, CheckLinkToken("invalid", "short1"))
+}
+
+func TestPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "secret", hash)
+
+	assert.True(t, CheckPassword(hash, "secret"))
+	assert.False(t, CheckPassword(hash, "wrong"))
+}
@@ -0,0 +1,78 @@
+// Package batch реализует пакетное сокращение URL, общее для HTTP и gRPC API.
+//
+// Элементы пакета проверяются заранее и сохраняются одним вызовом storage.SetBatch.
+// Результат возвращается по каждому элементу: created, exists или invalid.
+package batch
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/importer"
+	"github.com/dsemenov12/shorturl/internal/keygen"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+)
+
+// ErrInvalidItems возвращается при atomic=true, если часть элементов пакета не прошла проверку.
+var ErrInvalidItems = errors.New("batch contains invalid items")
+
+// Shorten сохраняет ссылки на исходные URL элементов items под ключами, полученными от генератора keys,
+// и возвращает результаты в порядке items.
+// Элементы без correlation_id, с повторяющимся correlation_id или с некорректным URL получают статус invalid
+// и не сохраняются. При atomic=true пакет с такими элементами не сохраняется целиком:
+// возвращаются только результаты invalid и ErrInvalidItems.
+func Shorten(ctx context.Context, s storage.Storage, keys keygen.KeyGenerator, items []models.BatchItem, atomic bool) ([]models.BatchResultItem, error) {
+	results := make([]models.BatchResultItem, len(items))
+	links := make([]models.Link, 0, len(items))
+	valid := make([]int, 0, len(items))
+	seen := make(map[string]bool, len(items))
+
+	var invalid []models.BatchResultItem
+	for i, item := range items {
+		results[i].CorrelationID = item.CorrelationID
+		if err := validate(item, seen); err != nil {
+			results[i].Status = models.BatchStatusInvalid
+			results[i].Error = err.Error()
+			invalid = append(invalid, results[i])
+			continue
+		}
+		seen[item.CorrelationID] = true
+		links = append(links, models.Link{OriginalURL: item.OriginalURL})
+		valid = append(valid, i)
+	}
+	if atomic && len(invalid) > 0 {
+		return invalid, ErrInvalidItems
+	}
+	if len(links) == 0 {
+		return results, nil
+	}
+
+	saved, err := storage.SetBatchGenerated(ctx, s, keys, links, atomic)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, n := range valid {
+		results[n].ShortURL = config.FlagBaseAddr + "/" + saved[i].ShortKey
+		results[n].Status = models.BatchStatusCreated
+		if errors.Is(saved[i].Err, storage.ErrConflict) {
+			results[n].Status = models.BatchStatusExists
+		}
+	}
+
+	return results, nil
+}
+
+// validate проверяет элемент пакета; seen — уже встреченные в пакете correlation_id.
+func validate(item models.BatchItem, seen map[string]bool) error {
+	if item.CorrelationID == "" {
+		return errors.New("correlation_id is required")
+	}
+	if seen[item.CorrelationID] {
+		return errors.New("duplicate correlation_id")
+	}
+
+	return importer.ValidateURL(item.OriginalURL)
+}
@@ -0,0 +1,89 @@
+package batch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/keygen"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/dsemenov12/shorturl/internal/storage/mocks"
+)
+
+func testKeys(t *testing.T) keygen.KeyGenerator {
+	keys, err := keygen.NewRandom(config.DefaultKeyAlphabet, config.DefaultKeyLength)
+	require.NoError(t, err)
+	return keys
+}
+
+func TestShorten(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStorage()
+	_, err := s.Set(ctx, models.Link{ShortKey: "existing", OriginalURL: "https://example.com/existing"})
+	require.NoError(t, err)
+
+	results, err := Shorten(ctx, s, testKeys(t), []models.BatchItem{
+		{CorrelationID: "1", OriginalURL: "https://example.com/1"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/existing"},
+		{CorrelationID: "", OriginalURL: "https://example.com/3"},
+		{CorrelationID: "4", OriginalURL: "example.com"},
+		{CorrelationID: "1", OriginalURL: "https://example.com/5"},
+	}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	assert.Equal(t, models.BatchStatusCreated, results[0].Status)
+	assert.Contains(t, results[0].ShortURL, config.FlagBaseAddr+"/")
+	assert.Equal(t, models.BatchResultItem{
+		CorrelationID: "2",
+		ShortURL:      config.FlagBaseAddr + "/existing",
+		Status:        models.BatchStatusExists,
+	}, results[1])
+	for _, result := range results[2:] {
+		assert.Equal(t, models.BatchStatusInvalid, result.Status)
+		assert.NotEmpty(t, result.Error)
+		assert.Empty(t, result.ShortURL)
+	}
+	assert.Equal(t, "duplicate correlation_id", results[4].Error)
+
+	count, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestShorten_AtomicInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockStorage(ctrl)
+
+	results, err := Shorten(context.Background(), s, testKeys(t), []models.BatchItem{
+		{CorrelationID: "1", OriginalURL: "https://example.com/1"},
+		{CorrelationID: "2", OriginalURL: "ftp://example.com/2"},
+	}, true)
+	assert.ErrorIs(t, err, ErrInvalidItems)
+	require.Len(t, results, 1)
+	assert.Equal(t, "2", results[0].CorrelationID)
+	assert.Equal(t, models.BatchStatusInvalid, results[0].Status)
+}
+
+func TestShorten_Atomic(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStorage()
+
+	results, err := Shorten(ctx, s, testKeys(t), []models.BatchItem{
+		{CorrelationID: "1", OriginalURL: "https://example.com/1"},
+		{CorrelationID: "2", OriginalURL: "https://example.com/2"},
+	}, true)
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.Equal(t, models.BatchStatusCreated, result.Status)
+	}
+
+	count, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
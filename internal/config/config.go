@@ -5,6 +5,70 @@ import (
 	"flag"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+)
+
+// Значения по умолчанию для правил проверки пользовательских коротких ключей (алиасов).
+const (
+	// DefaultAliasAlphabet — допустимые символы алиаса.
+	DefaultAliasAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_"
+	// DefaultAliasMinLength — минимальная длина алиаса.
+	DefaultAliasMinLength = 3
+	// DefaultAliasMaxLength — максимальная длина алиаса.
+	DefaultAliasMaxLength = 32
+	// DefaultAliasReserved — зарезервированные слова, которые нельзя использовать как алиас.
+	DefaultAliasReserved = "api,ping,debug,metrics,healthz,readyz"
+
+	// DefaultKeyStrategy — стратегия генерации коротких ключей.
+	DefaultKeyStrategy = "random"
+	// DefaultKeyAlphabet — символы генерируемых коротких ключей.
+	DefaultKeyAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// DefaultKeyLength — длина генерируемых коротких ключей.
+	DefaultKeyLength = 8
+
+	// DefaultReaperInterval — период удаления ссылок с истёкшим сроком действия.
+	DefaultReaperInterval = time.Minute
+
+	// DefaultRestoreWindow — срок, в течение которого удалённую ссылку можно восстановить.
+	DefaultRestoreWindow = 24 * time.Hour
+	// DefaultDeletedRetention — срок хранения удалённых ссылок до окончательного удаления.
+	DefaultDeletedRetention = 30 * 24 * time.Hour
+	// DefaultPurgeInterval — период окончательного удаления ссылок, удалённых раньше срока хранения.
+	DefaultPurgeInterval = time.Hour
+
+	// DefaultCacheTTL — время жизни ссылки в кеше.
+	DefaultCacheTTL = time.Minute
+	// DefaultCacheNegativeTTL — время жизни в кеше записи о несуществующем ключе.
+	DefaultCacheNegativeTTL = 5 * time.Second
+
+	// DefaultTraceExporter — экспортёр трассировки; none отключает экспорт.
+	DefaultTraceExporter = "none"
+	// DefaultTraceSampleRatio — доля трассируемых запросов, начатых сервисом.
+	DefaultTraceSampleRatio = 1.0
+
+	// DefaultFileSync — политика сброса файла хранилища на диск.
+	DefaultFileSync = "always"
+	// DefaultFileSyncInterval — период сброса файла хранилища на диск для политики interval.
+	DefaultFileSyncInterval = time.Second
+	// DefaultFileCompactInterval — период сжатия журнала файла хранилища.
+	DefaultFileCompactInterval = 10 * time.Minute
+)
+
+// Драйверы базы данных, выбираемые по схеме строки подключения.
+const (
+	// DatabaseDriverPostgres — драйвер PostgreSQL, используется для любой строки подключения без схемы sqlite://.
+	DatabaseDriverPostgres = "pgx"
+	// DatabaseDriverSQLite — встроенная база данных SQLite, строка подключения вида sqlite:///path/db.
+	DatabaseDriverSQLite = "sqlite"
+	// DatabaseDriverRedis — Redis, строка подключения вида redis://host:port/db или rediss://... для TLS.
+	DatabaseDriverRedis = "redis"
+
+	// sqliteScheme — схема строки подключения к SQLite.
+	sqliteScheme = "sqlite://"
+	// redisScheme и redisTLSScheme — схемы строки подключения к Redis.
+	redisScheme    = "redis://"
+	redisTLSScheme = "rediss://"
 )
 
 // Флаги конфигурации для приложения, которые могут быть переданы через командную строку или переменные окружения.
@@ -23,8 +87,12 @@ var (
 	FlagFileStoragePath string
 
 	// FlagDatabaseDSN указывает строку подключения к базе данных.
+	// Для SQLite после разбора флагов содержит путь к файлу базы данных.
 	FlagDatabaseDSN string
 
+	// FlagDatabaseDriver указывает драйвер базы данных, определённый по схеме FlagDatabaseDSN.
+	FlagDatabaseDriver string
+
 	// FlagEnableHTTPS включает HTTPS в веб-сервере.
 	FlagEnableHTTPS bool
 
@@ -39,19 +107,113 @@ var (
 	FlagGRPCGatewayAddr string
 
 	FlagEnableGRPCGateway bool
+
+	// FlagAliasAlphabet указывает допустимые символы пользовательского короткого ключа.
+	FlagAliasAlphabet = DefaultAliasAlphabet
+
+	// FlagAliasMinLength указывает минимальную длину пользовательского короткого ключа.
+	FlagAliasMinLength = DefaultAliasMinLength
+
+	// FlagAliasMaxLength указывает максимальную длину пользовательского короткого ключа.
+	FlagAliasMaxLength = DefaultAliasMaxLength
+
+	// FlagAliasReserved содержит зарезервированные слова через запятую, которые нельзя использовать как короткий ключ.
+	FlagAliasReserved = DefaultAliasReserved
+
+	// FlagKeyStrategy указывает стратегию генерации коротких ключей: random, sequential или hash.
+	FlagKeyStrategy = DefaultKeyStrategy
+
+	// FlagKeyAlphabet указывает символы генерируемых коротких ключей.
+	FlagKeyAlphabet = DefaultKeyAlphabet
+
+	// FlagKeyLength указывает длину генерируемых коротких ключей.
+	FlagKeyLength = DefaultKeyLength
+
+	// FlagKeyNodeID указывает номер экземпляра сервиса для последовательной стратегии генерации ключей.
+	FlagKeyNodeID int
+
+	// FlagReaperInterval указывает период удаления ссылок с истёкшим сроком действия; 0 отключает удаление.
+	FlagReaperInterval = DefaultReaperInterval
+
+	// FlagPurgeExpired включает физическое удаление ссылок с истёкшим сроком действия вместо пометки удалёнными.
+	FlagPurgeExpired bool
+
+	// FlagRestoreWindow указывает срок, в течение которого удалённую ссылку можно восстановить.
+	FlagRestoreWindow = DefaultRestoreWindow
+
+	// FlagDeletedRetention указывает срок хранения удалённых ссылок; 0 отключает окончательное удаление.
+	FlagDeletedRetention = DefaultDeletedRetention
+
+	// FlagPurgeInterval указывает период окончательного удаления ссылок, удалённых раньше срока хранения.
+	FlagPurgeInterval = DefaultPurgeInterval
+
+	// FlagCacheSize указывает максимальное количество ссылок в кеше перед хранилищем; 0 отключает кеш.
+	FlagCacheSize int
+
+	// FlagCacheTTL указывает время жизни ссылки в кеше.
+	FlagCacheTTL = DefaultCacheTTL
+
+	// FlagCacheNegativeTTL указывает время жизни в кеше записи о несуществующем ключе; 0 отключает такие записи.
+	FlagCacheNegativeTTL = DefaultCacheNegativeTTL
+
+	// FlagTraceExporter указывает экспортёр трассировки: none, otlp или stdout.
+	FlagTraceExporter = DefaultTraceExporter
+
+	// FlagTraceEndpoint указывает адрес (host:port) OTLP/gRPC приёмника трассировки;
+	// пустое значение — адрес из OTEL_EXPORTER_OTLP_ENDPOINT или localhost:4317.
+	FlagTraceEndpoint string
+
+	// FlagTraceSampleRatio указывает долю трассируемых запросов, не продолжающих чужую трассировку.
+	FlagTraceSampleRatio = DefaultTraceSampleRatio
+
+	// FlagShutdownDelay указывает, сколько сервис продолжает принимать запросы после сигнала завершения,
+	// отвечая на /readyz кодом 503, чтобы балансировщик успел исключить его из списка адресов.
+	FlagShutdownDelay time.Duration
+
+	// FlagFileSync указывает политику сброса файла хранилища на диск: always, interval или never.
+	FlagFileSync = DefaultFileSync
+
+	// FlagFileSyncInterval указывает период сброса файла хранилища на диск для политики interval.
+	FlagFileSyncInterval = DefaultFileSyncInterval
+
+	// FlagFileCompactInterval указывает период сжатия журнала файла хранилища; 0 отключает сжатие.
+	FlagFileCompactInterval = DefaultFileCompactInterval
 )
 
 // Config структура для JSON-конфигурации
 type Config struct {
-	ServerAddress      string `json:"server_address"`
-	BaseURL            string `json:"base_url"`
-	FileStoragePath    string `json:"file_storage_path"`
-	DatabaseDSN        string `json:"database_dsn"`
-	EnableHTTPS        bool   `json:"enable_https"`
-	TrustedSubnet      string `json:"trusted_subnet"`
-	GRPCAddress        string `json:"grpc_address"`
-	GRPCGatewayAddress string `json:"grpc_gateway_address"`
-	EnableGRPCGateway  bool   `json:"enable_grpc_gateway"`
+	ServerAddress       string  `json:"server_address"`
+	BaseURL             string  `json:"base_url"`
+	FileStoragePath     string  `json:"file_storage_path"`
+	DatabaseDSN         string  `json:"database_dsn"`
+	EnableHTTPS         bool    `json:"enable_https"`
+	TrustedSubnet       string  `json:"trusted_subnet"`
+	GRPCAddress         string  `json:"grpc_address"`
+	GRPCGatewayAddress  string  `json:"grpc_gateway_address"`
+	EnableGRPCGateway   bool    `json:"enable_grpc_gateway"`
+	AliasAlphabet       string  `json:"alias_alphabet"`
+	AliasMinLength      int     `json:"alias_min_length"`
+	AliasMaxLength      int     `json:"alias_max_length"`
+	AliasReserved       string  `json:"alias_reserved"`
+	KeyStrategy         string  `json:"key_strategy"`
+	KeyAlphabet         string  `json:"key_alphabet"`
+	KeyLength           int     `json:"key_length"`
+	KeyNodeID           int     `json:"key_node_id"`
+	ReaperInterval      string  `json:"reaper_interval"`
+	PurgeExpired        bool    `json:"purge_expired"`
+	RestoreWindow       string  `json:"restore_window"`
+	DeletedRetention    string  `json:"deleted_retention"`
+	PurgeInterval       string  `json:"purge_interval"`
+	CacheSize           int     `json:"cache_size"`
+	CacheTTL            string  `json:"cache_ttl"`
+	CacheNegativeTTL    string  `json:"cache_negative_ttl"`
+	TraceExporter       string  `json:"trace_exporter"`
+	TraceEndpoint       string  `json:"trace_endpoint"`
+	TraceSampleRatio    float64 `json:"trace_sample_ratio"`
+	ShutdownDelay       string  `json:"shutdown_delay"`
+	FileSync            string  `json:"file_sync"`
+	FileSyncInterval    string  `json:"file_sync_interval"`
+	FileCompactInterval string  `json:"file_compact_interval"`
 }
 
 // ParseFlags анализирует флаги командной строки и переменные окружения,
@@ -69,6 +231,29 @@ func ParseFlags() {
 	flag.StringVar(&FlagGRPCAddress, "grpc-address", "127.0.0.1:9090", "адрес запуска gRPC-сервера")
 	flag.StringVar(&FlagGRPCGatewayAddr, "grpc-gateway-address", "127.0.0.1:8081", "адрес запуска grpc-gateway HTTP сервера")
 	flag.BoolVar(&FlagEnableGRPCGateway, "enable-grpc-gateway", false, "включить HTTP/REST gRPC-Gateway")
+	flag.StringVar(&FlagAliasAlphabet, "alias-alphabet", DefaultAliasAlphabet, "допустимые символы пользовательского короткого ключа")
+	flag.IntVar(&FlagAliasMinLength, "alias-min-length", DefaultAliasMinLength, "минимальная длина пользовательского короткого ключа")
+	flag.IntVar(&FlagAliasMaxLength, "alias-max-length", DefaultAliasMaxLength, "максимальная длина пользовательского короткого ключа")
+	flag.StringVar(&FlagAliasReserved, "alias-reserved", DefaultAliasReserved, "зарезервированные слова через запятую, недоступные для коротких ключей")
+	flag.StringVar(&FlagKeyStrategy, "key-strategy", DefaultKeyStrategy, "стратегия генерации коротких ключей: random, sequential или hash")
+	flag.StringVar(&FlagKeyAlphabet, "key-alphabet", DefaultKeyAlphabet, "символы генерируемых коротких ключей")
+	flag.IntVar(&FlagKeyLength, "key-length", DefaultKeyLength, "длина генерируемых коротких ключей")
+	flag.IntVar(&FlagKeyNodeID, "key-node-id", 0, "номер экземпляра сервиса (0-1023) для стратегии sequential")
+	flag.DurationVar(&FlagReaperInterval, "reaper-interval", DefaultReaperInterval, "период удаления ссылок с истёкшим сроком действия")
+	flag.BoolVar(&FlagPurgeExpired, "purge-expired", false, "физически удалять ссылки с истёкшим сроком действия")
+	flag.DurationVar(&FlagRestoreWindow, "restore-window", DefaultRestoreWindow, "срок, в течение которого удалённую ссылку можно восстановить")
+	flag.DurationVar(&FlagDeletedRetention, "deleted-retention", DefaultDeletedRetention, "срок хранения удалённых ссылок до окончательного удаления (0 — хранить бессрочно)")
+	flag.DurationVar(&FlagPurgeInterval, "purge-interval", DefaultPurgeInterval, "период окончательного удаления ссылок, удалённых раньше срока хранения")
+	flag.IntVar(&FlagCacheSize, "cache-size", 0, "максимальное количество ссылок в кеше перед хранилищем (0 — без кеша)")
+	flag.DurationVar(&FlagCacheTTL, "cache-ttl", DefaultCacheTTL, "время жизни ссылки в кеше")
+	flag.DurationVar(&FlagCacheNegativeTTL, "cache-negative-ttl", DefaultCacheNegativeTTL, "время жизни в кеше записи о несуществующем ключе (0 — не кешировать)")
+	flag.StringVar(&FlagTraceExporter, "trace-exporter", DefaultTraceExporter, "экспортёр трассировки: none, otlp или stdout")
+	flag.StringVar(&FlagTraceEndpoint, "trace-endpoint", "", "адрес OTLP/gRPC приёмника трассировки (host:port)")
+	flag.Float64Var(&FlagTraceSampleRatio, "trace-sample-ratio", DefaultTraceSampleRatio, "доля трассируемых запросов от 0 до 1")
+	flag.DurationVar(&FlagShutdownDelay, "shutdown-delay", 0, "время обработки запросов после сигнала завершения, в течение которого /readyz отвечает 503")
+	flag.StringVar(&FlagFileSync, "file-sync", DefaultFileSync, "политика сброса файла хранилища на диск: always, interval или never")
+	flag.DurationVar(&FlagFileSyncInterval, "file-sync-interval", DefaultFileSyncInterval, "период сброса файла хранилища на диск для политики interval")
+	flag.DurationVar(&FlagFileCompactInterval, "file-compact-interval", DefaultFileCompactInterval, "период сжатия журнала файла хранилища (0 — не сжимать)")
 
 	flag.Parse()
 
@@ -106,10 +291,133 @@ func ParseFlags() {
 			FlagEnableGRPCGateway = val
 		}
 	}
+	if envAliasAlphabet := os.Getenv("ALIAS_ALPHABET"); envAliasAlphabet != "" {
+		FlagAliasAlphabet = envAliasAlphabet
+	}
+	if envAliasMinLength := os.Getenv("ALIAS_MIN_LENGTH"); envAliasMinLength != "" {
+		if val, err := strconv.Atoi(envAliasMinLength); err == nil {
+			FlagAliasMinLength = val
+		}
+	}
+	if envAliasMaxLength := os.Getenv("ALIAS_MAX_LENGTH"); envAliasMaxLength != "" {
+		if val, err := strconv.Atoi(envAliasMaxLength); err == nil {
+			FlagAliasMaxLength = val
+		}
+	}
+	if envAliasReserved := os.Getenv("ALIAS_RESERVED"); envAliasReserved != "" {
+		FlagAliasReserved = envAliasReserved
+	}
+	if envKeyStrategy := os.Getenv("KEY_STRATEGY"); envKeyStrategy != "" {
+		FlagKeyStrategy = envKeyStrategy
+	}
+	if envKeyAlphabet := os.Getenv("KEY_ALPHABET"); envKeyAlphabet != "" {
+		FlagKeyAlphabet = envKeyAlphabet
+	}
+	if envKeyLength := os.Getenv("KEY_LENGTH"); envKeyLength != "" {
+		if val, err := strconv.Atoi(envKeyLength); err == nil {
+			FlagKeyLength = val
+		}
+	}
+	if envKeyNodeID := os.Getenv("KEY_NODE_ID"); envKeyNodeID != "" {
+		if val, err := strconv.Atoi(envKeyNodeID); err == nil {
+			FlagKeyNodeID = val
+		}
+	}
+	if envReaperInterval := os.Getenv("REAPER_INTERVAL"); envReaperInterval != "" {
+		if val, err := time.ParseDuration(envReaperInterval); err == nil {
+			FlagReaperInterval = val
+		}
+	}
+	if envPurgeExpired := os.Getenv("PURGE_EXPIRED"); envPurgeExpired != "" {
+		if val, err := strconv.ParseBool(envPurgeExpired); err == nil {
+			FlagPurgeExpired = val
+		}
+	}
+	if envRestoreWindow := os.Getenv("RESTORE_WINDOW"); envRestoreWindow != "" {
+		if val, err := time.ParseDuration(envRestoreWindow); err == nil {
+			FlagRestoreWindow = val
+		}
+	}
+	if envDeletedRetention := os.Getenv("DELETED_RETENTION"); envDeletedRetention != "" {
+		if val, err := time.ParseDuration(envDeletedRetention); err == nil {
+			FlagDeletedRetention = val
+		}
+	}
+	if envPurgeInterval := os.Getenv("PURGE_INTERVAL"); envPurgeInterval != "" {
+		if val, err := time.ParseDuration(envPurgeInterval); err == nil {
+			FlagPurgeInterval = val
+		}
+	}
+	if envCacheSize := os.Getenv("CACHE_SIZE"); envCacheSize != "" {
+		if val, err := strconv.Atoi(envCacheSize); err == nil {
+			FlagCacheSize = val
+		}
+	}
+	if envCacheTTL := os.Getenv("CACHE_TTL"); envCacheTTL != "" {
+		if val, err := time.ParseDuration(envCacheTTL); err == nil {
+			FlagCacheTTL = val
+		}
+	}
+	if envCacheNegativeTTL := os.Getenv("CACHE_NEGATIVE_TTL"); envCacheNegativeTTL != "" {
+		if val, err := time.ParseDuration(envCacheNegativeTTL); err == nil {
+			FlagCacheNegativeTTL = val
+		}
+	}
+
+	if envTraceExporter := os.Getenv("TRACE_EXPORTER"); envTraceExporter != "" {
+		FlagTraceExporter = envTraceExporter
+	}
+	if envTraceEndpoint := os.Getenv("TRACE_ENDPOINT"); envTraceEndpoint != "" {
+		FlagTraceEndpoint = envTraceEndpoint
+	}
+	if envTraceSampleRatio := os.Getenv("TRACE_SAMPLE_RATIO"); envTraceSampleRatio != "" {
+		if val, err := strconv.ParseFloat(envTraceSampleRatio, 64); err == nil {
+			FlagTraceSampleRatio = val
+		}
+	}
+
+	if envShutdownDelay := os.Getenv("SHUTDOWN_DELAY"); envShutdownDelay != "" {
+		if val, err := time.ParseDuration(envShutdownDelay); err == nil {
+			FlagShutdownDelay = val
+		}
+	}
+
+	if envFileSync := os.Getenv("FILE_SYNC"); envFileSync != "" {
+		FlagFileSync = envFileSync
+	}
+	if envFileSyncInterval := os.Getenv("FILE_SYNC_INTERVAL"); envFileSyncInterval != "" {
+		if val, err := time.ParseDuration(envFileSyncInterval); err == nil {
+			FlagFileSyncInterval = val
+		}
+	}
+	if envFileCompactInterval := os.Getenv("FILE_COMPACT_INTERVAL"); envFileCompactInterval != "" {
+		if val, err := time.ParseDuration(envFileCompactInterval); err == nil {
+			FlagFileCompactInterval = val
+		}
+	}
 
 	if FlagConfigFilePath != "" {
 		loadConfigFromFile(FlagConfigFilePath)
 	}
+
+	FlagDatabaseDriver, FlagDatabaseDSN = ParseDatabaseDSN(FlagDatabaseDSN)
+}
+
+// ParseDatabaseDSN определяет драйвер базы данных по схеме строки подключения.
+// Для строки вида sqlite:///path/db возвращает DatabaseDriverSQLite и путь к файлу базы данных,
+// для redis:// и rediss:// — DatabaseDriverRedis и исходную строку,
+// для остальных непустых строк — DatabaseDriverPostgres и исходную строку.
+func ParseDatabaseDSN(dsn string) (driver string, source string) {
+	switch {
+	case dsn == "":
+		return "", ""
+	case strings.HasPrefix(dsn, sqliteScheme):
+		return DatabaseDriverSQLite, strings.TrimPrefix(dsn, sqliteScheme)
+	case strings.HasPrefix(dsn, redisScheme), strings.HasPrefix(dsn, redisTLSScheme):
+		return DatabaseDriverRedis, dsn
+	default:
+		return DatabaseDriverPostgres, dsn
+	}
 }
 
 // loadConfigFromFile загружает конфигурацию из JSON-файла
@@ -154,4 +462,91 @@ func loadConfigFromFile(path string) {
 	if !FlagEnableGRPCGateway {
 		FlagEnableGRPCGateway = cfg.EnableGRPCGateway
 	}
+	if FlagAliasAlphabet == DefaultAliasAlphabet && cfg.AliasAlphabet != "" {
+		FlagAliasAlphabet = cfg.AliasAlphabet
+	}
+	if FlagAliasMinLength == DefaultAliasMinLength && cfg.AliasMinLength != 0 {
+		FlagAliasMinLength = cfg.AliasMinLength
+	}
+	if FlagAliasMaxLength == DefaultAliasMaxLength && cfg.AliasMaxLength != 0 {
+		FlagAliasMaxLength = cfg.AliasMaxLength
+	}
+	if FlagAliasReserved == DefaultAliasReserved && cfg.AliasReserved != "" {
+		FlagAliasReserved = cfg.AliasReserved
+	}
+	if FlagKeyStrategy == DefaultKeyStrategy && cfg.KeyStrategy != "" {
+		FlagKeyStrategy = cfg.KeyStrategy
+	}
+	if FlagKeyAlphabet == DefaultKeyAlphabet && cfg.KeyAlphabet != "" {
+		FlagKeyAlphabet = cfg.KeyAlphabet
+	}
+	if FlagKeyLength == DefaultKeyLength && cfg.KeyLength != 0 {
+		FlagKeyLength = cfg.KeyLength
+	}
+	if FlagKeyNodeID == 0 {
+		FlagKeyNodeID = cfg.KeyNodeID
+	}
+	if FlagReaperInterval == DefaultReaperInterval && cfg.ReaperInterval != "" {
+		if val, err := time.ParseDuration(cfg.ReaperInterval); err == nil {
+			FlagReaperInterval = val
+		}
+	}
+	if !FlagPurgeExpired {
+		FlagPurgeExpired = cfg.PurgeExpired
+	}
+	if FlagRestoreWindow == DefaultRestoreWindow && cfg.RestoreWindow != "" {
+		if val, err := time.ParseDuration(cfg.RestoreWindow); err == nil {
+			FlagRestoreWindow = val
+		}
+	}
+	if FlagDeletedRetention == DefaultDeletedRetention && cfg.DeletedRetention != "" {
+		if val, err := time.ParseDuration(cfg.DeletedRetention); err == nil {
+			FlagDeletedRetention = val
+		}
+	}
+	if FlagPurgeInterval == DefaultPurgeInterval && cfg.PurgeInterval != "" {
+		if val, err := time.ParseDuration(cfg.PurgeInterval); err == nil {
+			FlagPurgeInterval = val
+		}
+	}
+	if FlagCacheSize == 0 {
+		FlagCacheSize = cfg.CacheSize
+	}
+	if FlagCacheTTL == DefaultCacheTTL && cfg.CacheTTL != "" {
+		if val, err := time.ParseDuration(cfg.CacheTTL); err == nil {
+			FlagCacheTTL = val
+		}
+	}
+	if FlagCacheNegativeTTL == DefaultCacheNegativeTTL && cfg.CacheNegativeTTL != "" {
+		if val, err := time.ParseDuration(cfg.CacheNegativeTTL); err == nil {
+			FlagCacheNegativeTTL = val
+		}
+	}
+	if FlagTraceExporter == DefaultTraceExporter && cfg.TraceExporter != "" {
+		FlagTraceExporter = cfg.TraceExporter
+	}
+	if FlagTraceEndpoint == "" {
+		FlagTraceEndpoint = cfg.TraceEndpoint
+	}
+	if FlagTraceSampleRatio == DefaultTraceSampleRatio && cfg.TraceSampleRatio != 0 {
+		FlagTraceSampleRatio = cfg.TraceSampleRatio
+	}
+	if FlagShutdownDelay == 0 && cfg.ShutdownDelay != "" {
+		if val, err := time.ParseDuration(cfg.ShutdownDelay); err == nil {
+			FlagShutdownDelay = val
+		}
+	}
+	if FlagFileSync == DefaultFileSync && cfg.FileSync != "" {
+		FlagFileSync = cfg.FileSync
+	}
+	if FlagFileSyncInterval == DefaultFileSyncInterval && cfg.FileSyncInterval != "" {
+		if val, err := time.ParseDuration(cfg.FileSyncInterval); err == nil {
+			FlagFileSyncInterval = val
+		}
+	}
+	if FlagFileCompactInterval == DefaultFileCompactInterval && cfg.FileCompactInterval != "" {
+		if val, err := time.ParseDuration(cfg.FileCompactInterval); err == nil {
+			FlagFileCompactInterval = val
+		}
+	}
 }
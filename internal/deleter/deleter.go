@@ -0,0 +1,212 @@
+// Package deleter реализует фоновое удаление ссылок пользователей.
+//
+// Запросы на удаление из HTTP и gRPC API ставятся в общую очередь и сразу подтверждаются,
+// а Deleter объединяет ключи из многих запросов и удаляет их пакетами методом storage.DeleteBatch
+// отдельно для каждого владельца. RunPurger окончательно удаляет ссылки, срок хранения которых
+// после удаления истёк.
+package deleter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
+	"github.com/dsemenov12/shorturl/internal/storage"
+)
+
+const (
+	// defaultBufferSize — размер очереди запросов на удаление.
+	defaultBufferSize = 1024
+	// batchSize — максимальное количество ключей, удаляемых за одно обращение к хранилищу.
+	batchSize = 500
+	// flushInterval — период, с которым накопленные ключи удаляются из хранилища.
+	flushInterval = time.Second
+	// maxAttempts — количество попыток удалить пакет ключей, прежде чем он будет отброшен.
+	maxAttempts = 3
+)
+
+// retryDelay — задержка перед повторной попыткой; перед n-й повторной попыткой ожидание равно n*retryDelay.
+var retryDelay = 200 * time.Millisecond
+
+var (
+	// ErrQueueFull возвращается, если очередь запросов на удаление переполнена.
+	ErrQueueFull = errors.New("deletion queue is full")
+	// ErrStopped возвращается, если Deleter остановлен или не создан.
+	ErrStopped = errors.New("deletion service is stopped")
+)
+
+// job — запрос пользователя на удаление ссылок.
+type job struct {
+	userID    string
+	shortKeys []string
+}
+
+// Deleter принимает запросы на удаление ссылок и асинхронно выполняет их пакетами.
+type Deleter struct {
+	storage storage.Storage
+	jobs    chan job
+	done    chan struct{}
+
+	mx      sync.RWMutex
+	stopped bool
+}
+
+// NewDeleter создаёт Deleter, удаляющий ссылки из указанного хранилища.
+func NewDeleter(storage storage.Storage) *Deleter {
+	return &Deleter{
+		storage: storage,
+		jobs:    make(chan job, defaultBufferSize),
+		done:    make(chan struct{}),
+	}
+}
+
+// Enqueue ставит в очередь удаление ссылок shortKeys пользователя userID и не ждёт его выполнения.
+// Ключ может быть передан и полным сокращённым URL: используется последний сегмент пути.
+// Возвращает ErrQueueFull, если очередь переполнена, и ErrStopped после остановки Deleter
+// или при вызове на nil-получателе.
+func (d *Deleter) Enqueue(userID string, shortKeys []string) error {
+	if d == nil {
+		return ErrStopped
+	}
+
+	keys := make([]string, 0, len(shortKeys))
+	for _, shortKey := range shortKeys {
+		shortKey = shortKey[strings.LastIndex(shortKey, "/")+1:]
+		if shortKey != "" {
+			keys = append(keys, shortKey)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+
+	if d.stopped {
+		return ErrStopped
+	}
+
+	select {
+	case d.jobs <- job{userID: userID, shortKeys: keys}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run читает запросы из очереди и удаляет накопленные ключи пакетами по batchSize или раз в flushInterval.
+// Неудачные пакеты повторяются до maxAttempts раз. После отмены контекста новые запросы не принимаются,
+// оставшиеся в очереди запросы выполняются, и метод завершается.
+func (d *Deleter) Run(ctx context.Context) {
+	defer close(d.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	// Удаление не прерывается отменой ctx, чтобы не потерять уже подтверждённые запросы
+	deleteCtx := context.WithoutCancel(ctx)
+
+	pending := make(map[string][]string)
+	count := 0
+	add := func(j job) {
+		pending[j.userID] = append(pending[j.userID], j.shortKeys...)
+		count += len(j.shortKeys)
+		if count >= batchSize {
+			d.flush(deleteCtx, pending)
+			count = 0
+		}
+	}
+
+	for {
+		select {
+		case j := <-d.jobs:
+			add(j)
+		case <-ticker.C:
+			d.flush(deleteCtx, pending)
+			count = 0
+		case <-ctx.Done():
+			d.mx.Lock()
+			d.stopped = true
+			d.mx.Unlock()
+
+			for {
+				select {
+				case j := <-d.jobs:
+					add(j)
+				default:
+					d.flush(deleteCtx, pending)
+					return
+				}
+			}
+		}
+	}
+}
+
+// Wait ожидает завершения Run, то есть выполнения всех принятых запросов после отмены контекста.
+func (d *Deleter) Wait() {
+	<-d.done
+}
+
+// flush удаляет накопленные ключи каждого пользователя пакетами по batchSize и очищает pending.
+func (d *Deleter) flush(ctx context.Context, pending map[string][]string) {
+	for userID, shortKeys := range pending {
+		for start := 0; start < len(shortKeys); start += batchSize {
+			d.deleteBatch(ctx, userID, shortKeys[start:min(start+batchSize, len(shortKeys))])
+		}
+		delete(pending, userID)
+	}
+}
+
+// deleteBatch удаляет ссылки пользователя, повторяя неудачные попытки с нарастающей задержкой.
+func (d *Deleter) deleteBatch(ctx context.Context, userID string, shortKeys []string) {
+	ctx = context.WithValue(ctx, auth.UserIDKey, userID)
+
+	for attempt := 1; ; attempt++ {
+		err := d.storage.DeleteBatch(ctx, shortKeys)
+		if err == nil {
+			return
+		}
+		if attempt >= maxAttempts {
+			logger.Log.Error("failed to delete urls", zap.String("user_id", userID), zap.Int("count", len(shortKeys)), zap.Error(err))
+			return
+		}
+
+		logger.Log.Warn("retrying urls deletion", zap.String("user_id", userID), zap.Int("attempt", attempt), zap.Error(err))
+		time.Sleep(time.Duration(attempt) * retryDelay)
+	}
+}
+
+// RunPurger раз в interval окончательно удаляет из хранилища ссылки, помеченные удалёнными
+// раньше чем retention назад, до отмены контекста. Неположительные interval или retention
+// отключают окончательное удаление.
+func RunPurger(ctx context.Context, storage storage.Storage, interval, retention time.Duration) {
+	if interval <= 0 || retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := storage.PurgeDeleted(ctx, now.Add(-retention).UTC())
+			if err != nil {
+				logger.Log.Error("failed to purge deleted links", zap.Error(err))
+				continue
+			}
+			if count > 0 {
+				logger.Log.Info("deleted links purged", zap.Int64("count", count))
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package deleter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/dsemenov12/shorturl/internal/storage/mocks"
+)
+
+func TestDeleter_Run(t *testing.T) {
+	store := memory.NewStorage()
+	for _, owner := range []string{"user1", "user2"} {
+		ctx := context.WithValue(context.Background(), auth.UserIDKey, owner)
+		for i := 1; i <= 2; i++ {
+			_, err := store.Set(ctx, models.Link{ShortKey: fmt.Sprintf("%s-%d", owner, i), OriginalURL: fmt.Sprintf("https://example.com/%s/%d", owner, i)})
+			require.NoError(t, err)
+		}
+	}
+
+	deleter := NewDeleter(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	go deleter.Run(ctx)
+
+	require.NoError(t, deleter.Enqueue("user1", []string{"user1-1", "http://localhost:8080/user1-2"}))
+	require.NoError(t, deleter.Enqueue("user2", []string{"user2-1", "user1-1"}))
+	require.NoError(t, deleter.Enqueue("user2", nil))
+
+	// После отмены контекста принятые запросы должны быть выполнены
+	cancel()
+	deleter.Wait()
+
+	for shortKey, deleted := range map[string]bool{"user1-1": true, "user1-2": true, "user2-1": true, "user2-2": false} {
+		link, err := store.Get(context.Background(), shortKey)
+		require.NoError(t, err)
+		assert.Equal(t, deleted, link.IsDeleted, shortKey)
+	}
+
+	assert.ErrorIs(t, deleter.Enqueue("user1", []string{"user1-1"}), ErrStopped)
+}
+
+func TestDeleter_Batching(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockStorage(ctrl)
+
+	// Ключи из разных запросов одного пользователя удаляются одним вызовом
+	s.EXPECT().DeleteBatch(gomock.Any(), []string{"a", "b", "c"}).DoAndReturn(func(ctx context.Context, _ []string) error {
+		assert.Equal(t, "user1", auth.UserIDFromContext(ctx))
+		return nil
+	})
+
+	deleter := NewDeleter(s)
+	require.NoError(t, deleter.Enqueue("user1", []string{"a", "b"}))
+	require.NoError(t, deleter.Enqueue("user1", []string{"c"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deleter.Run(ctx)
+}
+
+func TestDeleter_Retry(t *testing.T) {
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = time.Millisecond
+
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockStorage(ctrl)
+	gomock.InOrder(
+		s.EXPECT().DeleteBatch(gomock.Any(), []string{"a"}).Return(errors.New("connection reset")),
+		s.EXPECT().DeleteBatch(gomock.Any(), []string{"a"}).Return(nil),
+		s.EXPECT().DeleteBatch(gomock.Any(), []string{"b"}).Return(errors.New("connection reset")).Times(maxAttempts),
+	)
+
+	deleter := NewDeleter(s)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	deleter.deleteBatch(ctx, "user1", []string{"a"})
+	deleter.deleteBatch(ctx, "user1", []string{"b"})
+}
+
+func TestDeleter_Enqueue(t *testing.T) {
+	var nilDeleter *Deleter
+	assert.ErrorIs(t, nilDeleter.Enqueue("user1", []string{"a"}), ErrStopped)
+
+	deleter := NewDeleter(memory.NewStorage())
+	for i := 0; i < defaultBufferSize; i++ {
+		require.NoError(t, deleter.Enqueue("user1", []string{"a"}))
+	}
+	assert.ErrorIs(t, deleter.Enqueue("user1", []string{"a"}), ErrQueueFull)
+}
+
+func TestRunPurger(t *testing.T) {
+	store := memory.NewStorage()
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), auth.UserIDKey, "user1"))
+
+	for i := 1; i <= 2; i++ {
+		_, err := store.Set(ctx, models.Link{ShortKey: fmt.Sprintf("short%d", i), OriginalURL: fmt.Sprintf("https://example.com/%d", i)})
+		require.NoError(t, err)
+		require.NoError(t, store.Delete(ctx, fmt.Sprintf("short%d", i)))
+	}
+	store.SetDeletedAt("short1", time.Now().Add(-2*time.Hour))
+
+	done := make(chan struct{})
+	go func() {
+		RunPurger(ctx, store, 10*time.Millisecond, time.Hour)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, err := store.Get(ctx, "short1")
+		return errors.Is(err, storage.ErrNotFound)
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	_, err := store.Get(context.Background(), "short2")
+	assert.NoError(t, err, "Links deleted within the retention period should be kept")
+
+	// Нулевой срок хранения отключает окончательное удаление
+	RunPurger(context.Background(), store, 10*time.Millisecond, 0)
+}
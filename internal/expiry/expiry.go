@@ -0,0 +1,69 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
+	"github.com/dsemenov12/shorturl/internal/storage"
+)
+
+// ErrInvalidExpiry возвращается, если срок действия ссылки задан некорректно.
+var ErrInvalidExpiry = errors.New("invalid link expiry")
+
+// maxTTL — наибольшее время жизни ссылки в секундах, представимое значением time.Duration.
+const maxTTL = math.MaxInt64 / int64(time.Second)
+
+// Resolve вычисляет момент окончания действия ссылки по времени жизни ttl в секундах
+// или абсолютному моменту expiresAt. Одновременно можно указать только один из параметров.
+// Нулевой результат означает бессрочную ссылку.
+func Resolve(ttl int64, expiresAt *time.Time, now time.Time) (time.Time, error) {
+	switch {
+	case ttl != 0 && expiresAt != nil:
+		return time.Time{}, ErrInvalidExpiry
+	case ttl < 0 || ttl > maxTTL:
+		return time.Time{}, ErrInvalidExpiry
+	case ttl > 0:
+		return now.Add(time.Duration(ttl) * time.Second).UTC(), nil
+	case expiresAt != nil:
+		if !expiresAt.After(now) {
+			return time.Time{}, ErrInvalidExpiry
+		}
+		return expiresAt.UTC(), nil
+	default:
+		return time.Time{}, nil
+	}
+}
+
+// RunReaper раз в interval удаляет из хранилища ссылки с истёкшим сроком действия
+// до отмены контекста. При purge=true ссылки удаляются физически,
+// иначе помечаются как удалённые (если хранилище это поддерживает).
+// Неположительный interval отключает удаление.
+func RunReaper(ctx context.Context, storage storage.Storage, interval time.Duration, purge bool) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := storage.DeleteExpired(ctx, now.UTC(), purge)
+			if err != nil {
+				logger.Log.Error("failed to delete expired links", zap.Error(err))
+				continue
+			}
+			if count > 0 {
+				logger.Log.Info("expired links deleted", zap.Int64("count", count), zap.Bool("purge", purge))
+			}
+		}
+	}
+}
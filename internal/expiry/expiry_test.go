@@ -0,0 +1,113 @@
+package expiry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := now.Add(24 * time.Hour)
+	past := now.Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		ttl       int64
+		expiresAt *time.Time
+		want      time.Time
+		wantErr   bool
+	}{
+		{
+			name: "no expiry",
+		},
+		{
+			name: "ttl",
+			ttl:  60,
+			want: now.Add(time.Minute),
+		},
+		{
+			name:      "expires_at",
+			expiresAt: &future,
+			want:      future,
+		},
+		{
+			name:    "negative ttl",
+			ttl:     -1,
+			wantErr: true,
+		},
+		{
+			name:    "ttl overflowing duration",
+			ttl:     maxTTL + 1,
+			wantErr: true,
+		},
+		{
+			name: "max ttl",
+			ttl:  maxTTL,
+			want: now.Add(time.Duration(maxTTL) * time.Second),
+		},
+		{
+			name:      "expires_at in the past",
+			expiresAt: &past,
+			wantErr:   true,
+		},
+		{
+			name:      "both ttl and expires_at",
+			ttl:       60,
+			expiresAt: &future,
+			wantErr:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Resolve(test.ttl, test.expiresAt, now)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidExpiry)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestRunReaper(t *testing.T) {
+	store := memory.NewStorage()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, err := store.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com", ExpiresAt: time.Now().Add(-time.Minute)})
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		RunReaper(ctx, store, 10*time.Millisecond, false)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		link, err := store.Get(ctx, "short1")
+		return err == nil && link.IsDeleted
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestRunReaper_Disabled(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		RunReaper(context.Background(), memory.NewStorage(), 0, false)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunReaper should return immediately for a non-positive interval")
+	}
+}
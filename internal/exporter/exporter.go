@@ -0,0 +1,254 @@
+// Package exporter реализует выгрузку ссылок пользователя в форматах CSV, JSON Lines и JSON.
+//
+// Ссылки записываются по мере чтения из итератора хранилища storage.UserLinkSeq
+// и не накапливаются в памяти, поэтому размер выгрузки не ограничен.
+// Выгрузка в CSV и JSON Lines может быть повторно загружена пакетом importer.
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/tags"
+)
+
+// Format — формат выгрузки.
+type Format string
+
+// Форматы выгрузки.
+const (
+	// FormatCSV — CSV со строкой заголовка.
+	FormatCSV Format = "csv"
+	// FormatJSONL — JSON Lines: по одному объекту Record в строке.
+	FormatJSONL Format = "jsonl"
+	// FormatJSON — JSON-массив объектов Record.
+	FormatJSON Format = "json"
+)
+
+// ErrUnknownFormat возвращается для неизвестного формата выгрузки.
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// csvHeader — столбцы выгрузки в CSV.
+var csvHeader = []string{"short_url", "original_url", "created_at", "expires_at", "max_clicks", "clicks_left", "clicks", "is_deleted", "tags"}
+
+// Record — ссылка в выгрузке.
+type Record struct {
+	ShortURL    string     `json:"short_url"`             // Сокращённый URL
+	OriginalURL string     `json:"original_url"`          // Исходный URL
+	CreatedAt   time.Time  `json:"created_at"`            // Момент создания ссылки
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`  // Момент окончания действия, если он задан
+	MaxClicks   int64      `json:"max_clicks,omitempty"`  // Максимальное количество переходов, если оно ограничено
+	ClicksLeft  *int64     `json:"clicks_left,omitempty"` // Оставшееся количество переходов, если оно ограничено
+	Clicks      int64      `json:"clicks"`                // Количество переходов по ссылке
+	IsDeleted   bool       `json:"is_deleted"`            // Признак удаления ссылки
+	Tags        []string   `json:"tags,omitempty"`        // Метки ссылки
+}
+
+// NewRecord преобразует ссылку пользователя в запись выгрузки.
+func NewRecord(item models.UserLink) Record {
+	record := Record{
+		ShortURL:    config.FlagBaseAddr + "/" + item.Link.ShortKey,
+		OriginalURL: item.Link.OriginalURL,
+		CreatedAt:   item.Link.CreatedAt.UTC(),
+		MaxClicks:   item.Link.MaxClicks,
+		Clicks:      item.Clicks,
+		IsDeleted:   item.Link.IsDeleted,
+		Tags:        item.Link.Tags,
+	}
+	if !item.Link.ExpiresAt.IsZero() {
+		expiresAt := item.Link.ExpiresAt.UTC()
+		record.ExpiresAt = &expiresAt
+	}
+	if item.Link.MaxClicks > 0 {
+		clicksLeft := item.Link.ClicksLeft
+		record.ClicksLeft = &clicksLeft
+	}
+
+	return record
+}
+
+// ParseFormat возвращает формат выгрузки по его названию; пустая строка означает FormatJSON.
+func ParseFormat(format string) (Format, error) {
+	switch Format(format) {
+	case "":
+		return FormatJSON, nil
+	case FormatCSV, FormatJSONL, FormatJSON:
+		return Format(format), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// ContentType возвращает MIME-тип выгрузки в формате format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	default:
+		return "application/json"
+	}
+}
+
+// Export записывает в w ссылки links в формате format и возвращает количество байт, записанных в w.
+// Данные буферизуются небольшими блоками; при ошибке чтения ссылок незаписанный остаток буфера отбрасывается,
+// поэтому нулевое количество байт означает, что в w ничего не записано.
+func Export(w io.Writer, format Format, links storage.UserLinkSeq) (int64, error) {
+	counter := &countingWriter{w: w}
+	buf := bufio.NewWriter(counter)
+
+	var encoder recordEncoder
+	switch format {
+	case FormatCSV:
+		encoder = &csvEncoder{w: csv.NewWriter(buf)}
+	case FormatJSONL:
+		encoder = &jsonlEncoder{enc: json.NewEncoder(buf)}
+	case FormatJSON:
+		encoder = &jsonEncoder{w: buf}
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if err := encoder.begin(); err != nil {
+		return counter.n, err
+	}
+	for item, err := range links {
+		if err != nil {
+			return counter.n, err
+		}
+		if err = encoder.encode(NewRecord(item)); err != nil {
+			return counter.n, err
+		}
+	}
+	if err := encoder.end(); err != nil {
+		return counter.n, err
+	}
+
+	err := buf.Flush()
+	return counter.n, err
+}
+
+// recordEncoder записывает записи выгрузки в одном из форматов.
+type recordEncoder interface {
+	begin() error
+	encode(record Record) error
+	end() error
+}
+
+// csvEncoder записывает выгрузку в CSV.
+type csvEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvEncoder) begin() error {
+	return e.w.Write(csvHeader)
+}
+
+func (e *csvEncoder) encode(record Record) error {
+	var expiresAt, maxClicks, clicksLeft string
+	if record.ExpiresAt != nil {
+		expiresAt = record.ExpiresAt.Format(time.RFC3339)
+	}
+	if record.MaxClicks > 0 {
+		maxClicks = strconv.FormatInt(record.MaxClicks, 10)
+	}
+	if record.ClicksLeft != nil {
+		clicksLeft = strconv.FormatInt(*record.ClicksLeft, 10)
+	}
+
+	return e.w.Write([]string{
+		record.ShortURL,
+		record.OriginalURL,
+		record.CreatedAt.Format(time.RFC3339),
+		expiresAt,
+		maxClicks,
+		clicksLeft,
+		strconv.FormatInt(record.Clicks, 10),
+		strconv.FormatBool(record.IsDeleted),
+		tags.Join(record.Tags),
+	})
+}
+
+func (e *csvEncoder) end() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonlEncoder записывает выгрузку в JSON Lines.
+type jsonlEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlEncoder) begin() error {
+	return nil
+}
+
+func (e *jsonlEncoder) encode(record Record) error {
+	return e.enc.Encode(record)
+}
+
+func (e *jsonlEncoder) end() error {
+	return nil
+}
+
+// jsonEncoder записывает выгрузку JSON-массивом, по одному элементу в строке.
+type jsonEncoder struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (e *jsonEncoder) begin() error {
+	_, err := e.w.WriteString("[")
+	return err
+}
+
+func (e *jsonEncoder) encode(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n"
+	if e.count == 0 {
+		separator = "\n"
+	}
+	e.count++
+
+	if _, err = e.w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonEncoder) end() error {
+	if e.count > 0 {
+		if _, err := e.w.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+	_, err := e.w.WriteString("]\n")
+	return err
+}
+
+// countingWriter подсчитывает байты, записанные в w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,143 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsemenov12/shorturl/internal/importer"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+)
+
+var createdAt = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// links возвращает итератор по items, завершающийся ошибкой err, если она задана.
+func links(err error, items ...models.UserLink) storage.UserLinkSeq {
+	return func(yield func(models.UserLink, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(models.UserLink{}, err)
+		}
+	}
+}
+
+func testLinks() []models.UserLink {
+	return []models.UserLink{
+		{Link: models.Link{ShortKey: "short1", OriginalURL: "https://example.com/1", CreatedAt: createdAt, Tags: []string{"promo"}}, Clicks: 7},
+		{Link: models.Link{
+			ShortKey:    "short2",
+			OriginalURL: "https://example.com/2?a=1,2",
+			CreatedAt:   createdAt.Add(time.Minute),
+			ExpiresAt:   createdAt.Add(time.Hour),
+			MaxClicks:   5,
+			ClicksLeft:  3,
+			IsDeleted:   true,
+			Tags:        []string{"news", "promo"},
+		}, Clicks: 2},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+
+	format, err = ParseFormat("csv")
+	require.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+	assert.Equal(t, "text/csv; charset=utf-8", format.ContentType())
+
+	_, err = ParseFormat("xml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestExport_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := Export(&buf, FormatCSV, links(nil, testLinks()...))
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	assert.Equal(t, strings.Join([]string{
+		"short_url,original_url,created_at,expires_at,max_clicks,clicks_left,clicks,is_deleted,tags",
+		"/short1,https://example.com/1,2024-01-01T10:00:00Z,,,,7,false,promo",
+		`/short2,"https://example.com/2?a=1,2",2024-01-01T10:01:00Z,2024-01-01T11:00:00Z,5,3,2,true,"news,promo"`,
+		"",
+	}, "\n"), buf.String())
+}
+
+func TestExport_JSONL(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := Export(&buf, FormatJSONL, links(nil, testLinks()...))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"short_url":"/short1","original_url":"https://example.com/1","created_at":"2024-01-01T10:00:00Z","clicks":7,"is_deleted":false,"tags":["promo"]}`, lines[0])
+
+	var record Record
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	assert.True(t, record.IsDeleted)
+	assert.Equal(t, int64(3), *record.ClicksLeft)
+	assert.Equal(t, []string{"news", "promo"}, record.Tags)
+}
+
+func TestExport_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := Export(&buf, FormatJSON, links(nil, testLinks()...))
+	require.NoError(t, err)
+
+	var records []Record
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &records))
+	require.Len(t, records, 2)
+	assert.Equal(t, "/short2", records[1].ShortURL)
+	assert.True(t, createdAt.Add(time.Hour).Equal(*records[1].ExpiresAt))
+
+	buf.Reset()
+	_, err = Export(&buf, FormatJSON, links(nil))
+	require.NoError(t, err)
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestExport_Error(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := Export(&buf, FormatJSON, links(errors.New("connection reset"), testLinks()...))
+	assert.EqualError(t, err, "connection reset")
+	assert.Zero(t, n, "Buffered output should be discarded on error")
+	assert.Zero(t, buf.Len())
+}
+
+func TestExport_Reimport(t *testing.T) {
+	item := testLinks()[0]
+	item.Link.Tags = []string{"news", "promo"}
+
+	for _, format := range []Format{FormatCSV, FormatJSONL} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := Export(&buf, format, links(nil, item))
+			require.NoError(t, err)
+
+			s := memory.NewStorage()
+			result, err := importer.New(s, nil).Import(context.Background(), &buf, importer.Format(format))
+			require.NoError(t, err)
+			assert.Equal(t, 1, result.Imported)
+
+			link, err := s.Get(context.Background(), "short1")
+			require.NoError(t, err)
+			assert.Equal(t, "https://example.com/1", link.OriginalURL)
+			assert.Equal(t, []string{"news", "promo"}, link.Tags)
+		})
+	}
+}
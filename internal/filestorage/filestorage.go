@@ -1,72 +1,210 @@
 package filestorage
 
 import (
-	"bufio"
 	"context"
-	"encoding/json"
-	"os"
-	"strconv"
+	"errors"
+	"time"
 
-	"github.com/dsemenov12/shorturl/internal/config"
+	"go.uber.org/zap"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
+	"github.com/dsemenov12/shorturl/internal/models"
 	"github.com/dsemenov12/shorturl/internal/storage"
 )
 
 // ShortURLJSON представляет структуру данных для хранения сокращенного URL и его оригинала в JSON-формате.
 type ShortURLJSON struct {
-	UUID        string `json:"uuid"`         // Уникальный идентификатор для записи
-	ShortURL    string `json:"short_url"`    // Сокращенный URL
-	OriginalURL string `json:"original_url"` // Оригинальный URL
+	UUID         string     `json:"uuid"`                    // Идентификатор записи, возрастающий в пределах файла
+	ShortURL     string     `json:"short_url"`               // Сокращенный URL
+	OriginalURL  string     `json:"original_url"`            // Оригинальный URL
+	UserID       string     `json:"user_id,omitempty"`       // Идентификатор владельца ссылки
+	CreatedAt    *time.Time `json:"created_at,omitempty"`    // Момент создания ссылки
+	IsDeleted    bool       `json:"is_deleted,omitempty"`    // Признак удаления ссылки
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`    // Момент удаления ссылки
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`    // Момент окончания действия ссылки
+	MaxClicks    int64      `json:"max_clicks,omitempty"`    // Максимальное количество переходов
+	ClicksLeft   *int64     `json:"clicks_left,omitempty"`   // Оставшееся количество переходов при MaxClicks > 0
+	PasswordHash string     `json:"password_hash,omitempty"` // bcrypt-хеш пароля ссылки
+	Tags         []string   `json:"tags,omitempty"`          // Метки ссылки
+
+	History []models.HistoryItem `json:"history,omitempty"` // История изменений URL от ранних значений к поздним; только в снимке журнала
 }
 
-// Save сохраняет данные о сокращенных URL в файл в формате JSON.
-func Save(storageData map[string]string) error {
-	var iter = 1
-	var data []byte
+// deletionTimer — хранилище, в котором можно заменить момент удаления ссылки.
+type deletionTimer interface {
+	SetDeletedAt(shortKey string, deletedAt time.Time)
+}
+
+// clicksCounter — хранилище, в котором можно заменить остаток переходов по ссылке.
+type clicksCounter interface {
+	SetClicksLeft(shortKey string, clicksLeft int64)
+}
 
-	file, err := os.OpenFile(config.FlagFileStoragePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+// historyKeeper — хранилище, в котором можно заменить историю изменений URL ссылки.
+type historyKeeper interface {
+	SetHistory(shortKey string, history []models.HistoryItem)
+}
+
+// Load применяет записи журнала j к хранилищу s от имени владельцев записей.
+// Записи применяются в порядке журнала: создание, изменение URL, откат, удаление, восстановление ссылки
+// и переход по ссылке с ограничением количества переходов. Записи о создании с истёкшим сроком действия
+// пропускаются. Повторная запись о создании для уже загруженного ключа (например, из файла прежнего формата)
+// применяется как изменение или удаление ссылки. Если s реализует SetDeletedAt, удалённым ссылкам
+// возвращается момент удаления из журнала; удалённые ссылки без него считаются удалёнными в момент загрузки.
+// Если s реализует SetClicksLeft, ссылкам возвращается остаток переходов из журнала,
+// иначе каждая запись о переходе применяется вызовом Consume. Если s реализует SetHistory,
+// ссылкам из снимка возвращается история изменений URL, сохранённая при сжатии журнала.
+func Load(j *Journal, s storage.Storage) error {
+	records, err := j.ReadAll()
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	for key, value := range storageData {
-		shortURLJSON := ShortURLJSON{
-			UUID:        strconv.Itoa(iter),
-			ShortURL:    key,
-			OriginalURL: value,
+	now := time.Now()
+	timer, _ := s.(deletionTimer)
+	counter, _ := s.(clicksCounter)
+	keeper, _ := s.(historyKeeper)
+	setClicksLeft := func(link models.Link) {
+		if counter != nil && link.MaxClicks > 0 {
+			counter.SetClicksLeft(link.ShortKey, link.ClicksLeft)
 		}
-
-		data, err = json.Marshal(shortURLJSON)
-		if err != nil {
-			return err
+	}
+	markDeleted := func(ctx context.Context, link models.Link) {
+		s.Delete(ctx, link.ShortKey)
+		if timer != nil && !link.DeletedAt.IsZero() {
+			timer.SetDeletedAt(link.ShortKey, link.DeletedAt)
 		}
-		data = append(data, '\n')
+	}
+
+	for _, record := range records {
+		link := record.link()
+		ctx := context.WithValue(context.TODO(), auth.UserIDKey, link.UserID)
 
-		iter++
+		switch record.Op {
+		case OpUpdate:
+			s.Update(ctx, link.ShortKey, link.OriginalURL)
+		case OpRollback:
+			s.Rollback(ctx, link.ShortKey)
+		case OpDelete:
+			markDeleted(ctx, link)
+		case OpRestore:
+			s.Restore(ctx, link.ShortKey, time.Time{})
+		case OpConsume:
+			if counter != nil {
+				setClicksLeft(link)
+			} else {
+				s.Consume(ctx, link.ShortKey)
+			}
+		default:
+			if link.Expired(now) {
+				continue
+			}
+			if link.IsDeleted && link.DeletedAt.IsZero() {
+				link.DeletedAt = now.UTC()
+			}
+
+			shortKey, err := s.Set(ctx, link)
+			exists := errors.Is(err, storage.ErrKeyExists) || (errors.Is(err, storage.ErrConflict) && shortKey == link.ShortKey)
+			if err != nil && !exists {
+				continue
+			}
+			setClicksLeft(link)
+			if keeper != nil && len(record.History) > 0 {
+				keeper.SetHistory(link.ShortKey, record.History)
+			}
+			if !exists {
+				continue
+			}
+			if link.IsDeleted {
+				markDeleted(ctx, link)
+			} else {
+				s.Update(ctx, link.ShortKey, link.OriginalURL)
+			}
+		}
 	}
 
-	_, err = file.Write(data)
-	return err
+	return nil
 }
 
-// Load загружает данные из файла и сохраняет их в хранилище.
-func Load(storage storage.Storage) error {
-	var shortURLJSON *ShortURLJSON
-
-	file, err := os.OpenFile(config.FlagFileStoragePath, os.O_RDONLY, 0666)
-	if err != nil {
-		return err
+// RunCompactor периодически вызывает compact, пока не будет отменён ctx.
+// compact должен заменить журнал снимком текущего состояния с помощью Journal.Compact.
+func RunCompactor(ctx context.Context, interval time.Duration, compact func() error) {
+	if interval <= 0 {
+		return
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if err = json.Unmarshal(scanner.Bytes(), &shortURLJSON); err != nil {
-			return err
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := compact(); err != nil {
+				logger.Log.Error("Failed to compact file storage", zap.Error(err))
+			}
 		}
+	}
+}
 
-		storage.Set(context.TODO(), shortURLJSON.ShortURL, shortURLJSON.OriginalURL)
+// newShortURLJSON преобразует ссылку в запись файла с идентификатором id.
+func newShortURLJSON(id string, link models.Link) ShortURLJSON {
+	shortURLJSON := ShortURLJSON{
+		UUID:         id,
+		ShortURL:     link.ShortKey,
+		OriginalURL:  link.OriginalURL,
+		UserID:       link.UserID,
+		IsDeleted:    link.IsDeleted,
+		MaxClicks:    link.MaxClicks,
+		PasswordHash: link.PasswordHash,
+		Tags:         link.Tags,
+	}
+	if link.MaxClicks > 0 {
+		clicksLeft := link.ClicksLeft
+		shortURLJSON.ClicksLeft = &clicksLeft
+	}
+	if !link.ExpiresAt.IsZero() {
+		expiresAt := link.ExpiresAt
+		shortURLJSON.ExpiresAt = &expiresAt
+	}
+	if !link.CreatedAt.IsZero() {
+		createdAt := link.CreatedAt
+		shortURLJSON.CreatedAt = &createdAt
+	}
+	if !link.DeletedAt.IsZero() {
+		deletedAt := link.DeletedAt
+		shortURLJSON.DeletedAt = &deletedAt
 	}
 
-	return nil
+	return shortURLJSON
+}
+
+// link преобразует запись файла в ссылку.
+func (r ShortURLJSON) link() models.Link {
+	link := models.Link{
+		ShortKey:     r.ShortURL,
+		OriginalURL:  r.OriginalURL,
+		UserID:       r.UserID,
+		IsDeleted:    r.IsDeleted,
+		MaxClicks:    r.MaxClicks,
+		PasswordHash: r.PasswordHash,
+		ClicksLeft:   r.MaxClicks,
+		Tags:         r.Tags,
+	}
+	if r.ClicksLeft != nil {
+		link.ClicksLeft = *r.ClicksLeft
+	}
+	if r.ExpiresAt != nil {
+		link.ExpiresAt = *r.ExpiresAt
+	}
+	if r.CreatedAt != nil {
+		link.CreatedAt = *r.CreatedAt
+	}
+	if r.DeletedAt != nil {
+		link.DeletedAt = *r.DeletedAt
+	}
+
+	return link
 }
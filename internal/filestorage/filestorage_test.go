@@ -1,66 +1,246 @@
 package filestorage
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
-	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
 	mock_storage "github.com/dsemenov12/shorturl/internal/storage/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
+// openFile открывает журнал в файле name временного каталога теста.
+func openFile(t *testing.T, name string, data string) *Journal {
+	path := filepath.Join(t.TempDir(), name)
+	if data != "" {
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0666))
+	}
+
+	j, err := Open(path, Options{})
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		j.Close()
+	})
+	return j
+}
+
 // Тестируем сохранение данных в файл
 func TestSave(t *testing.T) {
-	// Создаем временный файл для хранения данных
-	config.FlagFileStoragePath = "test_storage.json"
-	defer os.Remove(config.FlagFileStoragePath)
+	j := openFile(t, "test_storage.json", "")
 
-	storageData := map[string]string{
-		"shorturl1": "http://example.com/1",
-		"shorturl2": "http://example.com/2",
+	storageData := []models.Link{
+		{ShortKey: "shorturl1", OriginalURL: "http://example.com/1"},
+		{ShortKey: "shorturl2", OriginalURL: "http://example.com/2"},
 	}
 
-	// Вызываем функцию Save
-	err := Save(storageData)
+	err := j.Append(OpSet, storageData...)
 	assert.NoError(t, err)
 
-	// Открываем файл и проверяем его содержимое
-	file, err := os.Open(config.FlagFileStoragePath)
+	// Проверяем содержимое файла
+	data, err := os.ReadFile(j.Path())
 	assert.NoError(t, err)
-	defer file.Close()
 
-	var expectedData = `{"uuid":"1","short_url":"shorturl1","original_url":"http://example.com/1"}
-{"uuid":"2","short_url":"shorturl2","original_url":"http://example.com/2"}
+	expectedData := `{"uuid":"1","short_url":"shorturl1","original_url":"http://example.com/1","op":"set"}
+{"uuid":"2","short_url":"shorturl2","original_url":"http://example.com/2","op":"set"}
 `
-	buf := make([]byte, len(expectedData))
-	_, err = file.Read(buf)
-	assert.NoError(t, err)
-	//assert.Equal(t, expectedData, string(buf))
+	assert.Equal(t, expectedData, string(data))
 }
 
 // Тестируем загрузку данных из файла
 func TestLoad(t *testing.T) {
-	// Создаем временный файл для загрузки данных
-	config.FlagFileStoragePath = "test_storage_load.json"
-	defer os.Remove(config.FlagFileStoragePath)
-
-	// Подготавливаем данные
-	expectedData := `{"uuid":"1","short_url":"shorturl1","original_url":"http://example.com/1"}
+	j := openFile(t, "test_storage_load.json", `{"uuid":"1","short_url":"shorturl1","original_url":"http://example.com/1"}
 {"uuid":"2","short_url":"shorturl2","original_url":"http://example.com/2"}
-`
-	err := os.WriteFile(config.FlagFileStoragePath, []byte(expectedData), 0666)
-	assert.NoError(t, err)
+`)
 
 	// Мокаем хранилище
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockStorage := mock_storage.NewMockStorage(ctrl)
 
-	mockStorage.EXPECT().Set(gomock.Any(), "shorturl1", "http://example.com/1").Return("shorturl1", nil)
-	mockStorage.EXPECT().Set(gomock.Any(), "shorturl2", "http://example.com/2").Return("shorturl2", nil)
+	mockStorage.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/1"}).Return("shorturl1", nil)
+	mockStorage.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "shorturl2", OriginalURL: "http://example.com/2"}).Return("shorturl2", nil)
+
+	err := Load(j, mockStorage)
+	assert.NoError(t, err)
+}
+
+// Тестируем загрузку файла с повторной записью для того же ключа
+func TestLoadUpdatedRecord(t *testing.T) {
+	j := openFile(t, "test_storage_load_updated.json", `{"uuid":"1","short_url":"shorturl1","original_url":"http://example.com/1"}
+{"uuid":"1","short_url":"shorturl1","original_url":"http://example.com/2"}
+`)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+
+	mockStorage.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/1"}).Return("shorturl1", nil)
+	mockStorage.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/2"}).Return("", storage.ErrKeyExists)
+	mockStorage.EXPECT().Update(gomock.Any(), "shorturl1", "http://example.com/2").Return(nil)
+
+	err := Load(j, mockStorage)
+	assert.NoError(t, err)
+}
+
+// Тестируем пропуск записей с истёкшим сроком действия при загрузке
+func TestLoadSkipsExpired(t *testing.T) {
+	j := openFile(t, "test_storage_load_expired.json", "")
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	err := j.Append(OpSet,
+		models.Link{ShortKey: "expired", OriginalURL: "http://example.com/1", ExpiresAt: time.Now().Add(-time.Hour)},
+		models.Link{ShortKey: "active", OriginalURL: "http://example.com/2", ExpiresAt: expiresAt},
+	)
+	assert.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+
+	mockStorage.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "active", OriginalURL: "http://example.com/2", ExpiresAt: expiresAt}).Return("active", nil)
 
-	// Вызываем функцию Load
-	err = Load(mockStorage)
+	err = Load(j, mockStorage)
 	assert.NoError(t, err)
 }
+
+// Тестируем замену журнала снимком оставшихся ссылок
+func TestCompact(t *testing.T) {
+	j := openFile(t, "test_storage_rewrite.json", "")
+
+	err := j.Append(OpSet,
+		models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/1"},
+		models.Link{ShortKey: "shorturl2", OriginalURL: "http://example.com/2"},
+	)
+	assert.NoError(t, err)
+
+	err = j.Compact(nil, models.Link{ShortKey: "shorturl2", OriginalURL: "http://example.com/2"})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(j.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, `{"uuid":"3","short_url":"shorturl2","original_url":"http://example.com/2","op":"set"}`+"\n", string(data))
+}
+
+// Тестируем сохранение владельца ссылки и загрузку записи об удалении
+func TestLoadOwnerAndDeleted(t *testing.T) {
+	j := openFile(t, "test_storage_load_owner.json", "")
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	link := models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/1", UserID: "user1", CreatedAt: createdAt}
+	deleted := link
+	deleted.IsDeleted = true
+	deleted.DeletedAt = createdAt.Add(time.Hour)
+	err := j.Append(OpSet, link, deleted)
+	assert.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+
+	var owners []string
+	mockStorage.EXPECT().Set(gomock.Any(), link).DoAndReturn(func(ctx context.Context, _ models.Link) (string, error) {
+		owners = append(owners, auth.UserIDFromContext(ctx))
+		return "shorturl1", nil
+	})
+	mockStorage.EXPECT().Set(gomock.Any(), deleted).Return("", storage.ErrKeyExists)
+	mockStorage.EXPECT().Delete(gomock.Any(), "shorturl1").DoAndReturn(func(ctx context.Context, _ string) error {
+		owners = append(owners, auth.UserIDFromContext(ctx))
+		return nil
+	})
+
+	err = Load(j, mockStorage)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user1", "user1"}, owners, "Records should be loaded on behalf of their owner")
+}
+
+// Тестируем применение записей об изменении, откате и удалении ссылки при загрузке
+func TestLoadJournalOps(t *testing.T) {
+	j := openFile(t, "test_storage_load_ops.json", "")
+
+	link := models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/1", UserID: "user1"}
+	assert.NoError(t, j.Append(OpSet, link))
+	assert.NoError(t, j.Append(OpUpdate, models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/2", UserID: "user1"}))
+	assert.NoError(t, j.Append(OpRollback, link))
+	assert.NoError(t, j.Append(OpDelete, link))
+	assert.NoError(t, j.Append(OpRestore, link))
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+
+	gomock.InOrder(
+		mockStorage.EXPECT().Set(gomock.Any(), link).Return("shorturl1", nil),
+		mockStorage.EXPECT().Update(gomock.Any(), "shorturl1", "http://example.com/2").Return(nil),
+		mockStorage.EXPECT().Rollback(gomock.Any(), "shorturl1").Return("http://example.com/1", nil),
+		mockStorage.EXPECT().Delete(gomock.Any(), "shorturl1").Return(nil),
+		mockStorage.EXPECT().Restore(gomock.Any(), "shorturl1", time.Time{}).Return("http://example.com/1", nil),
+	)
+
+	err := Load(j, mockStorage)
+	assert.NoError(t, err)
+}
+
+// Тестируем восстановление момента удаления ссылки при загрузке
+func TestLoadDeletedAt(t *testing.T) {
+	j := openFile(t, "test_storage_load_deleted_at.json", "")
+
+	deletedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	link := models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/1", UserID: "user1"}
+	deleted := link
+	deleted.IsDeleted = true
+	deleted.DeletedAt = deletedAt
+	legacy := models.Link{ShortKey: "shorturl2", OriginalURL: "http://example.com/2", UserID: "user1", IsDeleted: true}
+	assert.NoError(t, j.Append(OpSet, link, legacy))
+	assert.NoError(t, j.Append(OpDelete, deleted))
+
+	s := memory.NewStorage()
+	before := time.Now()
+	assert.NoError(t, Load(j, s))
+
+	loaded, err := s.Get(context.Background(), "shorturl1")
+	assert.NoError(t, err)
+	assert.True(t, loaded.IsDeleted)
+	assert.True(t, deletedAt.Equal(loaded.DeletedAt), "Deletion time should be taken from the journal")
+
+	loaded, err = s.Get(context.Background(), "shorturl2")
+	assert.NoError(t, err)
+	assert.False(t, loaded.DeletedAt.Before(before.Truncate(time.Second)), "Deleted links without deletion time should be deleted at load time")
+}
+
+// Тестируем восстановление остатка переходов при загрузке
+func TestLoadClicksLeft(t *testing.T) {
+	j := openFile(t, "test_storage_load_clicks_left.json", `{"uuid":"1","short_url":"legacy","original_url":"http://example.com/0","max_clicks":3}`+"\n")
+
+	link := models.Link{ShortKey: "shorturl1", OriginalURL: "http://example.com/1", UserID: "user1", MaxClicks: 2, ClicksLeft: 2}
+	assert.NoError(t, j.Append(OpSet, link))
+	link.ClicksLeft = 1
+	assert.NoError(t, j.Append(OpConsume, link))
+	link.ClicksLeft = 0
+	assert.NoError(t, j.Append(OpConsume, link))
+
+	s := memory.NewStorage()
+	assert.NoError(t, Load(j, s))
+
+	loaded, err := s.Get(context.Background(), "shorturl1")
+	assert.NoError(t, err)
+	assert.True(t, loaded.Exhausted())
+
+	loaded, err = s.Get(context.Background(), "legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), loaded.ClicksLeft, "Records without clicks_left should keep the full limit")
+
+	// Хранилище без SetClicksLeft получает каждый переход вызовом Consume
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+	mockStorage.EXPECT().Set(gomock.Any(), gomock.Any()).Return("", nil).Times(2)
+	mockStorage.EXPECT().Consume(gomock.Any(), "shorturl1").Return(nil).Times(2)
+	assert.NoError(t, Load(j, mockStorage))
+}
@@ -0,0 +1,395 @@
+package filestorage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/models"
+)
+
+// Op — тип записи журнала.
+type Op string
+
+// Типы записей журнала.
+const (
+	// OpSet — создание ссылки. Записи без типа из файлов прежнего формата считаются OpSet.
+	OpSet Op = "set"
+	// OpUpdate — изменение оригинального URL ссылки.
+	OpUpdate Op = "update"
+	// OpRollback — откат ссылки к предыдущему значению URL.
+	OpRollback Op = "rollback"
+	// OpDelete — удаление ссылки.
+	OpDelete Op = "delete"
+	// OpRestore — восстановление удалённой ссылки.
+	OpRestore Op = "restore"
+	// OpConsume — переход по ссылке с ограничением количества переходов; запись содержит новый остаток.
+	OpConsume Op = "consume"
+)
+
+// SyncPolicy определяет, когда записи журнала сбрасываются на диск вызовом fsync.
+type SyncPolicy string
+
+// Политики сброса журнала на диск.
+const (
+	// SyncAlways — fsync после каждой записи; запись не теряется при сбое после возврата из Append.
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval — fsync в фоне с заданным периодом; при сбое теряются записи за последний период.
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever — сброс на диск остаётся на усмотрение операционной системы.
+	SyncNever SyncPolicy = "never"
+)
+
+// Ошибки журнала.
+var (
+	// ErrUnknownSyncPolicy возвращается для неизвестной политики сброса на диск.
+	ErrUnknownSyncPolicy = errors.New("unknown file sync policy")
+	// ErrCorrupted возвращается, если повреждена запись журнала не в его конце.
+	ErrCorrupted = errors.New("file storage journal is corrupted")
+	// ErrClosed возвращается при записи в закрытый журнал.
+	ErrClosed = errors.New("file storage journal is closed")
+)
+
+// Options задаёт параметры журнала.
+type Options struct {
+	Sync         SyncPolicy    // Политика сброса на диск; пустое значение — SyncAlways
+	SyncInterval time.Duration // Период сброса для SyncInterval
+}
+
+// Record — запись журнала вместе с её типом.
+type Record struct {
+	ShortURLJSON
+	Op Op `json:"op,omitempty"` // Тип записи
+}
+
+// Journal — журнал предзаписи (write-ahead log) ссылок в формате JSON Lines.
+//
+// Каждая запись получает идентификатор, возрастающий в течение всей жизни файла,
+// в том числе после сжатия. Неполная последняя строка, оставшаяся после сбоя во время записи,
+// отбрасывается при открытии журнала. Сжатие атомарно заменяет журнал снимком текущего состояния.
+type Journal struct {
+	mx      sync.Mutex
+	path    string
+	file    *os.File
+	options Options
+	nextID  uint64
+	dirty   bool
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Open открывает журнал в файле path, создавая файл и его каталог при необходимости.
+// Неполная запись в конце файла, оставшаяся после сбоя, отбрасывается.
+func Open(path string, options Options) (*Journal, error) {
+	if options.Sync == "" {
+		options.Sync = SyncAlways
+	}
+	if err := validateSyncPolicy(options.Sync); err != nil {
+		return nil, err
+	}
+	if options.Sync == SyncInterval && options.SyncInterval <= 0 {
+		return nil, fmt.Errorf("%w: interval must be positive", ErrUnknownSyncPolicy)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	records, size, err := readRecords(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	// Отбрасываем неполную запись, чтобы новые записи не склеились с ней
+	if err = file.Truncate(size); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err = file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	j := &Journal{
+		path:    path,
+		file:    file,
+		options: options,
+		nextID:  1,
+		done:    make(chan struct{}),
+	}
+	for _, record := range records {
+		if id, err := strconv.ParseUint(record.UUID, 10, 64); err == nil && id >= j.nextID {
+			j.nextID = id + 1
+		}
+	}
+
+	if options.Sync == SyncInterval {
+		j.wg.Add(1)
+		go j.syncLoop()
+	}
+
+	return j, nil
+}
+
+// Path возвращает путь к файлу журнала.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// ReadAll читает все записи журнала в порядке их добавления.
+func (j *Journal) ReadAll() ([]Record, error) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if j.closed {
+		return nil, ErrClosed
+	}
+
+	file, err := os.Open(j.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, _, err := readRecords(file)
+	return records, err
+}
+
+// Append дописывает в журнал записи типа op для ссылок links одной операцией записи.
+func (j *Journal) Append(op Op, links ...models.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if j.closed {
+		return ErrClosed
+	}
+
+	data, err := j.marshal(op, links, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = j.file.Write(data); err != nil {
+		return err
+	}
+
+	return j.afterWrite()
+}
+
+// Compact атомарно заменяет журнал снимком links: записи пишутся во временный файл,
+// который сбрасывается на диск и переименовывается поверх журнала.
+// history содержит историю изменений URL по коротким ключам от ранних значений к поздним;
+// она сохраняется в записях снимка, чтобы изменения можно было откатить после перезапуска.
+// При сбое во время сжатия остаётся прежний журнал.
+func (j *Journal) Compact(history map[string][]models.HistoryItem, links ...models.Link) error {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if j.closed {
+		return ErrClosed
+	}
+
+	data, err := j.marshal(OpSet, links, history)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := j.path + ".tmp"
+	if err = writeFileSync(tmpPath, data); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = os.Rename(tmpPath, j.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = syncDir(filepath.Dir(j.path)); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	j.file.Close()
+	j.file = file
+	j.dirty = false
+
+	return nil
+}
+
+// Sync сбрасывает записанные данные журнала на диск.
+func (j *Journal) Sync() error {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+	return j.sync()
+}
+
+// Close сбрасывает данные на диск и закрывает журнал.
+func (j *Journal) Close() error {
+	j.mx.Lock()
+	if j.closed {
+		j.mx.Unlock()
+		return nil
+	}
+	j.closed = true
+	close(j.done)
+	j.mx.Unlock()
+
+	j.wg.Wait()
+
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	err := j.sync()
+	if closeErr := j.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// afterWrite применяет политику сброса на диск после записи. Вызывается под блокировкой.
+func (j *Journal) afterWrite() error {
+	j.dirty = true
+	if j.options.Sync == SyncAlways {
+		return j.sync()
+	}
+	return nil
+}
+
+// sync сбрасывает файл на диск, если в него были записи. Вызывается под блокировкой.
+func (j *Journal) sync() error {
+	if !j.dirty || j.options.Sync == SyncNever {
+		return nil
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+	j.dirty = false
+	return nil
+}
+
+// syncLoop периодически сбрасывает журнал на диск до закрытия журнала.
+func (j *Journal) syncLoop() {
+	defer j.wg.Done()
+
+	ticker := time.NewTicker(j.options.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-ticker.C:
+			j.Sync()
+		}
+	}
+}
+
+// marshal преобразует ссылки в строки журнала, присваивая им очередные идентификаторы.
+// Записи дополняются историей изменений ссылок из history, если она задана. Вызывается под блокировкой.
+func (j *Journal) marshal(op Op, links []models.Link, history map[string][]models.HistoryItem) ([]byte, error) {
+	var data []byte
+
+	for _, link := range links {
+		record := Record{ShortURLJSON: newShortURLJSON(strconv.FormatUint(j.nextID, 10), link), Op: op}
+		record.History = history[link.ShortKey]
+		line, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+		j.nextID++
+	}
+
+	return data, nil
+}
+
+// readRecords читает записи журнала и возвращает их вместе с размером корректной части файла.
+// Неполная или нечитаемая последняя строка считается оборванной записью и не учитывается;
+// нечитаемая строка в середине файла означает повреждение журнала.
+func readRecords(r io.Reader) ([]Record, int64, error) {
+	var records []Record
+	var size int64
+
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Строка без перевода строки — оборванная при сбое запись
+			return records, size, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var record Record
+		if len(bytes.TrimSpace(data)) > 0 {
+			if err = json.Unmarshal(data, &record); err != nil {
+				if _, peekErr := reader.Peek(1); errors.Is(peekErr, io.EOF) {
+					return records, size, nil
+				}
+				return nil, 0, fmt.Errorf("%w: line %d: %v", ErrCorrupted, line, err)
+			}
+			if record.Op == "" {
+				record.Op = OpSet
+			}
+			records = append(records, record)
+		}
+		size += int64(len(data))
+	}
+}
+
+// validateSyncPolicy проверяет, что политика сброса на диск известна.
+func validateSyncPolicy(policy SyncPolicy) error {
+	switch policy {
+	case SyncAlways, SyncInterval, SyncNever:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSyncPolicy, policy)
+	}
+}
+
+// writeFileSync записывает data в новый файл path и сбрасывает его на диск.
+func writeFileSync(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir сбрасывает на диск каталог, чтобы переименование файла пережило сбой.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,118 @@
+package filestorage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openTestJournal(t *testing.T, path string, options Options) *Journal {
+	j, err := Open(path, options)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		j.Close()
+	})
+	return j
+}
+
+// Тестируем возрастание идентификаторов записей после сжатия и повторного открытия
+func TestJournal_MonotonicIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+
+	j := openTestJournal(t, path, Options{})
+	require.NoError(t, j.Append(OpSet,
+		models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"},
+		models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2"},
+	))
+	require.NoError(t, j.Compact(nil, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2"}))
+	require.NoError(t, j.Close())
+
+	j = openTestJournal(t, path, Options{})
+	require.NoError(t, j.Append(OpDelete, models.Link{ShortKey: "short2"}))
+
+	records, err := j.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "3", records[0].UUID)
+	assert.Equal(t, OpSet, records[0].Op)
+	assert.Equal(t, "4", records[1].UUID)
+	assert.Equal(t, OpDelete, records[1].Op)
+}
+
+// Тестируем отбрасывание оборванной при сбое последней записи
+func TestJournal_TornTail(t *testing.T) {
+	tests := []struct {
+		name string
+		tail string
+	}{
+		{name: "without newline", tail: `{"uuid":"2","short_url":"sho`},
+		{name: "invalid json", tail: `{"uuid":"2","short_url":` + "\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "storage.json")
+			data := `{"uuid":"1","short_url":"short1","original_url":"http://example.com/1"}` + "\n" + test.tail
+			require.NoError(t, os.WriteFile(path, []byte(data), 0666))
+
+			j := openTestJournal(t, path, Options{})
+			require.NoError(t, j.Append(OpSet, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2"}))
+
+			records, err := j.ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+			assert.Equal(t, "short1", records[0].ShortURL)
+			assert.Equal(t, "short2", records[1].ShortURL)
+			assert.Equal(t, "2", records[1].UUID)
+		})
+	}
+}
+
+// Тестируем ошибку при повреждённой записи в середине журнала
+func TestJournal_Corrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	data := `{"uuid":"1","short_url":"short1","original_url":"http://example.com/1"}
+not a record
+{"uuid":"2","short_url":"short2","original_url":"http://example.com/2"}
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0666))
+
+	_, err := Open(path, Options{})
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+// Тестируем проверку политики сброса на диск
+func TestJournal_SyncPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{name: "default", options: Options{}},
+		{name: "always", options: Options{Sync: SyncAlways}},
+		{name: "interval", options: Options{Sync: SyncInterval, SyncInterval: time.Millisecond}},
+		{name: "never", options: Options{Sync: SyncNever}},
+		{name: "interval without period", options: Options{Sync: SyncInterval}, wantErr: true},
+		{name: "unknown", options: Options{Sync: "sometimes"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			j, err := Open(filepath.Join(t.TempDir(), "storage.json"), test.options)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrUnknownSyncPolicy)
+				return
+			}
+			require.NoError(t, err)
+
+			require.NoError(t, j.Append(OpSet, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"}))
+			require.NoError(t, j.Close())
+			assert.ErrorIs(t, j.Append(OpSet, models.Link{ShortKey: "short2"}), ErrClosed)
+		})
+	}
+}
@@ -3,14 +3,29 @@ package grpchandlers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/dsemenov12/shorturl/internal/alias"
+	"github.com/dsemenov12/shorturl/internal/analytics"
 	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/batch"
 	"github.com/dsemenov12/shorturl/internal/config"
-	"github.com/dsemenov12/shorturl/internal/rand"
+	"github.com/dsemenov12/shorturl/internal/deleter"
+	"github.com/dsemenov12/shorturl/internal/expiry"
+	"github.com/dsemenov12/shorturl/internal/exporter"
+	"github.com/dsemenov12/shorturl/internal/importer"
+	"github.com/dsemenov12/shorturl/internal/keygen"
+	"github.com/dsemenov12/shorturl/internal/metrics"
+	"github.com/dsemenov12/shorturl/internal/models"
 	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/tags"
 	pb "github.com/dsemenov12/shorturl/proto"
 )
 
@@ -18,24 +33,80 @@ import (
 type GRPCServer struct {
 	pb.UnimplementedShortenerServiceServer
 	storage storage.Storage
+	clicks  *analytics.Recorder
+	keys    keygen.KeyGenerator
+	deletes *deleter.Deleter
 }
 
 // NewGRPCServer создаёт новый экземпляр GRPCServer с указанным хранилищем.
-func NewGRPCServer(storage storage.Storage) *GRPCServer {
-	return &GRPCServer{storage: storage}
+// clicks используется для учёта переходов по коротким ссылкам и может быть nil.
+// keys генерирует короткие ключи; если он nil, используются случайные ключи с настройками по умолчанию.
+// deletes выполняет удаление ссылок пользователей; если он nil, удаление недоступно.
+func NewGRPCServer(storage storage.Storage, clicks *analytics.Recorder, keys keygen.KeyGenerator, deletes *deleter.Deleter) *GRPCServer {
+	if keys == nil {
+		keys, _ = keygen.NewRandom(config.DefaultKeyAlphabet, config.DefaultKeyLength)
+	}
+	return &GRPCServer{storage: storage, clicks: clicks, keys: keys, deletes: deletes}
 }
 
 // PostURL генерирует короткий ключ для URL, сохраняет его в хранилище и возвращает сокращённый URL.
+// Если в запросе передан alias, он используется как короткий ключ после проверки.
+// Срок действия ссылки задаётся полем ttl (в секундах) или expires_at (RFC 3339),
+// а максимальное количество переходов — полем max_clicks, метки ссылки — списком tags.
+// Если передан password, для перехода по ссылке потребуется пароль.
 func (s *GRPCServer) PostURL(ctx context.Context, req *pb.ShortenRequest) (*pb.ShortenResponse, error) {
-	shortKey := rand.RandStringBytes(8)
-	shortURL := config.FlagBaseAddr + "/" + shortKey
+	if req.Alias != "" {
+		if err := alias.Validate(req.Alias); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		expiresAt = &parsed
+	}
+	expiresAtResult, err := expiry.Resolve(req.Ttl, expiresAt, time.Now())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if req.MaxClicks < 0 {
+		return nil, status.Error(codes.InvalidArgument, "invalid max_clicks")
+	}
 
-	shortKeyResult, err := s.storage.Set(ctx, shortKey, req.Url)
+	linkTags, err := tags.NormalizeAll(req.Tags)
 	if err != nil {
-		shortURL = config.FlagBaseAddr + "/" + shortKeyResult
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	link := models.Link{
+		ShortKey:    req.Alias,
+		OriginalURL: req.Url,
+		ExpiresAt:   expiresAtResult,
+		MaxClicks:   req.MaxClicks,
+		Tags:        linkTags,
+	}
+	if req.Password != "" {
+		if link.PasswordHash, err = auth.HashPassword(req.Password); err != nil {
+			return nil, err
+		}
 	}
 
-	return &pb.ShortenResponse{Result: shortURL}, nil
+	shortKey, err := s.setLink(ctx, link)
+	switch {
+	case errors.Is(err, storage.ErrKeyExists) && req.Alias != "":
+		return nil, status.Error(codes.AlreadyExists, "alias already taken")
+	case errors.Is(err, storage.ErrConflict):
+		// URL уже сокращён — возвращаем существующую короткую ссылку
+	case err != nil:
+		return nil, err
+	}
+
+	return &pb.ShortenResponse{Result: config.FlagBaseAddr + "/" + shortKey}, nil
 }
 
 // ShortenPost дублирует логику PostURL, предоставляя альтернативный gRPC метод для сокращения URL.
@@ -44,94 +115,414 @@ func (s *GRPCServer) ShortenPost(ctx context.Context, req *pb.ShortenRequest) (*
 }
 
 // ShortenBatchPost обрабатывает пакет запросов на сокращение URL.
-// Для каждого элемента из входного списка создаёт короткий URL и возвращает список результатов.
+// Возвращает результат по каждому элементу со статусом created, exists или invalid.
+// При atomic=true сохраняет все элементы или ни одного; если часть элементов некорректна,
+// возвращает INVALID_ARGUMENT с их перечислением.
 func (s *GRPCServer) ShortenBatchPost(ctx context.Context, req *pb.ShortenBatchRequest) (*pb.ShortenBatchResponse, error) {
-	var items []*pb.ShortenBatchResponseItem
-	for _, item := range req.Items {
-		if item.CorrelationId == "" || item.OriginalUrl == "" {
-			continue
-		}
+	batchItems := make([]models.BatchItem, len(req.Items))
+	for i, item := range req.Items {
+		batchItems[i] = models.BatchItem{CorrelationID: item.CorrelationId, OriginalURL: item.OriginalUrl}
+	}
 
-		shortURL := config.FlagBaseAddr + "/" + item.CorrelationId
-		shortKeyResult, err := s.storage.Set(ctx, item.CorrelationId, item.OriginalUrl)
-		if err != nil {
-			shortURL = config.FlagBaseAddr + "/" + shortKeyResult
+	result, err := batch.Shorten(ctx, s.storage, s.keys, batchItems, req.Atomic)
+	if errors.Is(err, batch.ErrInvalidItems) {
+		invalid := make([]string, len(result))
+		for i, item := range result {
+			invalid[i] = fmt.Sprintf("%q: %s", item.CorrelationID, item.Error)
 		}
+		return nil, status.Errorf(codes.InvalidArgument, "%v: %s", err, strings.Join(invalid, "; "))
+	}
+	if err != nil {
+		return nil, storageError(err)
+	}
 
-		items = append(items, &pb.ShortenBatchResponseItem{
-			CorrelationId: item.CorrelationId,
-			ShortUrl:      shortURL,
-		})
+	items := make([]*pb.ShortenBatchResponseItem, len(result))
+	for i, item := range result {
+		items[i] = &pb.ShortenBatchResponseItem{
+			CorrelationId: item.CorrelationID,
+			ShortUrl:      item.ShortURL,
+			Status:        string(item.Status),
+			Error:         item.Error,
+		}
 	}
 
 	return &pb.ShortenBatchResponse{Items: items}, nil
 }
 
 // Redirect возвращает оригинальный URL по короткому ключу.
-// Возвращает ошибку, если URL был удалён или отсутствует.
+// Возвращает ошибку, если URL был удалён или отсутствует,
+// и FAILED_PRECONDITION, если срок действия ссылки истёк или лимит переходов исчерпан.
+// Для защищённой ссылки пароль передаётся в поле password: без него возвращается UNAUTHENTICATED,
+// с неверным паролем — PERMISSION_DENIED.
 func (s *GRPCServer) Redirect(ctx context.Context, req *pb.RedirectRequest) (*pb.RedirectResponse, error) {
-	url, _, isDeleted, err := s.storage.Get(ctx, req.Id)
+	link, err := s.storage.Get(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
-	if isDeleted {
+	if link.IsDeleted {
 		return nil, errors.New("url was deleted")
 	}
-	return &pb.RedirectResponse{Url: url}, nil
+	if link.Expired(time.Now()) {
+		return nil, status.Error(codes.FailedPrecondition, "link expired")
+	}
+	if link.Protected() {
+		if req.Password == "" {
+			return nil, status.Error(codes.Unauthenticated, "password required")
+		}
+		if !auth.CheckPassword(link.PasswordHash, req.Password) {
+			return nil, status.Error(codes.PermissionDenied, "invalid password")
+		}
+	}
+	if link.MaxClicks > 0 {
+		err = s.storage.Consume(ctx, req.Id)
+		if errors.Is(err, storage.ErrExhausted) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.clicks.Track(models.ClickEvent{
+		ShortKey:  req.Id,
+		Timestamp: time.Now().UTC(),
+		Referrer:  incomingHeader(ctx, "referer"),
+		UserAgent: incomingHeader(ctx, "user-agent"),
+	})
+
+	return &pb.RedirectResponse{Url: link.OriginalURL}, nil
+}
+
+// LinkStats возвращает владельцу короткой ссылки количество переходов по ней и временной ряд переходов.
+func (s *GRPCServer) LinkStats(ctx context.Context, req *pb.LinkStatsRequest) (*pb.LinkStatsResponse, error) {
+	userID, ok := ctx.Value(auth.UserIDKey).(string)
+	if !ok || userID == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "user not authenticated")
+	}
+
+	from, to, step, err := analytics.ParseRange(req.From, req.To, req.Interval, time.Now().UTC())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	link, err := s.storage.Get(ctx, req.Id)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if link.UserID != userID {
+		return nil, status.Error(codes.PermissionDenied, "access forbidden")
+	}
+	if link.IsDeleted {
+		return nil, status.Error(codes.NotFound, "url was deleted")
+	}
+
+	clicks, series, err := s.storage.GetStats(ctx, req.Id, from, to, step)
+	if err != nil {
+		return nil, err
+	}
+
+	var pbSeries []*pb.StatsBucket
+	for _, bucket := range series {
+		pbSeries = append(pbSeries, &pb.StatsBucket{
+			Time:   bucket.Time.Format(time.RFC3339),
+			Clicks: bucket.Clicks,
+		})
+	}
+
+	return &pb.LinkStatsResponse{
+		ShortUrl:    config.FlagBaseAddr + "/" + req.Id,
+		OriginalUrl: link.OriginalURL,
+		Clicks:      clicks,
+		Series:      pbSeries,
+	}, nil
 }
 
 // UserUrls возвращает список всех URL, сохранённых пользователем.
 // Пользователь определяется по userID, извлечённому из контекста.
-func (s *GRPCServer) UserUrls(ctx context.Context, _ *pb.Empty) (*pb.UserUrlsResponse, error) {
+func (s *GRPCServer) UserUrls(ctx context.Context, req *pb.UserUrlsRequest) (*pb.UserUrlsResponse, error) {
 	userID, ok := ctx.Value(auth.UserIDKey).(string)
 	if !ok || userID == "" {
 		return nil, status.Errorf(codes.Unauthenticated, "user not authenticated")
 	}
 
-	urls, err := s.storage.GetUserURL(ctx)
+	desc, err := storage.ParseListOrder(req.GetOrder())
 	if err != nil {
-		return nil, err
+		return nil, storageError(err)
+	}
+
+	page, err := s.storage.ListUserURLs(ctx, models.ListQuery{
+		Limit:   int(req.GetLimit()),
+		Cursor:  req.GetCursor(),
+		Sort:    models.ListSort(req.GetSort()),
+		Desc:    desc,
+		Search:  req.GetSearch(),
+		Tag:     req.GetTag(),
+		Deleted: models.DeletedFilter(req.GetDeleted()),
+	})
+	if err != nil {
+		return nil, storageError(err)
 	}
 
 	var pbUrls []*pb.URL
-	for _, u := range urls {
-		pbUrls = append(pbUrls, &pb.URL{
+	for _, u := range page.Items {
+		pbURL := &pb.URL{
 			ShortUrl:    u.ShortURL,
 			OriginalUrl: u.OriginalURL,
+			ClicksLeft:  u.ClicksLeft,
+			Clicks:      u.Clicks,
+			IsDeleted:   u.IsDeleted,
+			Tags:        u.Tags,
+		}
+		if u.CreatedAt != nil {
+			pbURL.CreatedAt = u.CreatedAt.Format(time.RFC3339)
+		}
+		pbUrls = append(pbUrls, pbURL)
+	}
+
+	return &pb.UserUrlsResponse{Urls: pbUrls, Total: int64(page.Total), NextCursor: page.NextCursor}, nil
+}
+
+// ImportUrls загружает ссылки пользователя из потока записей.
+// Записи сохраняются пакетами по мере поступления; ошибки отдельных записей не прерывают загрузку
+// и возвращаются в ответе с порядковым номером записи в потоке, начиная с единицы.
+func (s *GRPCServer) ImportUrls(stream pb.ShortenerService_ImportUrlsServer) error {
+	ctx := stream.Context()
+	userID, ok := ctx.Value(auth.UserIDKey).(string)
+	if !ok || userID == "" {
+		return status.Errorf(codes.Unauthenticated, "user not authenticated")
+	}
+
+	session := importer.New(s.storage, s.keys).Begin(ctx)
+	for line := 1; ; line++ {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		record := importer.Record{
+			ShortURL:    req.GetShortUrl(),
+			OriginalURL: req.GetOriginalUrl(),
+			MaxClicks:   req.GetMaxClicks(),
+			Tags:        req.GetTags(),
+		}
+		if req.GetExpiresAt() != "" {
+			expiresAt, err := time.Parse(time.RFC3339, req.GetExpiresAt())
+			if err != nil {
+				session.Reject(line, req.GetShortUrl(), fmt.Errorf("invalid expires_at: %w", err))
+				continue
+			}
+			record.ExpiresAt = &expiresAt
+		}
+
+		if err = session.Add(line, record); err != nil {
+			return err
+		}
+	}
+
+	result, err := session.Close()
+	if err != nil {
+		return err
+	}
+
+	resp := &pb.ImportUrlsResponse{
+		Total:    int64(result.Total),
+		Imported: int64(result.Imported),
+		Failed:   int64(result.Failed),
+	}
+	for _, rowErr := range result.Errors {
+		resp.Errors = append(resp.Errors, &pb.ImportRowError{
+			Line:     int64(rowErr.Line),
+			ShortUrl: rowErr.ShortURL,
+			Error:    rowErr.Error,
 		})
 	}
 
-	return &pb.UserUrlsResponse{Urls: pbUrls}, nil
+	return stream.SendAndClose(resp)
+}
+
+// ExportUserUrls передаёт в поток все ссылки пользователя, включая удалённые, с количеством переходов по ним.
+// Ссылки отправляются по мере чтения из хранилища в порядке создания.
+func (s *GRPCServer) ExportUserUrls(_ *pb.Empty, stream pb.ShortenerService_ExportUserUrlsServer) error {
+	ctx := stream.Context()
+	userID, ok := ctx.Value(auth.UserIDKey).(string)
+	if !ok || userID == "" {
+		return status.Errorf(codes.Unauthenticated, "user not authenticated")
+	}
+
+	for item, err := range s.storage.UserLinks(ctx) {
+		if err != nil {
+			return storageError(err)
+		}
+
+		record := exporter.NewRecord(item)
+		pbURL := &pb.URL{
+			ShortUrl:    record.ShortURL,
+			OriginalUrl: record.OriginalURL,
+			ClicksLeft:  record.ClicksLeft,
+			CreatedAt:   record.CreatedAt.Format(time.RFC3339),
+			Clicks:      &record.Clicks,
+			IsDeleted:   record.IsDeleted,
+			Tags:        record.Tags,
+		}
+		if err = stream.Send(pbURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// DeleteUserUrls обрабатывает запрос на удаление списка коротких URL пользователя.
-// Для каждого URL вызывает метод удаления из хранилища.
+// DeleteUserUrls ставит в очередь удаление списка коротких URL пользователя и не ждёт его выполнения.
+// Удаляются только ссылки текущего пользователя. Если очередь переполнена или сервис удаления
+// остановлен, возвращает UNAVAILABLE.
 func (s *GRPCServer) DeleteUserUrls(ctx context.Context, req *pb.DeleteUserUrlsRequest) (*pb.Empty, error) {
 	userID, ok := ctx.Value(auth.UserIDKey).(string)
 	if !ok || userID == "" {
 		return nil, status.Errorf(codes.Unauthenticated, "user not authenticated")
 	}
 
-	// Можно запускать удаление в фоне или сразу делать синхронно
-	for _, shortURL := range req.ShortUrls {
-		s.storage.Delete(ctx, shortURL)
+	if err := s.deletes.Enqueue(userID, req.ShortUrls); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
 	}
 	return &pb.Empty{}, nil
 }
 
-// InternalStats возвращает статистику по количеству сохранённых URL и пользователей.
-func (s *GRPCServer) InternalStats(ctx context.Context, _ *pb.Empty) (*pb.StatsResponse, error) {
-	countUrls, err := s.storage.CountURLs(ctx)
+// UpdateURL заменяет оригинальный URL короткой ссылки пользователя.
+func (s *GRPCServer) UpdateURL(ctx context.Context, req *pb.UpdateURLRequest) (*pb.URL, error) {
+	userID, ok := ctx.Value(auth.UserIDKey).(string)
+	if !ok || userID == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "user not authenticated")
+	}
+	if req.Url == "" {
+		return nil, status.Error(codes.InvalidArgument, "empty url")
+	}
+
+	if err := s.storage.Update(ctx, req.Id, req.Url); err != nil {
+		return nil, storageError(err)
+	}
+
+	return &pb.URL{
+		ShortUrl:    config.FlagBaseAddr + "/" + req.Id,
+		OriginalUrl: req.Url,
+	}, nil
+}
+
+// URLHistory возвращает историю изменений оригинального URL короткой ссылки пользователя.
+func (s *GRPCServer) URLHistory(ctx context.Context, req *pb.URLHistoryRequest) (*pb.URLHistoryResponse, error) {
+	userID, ok := ctx.Value(auth.UserIDKey).(string)
+	if !ok || userID == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "user not authenticated")
+	}
+
+	history, err := s.storage.GetHistory(ctx, req.Id)
 	if err != nil {
-		return nil, err
+		return nil, storageError(err)
+	}
+
+	var items []*pb.URLHistoryItem
+	for _, item := range history {
+		items = append(items, &pb.URLHistoryItem{
+			OriginalUrl: item.OriginalURL,
+			ChangedAt:   item.ChangedAt.Format(time.RFC3339),
+		})
+	}
+
+	return &pb.URLHistoryResponse{Items: items}, nil
+}
+
+// RollbackURL восстанавливает предыдущий оригинальный URL короткой ссылки пользователя.
+func (s *GRPCServer) RollbackURL(ctx context.Context, req *pb.RollbackURLRequest) (*pb.URL, error) {
+	userID, ok := ctx.Value(auth.UserIDKey).(string)
+	if !ok || userID == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "user not authenticated")
+	}
+
+	originalURL, err := s.storage.Rollback(ctx, req.Id)
+	if err != nil {
+		return nil, storageError(err)
+	}
+
+	return &pb.URL{
+		ShortUrl:    config.FlagBaseAddr + "/" + req.Id,
+		OriginalUrl: originalURL,
+	}, nil
+}
+
+// RestoreURL восстанавливает удалённую ссылку пользователя, если с момента удаления
+// прошло не больше config.FlagRestoreWindow.
+func (s *GRPCServer) RestoreURL(ctx context.Context, req *pb.RestoreURLRequest) (*pb.URL, error) {
+	userID, ok := ctx.Value(auth.UserIDKey).(string)
+	if !ok || userID == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "user not authenticated")
 	}
-	countUsers, err := s.storage.CountUsers(ctx)
+
+	originalURL, err := s.storage.Restore(ctx, req.Id, time.Now().Add(-config.FlagRestoreWindow))
+	if err != nil {
+		return nil, storageError(err)
+	}
+
+	return &pb.URL{
+		ShortUrl:    config.FlagBaseAddr + "/" + req.Id,
+		OriginalUrl: originalURL,
+	}, nil
+}
+
+// InternalStats возвращает статистику по количеству сохранённых URL и пользователей.
+func (s *GRPCServer) InternalStats(ctx context.Context, _ *pb.Empty) (*pb.StatsResponse, error) {
+	counts, err := metrics.ReadCounts(ctx, s.storage)
 	if err != nil {
 		return nil, err
 	}
 
 	return &pb.StatsResponse{
-		Urls:  int64(countUrls),
-		Users: int64(countUsers),
+		Urls:  int64(counts.URLs),
+		Users: int64(counts.Users),
 	}, nil
 }
+
+// setLink сохраняет ссылку в хранилище и возвращает её короткий ключ.
+// Если ключ не задан пользователем, он создаётся генератором сервера с повтором при коллизиях.
+func (s *GRPCServer) setLink(ctx context.Context, link models.Link) (string, error) {
+	if link.ShortKey != "" {
+		return s.storage.Set(ctx, link)
+	}
+	return storage.SetGenerated(ctx, s.storage, s.keys, link)
+}
+
+// storageError преобразует ошибку хранилища в gRPC-ошибку с соответствующим кодом.
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, storage.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, storage.ErrDeleted), errors.Is(err, storage.ErrNoHistory),
+		errors.Is(err, storage.ErrNotDeleted), errors.Is(err, storage.ErrRestoreExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, storage.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, storage.ErrInvalidQuery):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return err
+	}
+}
+
+// incomingHeader возвращает значение заголовка из входящих метаданных запроса.
+// Заголовки, проксированные через grpc-gateway, имеют префикс "grpcgateway-" и проверяются первыми.
+func incomingHeader(ctx context.Context, name string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get("grpcgateway-" + name); len(values) > 0 {
+		return values[0]
+	}
+	if values := md.Get(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -3,16 +3,22 @@ package grpchandlers_test
 import (
 	"context"
 	"errors"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/dsemenov12/shorturl/internal/auth"
 	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/deleter"
 	"github.com/dsemenov12/shorturl/internal/grpchandlers"
 	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
 	mock_storage "github.com/dsemenov12/shorturl/internal/storage/mocks"
 	pb "github.com/dsemenov12/shorturl/proto"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -22,10 +28,10 @@ func TestGRPCServer_PostURL(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStorage := mock_storage.NewMockStorage(ctrl)
-	srv := grpchandlers.NewGRPCServer(mockStorage)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
 
 	mockStorage.EXPECT().
-		Set(gomock.Any(), gomock.Any(), gomock.Any()).
+		Set(gomock.Any(), gomock.Any()).
 		Return("shortkey", nil).
 		Times(1)
 
@@ -37,12 +43,61 @@ func TestGRPCServer_PostURL(t *testing.T) {
 	assert.Contains(t, resp.Result, config.FlagBaseAddr)
 }
 
+func TestGRPCServer_PostURLAlias(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
+
+	t.Run("success", func(t *testing.T) {
+		mockStorage.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "spring-sale", OriginalURL: "https://example.com"}).Return("spring-sale", nil)
+
+		resp, err := srv.PostURL(context.Background(), &pb.ShortenRequest{Url: "https://example.com", Alias: "spring-sale"})
+		assert.NoError(t, err)
+		assert.Equal(t, config.FlagBaseAddr+"/spring-sale", resp.Result)
+	})
+
+	t.Run("alias taken", func(t *testing.T) {
+		mockStorage.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "spring-sale", OriginalURL: "https://example.com"}).Return("", storage.ErrKeyExists)
+
+		resp, err := srv.PostURL(context.Background(), &pb.ShortenRequest{Url: "https://example.com", Alias: "spring-sale"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	t.Run("invalid expiry", func(t *testing.T) {
+		resp, err := srv.PostURL(context.Background(), &pb.ShortenRequest{Url: "https://example.com", Ttl: -1})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("reserved alias", func(t *testing.T) {
+		resp, err := srv.PostURL(context.Background(), &pb.ShortenRequest{Url: "https://example.com", Alias: "api"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		mockStorage.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "spring-sale", OriginalURL: "https://example.com", Tags: []string{"promo", "news"}}).Return("spring-sale", nil)
+
+		_, err := srv.PostURL(context.Background(), &pb.ShortenRequest{Url: "https://example.com", Alias: "spring-sale", Tags: []string{"Promo", "news", "promo"}})
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		resp, err := srv.PostURL(context.Background(), &pb.ShortenRequest{Url: "https://example.com", Tags: []string{"spring sale"}})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
 func TestGRPCServer_ShortenBatchPost(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockStorage := mock_storage.NewMockStorage(ctrl)
-	srv := grpchandlers.NewGRPCServer(mockStorage)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
 
 	req := &pb.ShortenBatchRequest{
 		Items: []*pb.ShortenBatchItem{
@@ -53,18 +108,33 @@ func TestGRPCServer_ShortenBatchPost(t *testing.T) {
 		},
 	}
 
-	// для валидных элементов ожидаем вызовы Set
-	mockStorage.EXPECT().Set(gomock.Any(), "id1", "https://a.com").Return("id1", nil)
-	mockStorage.EXPECT().Set(gomock.Any(), "id2", "https://b.com").Return("id2", nil)
+	t.Run("partial failure", func(t *testing.T) {
+		// корректные элементы сохраняются одним вызовом SetBatch
+		mockStorage.EXPECT().SetBatch(gomock.Any(), gomock.Len(2), false).Return([]storage.BatchResult{
+			{ShortKey: "new"},
+			{ShortKey: "old", Err: storage.ErrConflict},
+		}, nil)
 
-	resp, err := srv.ShortenBatchPost(context.Background(), req)
-	assert.NoError(t, err)
-	assert.Len(t, resp.Items, 2)
+		resp, err := srv.ShortenBatchPost(context.Background(), req)
+		assert.NoError(t, err)
+		require.Len(t, resp.Items, 4)
+
+		assert.Equal(t, config.FlagBaseAddr+"/new", resp.Items[0].ShortUrl)
+		assert.Equal(t, "created", resp.Items[0].Status)
+		assert.Equal(t, config.FlagBaseAddr+"/old", resp.Items[1].ShortUrl)
+		assert.Equal(t, "exists", resp.Items[1].Status)
+		for _, item := range resp.Items[2:] {
+			assert.Equal(t, "invalid", item.Status)
+			assert.NotEmpty(t, item.Error)
+		}
+	})
 
-	for _, item := range resp.Items {
-		assert.Contains(t, item.ShortUrl, config.FlagBaseAddr)
-		assert.NotEmpty(t, item.CorrelationId)
-	}
+	t.Run("atomic with invalid items", func(t *testing.T) {
+		resp, err := srv.ShortenBatchPost(context.Background(), &pb.ShortenBatchRequest{Items: req.Items, Atomic: true})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), `"id4"`)
+	})
 }
 
 func TestGRPCServer_Redirect(t *testing.T) {
@@ -72,12 +142,12 @@ func TestGRPCServer_Redirect(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStorage := mock_storage.NewMockStorage(ctrl)
-	srv := grpchandlers.NewGRPCServer(mockStorage)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
 
 	t.Run("success", func(t *testing.T) {
 		mockStorage.EXPECT().
 			Get(gomock.Any(), "short").
-			Return("https://example.com", "", false, nil)
+			Return(models.Link{ShortKey: "short", OriginalURL: "https://example.com"}, nil)
 
 		resp, err := srv.Redirect(context.Background(), &pb.RedirectRequest{Id: "short"})
 		assert.NoError(t, err)
@@ -87,7 +157,7 @@ func TestGRPCServer_Redirect(t *testing.T) {
 	t.Run("deleted", func(t *testing.T) {
 		mockStorage.EXPECT().
 			Get(gomock.Any(), "deleted").
-			Return("", "", true, nil)
+			Return(models.Link{ShortKey: "deleted", IsDeleted: true}, nil)
 
 		resp, err := srv.Redirect(context.Background(), &pb.RedirectRequest{Id: "deleted"})
 		assert.Nil(t, resp)
@@ -97,12 +167,54 @@ func TestGRPCServer_Redirect(t *testing.T) {
 	t.Run("not found error", func(t *testing.T) {
 		mockStorage.EXPECT().
 			Get(gomock.Any(), "missing").
-			Return("", "", false, errors.New("not found"))
+			Return(models.Link{}, errors.New("not found"))
 
 		resp, err := srv.Redirect(context.Background(), &pb.RedirectRequest{Id: "missing"})
 		assert.Nil(t, resp)
 		assert.Error(t, err)
 	})
+
+	t.Run("clicks exhausted", func(t *testing.T) {
+		mockStorage.EXPECT().
+			Get(gomock.Any(), "once").
+			Return(models.Link{ShortKey: "once", OriginalURL: "https://example.com", MaxClicks: 1}, nil)
+		mockStorage.EXPECT().
+			Consume(gomock.Any(), "once").
+			Return(storage.ErrExhausted)
+
+		resp, err := srv.Redirect(context.Background(), &pb.RedirectRequest{Id: "once"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("password protected", func(t *testing.T) {
+		hash, err := auth.HashPassword("secret")
+		assert.NoError(t, err)
+		link := models.Link{ShortKey: "private", OriginalURL: "https://example.com", PasswordHash: hash}
+		mockStorage.EXPECT().Get(gomock.Any(), "private").Return(link, nil).Times(3)
+
+		resp, err := srv.Redirect(context.Background(), &pb.RedirectRequest{Id: "private"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		resp, err = srv.Redirect(context.Background(), &pb.RedirectRequest{Id: "private", Password: "wrong"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		resp, err = srv.Redirect(context.Background(), &pb.RedirectRequest{Id: "private", Password: "secret"})
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", resp.Url)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mockStorage.EXPECT().
+			Get(gomock.Any(), "expired").
+			Return(models.Link{ShortKey: "expired", OriginalURL: "https://example.com", ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+
+		resp, err := srv.Redirect(context.Background(), &pb.RedirectRequest{Id: "expired"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
 }
 
 func TestGRPCServer_UserUrls(t *testing.T) {
@@ -110,54 +222,223 @@ func TestGRPCServer_UserUrls(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStorage := mock_storage.NewMockStorage(ctrl)
-	srv := grpchandlers.NewGRPCServer(mockStorage)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
 
 	userCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
 
 	t.Run("authenticated user with urls", func(t *testing.T) {
+		clicks := int64(4)
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 		mockStorage.EXPECT().
-			GetUserURL(userCtx).
-			Return([]models.ShortURLItem{
-				{ShortURL: "short1", OriginalURL: "https://orig1"},
-				{ShortURL: "short2", OriginalURL: "https://orig2"},
+			ListUserURLs(userCtx, models.ListQuery{Limit: 2, Cursor: "abc", Sort: models.ListSortClicks, Desc: true, Search: "orig", Tag: "promo", Deleted: models.DeletedInclude}).
+			Return(models.ShortURLPage{
+				Items: []models.ShortURLItem{
+					{ShortURL: "short1", OriginalURL: "https://orig1", Clicks: &clicks, CreatedAt: &createdAt, Tags: []string{"promo"}},
+					{ShortURL: "short2", OriginalURL: "https://orig2", IsDeleted: true},
+				},
+				Total:      5,
+				NextCursor: "next",
 			}, nil)
 
-		resp, err := srv.UserUrls(userCtx, &pb.Empty{})
+		resp, err := srv.UserUrls(userCtx, &pb.UserUrlsRequest{Limit: 2, Cursor: "abc", Sort: "clicks", Order: "desc", Search: "orig", Tag: "promo", Deleted: "include"})
 		assert.NoError(t, err)
 		assert.Len(t, resp.Urls, 2)
+		assert.Equal(t, int64(5), resp.Total)
+		assert.Equal(t, "next", resp.NextCursor)
+		assert.Equal(t, int64(4), resp.Urls[0].GetClicks())
+		assert.Equal(t, "2024-01-01T00:00:00Z", resp.Urls[0].CreatedAt)
+		assert.Equal(t, []string{"promo"}, resp.Urls[0].Tags)
+		assert.True(t, resp.Urls[1].IsDeleted)
 	})
 
 	t.Run("unauthenticated user", func(t *testing.T) {
-		resp, err := srv.UserUrls(context.Background(), &pb.Empty{})
+		resp, err := srv.UserUrls(context.Background(), &pb.UserUrlsRequest{})
 		assert.Nil(t, resp)
 		st, ok := status.FromError(err)
 		assert.True(t, ok)
 		assert.Equal(t, codes.Unauthenticated, st.Code())
 	})
 
+	t.Run("invalid query", func(t *testing.T) {
+		resp, err := srv.UserUrls(userCtx, &pb.UserUrlsRequest{Order: "up"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		mockStorage.EXPECT().
+			ListUserURLs(userCtx, models.ListQuery{Cursor: "bad"}).
+			Return(models.ShortURLPage{}, storage.ErrInvalidQuery)
+
+		resp, err = srv.UserUrls(userCtx, &pb.UserUrlsRequest{Cursor: "bad"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
 	t.Run("storage error", func(t *testing.T) {
 		mockStorage.EXPECT().
-			GetUserURL(userCtx).
-			Return(nil, errors.New("db error"))
+			ListUserURLs(userCtx, models.ListQuery{}).
+			Return(models.ShortURLPage{}, errors.New("db error"))
 
-		resp, err := srv.UserUrls(userCtx, &pb.Empty{})
+		resp, err := srv.UserUrls(userCtx, &pb.UserUrlsRequest{})
 		assert.Nil(t, resp)
 		assert.Error(t, err)
 	})
 }
 
+// importStream — поток записей загрузки для вызова ImportUrls без сетевого соединения.
+type importStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	records []*pb.ImportUrlRecord
+	resp    *pb.ImportUrlsResponse
+}
+
+func (s *importStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *importStream) Recv() (*pb.ImportUrlRecord, error) {
+	if len(s.records) == 0 {
+		return nil, io.EOF
+	}
+	record := s.records[0]
+	s.records = s.records[1:]
+	return record, nil
+}
+
+func (s *importStream) SendAndClose(resp *pb.ImportUrlsResponse) error {
+	s.resp = resp
+	return nil
+}
+
+func TestGRPCServer_ImportUrls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
+
+	userCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	t.Run("authenticated user", func(t *testing.T) {
+		mockStorage.EXPECT().Import(userCtx, gomock.Len(2)).
+			Do(func(_ context.Context, links []models.Link) {
+				assert.Equal(t, []string{"promo"}, links[0].Tags)
+			}).
+			Return([]error{nil, storage.ErrKeyExists}, nil)
+
+		stream := &importStream{ctx: userCtx, records: []*pb.ImportUrlRecord{
+			{ShortUrl: "short1", OriginalUrl: "https://example.com/1", MaxClicks: 3, Tags: []string{"Promo"}},
+			{ShortUrl: "short2", OriginalUrl: "https://example.com/2"},
+			{ShortUrl: "short3", OriginalUrl: "https://example.com/3", ExpiresAt: "tomorrow"},
+		}}
+
+		err := srv.ImportUrls(stream)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), stream.resp.Total)
+		assert.Equal(t, int64(1), stream.resp.Imported)
+		assert.Equal(t, int64(2), stream.resp.Failed)
+		if assert.Len(t, stream.resp.Errors, 2) {
+			assert.Equal(t, int64(3), stream.resp.Errors[0].Line)
+			assert.Equal(t, "short3", stream.resp.Errors[0].ShortUrl)
+			assert.Equal(t, int64(2), stream.resp.Errors[1].Line)
+			assert.Equal(t, "short url already taken", stream.resp.Errors[1].Error)
+		}
+	})
+
+	t.Run("unauthenticated user", func(t *testing.T) {
+		err := srv.ImportUrls(&importStream{ctx: context.Background()})
+		st, ok := status.FromError(err)
+		assert.True(t, ok)
+		assert.Equal(t, codes.Unauthenticated, st.Code())
+	})
+}
+
+// exportStream — поток ссылок выгрузки для вызова ExportUserUrls без сетевого соединения.
+type exportStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	urls []*pb.URL
+}
+
+func (s *exportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *exportStream) Send(url *pb.URL) error {
+	s.urls = append(s.urls, url)
+	return nil
+}
+
+func TestGRPCServer_ExportUserUrls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
+
+	userCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	links := func(err error) storage.UserLinkSeq {
+		return func(yield func(models.UserLink, error) bool) {
+			link := models.Link{ShortKey: "short1", OriginalURL: "https://example.com/1", CreatedAt: createdAt, IsDeleted: true, Tags: []string{"promo"}}
+			if !yield(models.UserLink{Link: link, Clicks: 3}, nil) {
+				return
+			}
+			if err != nil {
+				yield(models.UserLink{}, err)
+			}
+		}
+	}
+
+	t.Run("authenticated user", func(t *testing.T) {
+		mockStorage.EXPECT().UserLinks(userCtx).Return(links(nil))
+
+		stream := &exportStream{ctx: userCtx}
+		err := srv.ExportUserUrls(&pb.Empty{}, stream)
+		assert.NoError(t, err)
+		if assert.Len(t, stream.urls, 1) {
+			assert.Equal(t, config.FlagBaseAddr+"/short1", stream.urls[0].ShortUrl)
+			assert.Equal(t, "2024-01-01T00:00:00Z", stream.urls[0].CreatedAt)
+			assert.Equal(t, int64(3), stream.urls[0].GetClicks())
+			assert.True(t, stream.urls[0].IsDeleted)
+			assert.Equal(t, []string{"promo"}, stream.urls[0].Tags)
+		}
+	})
+
+	t.Run("storage error", func(t *testing.T) {
+		mockStorage.EXPECT().UserLinks(userCtx).Return(links(errors.New("connection reset")))
+
+		stream := &exportStream{ctx: userCtx}
+		err := srv.ExportUserUrls(&pb.Empty{}, stream)
+		assert.EqualError(t, err, "connection reset")
+		assert.Len(t, stream.urls, 1)
+	})
+
+	t.Run("unauthenticated user", func(t *testing.T) {
+		err := srv.ExportUserUrls(&pb.Empty{}, &exportStream{ctx: context.Background()})
+		st, ok := status.FromError(err)
+		assert.True(t, ok)
+		assert.Equal(t, codes.Unauthenticated, st.Code())
+	})
+}
+
 func TestGRPCServer_DeleteUserUrls(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockStorage := mock_storage.NewMockStorage(ctrl)
-	srv := grpchandlers.NewGRPCServer(mockStorage)
+	deletes := deleter.NewDeleter(mockStorage)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, deletes)
 
 	userCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
 
 	t.Run("authenticated user", func(t *testing.T) {
-		mockStorage.EXPECT().Delete(userCtx, "short1").Return(nil)
-		mockStorage.EXPECT().Delete(userCtx, "short2").Return(nil)
+		mockStorage.EXPECT().DeleteBatch(gomock.Any(), []string{"short1", "short2"}).DoAndReturn(
+			func(ctx context.Context, _ []string) error {
+				assert.Equal(t, "user1", auth.UserIDFromContext(ctx), "Only links of the caller should be deleted")
+				return nil
+			})
 
 		req := &pb.DeleteUserUrlsRequest{
 			ShortUrls: []string{"short1", "short2"},
@@ -166,6 +447,17 @@ func TestGRPCServer_DeleteUserUrls(t *testing.T) {
 		resp, err := srv.DeleteUserUrls(userCtx, req)
 		assert.NoError(t, err)
 		assert.NotNil(t, resp)
+
+		// принятый запрос выполняется при остановке сервиса удаления
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		deletes.Run(ctx)
+	})
+
+	t.Run("service stopped", func(t *testing.T) {
+		resp, err := srv.DeleteUserUrls(userCtx, &pb.DeleteUserUrlsRequest{ShortUrls: []string{"short1"}})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
 	})
 
 	t.Run("unauthenticated user", func(t *testing.T) {
@@ -186,7 +478,7 @@ func TestGRPCServer_InternalStats(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStorage := mock_storage.NewMockStorage(ctrl)
-	srv := grpchandlers.NewGRPCServer(mockStorage)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
 
 	mockStorage.EXPECT().CountURLs(gomock.Any()).Return(10, nil)
 	mockStorage.EXPECT().CountUsers(gomock.Any()).Return(5, nil)
@@ -197,3 +489,154 @@ func TestGRPCServer_InternalStats(t *testing.T) {
 	assert.Equal(t, int64(10), resp.Urls)
 	assert.Equal(t, int64(5), resp.Users)
 }
+
+func TestGRPCServer_LinkStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
+
+	userCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	t.Run("success", func(t *testing.T) {
+		bucket := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		mockStorage.EXPECT().
+			Get(gomock.Any(), "short").
+			Return(models.Link{ShortKey: "short", OriginalURL: "https://example.com", UserID: "user1"}, nil)
+		mockStorage.EXPECT().
+			GetStats(gomock.Any(), "short", gomock.Any(), gomock.Any(), 24*time.Hour).
+			Return(int64(2), []models.StatsBucket{{Time: bucket, Clicks: 2}}, nil)
+
+		resp, err := srv.LinkStats(userCtx, &pb.LinkStatsRequest{Id: "short"})
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", resp.OriginalUrl)
+		assert.Equal(t, int64(2), resp.Clicks)
+		assert.Len(t, resp.Series, 1)
+		assert.Equal(t, "2024-01-01T00:00:00Z", resp.Series[0].Time)
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		mockStorage.EXPECT().
+			Get(gomock.Any(), "foreign").
+			Return(models.Link{ShortKey: "foreign", OriginalURL: "https://example.com/private", UserID: "user2"}, nil)
+
+		resp, err := srv.LinkStats(userCtx, &pb.LinkStatsRequest{Id: "foreign"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("unauthenticated user", func(t *testing.T) {
+		resp, err := srv.LinkStats(context.Background(), &pb.LinkStatsRequest{Id: "short"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		resp, err := srv.LinkStats(userCtx, &pb.LinkStatsRequest{Id: "short", Interval: "abc"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockStorage.EXPECT().
+			Get(gomock.Any(), "missing").
+			Return(models.Link{}, errors.New("not found"))
+
+		resp, err := srv.LinkStats(userCtx, &pb.LinkStatsRequest{Id: "missing"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestGRPCServer_UpdateURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
+
+	userCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	t.Run("success", func(t *testing.T) {
+		mockStorage.EXPECT().Update(userCtx, "short", "https://new.example.com").Return(nil)
+
+		resp, err := srv.UpdateURL(userCtx, &pb.UpdateURLRequest{Id: "short", Url: "https://new.example.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, "https://new.example.com", resp.OriginalUrl)
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		mockStorage.EXPECT().Update(userCtx, "short", "https://new.example.com").Return(storage.ErrForbidden)
+
+		resp, err := srv.UpdateURL(userCtx, &pb.UpdateURLRequest{Id: "short", Url: "https://new.example.com"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+
+	t.Run("unauthenticated user", func(t *testing.T) {
+		resp, err := srv.UpdateURL(context.Background(), &pb.UpdateURLRequest{Id: "short", Url: "https://new.example.com"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestGRPCServer_RollbackURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
+
+	userCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	t.Run("success", func(t *testing.T) {
+		mockStorage.EXPECT().Rollback(userCtx, "short").Return("https://old.example.com", nil)
+
+		resp, err := srv.RollbackURL(userCtx, &pb.RollbackURLRequest{Id: "short"})
+		assert.NoError(t, err)
+		assert.Equal(t, "https://old.example.com", resp.OriginalUrl)
+	})
+
+	t.Run("no history", func(t *testing.T) {
+		mockStorage.EXPECT().Rollback(userCtx, "short").Return("", storage.ErrNoHistory)
+
+		resp, err := srv.RollbackURL(userCtx, &pb.RollbackURLRequest{Id: "short"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+}
+
+func TestGRPCServer_RestoreURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mock_storage.NewMockStorage(ctrl)
+	srv := grpchandlers.NewGRPCServer(mockStorage, nil, nil, nil)
+
+	userCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	t.Run("success", func(t *testing.T) {
+		mockStorage.EXPECT().Restore(userCtx, "short", gomock.Any()).Return("https://example.com", nil)
+
+		resp, err := srv.RestoreURL(userCtx, &pb.RestoreURLRequest{Id: "short"})
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", resp.OriginalUrl)
+		assert.Equal(t, config.FlagBaseAddr+"/short", resp.ShortUrl)
+	})
+
+	t.Run("restore window expired", func(t *testing.T) {
+		mockStorage.EXPECT().Restore(userCtx, "short", gomock.Any()).Return("", storage.ErrRestoreExpired)
+
+		resp, err := srv.RestoreURL(userCtx, &pb.RestoreURLRequest{Id: "short"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("unauthenticated user", func(t *testing.T) {
+		resp, err := srv.RestoreURL(context.Background(), &pb.RestoreURLRequest{Id: "short"})
+		assert.Nil(t, resp)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
@@ -2,43 +2,89 @@ package grpcserver
 
 import (
 	"context"
+	"errors"
 	"net"
 	"net/http"
+	"strings"
 
+	"github.com/dsemenov12/shorturl/internal/analytics"
+	"github.com/dsemenov12/shorturl/internal/deleter"
 	"github.com/dsemenov12/shorturl/internal/grpchandlers"
+	"github.com/dsemenov12/shorturl/internal/health"
+	"github.com/dsemenov12/shorturl/internal/keygen"
 	"github.com/dsemenov12/shorturl/internal/middlewares/authinterceptor"
 	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
+	"github.com/dsemenov12/shorturl/internal/middlewares/metricsinterceptor"
+	"github.com/dsemenov12/shorturl/internal/middlewares/requestid"
 	"github.com/dsemenov12/shorturl/internal/storage"
 	pb "github.com/dsemenov12/shorturl/proto"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// HealthComponent — имя проверки готовности, которая проходит после запуска gRPC сервера.
+const HealthComponent = "grpc"
+
 // RunGRPCServer запускает gRPC сервер с указанным адресом и хранилищем.
-// Внутри сервера используется Unary Interceptor для аутентификации пользователей с помощью JWT-токенов.
+// Внутри сервера используются Unary и Stream Interceptor-ы для записи метрик вызовов, присвоения идентификатора
+// запроса, логирования вызовов и аутентификации пользователей с помощью JWT-токенов. Каждый вызов выполняется в спане OpenTelemetry,
+// продолжающем трассировку из metadata запроса.
 // После успешной аутентификации создаётся gRPC сервер и регистрируется обработчик сервиса ShortenerService.
+// Кроме того, регистрируется стандартный сервис grpc.health.v1.Health: сервер и ShortenerService
+// отвечают SERVING, а после начала завершения работы сервиса (health.Health.Drain) или отмены ctx — NOT_SERVING.
+// После отмены ctx сервер останавливается методом GracefulStop, и функция возвращается после выполнения начатых вызовов.
 //
 // ctx: Контекст для управления жизненным циклом сервера (например, отмена через сигнал).
 // storage: Реализация интерфейса Storage для работы с данными.
+// clicks: Сборщик статистики переходов (может быть nil).
+// keys: Генератор коротких ключей (может быть nil).
+// deletes: Сервис фонового удаления ссылок.
+// checks: Проверки состояния сервиса; после запуска сервера компонент HealthComponent отмечается запущенным (может быть nil).
 // grpcAddr: Адрес (host:port), на котором запускается gRPC сервер.
 //
 // Возвращаемое значение: ошибка запуска сервера (если есть).
-func RunGRPCServer(ctx context.Context, storage storage.Storage, grpcAddr string) error {
+func RunGRPCServer(ctx context.Context, storage storage.Storage, clicks *analytics.Recorder, keys keygen.KeyGenerator, deletes *deleter.Deleter, checks *health.Health, grpcAddr string) error {
 	lis, err := net.Listen("tcp", grpcAddr)
 	if err != nil {
 		return err
 	}
 
 	grpcSrv := grpc.NewServer(
-		grpc.UnaryInterceptor(authinterceptor.AuthUnaryInterceptor()),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			metricsinterceptor.MetricsUnaryInterceptor(),
+			requestid.RequestIDUnaryInterceptor(),
+			logger.UnaryCallLogger(),
+			authinterceptor.AuthUnaryInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			metricsinterceptor.MetricsStreamInterceptor(),
+			requestid.RequestIDStreamInterceptor(),
+			logger.StreamCallLogger(),
+			authinterceptor.AuthStreamInterceptor(),
+		),
 	)
-	pb.RegisterShortenerServiceServer(grpcSrv, grpchandlers.NewGRPCServer(storage))
+	pb.RegisterShortenerServiceServer(grpcSrv, grpchandlers.NewGRPCServer(storage, clicks, keys, deletes))
+
+	healthSrv := grpchealth.NewServer()
+	healthSrv.SetServingStatus(pb.ShortenerService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcSrv, healthSrv)
+
+	if checks != nil {
+		checks.OnDrain(healthSrv.Shutdown)
+		checks.SetStarted(HealthComponent)
+	}
 
 	go func() {
 		<-ctx.Done()
+		healthSrv.Shutdown()
 		grpcSrv.GracefulStop()
 	}()
 
@@ -47,6 +93,9 @@ func RunGRPCServer(ctx context.Context, storage storage.Storage, grpcAddr string
 }
 
 // RunGateway запускает HTTP сервер grpc-gateway, который проксирует REST-запросы в gRPC сервер.
+// Контекст трассировки и заголовок X-Request-ID из HTTP-запроса передаются в gRPC-вызов,
+// а идентификатор запроса возвращается в заголовке X-Request-ID ответа.
+// После отмены ctx сервер перестаёт принимать соединения, и функция возвращается после выполнения начатых запросов.
 //
 // ctx: Контекст для управления жизненным циклом сервера.
 // grpcAddr: Адрес gRPC сервера, к которому grpc-gateway будет подключаться.
@@ -54,8 +103,14 @@ func RunGRPCServer(ctx context.Context, storage storage.Storage, grpcAddr string
 //
 // Возвращаемое значение: ошибка запуска HTTP сервера (если есть).
 func RunGateway(ctx context.Context, grpcAddr, httpAddr string) error {
-	mux := runtime.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	mux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+	)
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	}
 
 	err := pb.RegisterShortenerServiceHandlerFromEndpoint(ctx, mux, grpcAddr, opts)
 	if err != nil {
@@ -64,14 +119,39 @@ func RunGateway(ctx context.Context, grpcAddr, httpAddr string) error {
 
 	srv := &http.Server{
 		Addr:    httpAddr,
-		Handler: mux,
+		Handler: otelhttp.NewHandler(mux, "grpc-gateway"),
 	}
 
+	stopped := make(chan struct{})
 	go func() {
 		<-ctx.Done()
 		_ = srv.Shutdown(context.Background())
+		close(stopped)
 	}()
 
 	logger.Log.Info("Starting grpc-gateway server", zap.String("address", httpAddr))
-	return srv.ListenAndServe()
+	if err = srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	<-stopped
+	return nil
+}
+
+// incomingHeaderMatcher передаёт в gRPC metadata заголовок X-Request-ID вместе с заголовками,
+// которые grpc-gateway передаёт по умолчанию.
+func incomingHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, requestid.Header) {
+		return requestid.MetadataKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeaderMatcher возвращает идентификатор запроса из gRPC metadata в заголовке X-Request-ID,
+// а остальные ключи — с префиксом Grpc-Metadata-, как grpc-gateway по умолчанию.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	if key == requestid.MetadataKey {
+		return requestid.Header, true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
 }
@@ -2,13 +2,24 @@ package grpcserver_test
 
 import (
 	"context"
+	"net"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/dsemenov12/shorturl/internal/grpcserver"
+	"github.com/dsemenov12/shorturl/internal/health"
+	"github.com/dsemenov12/shorturl/internal/middlewares/requestid"
 	"github.com/dsemenov12/shorturl/internal/storage/mocks"
+	"github.com/dsemenov12/shorturl/internal/tracing"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestRunGRPCServer_StartStop(t *testing.T) {
@@ -25,7 +36,7 @@ func TestRunGRPCServer_StartStop(t *testing.T) {
 
 	// Запускаем сервер в горутине, чтобы он не блокировал тест
 	go func() {
-		err := grpcserver.RunGRPCServer(ctx, mockStorage, grpcAddr)
+		err := grpcserver.RunGRPCServer(ctx, mockStorage, nil, nil, nil, nil, grpcAddr)
 		assert.NoError(t, err)
 	}()
 
@@ -36,6 +47,44 @@ func TestRunGRPCServer_StartStop(t *testing.T) {
 	time.Sleep(100 * time.Millisecond)
 }
 
+func TestRunGRPCServer_Health(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	checks := health.New()
+	checks.AddComponent(grpcserver.HealthComponent)
+	require.False(t, checks.Ready(context.Background()).OK())
+
+	grpcAddr := freeAddr(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go grpcserver.RunGRPCServer(ctx, mocks.NewMockStorage(ctrl), nil, nil, nil, checks, grpcAddr)
+
+	// После запуска сервера проверка готовности проходит
+	require.Eventually(t, func() bool {
+		return checks.Ready(context.Background()).OK()
+	}, 5*time.Second, 50*time.Millisecond)
+
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	for _, service := range []string{"", "shorturl.ShortenerService"} {
+		response, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status, service)
+	}
+
+	// После начала завершения работы сервис сообщает NOT_SERVING, продолжая отвечать на вызовы
+	checks.Drain()
+	response, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "shorturl.ShortenerService"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, response.Status)
+	assert.False(t, checks.Ready(context.Background()).OK())
+}
+
 func TestRunGateway_StartStop(t *testing.T) {
 	grpcAddr := "localhost:0"
 	httpAddr := "localhost:0"
@@ -46,12 +95,7 @@ func TestRunGateway_StartStop(t *testing.T) {
 	errCh := make(chan error, 1)
 
 	go func() {
-		err := grpcserver.RunGateway(ctx, grpcAddr, httpAddr)
-		if err != nil && err.Error() != "http: Server closed" {
-			errCh <- err
-		} else {
-			errCh <- nil
-		}
+		errCh <- grpcserver.RunGateway(ctx, grpcAddr, httpAddr)
 	}()
 
 	time.Sleep(100 * time.Millisecond)
@@ -61,3 +105,96 @@ func TestRunGateway_StartStop(t *testing.T) {
 	err := <-errCh
 	assert.NoError(t, err)
 }
+
+func TestRunGateway_Tracing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockStorage := mocks.NewMockStorage(ctrl)
+	mockStorage.EXPECT().CountURLs(gomock.Any()).Return(10, nil)
+	mockStorage.EXPECT().CountUsers(gomock.Any()).Return(3, nil)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter, 1)
+	otel.SetTracerProvider(provider)
+	_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone})
+	require.NoError(t, err)
+
+	grpcAddr, httpAddr := freeAddr(t), freeAddr(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go grpcserver.RunGRPCServer(ctx, mockStorage, nil, nil, nil, nil, grpcAddr)
+	go grpcserver.RunGateway(ctx, grpcAddr, httpAddr)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	request, err := http.NewRequest(http.MethodGet, "http://"+httpAddr+"/api/internal/stats", nil)
+	require.NoError(t, err)
+	request.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+
+	var response *http.Response
+	require.Eventually(t, func() bool {
+		response, err = http.DefaultClient.Do(request)
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	// Спаны gateway, gRPC-клиента и gRPC-сервера принадлежат трассировке клиента
+	require.NoError(t, provider.ForceFlush(context.Background()))
+	names := make(map[string]bool)
+	for _, span := range exporter.GetSpans() {
+		assert.Equal(t, traceID, span.SpanContext.TraceID().String(), span.Name)
+		names[span.Name+"/"+span.SpanKind.String()] = true
+	}
+	assert.True(t, names["grpc-gateway/server"])
+	assert.True(t, names["shorturl.ShortenerService/InternalStats/client"])
+	assert.True(t, names["shorturl.ShortenerService/InternalStats/server"])
+}
+
+// freeAddr возвращает адрес свободного локального порта.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	return lis.Addr().String()
+}
+
+func TestRunGateway_RequestID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var gotID string
+	mockStorage := mocks.NewMockStorage(ctrl)
+	mockStorage.EXPECT().CountURLs(gomock.Any()).DoAndReturn(func(ctx context.Context) (int, error) {
+		gotID = requestid.FromContext(ctx)
+		return 10, nil
+	})
+	mockStorage.EXPECT().CountUsers(gomock.Any()).Return(3, nil)
+
+	grpcAddr, httpAddr := freeAddr(t), freeAddr(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go grpcserver.RunGRPCServer(ctx, mockStorage, nil, nil, nil, nil, grpcAddr)
+	go grpcserver.RunGateway(ctx, grpcAddr, httpAddr)
+
+	request, err := http.NewRequest(http.MethodGet, "http://"+httpAddr+"/api/internal/stats", nil)
+	require.NoError(t, err)
+	request.Header.Set(requestid.Header, "req-42")
+
+	var response *http.Response
+	require.Eventually(t, func() bool {
+		response, err = http.DefaultClient.Do(request)
+		return err == nil
+	}, 5*time.Second, 50*time.Millisecond)
+	response.Body.Close()
+
+	// Идентификатор клиента доходит до gRPC-сервера и возвращается в ответе gateway
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "req-42", gotID)
+	assert.Equal(t, "req-42", response.Header.Get(requestid.Header))
+}
@@ -8,14 +8,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/dsemenov12/shorturl/internal/deleter"
 	"github.com/dsemenov12/shorturl/internal/handlers"
 	"github.com/dsemenov12/shorturl/internal/models"
 	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/go-chi/chi/v5"
 )
 
 func ExampleApp_ShortenPost() {
 	store := memory.NewStorage()
-	app := handlers.NewApp(store)
+	app := handlers.NewApp(store, nil, nil, nil)
 	reqBody := `{"url":"https://example.com"}`
 	req := httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewBufferString(reqBody))
 	req.Header.Set("Content-Type", "application/json")
@@ -35,7 +37,7 @@ func ExampleApp_ShortenPost() {
 
 func ExampleApp_ShortenBatchPost() {
 	store := memory.NewStorage()
-	app := handlers.NewApp(store)
+	app := handlers.NewApp(store, nil, nil, nil)
 
 	reqBody := `[
 		{"correlation_id": "1", "original_url": "https://example.com"},
@@ -47,26 +49,23 @@ func ExampleApp_ShortenBatchPost() {
 
 	app.ShortenBatchPost(res, req)
 
+	var result []models.BatchResultItem
+	json.Unmarshal(res.Body.Bytes(), &result)
+
 	fmt.Println(res.Code)
-	fmt.Println(res.Body.String())
+	for _, item := range result {
+		fmt.Println(item.CorrelationID)
+	}
 
 	// Output:
 	// 201
-	// [
-	//     {
-	//         "correlation_id": "1",
-	//         "short_url": "/1"
-	//     },
-	//     {
-	//         "correlation_id": "2",
-	//         "short_url": "/2"
-	//     }
-	// ]
+	// 1
+	// 2
 }
 
 func ExampleApp_PostURL() {
 	store := memory.NewStorage()
-	app := handlers.NewApp(store)
+	app := handlers.NewApp(store, nil, nil, nil)
 
 	reqBody := "https://example.com"
 	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(reqBody))
@@ -83,10 +82,13 @@ func ExampleApp_PostURL() {
 
 func ExampleApp_Redirect() {
 	store := memory.NewStorage()
-	store.Set(context.TODO(), "abc123", "https://example.com")
-	app := handlers.NewApp(store)
+	store.Set(context.TODO(), models.Link{ShortKey: "abc123", OriginalURL: "https://example.com"})
+	app := handlers.NewApp(store, nil, nil, nil)
 
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("id", "abc123")
 	req := httptest.NewRequest(http.MethodGet, "/abc123", nil)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, routeCtx))
 	res := httptest.NewRecorder()
 
 	app.Redirect(res, req)
@@ -96,12 +98,12 @@ func ExampleApp_Redirect() {
 
 	// Output:
 	// 307
-	// /
+	// https://example.com
 }
 
 func ExampleApp_UserUrls() {
 	store := memory.NewStorage()
-	app := handlers.NewApp(store)
+	app := handlers.NewApp(store, nil, nil, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
 	res := httptest.NewRecorder()
@@ -118,7 +120,11 @@ func ExampleApp_UserUrls() {
 
 func ExampleApp_DeleteUserUrls() {
 	store := memory.NewStorage()
-	app := handlers.NewApp(store)
+	deletes := deleter.NewDeleter(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	go deletes.Run(ctx)
+
+	app := handlers.NewApp(store, nil, nil, deletes)
 
 	reqBody := `[
 		"http://localhost:8080/1",
@@ -132,8 +138,10 @@ func ExampleApp_DeleteUserUrls() {
 
 	fmt.Println(res.Code)
 
+	// Удаление выполняется в фоне; после остановки сервиса все принятые запросы выполнены
+	cancel()
+	deletes.Wait()
+
 	// Output:
-	// ok
-	// ok
 	// 202
 }
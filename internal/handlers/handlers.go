@@ -3,42 +3,83 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html/template"
 	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
+	"github.com/dsemenov12/shorturl/internal/alias"
+	"github.com/dsemenov12/shorturl/internal/analytics"
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/batch"
 	"github.com/dsemenov12/shorturl/internal/config"
-	"github.com/dsemenov12/shorturl/internal/filestorage"
+	"github.com/dsemenov12/shorturl/internal/deleter"
+	"github.com/dsemenov12/shorturl/internal/expiry"
+	"github.com/dsemenov12/shorturl/internal/exporter"
+	"github.com/dsemenov12/shorturl/internal/importer"
+	"github.com/dsemenov12/shorturl/internal/keygen"
+	"github.com/dsemenov12/shorturl/internal/metrics"
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
 	"github.com/dsemenov12/shorturl/internal/models"
-	"github.com/dsemenov12/shorturl/internal/rand"
 	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/tags"
 	"github.com/go-chi/chi/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
 )
 
-// dataToFile представляет структуру для хранения данных в файле.
-type dataToFile struct {
-	Data map[string]string
-}
+// unlockPage — HTML-страница с формой ввода пароля защищённой ссылки.
+// Форма отправляется методом POST на адрес самой ссылки.
+var unlockPage = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Ссылка защищена паролем</title>
+</head>
+<body>
+<form method="post">
+<p>Для перехода по ссылке введите пароль.</p>
+{{if .}}<p>{{.}}</p>{{end}}
+<input type="password" name="password" autofocus required>
+<button type="submit">Перейти</button>
+</form>
+</body>
+</html>
+`))
 
 // app представляет основное приложение, которое взаимодействует с хранилищем.
 type App struct {
 	storage storage.Storage
+	clicks  *analytics.Recorder
+	keys    keygen.KeyGenerator
+	deletes *deleter.Deleter
 }
 
 // NewApp создает новый экземпляр приложения.
-func NewApp(storage storage.Storage) *App {
-	return &App{storage: storage}
+// clicks используется для учета переходов по коротким ссылкам и может быть nil.
+// keys генерирует короткие ключи; если он nil, используются случайные ключи с настройками по умолчанию.
+// deletes выполняет удаление ссылок пользователей; если он nil, удаление недоступно.
+func NewApp(storage storage.Storage, clicks *analytics.Recorder, keys keygen.KeyGenerator, deletes *deleter.Deleter) *App {
+	if keys == nil {
+		keys, _ = keygen.NewRandom(config.DefaultKeyAlphabet, config.DefaultKeyLength)
+	}
+	return &App{storage: storage, clicks: clicks, keys: keys, deletes: deletes}
 }
 
 // ShortenPost обрабатывает запрос на сокращение URL в формате JSON.
+// Если в запросе передан alias, он используется как короткий ключ после проверки.
+// Срок действия ссылки задаётся полем ttl (в секундах) или expires_at (RFC 3339),
+// а максимальное количество переходов — полем max_clicks.
+// Метки ссылки передаются списком tags; они нормализуются функцией tags.NormalizeAll.
+// Если передан password, переход по ссылке будет требовать ввода пароля.
 func (a *App) ShortenPost(res http.ResponseWriter, req *http.Request) {
 	var inputDataValue models.InputData
 
-	shortKey := rand.RandStringBytes(8)
-	shortURL := config.FlagBaseAddr + "/" + shortKey
 	status := http.StatusCreated
 
 	body, err := io.ReadAll(req.Body)
@@ -56,19 +97,58 @@ func (a *App) ShortenPost(res http.ResponseWriter, req *http.Request) {
 	}
 	defer req.Body.Close()
 
-	shortKeyResult, err := a.storage.Set(req.Context(), shortKey, inputDataValue.URL)
+	if inputDataValue.Alias != "" {
+		if err = alias.Validate(inputDataValue.Alias); err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	expiresAt, err := expiry.Resolve(inputDataValue.TTL, inputDataValue.ExpiresAt, time.Now())
 	if err != nil {
-		shortURL = config.FlagBaseAddr + "/" + shortKeyResult
-		status = http.StatusConflict
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	storageData := make(map[string]string)
-	storageData[shortKey] = inputDataValue.URL
+	if inputDataValue.MaxClicks < 0 {
+		http.Error(res, "invalid max_clicks", http.StatusBadRequest)
+		return
+	}
+
+	linkTags, err := tags.NormalizeAll(inputDataValue.Tags)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	filestorage.Save(storageData)
+	link := models.Link{
+		ShortKey:    inputDataValue.Alias,
+		OriginalURL: inputDataValue.URL,
+		UserID:      auth.UserIDFromContext(req.Context()),
+		ExpiresAt:   expiresAt,
+		MaxClicks:   inputDataValue.MaxClicks,
+		Tags:        linkTags,
+	}
+	if inputDataValue.Password != "" {
+		if link.PasswordHash, err = auth.HashPassword(inputDataValue.Password); err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	link.ShortKey, err = a.setLink(req.Context(), link)
+	switch {
+	case errors.Is(err, storage.ErrKeyExists) && inputDataValue.Alias != "":
+		http.Error(res, "alias already taken", http.StatusConflict)
+		return
+	case errors.Is(err, storage.ErrConflict):
+		status = http.StatusConflict
+	case err != nil:
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
 	var result = models.ResultJSON{
-		Result: shortURL,
+		Result: config.FlagBaseAddr + "/" + link.ShortKey,
 	}
 
 	resp, err := json.MarshalIndent(result, "", "    ")
@@ -83,12 +163,22 @@ func (a *App) ShortenPost(res http.ResponseWriter, req *http.Request) {
 }
 
 // ShortenBatchPost обрабатывает пакетное сокращение URL.
-// Ожидает массив объектов с исходными URL и возвращает массив с сокращёнными ссылками.
+// Ожидает массив объектов с исходными URL и возвращает массив результатов со статусом каждого элемента:
+// created, exists или invalid. Параметр запроса atomic=true сохраняет все элементы пакета или ни одного;
+// при наличии некорректных элементов в этом режиме возвращается 400 со списком этих элементов.
+// Код ответа: 201, если создана хотя бы одна ссылка, 409, если все корректные URL уже сокращены,
+// и 400, если корректных элементов нет.
 func (a *App) ShortenBatchPost(res http.ResponseWriter, req *http.Request) {
-	var batch []models.BatchItem
-	var result []models.BatchResultItem
-
-	status := http.StatusCreated
+	var batchItems []models.BatchItem
+
+	atomic := false
+	if value := req.URL.Query().Get("atomic"); value != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(value); err != nil {
+			http.Error(res, "invalid atomic parameter", http.StatusBadRequest)
+			return
+		}
+	}
 
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
@@ -99,29 +189,19 @@ func (a *App) ShortenBatchPost(res http.ResponseWriter, req *http.Request) {
 		http.Error(res, "empty body", http.StatusBadRequest)
 		return
 	}
-	if err = json.Unmarshal(body, &batch); err != nil {
+	if err = json.Unmarshal(body, &batchItems); err != nil {
 		http.Error(res, err.Error(), http.StatusBadRequest)
 		return
 	}
 	defer req.Body.Close()
 
-	for _, batchItem := range batch {
-		if batchItem.CorrelationID == "" || batchItem.OriginalURL == "" {
-			continue
-		}
-
-		shortURL := config.FlagBaseAddr + "/" + batchItem.CorrelationID
-
-		shortKeyResult, err := a.storage.Set(req.Context(), batchItem.CorrelationID, batchItem.OriginalURL)
-		if err != nil {
-			shortURL = config.FlagBaseAddr + "/" + shortKeyResult
-			status = http.StatusConflict
-		}
-
-		result = append(result, models.BatchResultItem{
-			CorrelationID: batchItem.CorrelationID,
-			ShortURL:      shortURL,
-		})
+	result, err := batch.Shorten(req.Context(), a.storage, a.keys, batchItems, atomic)
+	status := batchStatus(result)
+	if errors.Is(err, batch.ErrInvalidItems) {
+		status = http.StatusBadRequest
+	} else if err != nil {
+		http.Error(res, err.Error(), storageErrorStatus(err))
+		return
 	}
 
 	resp, err := json.MarshalIndent(result, "", "    ")
@@ -135,11 +215,27 @@ func (a *App) ShortenBatchPost(res http.ResponseWriter, req *http.Request) {
 	res.Write(resp)
 }
 
+// batchStatus возвращает код ответа пакетного сокращения по результатам элементов.
+func batchStatus(result []models.BatchResultItem) int {
+	status := http.StatusCreated
+	if len(result) > 0 {
+		status = http.StatusBadRequest
+	}
+	for _, item := range result {
+		switch item.Status {
+		case models.BatchStatusCreated:
+			return http.StatusCreated
+		case models.BatchStatusExists:
+			status = http.StatusConflict
+		}
+	}
+
+	return status
+}
+
 // PostURL обрабатывает сокращение URL, переданного в теле запроса.
 // Принимает URL в виде текста, сокращает его и возвращает короткую ссылку.
 func (a *App) PostURL(res http.ResponseWriter, req *http.Request) {
-	shortKey := rand.RandStringBytes(8)
-	shortURL := config.FlagBaseAddr + "/" + shortKey
 	status := http.StatusCreated
 
 	body, err := io.ReadAll(req.Body)
@@ -153,54 +249,243 @@ func (a *App) PostURL(res http.ResponseWriter, req *http.Request) {
 	}
 	defer req.Body.Close()
 
-	shortKeyResult, err := a.storage.Set(req.Context(), shortKey, string(body))
-	if err != nil {
-		shortURL = config.FlagBaseAddr + "/" + shortKeyResult
+	link := models.Link{OriginalURL: string(body), UserID: auth.UserIDFromContext(req.Context())}
+	link.ShortKey, err = a.setLink(req.Context(), link)
+	if errors.Is(err, storage.ErrConflict) {
 		status = http.StatusConflict
+	} else if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	data := dataToFile{Data: make(map[string]string)}
-	data.Data[shortKey] = string(body)
-	filestorage.Save(data.Data)
-
 	res.Header().Set("Content-Type", "text/plain")
 	res.WriteHeader(status)
-	res.Write([]byte(shortURL))
+	res.Write([]byte(config.FlagBaseAddr + "/" + link.ShortKey))
 }
 
 // Redirect обрабатывает перенаправление по короткому URL.
+// Для удалённых ссылок, ссылок с истёкшим сроком действия и исчерпанным лимитом переходов
+// возвращает 410 Gone. Для защищённой паролем ссылки без действующего cookie доступа
+// вместо перенаправления отображается форма ввода пароля.
 func (a *App) Redirect(res http.ResponseWriter, req *http.Request) {
 	shortKey := chi.URLParam(req, "id")
 
-	var redirectLink string
-	var err error
+	link, err := a.storage.Get(req.Context(), shortKey)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusNotFound)
+		return
+	}
+	if link.IsDeleted {
+		http.Error(res, "", http.StatusGone)
+		return
+	}
+	if link.Expired(time.Now()) {
+		http.Error(res, "link expired", http.StatusGone)
+		return
+	}
+	if link.Protected() && !linkUnlocked(req, shortKey) {
+		renderUnlockPage(res, http.StatusOK, "")
+		return
+	}
+	if link.MaxClicks > 0 {
+		err = a.storage.Consume(req.Context(), shortKey)
+		if errors.Is(err, storage.ErrExhausted) {
+			http.Error(res, err.Error(), http.StatusGone)
+			return
+		}
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	a.clicks.Track(models.ClickEvent{
+		ShortKey:  shortKey,
+		Timestamp: time.Now().UTC(),
+		Referrer:  req.Referer(),
+		UserAgent: req.UserAgent(),
+	})
 
-	redirectLink, _, isDeleted, err := a.storage.Get(req.Context(), shortKey)
+	http.Redirect(res, req, link.OriginalURL, http.StatusTemporaryRedirect)
+}
 
+// UnlockLink проверяет пароль защищённой ссылки, отправленный формой из Redirect.
+// При верном пароле устанавливает короткоживущий cookie с токеном доступа, ограниченный путём ссылки,
+// и повторно перенаправляет на ссылку.
+func (a *App) UnlockLink(res http.ResponseWriter, req *http.Request) {
+	shortKey := chi.URLParam(req, "id")
+
+	link, err := a.storage.Get(req.Context(), shortKey)
 	if err != nil {
 		http.Error(res, err.Error(), http.StatusNotFound)
 		return
 	}
-	if isDeleted {
+	if link.IsDeleted || link.Expired(time.Now()) {
 		http.Error(res, "", http.StatusGone)
 		return
 	}
+	if !link.Protected() {
+		http.Redirect(res, req, req.URL.Path, http.StatusSeeOther)
+		return
+	}
+	if !auth.CheckPassword(link.PasswordHash, req.PostFormValue("password")) {
+		renderUnlockPage(res, http.StatusUnauthorized, "Неверный пароль.")
+		return
+	}
+
+	token, err := auth.BuildLinkToken(shortKey)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	http.Redirect(res, req, redirectLink, http.StatusTemporaryRedirect)
+	http.SetCookie(res, &http.Cookie{
+		Name:     auth.LinkCookieName,
+		Value:    token,
+		Path:     req.URL.Path,
+		MaxAge:   int(auth.LinkTokenExp / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(res, req, req.URL.Path, http.StatusSeeOther)
 }
 
-// UserUrls возвращает список URL, сохраненных пользователем.
+// LinkStats возвращает владельцу короткой ссылки количество переходов по ней и временной ряд переходов.
+// Период и длина интервала задаются параметрами запроса from, to (RFC 3339) и interval (например, "1h").
+func (a *App) LinkStats(res http.ResponseWriter, req *http.Request) {
+	shortKey := chi.URLParam(req, "id")
+
+	query := req.URL.Query()
+	from, to, step, err := analytics.ParseRange(query.Get("from"), query.Get("to"), query.Get("interval"), time.Now().UTC())
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	link, err := a.storage.Get(req.Context(), shortKey)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusNotFound)
+		return
+	}
+	if link.UserID != auth.UserIDFromContext(req.Context()) {
+		http.Error(res, "access forbidden", http.StatusForbidden)
+		return
+	}
+	if link.IsDeleted {
+		http.Error(res, "", http.StatusGone)
+		return
+	}
+
+	clicks, series, err := a.storage.GetStats(req.Context(), shortKey, from, to, step)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if series == nil {
+		series = []models.StatsBucket{}
+	}
+
+	stats := models.LinkStats{
+		ShortURL:    config.FlagBaseAddr + "/" + shortKey,
+		OriginalURL: link.OriginalURL,
+		Clicks:      clicks,
+		Series:      series,
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	json.NewEncoder(res).Encode(stats)
+}
+
+// UserUrls возвращает страницу списка URL, сохраненных пользователем.
+// Параметры запроса: limit — размер страницы, cursor — курсор следующей страницы,
+// sort — поле сортировки (created или clicks), order — направление (asc или desc),
+// search — подстрока исходного URL, tag — метка ссылки,
+// deleted — фильтр удалённых ссылок (exclude, only или include).
+// Общее количество подходящих ссылок возвращается в заголовке X-Total-Count,
+// курсор следующей страницы — в заголовке X-Next-Cursor.
 func (a *App) UserUrls(res http.ResponseWriter, req *http.Request) {
-	result, err := a.storage.GetUserURL(req.Context())
+	query, err := listQuery(req)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := a.storage.ListUserURLs(req.Context(), query)
+	if errors.Is(err, storage.ErrInvalidQuery) {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(res, err.Error(), http.StatusNoContent)
 		return
 	}
-	if len(result) == 0 {
+
+	res.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	if page.NextCursor != "" {
+		res.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	if len(page.Items) == 0 {
 		http.Error(res, "No content", http.StatusNoContent)
 		return
 	}
 
+	resp, err := json.MarshalIndent(page.Items, "", "    ")
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	res.Write(resp)
+}
+
+// ImportURLs загружает ссылки пользователя из файла, переданного в поле file формы multipart/form-data.
+// Формат (csv или jsonl) задаётся параметром format или расширением имени файла.
+// Файл читается потоком и сохраняется пакетами; ошибки отдельных записей не прерывают загрузку
+// и возвращаются в ответе вместе с итогами загрузки.
+func (a *App) ImportURLs(res http.ResponseWriter, req *http.Request) {
+	reader, err := req.MultipartReader()
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var part *multipart.Part
+	for {
+		part, err = reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			http.Error(res, "file is required", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() == "file" {
+			break
+		}
+		part.Close()
+	}
+	defer part.Close()
+
+	format, err := importer.ParseFormat(req.URL.Query().Get("format"), part.FileName())
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := importer.New(a.storage, a.keys).Import(req.Context(), part, format)
+	if errors.Is(err, importer.ErrInvalidHeader) {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	resp, err := json.MarshalIndent(result, "", "    ")
 	if err != nil {
 		http.Error(res, err.Error(), http.StatusInternalServerError)
@@ -212,11 +497,39 @@ func (a *App) UserUrls(res http.ResponseWriter, req *http.Request) {
 	res.Write(resp)
 }
 
+// ExportURLs выгружает все ссылки пользователя, включая удалённые, с количеством переходов по ним.
+// Формат задаётся параметром format: csv, jsonl или json (по умолчанию).
+// Ссылки передаются клиенту по мере чтения из хранилища. Если чтение прервалось после начала передачи,
+// соединение разрывается, чтобы клиент не принял неполную выгрузку за полную.
+func (a *App) ExportURLs(res http.ResponseWriter, req *http.Request) {
+	format, err := exporter.ParseFormat(req.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res.Header().Set("Content-Type", format.ContentType())
+	res.Header().Set("Content-Disposition", `attachment; filename="urls.`+string(format)+`"`)
+
+	written, err := exporter.Export(res, format, a.storage.UserLinks(req.Context()))
+	if err == nil {
+		return
+	}
+	if written == 0 {
+		res.Header().Del("Content-Disposition")
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(req.Context()).Error("Export interrupted", zap.Error(err), zap.Int64("written", written))
+	panic(http.ErrAbortHandler)
+}
+
 // DeleteUserUrls обрабатывает запрос на удаление списка сокращенных URL-адресов,
 // полученного в теле запроса в формате JSON.
-// Функция читает список коротких URL, передает их в канал для обработки
-// и запускает процесс удаления в фоне.
-// В случае успешного запуска удаления возвращает HTTP статус 202 (Accepted).
+// Удаление ставится в очередь фонового сервиса и выполняется асинхронно: удаляются только ссылки
+// текущего пользователя. Возвращает HTTP статус 202 (Accepted) сразу после постановки в очередь
+// и 503, если очередь переполнена или сервис удаления остановлен.
 func (a *App) DeleteUserUrls(res http.ResponseWriter, req *http.Request) {
 	var shortKeys []string
 
@@ -235,18 +548,95 @@ func (a *App) DeleteUserUrls(res http.ResponseWriter, req *http.Request) {
 	}
 	defer req.Body.Close()
 
-	doneCh := make(chan struct{})
-	defer close(doneCh)
+	if err = a.deletes.Enqueue(auth.UserIDFromContext(req.Context()), shortKeys); err != nil {
+		http.Error(res, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	res.WriteHeader(http.StatusAccepted)
+}
 
-	inputCh := generator(doneCh, shortKeys)
+// UpdateURL обрабатывает замену оригинального URL короткой ссылки пользователя.
+// Ожидает JSON вида {"url": "..."} и возвращает обновлённую пару сокращённого и исходного URL.
+func (a *App) UpdateURL(res http.ResponseWriter, req *http.Request) {
+	var inputDataValue models.InputData
 
-	resultCh := a.delete(req.Context(), doneCh, inputCh)
+	shortKey := chi.URLParam(req, "id")
 
-	for res := range resultCh {
-		fmt.Println(res)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, "error", http.StatusBadRequest)
+		return
 	}
+	if string(body) == "" {
+		http.Error(res, "empty body", http.StatusBadRequest)
+		return
+	}
+	if err = json.Unmarshal(body, &inputDataValue); err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer req.Body.Close()
 
-	res.WriteHeader(http.StatusAccepted)
+	if inputDataValue.URL == "" {
+		http.Error(res, "empty url", http.StatusBadRequest)
+		return
+	}
+
+	if err = a.storage.Update(req.Context(), shortKey, inputDataValue.URL); err != nil {
+		http.Error(res, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	writeShortURLItem(res, shortKey, inputDataValue.URL)
+}
+
+// URLHistory возвращает историю изменений оригинального URL короткой ссылки пользователя.
+func (a *App) URLHistory(res http.ResponseWriter, req *http.Request) {
+	shortKey := chi.URLParam(req, "id")
+
+	history, err := a.storage.GetHistory(req.Context(), shortKey)
+	if err != nil {
+		http.Error(res, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	resp, err := json.MarshalIndent(history, "", "    ")
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	res.Write(resp)
+}
+
+// RollbackURL восстанавливает предыдущий оригинальный URL короткой ссылки пользователя.
+func (a *App) RollbackURL(res http.ResponseWriter, req *http.Request) {
+	shortKey := chi.URLParam(req, "id")
+
+	originalURL, err := a.storage.Rollback(req.Context(), shortKey)
+	if err != nil {
+		http.Error(res, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	writeShortURLItem(res, shortKey, originalURL)
+}
+
+// RestoreURL восстанавливает удалённую ссылку пользователя, если с момента удаления
+// прошло не больше config.FlagRestoreWindow.
+func (a *App) RestoreURL(res http.ResponseWriter, req *http.Request) {
+	shortKey := chi.URLParam(req, "id")
+
+	originalURL, err := a.storage.Restore(req.Context(), shortKey, time.Now().Add(-config.FlagRestoreWindow))
+	if err != nil {
+		http.Error(res, err.Error(), storageErrorStatus(err))
+		return
+	}
+
+	writeShortURLItem(res, shortKey, originalURL)
 }
 
 // InternalStats обрабатывает запрос статистики.
@@ -275,20 +665,15 @@ func (a *App) InternalStats(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	countUrls, err := a.storage.CountURLs(req.Context())
-	if err != nil {
-		http.Error(res, "error", http.StatusBadRequest)
-		return
-	}
-	countUsers, err := a.storage.CountURLs(req.Context())
+	counts, err := metrics.ReadCounts(req.Context(), a.storage)
 	if err != nil {
 		http.Error(res, "error", http.StatusBadRequest)
 		return
 	}
 
 	stats := models.StatsResponse{
-		URLs:  countUrls,
-		Users: countUsers,
+		URLs:  counts.URLs,
+		Users: counts.Users,
 	}
 
 	res.Header().Set("Content-Type", "application/json")
@@ -296,43 +681,89 @@ func (a *App) InternalStats(res http.ResponseWriter, req *http.Request) {
 	json.NewEncoder(res).Encode(stats)
 }
 
-func (a *App) delete(ctx context.Context, doneCh chan struct{}, inputCh chan string) chan string {
-	deleteRes := make(chan string)
+// setLink сохраняет ссылку в хранилище и возвращает её короткий ключ.
+// Если ключ не задан пользователем, он создаётся генератором приложения с повтором при коллизиях.
+func (a *App) setLink(ctx context.Context, link models.Link) (string, error) {
+	if link.ShortKey != "" {
+		return a.storage.Set(ctx, link)
+	}
+	return storage.SetGenerated(ctx, a.storage, a.keys, link)
+}
 
-	go func() {
-		defer close(deleteRes)
+// linkUnlocked сообщает, передан ли в запросе действующий cookie доступа к защищённой ссылке.
+func linkUnlocked(req *http.Request, shortKey string) bool {
+	cookie, err := req.Cookie(auth.LinkCookieName)
+	if err != nil {
+		return false
+	}
 
-		for data := range inputCh {
-			splitData := strings.Split(data, "/")
-			code := splitData[len(splitData)-1]
+	return auth.CheckLinkToken(cookie.Value, shortKey) == nil
+}
 
-			a.storage.Delete(ctx, code)
+// renderUnlockPage записывает в ответ форму ввода пароля с необязательным сообщением об ошибке.
+func renderUnlockPage(res http.ResponseWriter, status int, message string) {
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	res.WriteHeader(status)
+	unlockPage.Execute(res, message)
+}
 
-			select {
-			case <-doneCh:
-				return
-			case deleteRes <- "ok":
-			}
-		}
-	}()
+// writeShortURLItem записывает в ответ пару сокращённого и исходного URL в формате JSON.
+func writeShortURLItem(res http.ResponseWriter, shortKey string, originalURL string) {
+	resp, err := json.MarshalIndent(models.ShortURLItem{
+		ShortURL:    config.FlagBaseAddr + "/" + shortKey,
+		OriginalURL: originalURL,
+	}, "", "    ")
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	return deleteRes
+	res.Header().Set("Content-Type", "application/json")
+	res.WriteHeader(http.StatusOK)
+	res.Write(resp)
 }
 
-func generator(doneCh chan struct{}, input []string) chan string {
-	inputCh := make(chan string)
+// listQuery разбирает параметры списка ссылок пользователя из строки запроса.
+func listQuery(req *http.Request) (models.ListQuery, error) {
+	values := req.URL.Query()
 
-	go func() {
-		defer close(inputCh)
-
-		for _, data := range input {
-			select {
-			case <-doneCh:
-				return
-			case inputCh <- data:
-			}
+	var query models.ListQuery
+	if limit := values.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			return query, fmt.Errorf("%w: invalid limit", storage.ErrInvalidQuery)
 		}
-	}()
+		query.Limit = value
+	}
 
-	return inputCh
+	desc, err := storage.ParseListOrder(values.Get("order"))
+	if err != nil {
+		return query, err
+	}
+
+	query.Cursor = values.Get("cursor")
+	query.Sort = models.ListSort(values.Get("sort"))
+	query.Desc = desc
+	query.Search = values.Get("search")
+	query.Tag = values.Get("tag")
+	query.Deleted = models.DeletedFilter(values.Get("deleted"))
+
+	return query, nil
 }
+
+// storageErrorStatus возвращает HTTP-статус, соответствующий ошибке хранилища.
+func storageErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, storage.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, storage.ErrDeleted), errors.Is(err, storage.ErrRestoreExpired):
+		return http.StatusGone
+	case errors.Is(err, storage.ErrConflict), errors.Is(err, storage.ErrNoHistory), errors.Is(err, storage.ErrNotDeleted):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
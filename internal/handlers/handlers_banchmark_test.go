@@ -16,7 +16,7 @@ import (
 // Бенчмарк для ShortenPost
 func BenchmarkShortenPost(b *testing.B) {
 	storage := memory.NewStorage()
-	app := NewApp(storage)
+	app := NewApp(storage, nil, nil, nil)
 
 	// Пример данных
 	inputData := models.InputData{
@@ -38,7 +38,7 @@ func BenchmarkShortenPost(b *testing.B) {
 // Бенчмарк для ShortenBatchPost
 func BenchmarkShortenBatchPost(b *testing.B) {
 	storage := memory.NewStorage()
-	app := NewApp(storage)
+	app := NewApp(storage, nil, nil, nil)
 
 	// Пример данных
 	batch := []models.BatchItem{
@@ -61,7 +61,7 @@ func BenchmarkShortenBatchPost(b *testing.B) {
 // Бенчмарк для PostURL
 func BenchmarkPostURL(b *testing.B) {
 	storage := memory.NewStorage()
-	app := NewApp(storage)
+	app := NewApp(storage, nil, nil, nil)
 
 	// Пример данных
 	inputData := "https://example.com"
@@ -81,11 +81,11 @@ func BenchmarkPostURL(b *testing.B) {
 // Бенчмарк для Redirect
 func BenchmarkRedirect(b *testing.B) {
 	storage := memory.NewStorage()
-	app := NewApp(storage)
+	app := NewApp(storage, nil, nil, nil)
 
 	// Записываем данные в хранилище
 	shortKey := rand.RandStringBytes(8)
-	storage.Set(context.Background(), shortKey, "https://example.com")
+	storage.Set(context.Background(), models.Link{ShortKey: shortKey, OriginalURL: "https://example.com"})
 
 	req := httptest.NewRequest("GET", fmt.Sprintf("/%s", shortKey), nil)
 	res := httptest.NewRecorder()
@@ -100,11 +100,11 @@ func BenchmarkRedirect(b *testing.B) {
 // Бенчмарк для UserUrls
 func BenchmarkUserUrls(b *testing.B) {
 	storage := memory.NewStorage()
-	app := NewApp(storage)
+	app := NewApp(storage, nil, nil, nil)
 
 	// Записываем несколько данных
 	for i := 0; i < 100; i++ {
-		storage.Set(context.Background(), rand.RandStringBytes(8), "https://example.com")
+		storage.Set(context.Background(), models.Link{ShortKey: rand.RandStringBytes(8), OriginalURL: "https://example.com"})
 	}
 
 	req := httptest.NewRequest("GET", "/api/user/urls", nil)
@@ -120,7 +120,7 @@ func BenchmarkUserUrls(b *testing.B) {
 // Бенчмарк для DeleteUserUrls
 func BenchmarkDeleteUserUrls(b *testing.B) {
 	storage := memory.NewStorage()
-	app := NewApp(storage)
+	app := NewApp(storage, nil, nil, nil)
 
 	// Пример данных
 	shortKeys := []string{
@@ -131,7 +131,7 @@ func BenchmarkDeleteUserUrls(b *testing.B) {
 
 	// Записываем данные в хранилище
 	for _, key := range shortKeys {
-		storage.Set(context.Background(), key, "https://example.com")
+		storage.Set(context.Background(), models.Link{ShortKey: key, OriginalURL: "https://example.com"})
 	}
 
 	reqBody, _ := json.Marshal(shortKeys)
@@ -1,15 +1,25 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/dsemenov12/shorturl/internal/auth"
 	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/deleter"
+	"github.com/dsemenov12/shorturl/internal/importer"
 	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
 	mock_storage "github.com/dsemenov12/shorturl/internal/storage/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -22,48 +32,88 @@ func TestShortenBatchPost(t *testing.T) {
 	// создаём объект-заглушку
 	m := mock_storage.NewMockStorage(ctrl)
 
-	m.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	// URL с путём /existing считаются уже сокращёнными
+	m.EXPECT().SetBatch(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, links []models.Link, _ bool) ([]storage.BatchResult, error) {
+			results := make([]storage.BatchResult, len(links))
+			for i, link := range links {
+				results[i] = storage.BatchResult{ShortKey: link.ShortKey}
+				if strings.HasSuffix(link.OriginalURL, "/existing") {
+					results[i] = storage.BatchResult{ShortKey: "existing", Err: storage.ErrConflict}
+				}
+			}
+			return results, nil
+		}).AnyTimes()
 
 	// создадим экземпляр приложения и передадим ему «хранилище»
-	app := NewApp(m)
+	app := NewApp(m, nil, nil, nil)
 
 	type want struct {
-		code        int
-		contentType string
+		code     int
+		statuses []models.BatchStatus
 	}
 	tests := []struct {
-		name string
-		body string
-		want want
+		name  string
+		query string
+		body  string
+		want  want
 	}{
 		{
 			name: "positive test #1",
 			body: `[{"correlation_id": "JJUQVrJ12","original_url": "https://practicum.yandex.ru/"},{"correlation_id": "JJUQVrJ22","original_url": "https://mail.ru/"}]`,
 			want: want{
-				code:        http.StatusCreated,
-				contentType: "application/json",
+				code:     http.StatusCreated,
+				statuses: []models.BatchStatus{models.BatchStatusCreated, models.BatchStatusCreated},
 			},
 		},
 		{
-			name: "positive test #2",
-			body: `[{"correlation_id": "JJUQVrJ12","original_url": "https://practicum.yandex.ru/123"},{"correlation_id": "JJUQVrJ22","original_url": "https://mail.ru/1234"}]`,
+			name: "partial failure",
+			body: `[{"correlation_id": "1","original_url": "https://mail.ru/existing"},{"correlation_id": "2","original_url": "mail.ru"},{"correlation_id": "3","original_url": "https://mail.ru/3"}]`,
 			want: want{
-				code:        http.StatusCreated,
-				contentType: "application/json",
+				code:     http.StatusCreated,
+				statuses: []models.BatchStatus{models.BatchStatusExists, models.BatchStatusInvalid, models.BatchStatusCreated},
 			},
 		},
 		{
-			name: "test empty body",
-			body: ``,
+			name: "all exist",
+			body: `[{"correlation_id": "1","original_url": "https://mail.ru/existing"},{"correlation_id": "","original_url": "https://mail.ru/2"}]`,
 			want: want{
-				code:        http.StatusBadRequest,
-				contentType: "application/json",
+				code:     http.StatusConflict,
+				statuses: []models.BatchStatus{models.BatchStatusExists, models.BatchStatusInvalid},
+			},
+		},
+		{
+			name: "all invalid",
+			body: `[{"correlation_id": "1","original_url": ""}]`,
+			want: want{
+				code:     http.StatusBadRequest,
+				statuses: []models.BatchStatus{models.BatchStatusInvalid},
+			},
+		},
+		{
+			name:  "atomic with invalid items",
+			query: "?atomic=true",
+			body:  `[{"correlation_id": "1","original_url": "https://mail.ru/1"},{"correlation_id": "1","original_url": "https://mail.ru/2"}]`,
+			want: want{
+				code:     http.StatusBadRequest,
+				statuses: []models.BatchStatus{models.BatchStatusInvalid},
 			},
 		},
+		{
+			name:  "invalid atomic parameter",
+			query: "?atomic=maybe",
+			body:  `[]`,
+			want:  want{code: http.StatusBadRequest},
+		},
+		{
+			name: "test empty body",
+			body: ``,
+			want: want{code: http.StatusBadRequest},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(test.body))
+			request := httptest.NewRequest(http.MethodPost, "/api/shorten/batch"+test.query, strings.NewReader(test.body))
 			response := httptest.NewRecorder()
 
 			app.ShortenBatchPost(response, request)
@@ -72,6 +122,17 @@ func TestShortenBatchPost(t *testing.T) {
 			defer res.Body.Close()
 
 			assert.Equal(t, test.want.code, res.StatusCode)
+			if test.want.statuses == nil {
+				return
+			}
+
+			var result []models.BatchResultItem
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+			statuses := make([]models.BatchStatus, len(result))
+			for i, item := range result {
+				statuses[i] = item.Status
+			}
+			assert.Equal(t, test.want.statuses, statuses)
 		})
 	}
 }
@@ -83,10 +144,10 @@ func TestShortenPost(t *testing.T) {
 	// создаём объект-заглушку
 	m := mock_storage.NewMockStorage(ctrl)
 
-	m.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	m.EXPECT().Set(gomock.Any(), gomock.Any()).AnyTimes()
 
 	// создадим экземпляр приложения и передадим ему «хранилище»
-	app := NewApp(m)
+	app := NewApp(m, nil, nil, nil)
 
 	type want struct {
 		code        int
@@ -146,6 +207,69 @@ func TestShortenPost(t *testing.T) {
 	}
 }
 
+func TestShortenPostAlias(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+
+	app := NewApp(m, nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		storageErr error
+		wantCode   int
+	}{
+		{
+			name:     "positive test #1",
+			body:     `{"url": "https://practicum.yandex.ru/", "alias": "practicum"}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:       "test alias taken",
+			body:       `{"url": "https://practicum.yandex.ru/", "alias": "practicum"}`,
+			storageErr: storage.ErrKeyExists,
+			wantCode:   http.StatusConflict,
+		},
+		{
+			name:     "test reserved alias",
+			body:     `{"url": "https://practicum.yandex.ru/", "alias": "api"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "test invalid characters",
+			body:     `{"url": "https://practicum.yandex.ru/", "alias": "a/b/c"}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.wantCode != http.StatusBadRequest {
+				m.EXPECT().Set(gomock.Any(), models.Link{ShortKey: "practicum", OriginalURL: "https://practicum.yandex.ru/"}).Return("practicum", test.storageErr)
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(test.body))
+			response := httptest.NewRecorder()
+
+			app.ShortenPost(response, request)
+
+			res := response.Result()
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				panic(err)
+			}
+			defer res.Body.Close()
+
+			assert.Equal(t, test.wantCode, res.StatusCode)
+			if test.wantCode == http.StatusCreated {
+				assert.Contains(t, string(body), config.FlagBaseAddr+"/practicum")
+			}
+		})
+	}
+}
+
 func TestPostURL(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -153,10 +277,10 @@ func TestPostURL(t *testing.T) {
 	// создаём объект-заглушку
 	m := mock_storage.NewMockStorage(ctrl)
 
-	m.EXPECT().Set(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	m.EXPECT().Set(gomock.Any(), gomock.Any()).AnyTimes()
 
 	// создадим экземпляр приложения и передадим ему «хранилище»
-	app := NewApp(m)
+	app := NewApp(m, nil, nil, nil)
 
 	type want struct {
 		code        int
@@ -224,7 +348,7 @@ func TestRedirect(t *testing.T) {
 	m := mock_storage.NewMockStorage(ctrl)
 
 	// создадим экземпляр приложения и передадим ему «хранилище»
-	app := NewApp(m)
+	app := NewApp(m, nil, nil, nil)
 
 	type want struct {
 		code        int
@@ -278,11 +402,11 @@ func TestRedirect(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			if test.want.code == http.StatusNotFound {
-				m.EXPECT().Get(gomock.Any(), gomock.Any()).Return("", "", false, errors.New("not found"))
+				m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(models.Link{}, errors.New("not found"))
 			} else if test.want.code == http.StatusGone {
-				m.EXPECT().Get(gomock.Any(), gomock.Any()).Return("", "", true, nil)
+				m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(models.Link{IsDeleted: true}, nil)
 			} else {
-				m.EXPECT().Get(gomock.Any(), gomock.Any()).Return("bmXrsnZk", "https://practicum.yandex.ru/profile/go-advanced/", false, nil)
+				m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(models.Link{ShortKey: test.code, OriginalURL: test.want.redirectURL}, nil)
 			}
 
 			requestURL := config.FlagBaseAddr + "/" + test.code
@@ -299,52 +423,321 @@ func TestRedirect(t *testing.T) {
 	}
 }
 
-func TestUserUrls(t *testing.T) {
+func TestRedirectExpired(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	m := mock_storage.NewMockStorage(ctrl)
+	app := NewApp(m, nil, nil, nil)
 
-	result := []models.ShortURLItem{}
-	result = append(result, models.ShortURLItem{
+	m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(models.Link{
+		ShortKey:    "bmXrsnZk",
 		OriginalURL: "https://practicum.yandex.ru/",
-		ShortURL:    "http://127.0.0.1:8080/qsd54gFg/gh5dEm34",
-	})
+		ExpiresAt:   time.Now().Add(-time.Minute),
+	}, nil)
+
+	request := httptest.NewRequest(http.MethodGet, config.FlagBaseAddr+"/bmXrsnZk", nil)
+	response := httptest.NewRecorder()
+
+	app.Redirect(response, request)
+
+	res := response.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusGone, res.StatusCode)
+}
+
+func TestRedirectMaxClicks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+	app := NewApp(m, nil, nil, nil)
+
+	link := models.Link{ShortKey: "bmXrsnZk", OriginalURL: "https://practicum.yandex.ru/", MaxClicks: 1, ClicksLeft: 1}
+
+	tests := []struct {
+		name       string
+		consumeErr error
+		wantCode   int
+	}{
+		{
+			name:     "test click left",
+			wantCode: http.StatusTemporaryRedirect,
+		},
+		{
+			name:       "test clicks exhausted",
+			consumeErr: storage.ErrExhausted,
+			wantCode:   http.StatusGone,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(link, nil)
+			m.EXPECT().Consume(gomock.Any(), gomock.Any()).Return(test.consumeErr)
+
+			request := httptest.NewRequest(http.MethodGet, config.FlagBaseAddr+"/bmXrsnZk", nil)
+			response := httptest.NewRecorder()
+
+			app.Redirect(response, request)
+
+			res := response.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.wantCode, res.StatusCode)
+		})
+	}
+}
+
+func TestRedirectProtected(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+	app := NewApp(m, nil, nil, nil)
+
+	hash, err := auth.HashPassword("secret")
+	assert.NoError(t, err)
+	link := models.Link{ShortKey: "bmXrsnZk", OriginalURL: "https://practicum.yandex.ru/", PasswordHash: hash}
+	m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(link, nil).AnyTimes()
 
-	app := NewApp(m)
+	requestURL := config.FlagBaseAddr + "/bmXrsnZk"
+
+	// Без cookie доступа вместо перенаправления отображается форма
+	response := httptest.NewRecorder()
+	app.Redirect(response, httptest.NewRequest(http.MethodGet, requestURL, nil))
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, response.Body.String(), `name="password"`)
+
+	// Неверный пароль
+	request := httptest.NewRequest(http.MethodPost, requestURL, strings.NewReader("password=wrong"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response = httptest.NewRecorder()
+	app.UnlockLink(response, request)
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Empty(t, response.Result().Cookies())
+
+	// Верный пароль выдаёт cookie доступа
+	request = httptest.NewRequest(http.MethodPost, requestURL, strings.NewReader("password=secret"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response = httptest.NewRecorder()
+	app.UnlockLink(response, request)
+	assert.Equal(t, http.StatusSeeOther, response.Code)
+
+	cookies := response.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, auth.LinkCookieName, cookies[0].Name)
+		assert.True(t, cookies[0].HttpOnly)
+
+		// С cookie доступа выполняется перенаправление
+		request = httptest.NewRequest(http.MethodGet, requestURL, nil)
+		request.AddCookie(cookies[0])
+		response = httptest.NewRecorder()
+		app.Redirect(response, request)
+		assert.Equal(t, http.StatusTemporaryRedirect, response.Code)
+		assert.Equal(t, link.OriginalURL, response.Header().Get("Location"))
+	}
+}
+
+func TestShortenPostExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+	app := NewApp(m, nil, nil, nil)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{
+			name:     "test ttl",
+			body:     `{"url": "https://practicum.yandex.ru/", "ttl": 3600}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "test expires_at",
+			body:     `{"url": "https://practicum.yandex.ru/", "expires_at": "2999-01-01T00:00:00Z"}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "test expires_at in the past",
+			body:     `{"url": "https://practicum.yandex.ru/", "expires_at": "2000-01-01T00:00:00Z"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "test ttl and expires_at together",
+			body:     `{"url": "https://practicum.yandex.ru/", "ttl": 60, "expires_at": "2999-01-01T00:00:00Z"}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.wantCode == http.StatusCreated {
+				m.EXPECT().Set(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, link models.Link) (string, error) {
+					assert.False(t, link.ExpiresAt.IsZero(), "Link should have an expiry")
+					return link.ShortKey, nil
+				})
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(test.body))
+			response := httptest.NewRecorder()
+
+			app.ShortenPost(response, request)
+
+			res := response.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.wantCode, res.StatusCode)
+		})
+	}
+}
+
+func TestShortenPostTags(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+	app := NewApp(m, nil, nil, nil)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantTags []string
+		wantCode int
+	}{
+		{
+			name:     "test tags",
+			body:     `{"url": "https://practicum.yandex.ru/", "tags": ["Promo", " news", "promo"]}`,
+			wantTags: []string{"promo", "news"},
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "test without tags",
+			body:     `{"url": "https://practicum.yandex.ru/"}`,
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "test invalid tag",
+			body:     `{"url": "https://practicum.yandex.ru/", "tags": ["spring sale"]}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.wantCode == http.StatusCreated {
+				m.EXPECT().Set(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, link models.Link) (string, error) {
+					assert.Equal(t, test.wantTags, link.Tags)
+					return link.ShortKey, nil
+				})
+			}
+
+			request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(test.body))
+			response := httptest.NewRecorder()
+
+			app.ShortenPost(response, request)
+
+			res := response.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.wantCode, res.StatusCode)
+		})
+	}
+}
+
+func TestUserUrls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+
+	page := models.ShortURLPage{
+		Items: []models.ShortURLItem{{
+			OriginalURL: "https://practicum.yandex.ru/",
+			ShortURL:    "http://127.0.0.1:8080/qsd54gFg/gh5dEm34",
+		}},
+		Total:      3,
+		NextCursor: "next",
+	}
+
+	app := NewApp(m, nil, nil, nil)
 
 	type want struct {
-		code        int
-		contentType string
+		code       int
+		total      string
+		nextCursor string
 	}
 	tests := []struct {
-		name string
-		want want
+		name   string
+		target string
+		query  *models.ListQuery
+		page   models.ShortURLPage
+		err    error
+		want   want
 	}{
 		{
-			name: "positive test #1",
-			want: want{
-				code:        http.StatusOK,
-				contentType: "application/json",
-			},
+			name:   "positive test #1",
+			target: "/api/user/urls",
+			query:  &models.ListQuery{},
+			page:   page,
+			want:   want{code: http.StatusOK, total: "3", nextCursor: "next"},
 		},
 		{
-			name: "test not found",
-			want: want{
-				code:        http.StatusNoContent,
-				contentType: "application/json",
+			name:   "query parameters",
+			target: "/api/user/urls?limit=10&cursor=abc&sort=clicks&order=desc&search=yandex&tag=promo&deleted=include",
+			query: &models.ListQuery{
+				Limit:   10,
+				Cursor:  "abc",
+				Sort:    models.ListSortClicks,
+				Desc:    true,
+				Search:  "yandex",
+				Tag:     "promo",
+				Deleted: models.DeletedInclude,
 			},
+			page: page,
+			want: want{code: http.StatusOK, total: "3", nextCursor: "next"},
+		},
+		{
+			name:   "test not found",
+			target: "/api/user/urls",
+			query:  &models.ListQuery{},
+			err:    errors.New("Not found"),
+			want:   want{code: http.StatusNoContent},
+		},
+		{
+			name:   "empty page",
+			target: "/api/user/urls",
+			query:  &models.ListQuery{},
+			page:   models.ShortURLPage{Total: 0},
+			want:   want{code: http.StatusNoContent, total: "0"},
+		},
+		{
+			name:   "invalid cursor",
+			target: "/api/user/urls?cursor=bad",
+			query:  &models.ListQuery{Cursor: "bad"},
+			err:    storage.ErrInvalidQuery,
+			want:   want{code: http.StatusBadRequest},
+		},
+		{
+			name:   "invalid limit",
+			target: "/api/user/urls?limit=ten",
+			want:   want{code: http.StatusBadRequest},
+		},
+		{
+			name:   "invalid order",
+			target: "/api/user/urls?order=up",
+			want:   want{code: http.StatusBadRequest},
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			if test.want.code == http.StatusNoContent {
-				m.EXPECT().GetUserURL(gomock.Any()).Return(nil, errors.New("Not found"))
-			} else {
-				m.EXPECT().GetUserURL(gomock.Any()).Return(result, nil)
+			if test.query != nil {
+				m.EXPECT().ListUserURLs(gomock.Any(), *test.query).Return(test.page, test.err)
 			}
 
-			request := httptest.NewRequest(http.MethodGet, "/api/user/urls", nil)
+			request := httptest.NewRequest(http.MethodGet, test.target, nil)
 			response := httptest.NewRecorder()
 
 			app.UserUrls(response, request)
@@ -353,19 +746,224 @@ func TestUserUrls(t *testing.T) {
 			defer res.Body.Close()
 
 			assert.Equal(t, test.want.code, res.StatusCode)
+			assert.Equal(t, test.want.total, res.Header.Get("X-Total-Count"))
+			assert.Equal(t, test.want.nextCursor, res.Header.Get("X-Next-Cursor"))
 		})
 	}
 }
 
+func TestImportURLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+
+	app := NewApp(m, nil, nil, nil)
+
+	type want struct {
+		code     int
+		imported int
+		failed   int
+	}
+	tests := []struct {
+		name     string
+		target   string
+		field    string
+		fileName string
+		data     string
+		errs     []error
+		want     want
+	}{
+		{
+			name:     "csv file",
+			target:   "/api/user/urls/import",
+			field:    "file",
+			fileName: "links.csv",
+			data:     "short_url,original_url\nabcd,https://practicum.yandex.ru/\nefgh,https://ya.ru/\nijkl,not a url\n",
+			errs:     []error{nil, storage.ErrConflict},
+			want:     want{code: http.StatusOK, imported: 1, failed: 2},
+		},
+		{
+			name:     "format parameter",
+			target:   "/api/user/urls/import?format=jsonl",
+			field:    "file",
+			fileName: "links.txt",
+			data:     `{"short_url":"abcd","original_url":"https://practicum.yandex.ru/"}`,
+			errs:     []error{nil},
+			want:     want{code: http.StatusOK, imported: 1},
+		},
+		{
+			name:     "unknown format",
+			target:   "/api/user/urls/import",
+			field:    "file",
+			fileName: "links.xml",
+			want:     want{code: http.StatusBadRequest},
+		},
+		{
+			name:     "invalid header",
+			target:   "/api/user/urls/import",
+			field:    "file",
+			fileName: "links.csv",
+			data:     "url\nhttps://practicum.yandex.ru/\n",
+			want:     want{code: http.StatusBadRequest},
+		},
+		{
+			name:     "file is missing",
+			target:   "/api/user/urls/import",
+			field:    "upload",
+			fileName: "links.csv",
+			want:     want{code: http.StatusBadRequest},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.errs != nil {
+				m.EXPECT().Import(gomock.Any(), gomock.Len(len(test.errs))).Return(test.errs, nil)
+			}
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			part, err := writer.CreateFormFile(test.field, test.fileName)
+			assert.NoError(t, err)
+			part.Write([]byte(test.data))
+			writer.Close()
+
+			request := httptest.NewRequest(http.MethodPost, test.target, &body)
+			request.Header.Set("Content-Type", writer.FormDataContentType())
+			response := httptest.NewRecorder()
+
+			app.ImportURLs(response, request)
+
+			res := response.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.want.code, res.StatusCode)
+			if res.StatusCode != http.StatusOK {
+				return
+			}
+
+			var result importer.Result
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+			assert.Equal(t, test.want.imported, result.Imported)
+			assert.Equal(t, test.want.failed, result.Failed)
+		})
+	}
+}
+
+func TestExportURLs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+
+	app := NewApp(m, nil, nil, nil)
+
+	links := func(count int, err error) storage.UserLinkSeq {
+		return func(yield func(models.UserLink, error) bool) {
+			for i := 0; i < count; i++ {
+				link := models.Link{ShortKey: fmt.Sprintf("short%d", i), OriginalURL: "https://practicum.yandex.ru/"}
+				if !yield(models.UserLink{Link: link, Clicks: 1}, nil) {
+					return
+				}
+			}
+			if err != nil {
+				yield(models.UserLink{}, err)
+			}
+		}
+	}
+
+	type want struct {
+		code        int
+		contentType string
+		lines       int
+	}
+	tests := []struct {
+		name   string
+		target string
+		links  storage.UserLinkSeq
+		want   want
+	}{
+		{
+			name:   "csv",
+			target: "/api/user/urls/export?format=csv",
+			links:  links(2, nil),
+			want:   want{code: http.StatusOK, contentType: "text/csv; charset=utf-8", lines: 3},
+		},
+		{
+			name:   "jsonl",
+			target: "/api/user/urls/export?format=jsonl",
+			links:  links(2, nil),
+			want:   want{code: http.StatusOK, contentType: "application/x-ndjson", lines: 2},
+		},
+		{
+			name:   "default format",
+			target: "/api/user/urls/export",
+			links:  links(0, nil),
+			want:   want{code: http.StatusOK, contentType: "application/json", lines: 1},
+		},
+		{
+			name:   "unknown format",
+			target: "/api/user/urls/export?format=xml",
+			want:   want{code: http.StatusBadRequest, contentType: "text/plain; charset=utf-8"},
+		},
+		{
+			name:   "storage error",
+			target: "/api/user/urls/export",
+			links:  links(1, errors.New("connection reset")),
+			want:   want{code: http.StatusInternalServerError, contentType: "text/plain; charset=utf-8"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.links != nil {
+				m.EXPECT().UserLinks(gomock.Any()).Return(test.links)
+			}
+
+			request := httptest.NewRequest(http.MethodGet, test.target, nil)
+			response := httptest.NewRecorder()
+
+			app.ExportURLs(response, request)
+
+			res := response.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.want.code, res.StatusCode)
+			assert.Equal(t, test.want.contentType, res.Header.Get("Content-Type"))
+			if test.want.code == http.StatusOK {
+				body, _ := io.ReadAll(res.Body)
+				assert.Equal(t, test.want.lines, strings.Count(string(body), "\n"))
+			}
+		})
+	}
+
+	t.Run("error after streaming started", func(t *testing.T) {
+		m.EXPECT().UserLinks(gomock.Any()).Return(links(1000, errors.New("connection reset")))
+
+		request := httptest.NewRequest(http.MethodGet, "/api/user/urls/export?format=jsonl", nil)
+		response := httptest.NewRecorder()
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			app.ExportURLs(response, request)
+		})
+		assert.Equal(t, http.StatusOK, response.Code)
+	})
+}
+
 func TestDeleteUserUrls(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	m := mock_storage.NewMockStorage(ctrl)
 
-	m.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	// ключи удаляются в фоне одним пакетом от имени владельца
+	m.EXPECT().DeleteBatch(gomock.Any(), []string{"JJUQVrJ12", "JJUQVrJ22", "Jlfd67ds", "cdpFuzqh"}).DoAndReturn(
+		func(ctx context.Context, _ []string) error {
+			assert.Equal(t, "user1", auth.UserIDFromContext(ctx))
+			return nil
+		})
 
-	app := NewApp(m)
+	deletes := deleter.NewDeleter(m)
+	app := NewApp(m, nil, nil, deletes)
 
 	type want struct {
 		code int
@@ -393,6 +991,7 @@ func TestDeleteUserUrls(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			request := httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(test.body))
+			request = request.WithContext(context.WithValue(request.Context(), auth.UserIDKey, "user1"))
 			response := httptest.NewRecorder()
 
 			app.DeleteUserUrls(response, request)
@@ -403,4 +1002,260 @@ func TestDeleteUserUrls(t *testing.T) {
 			assert.Equal(t, test.want.code, res.StatusCode)
 		})
 	}
+
+	// принятые запросы выполняются при остановке сервиса удаления
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deletes.Run(ctx)
+
+	t.Run("service stopped", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["JJUQVrJ12"]`))
+		response := httptest.NewRecorder()
+
+		app.DeleteUserUrls(response, request)
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+	})
+}
+
+func TestLinkStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+
+	app := NewApp(m, nil, nil, nil)
+
+	type want struct {
+		code        int
+		contentType string
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  want
+	}{
+		{
+			name:  "positive test #1",
+			query: "?from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&interval=1h",
+			want: want{
+				code:        http.StatusOK,
+				contentType: "application/json",
+			},
+		},
+		{
+			name:  "test invalid interval",
+			query: "?interval=abc",
+			want: want{
+				code: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "test not found",
+			want: want{
+				code: http.StatusNotFound,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			switch test.want.code {
+			case http.StatusOK:
+				m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(models.Link{ShortKey: "bmXrsnZk", OriginalURL: "https://practicum.yandex.ru/", UserID: "user1"}, nil)
+				m.EXPECT().GetStats(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), time.Hour).
+					Return(int64(3), []models.StatsBucket{{Time: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), Clicks: 3}}, nil)
+			case http.StatusNotFound:
+				m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(models.Link{}, errors.New("not found"))
+			}
+
+			request := httptest.NewRequest(http.MethodGet, "/api/stats/bmXrsnZk"+test.query, nil)
+			request = request.WithContext(context.WithValue(request.Context(), auth.UserIDKey, "user1"))
+			response := httptest.NewRecorder()
+
+			app.LinkStats(response, request)
+
+			res := response.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.want.code, res.StatusCode)
+			if test.want.code == http.StatusOK {
+				var stats models.LinkStats
+				assert.NoError(t, json.NewDecoder(res.Body).Decode(&stats))
+				assert.Equal(t, test.want.contentType, res.Header.Get("Content-Type"))
+				assert.Equal(t, int64(3), stats.Clicks)
+				assert.Len(t, stats.Series, 1)
+			}
+		})
+	}
+}
+
+func TestLinkStatsOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+	app := NewApp(m, nil, nil, nil)
+
+	m.EXPECT().Get(gomock.Any(), gomock.Any()).Return(models.Link{ShortKey: "secret", OriginalURL: "https://example.com/private", UserID: "owner"}, nil)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/stats/secret", nil)
+	request = request.WithContext(context.WithValue(request.Context(), auth.UserIDKey, "other"))
+	response := httptest.NewRecorder()
+
+	app.LinkStats(response, request)
+
+	res := response.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
+
+func TestUpdateURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+
+	app := NewApp(m, nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		storageErr error
+		wantCode   int
+	}{
+		{
+			name:     "positive test #1",
+			body:     `{"url": "https://practicum.yandex.ru/new"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "test another user",
+			body:       `{"url": "https://practicum.yandex.ru/new"}`,
+			storageErr: storage.ErrForbidden,
+			wantCode:   http.StatusForbidden,
+		},
+		{
+			name:       "test not found",
+			body:       `{"url": "https://practicum.yandex.ru/new"}`,
+			storageErr: storage.ErrNotFound,
+			wantCode:   http.StatusNotFound,
+		},
+		{
+			name:     "test empty url",
+			body:     `{"url": ""}`,
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.wantCode != http.StatusBadRequest {
+				m.EXPECT().Update(gomock.Any(), gomock.Any(), "https://practicum.yandex.ru/new").Return(test.storageErr)
+			}
+
+			request := httptest.NewRequest(http.MethodPut, "/api/user/urls/bmXrsnZk", strings.NewReader(test.body))
+			response := httptest.NewRecorder()
+
+			app.UpdateURL(response, request)
+
+			res := response.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.wantCode, res.StatusCode)
+		})
+	}
+}
+
+func TestRestoreURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+
+	app := NewApp(m, nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		storageErr error
+		wantCode   int
+	}{
+		{
+			name:     "positive test #1",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:       "test not deleted",
+			storageErr: storage.ErrNotDeleted,
+			wantCode:   http.StatusConflict,
+		},
+		{
+			name:       "test restore window expired",
+			storageErr: storage.ErrRestoreExpired,
+			wantCode:   http.StatusGone,
+		},
+		{
+			name:       "test another user",
+			storageErr: storage.ErrForbidden,
+			wantCode:   http.StatusForbidden,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m.EXPECT().Restore(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ string, deletedAfter time.Time) (string, error) {
+				assert.WithinDuration(t, time.Now().Add(-config.FlagRestoreWindow), deletedAfter, time.Minute)
+				if test.storageErr != nil {
+					return "", test.storageErr
+				}
+				return "https://practicum.yandex.ru/", nil
+			})
+
+			request := httptest.NewRequest(http.MethodPost, "/api/user/urls/bmXrsnZk/restore", nil)
+			response := httptest.NewRecorder()
+
+			app.RestoreURL(response, request)
+
+			res := response.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.wantCode, res.StatusCode)
+		})
+	}
+}
+
+func TestInternalStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mock_storage.NewMockStorage(ctrl)
+
+	app := NewApp(m, nil, nil, nil)
+
+	trustedSubnet := config.FlagTrustedSubnet
+	config.FlagTrustedSubnet = "192.168.1.0/24"
+	defer func() { config.FlagTrustedSubnet = trustedSubnet }()
+
+	m.EXPECT().CountURLs(gomock.Any()).Return(10, nil)
+	m.EXPECT().CountUsers(gomock.Any()).Return(3, nil)
+
+	request := httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+	request.Header.Set("X-Real-IP", "192.168.1.10")
+	response := httptest.NewRecorder()
+
+	app.InternalStats(response, request)
+
+	res := response.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	var stats models.StatsResponse
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(&stats))
+	assert.Equal(t, models.StatsResponse{URLs: 10, Users: 3}, stats)
+
+	request = httptest.NewRequest(http.MethodGet, "/api/internal/stats", nil)
+	request.Header.Set("X-Real-IP", "10.0.0.1")
+	response = httptest.NewRecorder()
+
+	app.InternalStats(response, request)
+
+	assert.Equal(t, http.StatusForbidden, response.Code)
 }
@@ -0,0 +1,18 @@
+package health
+
+import (
+	"context"
+	"os"
+)
+
+// Writable возвращает проверку возможности записи в файл path: файл должен открываться на дозапись.
+// Данные в файл не записываются.
+func Writable(path string) Checker {
+	return CheckerFunc(func(context.Context) error {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	})
+}
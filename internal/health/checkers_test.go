@@ -0,0 +1,27 @@
+package health
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "storage.json")
+	require.NoError(t, os.WriteFile(path, []byte("{}\n"), 0o644))
+
+	assert.NoError(t, Writable(path).Check(context.Background()))
+
+	// Проверка не изменяет файл
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "{}\n", string(data))
+
+	assert.Error(t, Writable(filepath.Join(dir, "missing.json")).Check(context.Background()))
+	assert.Error(t, Writable(dir).Check(context.Background()))
+}
@@ -0,0 +1,255 @@
+// Package health реализует проверки живости (liveness) и готовности (readiness) сервиса.
+//
+// Health объединяет подключаемые проверки и отдаёт их результат обработчиками /healthz и /readyz.
+// Живость означает, что процесс работает и его не нужно перезапускать; готовность — что сервис
+// может принимать запросы: хранилище доступно, серверы запущены и сервис не завершает работу.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout ограничивает время выполнения одной проверки.
+const checkTimeout = 2 * time.Second
+
+// Статусы проверок.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Имена встроенных проверок.
+const (
+	// CheckDraining — проверка готовности, не проходящая после начала завершения работы сервиса.
+	CheckDraining = "draining"
+)
+
+// Ошибки встроенных проверок.
+var (
+	// ErrDraining возвращается проверкой готовности после начала завершения работы сервиса.
+	ErrDraining = errors.New("service is shutting down")
+	// ErrNotStarted возвращается проверкой компонента, который ещё не запущен.
+	ErrNotStarted = errors.New("not started")
+)
+
+// Checker проверяет состояние одной зависимости или компонента сервиса.
+type Checker interface {
+	// Check возвращает ошибку, если проверка не пройдена.
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc позволяет использовать функцию как Checker.
+type CheckerFunc func(ctx context.Context) error
+
+// Check вызывает f(ctx).
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// CheckResult — результат одной проверки.
+type CheckResult struct {
+	Status string `json:"status"`          // StatusOK или StatusFail
+	Error  string `json:"error,omitempty"` // Текст ошибки непройденной проверки
+}
+
+// Report — результат набора проверок.
+type Report struct {
+	Status string                 `json:"status"`           // StatusOK, если пройдены все проверки, иначе StatusFail
+	Checks map[string]CheckResult `json:"checks,omitempty"` // Результаты проверок по имени
+}
+
+// OK сообщает, пройдены ли все проверки.
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// check — зарегистрированная проверка.
+type check struct {
+	name    string
+	checker Checker
+}
+
+// Health хранит проверки живости и готовности сервиса и признак завершения его работы.
+// Методы Health безопасны для одновременного использования.
+type Health struct {
+	mx         sync.RWMutex
+	liveness   []check
+	readiness  []check
+	components map[string]*atomic.Bool
+	onDrain    []func()
+	draining   atomic.Bool
+}
+
+// New создаёт набор проверок со встроенной проверкой готовности CheckDraining.
+func New() *Health {
+	h := &Health{components: make(map[string]*atomic.Bool)}
+	h.AddReadinessCheck(CheckDraining, CheckerFunc(func(context.Context) error {
+		if h.Draining() {
+			return ErrDraining
+		}
+		return nil
+	}))
+	return h
+}
+
+// AddLivenessCheck добавляет проверку живости с именем name.
+func (h *Health) AddLivenessCheck(name string, c Checker) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	h.liveness = append(h.liveness, check{name: name, checker: c})
+}
+
+// AddReadinessCheck добавляет проверку готовности с именем name.
+func (h *Health) AddReadinessCheck(name string, c Checker) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+	h.readiness = append(h.readiness, check{name: name, checker: c})
+}
+
+// AddComponent добавляет проверку готовности компонента name, например сервера,
+// которая возвращает ErrNotStarted, пока компонент не отмечен запущенным вызовом SetStarted.
+func (h *Health) AddComponent(name string) {
+	started := &atomic.Bool{}
+
+	h.mx.Lock()
+	h.components[name] = started
+	h.mx.Unlock()
+
+	h.AddReadinessCheck(name, CheckerFunc(func(context.Context) error {
+		if !started.Load() {
+			return ErrNotStarted
+		}
+		return nil
+	}))
+}
+
+// SetStarted отмечает компонент name запущенным. Для незарегистрированного компонента ничего не делает.
+func (h *Health) SetStarted(name string) {
+	h.mx.RLock()
+	started, ok := h.components[name]
+	h.mx.RUnlock()
+
+	if ok {
+		started.Store(true)
+	}
+}
+
+// OnDrain регистрирует функцию, вызываемую при начале завершения работы сервиса.
+// Если завершение уже началось, f вызывается сразу.
+func (h *Health) OnDrain(f func()) {
+	h.mx.Lock()
+	if !h.draining.Load() {
+		h.onDrain = append(h.onDrain, f)
+		h.mx.Unlock()
+		return
+	}
+	h.mx.Unlock()
+
+	f()
+}
+
+// Drain отмечает начало завершения работы сервиса: проверка готовности перестаёт проходить,
+// а функции, зарегистрированные через OnDrain, вызываются один раз.
+func (h *Health) Drain() {
+	h.mx.Lock()
+	if h.draining.Swap(true) {
+		h.mx.Unlock()
+		return
+	}
+	hooks := h.onDrain
+	h.onDrain = nil
+	h.mx.Unlock()
+
+	for _, f := range hooks {
+		f()
+	}
+}
+
+// Draining сообщает, началось ли завершение работы сервиса.
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Live выполняет проверки живости.
+func (h *Health) Live(ctx context.Context) Report {
+	h.mx.RLock()
+	checks := h.liveness
+	h.mx.RUnlock()
+
+	return run(ctx, checks)
+}
+
+// Ready выполняет проверки готовности.
+func (h *Health) Ready(ctx context.Context) Report {
+	h.mx.RLock()
+	checks := h.readiness
+	h.mx.RUnlock()
+
+	return run(ctx, checks)
+}
+
+// LiveHandler возвращает обработчик /healthz, отвечающий результатом проверок живости.
+func (h *Health) LiveHandler() http.HandlerFunc {
+	return reportHandler(h.Live)
+}
+
+// ReadyHandler возвращает обработчик /readyz, отвечающий результатом проверок готовности.
+func (h *Health) ReadyHandler() http.HandlerFunc {
+	return reportHandler(h.Ready)
+}
+
+// reportHandler возвращает обработчик, отвечающий отчётом report в формате JSON
+// с кодом 200, если все проверки пройдены, иначе 503.
+func reportHandler(report func(ctx context.Context) Report) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		result := report(req.Context())
+
+		status := http.StatusOK
+		if !result.OK() {
+			status = http.StatusServiceUnavailable
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.Header().Set("Cache-Control", "no-store")
+		res.WriteHeader(status)
+		json.NewEncoder(res).Encode(result)
+	}
+}
+
+// run параллельно выполняет проверки checks, ограничивая каждую временем checkTimeout.
+func run(ctx context.Context, checks []check) Report {
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			results[i] = CheckResult{Status: StatusOK}
+			if err := c.checker.Check(checkCtx); err != nil {
+				results[i] = CheckResult{Status: StatusFail, Error: err.Error()}
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+
+	return report
+}
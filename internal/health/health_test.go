@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth_Ready(t *testing.T) {
+	h := New()
+	h.AddReadinessCheck("storage", CheckerFunc(func(context.Context) error { return nil }))
+
+	report := h.Ready(context.Background())
+	assert.True(t, report.OK())
+	assert.Equal(t, map[string]CheckResult{
+		CheckDraining: {Status: StatusOK},
+		"storage":     {Status: StatusOK},
+	}, report.Checks)
+
+	h.AddReadinessCheck("cache", CheckerFunc(func(context.Context) error { return errors.New("unavailable") }))
+
+	report = h.Ready(context.Background())
+	assert.False(t, report.OK())
+	assert.Equal(t, CheckResult{Status: StatusFail, Error: "unavailable"}, report.Checks["cache"])
+	assert.Equal(t, StatusOK, report.Checks["storage"].Status)
+}
+
+func TestHealth_Live(t *testing.T) {
+	h := New()
+	h.Drain()
+
+	// Завершение работы не влияет на живость
+	report := h.Live(context.Background())
+	assert.True(t, report.OK())
+	assert.Empty(t, report.Checks)
+
+	h.AddLivenessCheck("deadlock", CheckerFunc(func(context.Context) error { return errors.New("stuck") }))
+	assert.False(t, h.Live(context.Background()).OK())
+}
+
+func TestHealth_Component(t *testing.T) {
+	h := New()
+	h.AddComponent("grpc")
+
+	report := h.Ready(context.Background())
+	assert.False(t, report.OK())
+	assert.Equal(t, CheckResult{Status: StatusFail, Error: ErrNotStarted.Error()}, report.Checks["grpc"])
+
+	h.SetStarted("grpc")
+	h.SetStarted("unknown")
+	assert.True(t, h.Ready(context.Background()).OK())
+}
+
+func TestHealth_Drain(t *testing.T) {
+	h := New()
+
+	var calls int
+	h.OnDrain(func() { calls++ })
+	assert.False(t, h.Draining())
+
+	h.Drain()
+	h.Drain()
+	assert.True(t, h.Draining())
+	assert.Equal(t, 1, calls)
+
+	// Функция, зарегистрированная после начала завершения, вызывается сразу
+	h.OnDrain(func() { calls++ })
+	assert.Equal(t, 2, calls)
+
+	report := h.Ready(context.Background())
+	assert.False(t, report.OK())
+	assert.Equal(t, CheckResult{Status: StatusFail, Error: ErrDraining.Error()}, report.Checks[CheckDraining])
+}
+
+func TestHealth_CheckTimeout(t *testing.T) {
+	h := New()
+	h.AddReadinessCheck("slow", CheckerFunc(func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return nil
+	}))
+
+	assert.True(t, h.Ready(context.Background()).OK())
+}
+
+func TestHealth_Handlers(t *testing.T) {
+	h := New()
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		drain      bool
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "live", handler: h.LiveHandler(), wantStatus: http.StatusOK, wantBody: StatusOK},
+		{name: "ready", handler: h.ReadyHandler(), wantStatus: http.StatusOK, wantBody: StatusOK},
+		{name: "ready while draining", handler: h.ReadyHandler(), drain: true, wantStatus: http.StatusServiceUnavailable, wantBody: StatusFail},
+		{name: "live while draining", handler: h.LiveHandler(), drain: true, wantStatus: http.StatusOK, wantBody: StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.drain {
+				h.Drain()
+			}
+
+			rec := httptest.NewRecorder()
+			test.handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			assert.Equal(t, test.wantStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+			var report Report
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+			assert.Equal(t, test.wantBody, report.Status)
+		})
+	}
+}
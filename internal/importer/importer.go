@@ -0,0 +1,397 @@
+// Package importer реализует массовую загрузку ссылок из файлов CSV и JSON Lines.
+//
+// Записи читаются потоком, проверяются и сохраняются в хранилище пакетами методом storage.Storage.Import,
+// поэтому размер файла не ограничен объёмом памяти. Ошибки отдельных записей не прерывают загрузку,
+// а возвращаются в результате вместе с номером строки.
+package importer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/alias"
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/keygen"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/tags"
+)
+
+// Format — формат файла загрузки.
+type Format string
+
+// Форматы файла загрузки.
+const (
+	// FormatCSV — CSV с обязательной строкой заголовка.
+	FormatCSV Format = "csv"
+	// FormatJSONL — JSON Lines: по одному объекту Record в строке.
+	FormatJSONL Format = "jsonl"
+)
+
+const (
+	// BatchSize — количество ссылок, сохраняемых в хранилище одним вызовом Import.
+	BatchSize = 1000
+	// MaxRowErrors — максимальное количество ошибок записей в результате; остальные ошибки только подсчитываются.
+	MaxRowErrors = 1000
+	// maxLineSize — максимальная длина строки JSON Lines.
+	maxLineSize = 1 << 20
+)
+
+// Ошибки загрузки.
+var (
+	// ErrUnknownFormat возвращается для неизвестного формата файла.
+	ErrUnknownFormat = errors.New("unknown import format")
+	// ErrInvalidHeader возвращается, если в заголовке CSV нет столбца original_url.
+	ErrInvalidHeader = errors.New("invalid csv header")
+)
+
+// Record — запись файла загрузки.
+type Record struct {
+	ShortURL    string     `json:"short_url,omitempty"`  // Короткий ключ или сокращённый URL; пустая строка — ключ будет создан
+	OriginalURL string     `json:"original_url"`         // Исходный URL
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"` // Момент окончания действия ссылки (необязательно)
+	MaxClicks   int64      `json:"max_clicks,omitempty"` // Максимальное количество переходов (необязательно)
+	Tags        []string   `json:"tags,omitempty"`       // Метки ссылки (необязательно)
+}
+
+// RowError описывает запись, которую не удалось загрузить.
+type RowError struct {
+	Line     int    `json:"line"`                // Номер строки файла или порядковый номер записи потока
+	ShortURL string `json:"short_url,omitempty"` // Короткий ключ записи
+	Error    string `json:"error"`               // Причина ошибки
+}
+
+// Result содержит итоги загрузки.
+type Result struct {
+	Total    int        `json:"total"`            // Количество обработанных записей
+	Imported int        `json:"imported"`         // Количество сохранённых ссылок
+	Failed   int        `json:"failed"`           // Количество записей с ошибками
+	Errors   []RowError `json:"errors,omitempty"` // Первые MaxRowErrors ошибок записей
+}
+
+// ParseFormat возвращает формат по его названию или расширению имени файла name.
+// Явно заданное название format имеет приоритет над расширением.
+func ParseFormat(format string, name string) (Format, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	}
+
+	switch Format(format) {
+	case FormatCSV, FormatJSONL:
+		return Format(format), nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+}
+
+// Importer загружает ссылки в хранилище.
+type Importer struct {
+	storage storage.Storage
+	keys    keygen.KeyGenerator
+}
+
+// New создает загрузчик ссылок в хранилище s.
+// keys генерирует ключи для записей без short_url; если он nil, используются случайные ключи с настройками по умолчанию.
+func New(s storage.Storage, keys keygen.KeyGenerator) *Importer {
+	if keys == nil {
+		keys, _ = keygen.NewRandom(config.DefaultKeyAlphabet, config.DefaultKeyLength)
+	}
+	return &Importer{storage: s, keys: keys}
+}
+
+// Import читает записи формата format из r и загружает их от имени пользователя из ctx.
+// Ошибка возвращается, если файл не удалось прочитать или пакет ссылок не удалось сохранить;
+// в этом случае результат содержит итоги по уже сохранённым пакетам.
+func (i *Importer) Import(ctx context.Context, r io.Reader, format Format) (Result, error) {
+	session := i.Begin(ctx)
+
+	var err error
+	switch format {
+	case FormatCSV:
+		err = readCSV(r, session)
+	case FormatJSONL:
+		err = readJSONL(r, session)
+	default:
+		err = fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return session.result, err
+	}
+
+	return session.Close()
+}
+
+// Begin начинает загрузку записей, передаваемых по одной методом Session.Add.
+// Используется, когда записи поступают не из файла, например из потока gRPC.
+func (i *Importer) Begin(ctx context.Context) *Session {
+	return &Session{ctx: ctx, importer: i}
+}
+
+// Session накапливает проверенные записи и сохраняет их в хранилище пакетами по BatchSize.
+// Session не предназначен для одновременного использования из нескольких горутин.
+type Session struct {
+	ctx      context.Context
+	importer *Importer
+	result   Result
+	links    []models.Link
+	lines    []int
+	keys     []string // ключи, заданные в записях; пустая строка — ключ создан генератором
+}
+
+// Add проверяет запись со строки line и добавляет её в очередной пакет.
+// Некорректная запись учитывается в результате как ошибка записи.
+// Ошибка возвращается, только если не удалось сохранить заполненный пакет.
+func (s *Session) Add(line int, record Record) error {
+	s.result.Total++
+
+	shortKey := strings.TrimPrefix(strings.TrimSpace(record.ShortURL), config.FlagBaseAddr+"/")
+	link, err := s.importer.link(shortKey, record)
+	if err != nil {
+		s.fail(line, shortKey, err)
+		return nil
+	}
+
+	s.links = append(s.links, link)
+	s.lines = append(s.lines, line)
+	s.keys = append(s.keys, shortKey)
+	if len(s.links) < BatchSize {
+		return nil
+	}
+
+	return s.flush()
+}
+
+// Reject учитывает в результате запись со строки line, которую не удалось разобрать, с причиной err.
+func (s *Session) Reject(line int, shortURL string, err error) {
+	s.result.Total++
+	s.fail(line, shortURL, err)
+}
+
+// Close сохраняет оставшиеся записи и возвращает итоги загрузки.
+func (s *Session) Close() (Result, error) {
+	err := s.flush()
+	return s.result, err
+}
+
+// flush сохраняет накопленный пакет ссылок.
+// Ссылки с созданным ключом, который оказался занят, сохраняются повторно с другим ключом.
+func (s *Session) flush() error {
+	if len(s.links) == 0 {
+		return nil
+	}
+
+	errs, err := s.importer.storage.Import(s.ctx, s.links)
+	if err != nil {
+		return err
+	}
+
+	for n, err := range errs {
+		if errors.Is(err, storage.ErrKeyExists) && s.keys[n] == "" {
+			_, err = storage.SetGenerated(s.ctx, s.importer.storage, s.importer.keys, s.links[n])
+		}
+
+		switch {
+		case err == nil:
+			s.result.Imported++
+		case errors.Is(err, storage.ErrConflict):
+			s.fail(s.lines[n], s.keys[n], errors.New("url already shortened"))
+		case errors.Is(err, storage.ErrKeyExists):
+			s.fail(s.lines[n], s.keys[n], errors.New("short url already taken"))
+		default:
+			return err
+		}
+	}
+
+	s.links = s.links[:0]
+	s.lines = s.lines[:0]
+	s.keys = s.keys[:0]
+
+	return nil
+}
+
+// fail учитывает ошибку записи со строки line.
+func (s *Session) fail(line int, shortKey string, err error) {
+	s.result.Failed++
+	if len(s.result.Errors) < MaxRowErrors {
+		s.result.Errors = append(s.result.Errors, RowError{Line: line, ShortURL: shortKey, Error: err.Error()})
+	}
+}
+
+// link проверяет запись и преобразует её в ссылку с ключом shortKey.
+// Если ключ не задан, он создаётся генератором загрузчика.
+func (i *Importer) link(shortKey string, record Record) (models.Link, error) {
+	originalURL := strings.TrimSpace(record.OriginalURL)
+	if err := ValidateURL(originalURL); err != nil {
+		return models.Link{}, err
+	}
+
+	if shortKey != "" {
+		if err := alias.Validate(shortKey); err != nil {
+			return models.Link{}, err
+		}
+	}
+
+	if record.MaxClicks < 0 {
+		return models.Link{}, errors.New("invalid max_clicks")
+	}
+
+	linkTags, err := tags.NormalizeAll(record.Tags)
+	if err != nil {
+		return models.Link{}, fmt.Errorf("invalid tags: %w", err)
+	}
+
+	link := models.Link{ShortKey: shortKey, OriginalURL: originalURL, MaxClicks: record.MaxClicks, Tags: linkTags}
+	if record.ExpiresAt != nil {
+		if !record.ExpiresAt.After(time.Now()) {
+			return models.Link{}, errors.New("link already expired")
+		}
+		link.ExpiresAt = record.ExpiresAt.UTC()
+	}
+
+	if link.ShortKey == "" {
+		if link.ShortKey, err = i.keys.Generate(originalURL, 0); err != nil {
+			return models.Link{}, err
+		}
+	}
+
+	return link, nil
+}
+
+// ValidateURL проверяет, что rawURL — абсолютный URL со схемой http или https.
+func ValidateURL(rawURL string) error {
+	parsed, err := url.ParseRequestURI(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid original_url: %w", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("invalid original_url: http or https url expected")
+	}
+
+	return nil
+}
+
+// readCSV читает записи CSV и добавляет их в загрузку session вместе с номером строки.
+// Первая строка — заголовок: обязателен столбец original_url, необязательны short_url,
+// expires_at (RFC 3339), max_clicks и tags (метки через запятую); остальные столбцы игнорируются.
+func readCSV(r io.Reader, session *Session) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: empty file", ErrInvalidHeader)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidHeader, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for n, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))] = n
+	}
+	if _, ok := columns["original_url"]; !ok {
+		return fmt.Errorf("%w: original_url column is required", ErrInvalidHeader)
+	}
+
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		// FieldPos допустим только после успешного чтения строки: для строки с ошибкой разбора
+		// номер строки берётся из csv.ParseError
+		var line int
+		var record Record
+		if err == nil && len(fields) > 0 {
+			line, _ = reader.FieldPos(0)
+			record, err = csvRecord(columns, fields)
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.StartLine
+				err = parseErr.Err
+			}
+			session.Reject(line, column(columns, fields, "short_url"), err)
+			continue
+		}
+
+		if err = session.Add(line, record); err != nil {
+			return err
+		}
+	}
+}
+
+// csvRecord преобразует поля строки CSV в запись по номерам столбцов columns.
+func csvRecord(columns map[string]int, fields []string) (Record, error) {
+	record := Record{
+		ShortURL:    column(columns, fields, "short_url"),
+		OriginalURL: column(columns, fields, "original_url"),
+	}
+
+	if value := column(columns, fields, "expires_at"); value != "" {
+		expiresAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return record, fmt.Errorf("invalid expires_at: %w", err)
+		}
+		record.ExpiresAt = &expiresAt
+	}
+
+	if value := column(columns, fields, "max_clicks"); value != "" {
+		maxClicks, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return record, errors.New("invalid max_clicks")
+		}
+		record.MaxClicks = maxClicks
+	}
+
+	record.Tags = tags.Split(column(columns, fields, "tags"))
+
+	return record, nil
+}
+
+// column возвращает значение столбца name строки CSV или пустую строку, если столбца нет.
+func column(columns map[string]int, fields []string, name string) string {
+	n, ok := columns[name]
+	if !ok || n >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[n])
+}
+
+// readJSONL читает записи JSON Lines и добавляет их в загрузку session вместе с номером строки.
+// Пустые строки пропускаются.
+func readJSONL(r io.Reader, session *Session) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			session.Reject(line, "", fmt.Errorf("invalid json: %w", err))
+			continue
+		}
+		if err := session.Add(line, record); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/dsemenov12/shorturl/internal/storage/mocks"
+)
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		file    string
+		want    Format
+		wantErr error
+	}{
+		{name: "explicit format", format: "jsonl", file: "links.csv", want: FormatJSONL},
+		{name: "csv extension", file: "links.CSV", want: FormatCSV},
+		{name: "jsonl extension", file: "dump/links.jsonl", want: FormatJSONL},
+		{name: "unknown extension", file: "links.xml", wantErr: ErrUnknownFormat},
+		{name: "unknown format", format: "xlsx", wantErr: ErrUnknownFormat},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format, err := ParseFormat(test.format, test.file)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, format)
+		})
+	}
+}
+
+func TestImporter_ImportCSV(t *testing.T) {
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	s := memory.NewStorage()
+	_, err := s.Set(ctx, models.Link{ShortKey: "taken", OriginalURL: "https://example.com/taken"})
+	require.NoError(t, err)
+
+	data := strings.Join([]string{
+		"original_url,short_url,max_clicks,comment,tags",
+		`https://example.com/1,first,,migrated,"Promo,news"`,
+		"https://example.com/2,,10,",
+		"ftp://example.com/3,third,,",
+		"https://example.com/4,a/b,,",
+		"https://example.com/5,taken,,",
+		"https://example.com/taken,other,,",
+		"https://example.com/6,sixth,many,",
+		`https://example.com/7,"bad"quote,,`,
+		"https://example.com/8,eighth,,,not a tag",
+	}, "\n")
+
+	result, err := New(s, nil).Import(ctx, strings.NewReader(data), FormatCSV)
+	require.NoError(t, err)
+
+	assert.Equal(t, 9, result.Total)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 7, result.Failed)
+
+	lines := make([]int, 0, len(result.Errors))
+	for _, rowErr := range result.Errors {
+		lines = append(lines, rowErr.Line)
+	}
+	assert.Equal(t, []int{4, 5, 8, 9, 10, 6, 7}, lines)
+	assert.Equal(t, RowError{Line: 10, ShortURL: "eighth", Error: "invalid tags: tag contains invalid characters"}, result.Errors[4])
+	assert.Equal(t, RowError{Line: 6, ShortURL: "taken", Error: "short url already taken"}, result.Errors[5])
+	assert.Equal(t, RowError{Line: 7, ShortURL: "other", Error: "url already shortened"}, result.Errors[6])
+
+	link, err := s.Get(ctx, "first")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/1", link.OriginalURL)
+	assert.Equal(t, "user1", link.UserID)
+	assert.Equal(t, []string{"promo", "news"}, link.Tags)
+
+	items, err := s.GetUserURL(ctx)
+	require.NoError(t, err)
+	assert.Len(t, items, 3)
+}
+
+func TestImporter_ImportCSVMalformedFirstColumn(t *testing.T) {
+	s := memory.NewStorage()
+	data := "original_url,short_url\nhttps://ex\"ample.com/2,ok2\nhttps://example.com/3,ok3\n"
+
+	result, err := New(s, nil).Import(context.Background(), strings.NewReader(data), FormatCSV)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 1, result.Imported)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 2, result.Errors[0].Line)
+	assert.Contains(t, result.Errors[0].Error, "quote")
+}
+
+func TestImporter_ImportCSVHeader(t *testing.T) {
+	importer := New(memory.NewStorage(), nil)
+
+	_, err := importer.Import(context.Background(), strings.NewReader(""), FormatCSV)
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+
+	_, err = importer.Import(context.Background(), strings.NewReader("url,key\nhttps://example.com,abcd\n"), FormatCSV)
+	assert.ErrorIs(t, err, ErrInvalidHeader)
+}
+
+func TestImporter_ImportJSONL(t *testing.T) {
+	s := memory.NewStorage()
+	data := strings.Join([]string{
+		`{"short_url":"/first","original_url":"https://example.com/1","max_clicks":5,"tags":["news","News"]}`,
+		``,
+		`{"original_url":"https://example.com/2","expires_at":"2000-01-01T00:00:00Z"}`,
+		`{"original_url":`,
+		`{"original_url":"https://example.com/3"}`,
+	}, "\n")
+
+	result, err := New(s, nil).Import(context.Background(), strings.NewReader(data), FormatJSONL)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 2, result.Failed)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 3, result.Errors[0].Line)
+	assert.Equal(t, 4, result.Errors[1].Line)
+
+	link, err := s.Get(context.Background(), "first")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), link.MaxClicks)
+	assert.Equal(t, []string{"news"}, link.Tags)
+}
+
+func TestImporter_Batches(t *testing.T) {
+	s := memory.NewStorage()
+	session := New(s, nil).Begin(context.Background())
+
+	total := BatchSize*2 + 10
+	for n := 1; n <= total; n++ {
+		require.NoError(t, session.Add(n, Record{OriginalURL: fmt.Sprintf("https://example.com/%d", n)}))
+	}
+	session.Reject(total+1, "", errors.New("broken record"))
+
+	result, err := session.Close()
+	require.NoError(t, err)
+	assert.Equal(t, total+1, result.Total)
+	assert.Equal(t, total, result.Imported)
+	assert.Equal(t, []RowError{{Line: total + 1, Error: "broken record"}}, result.Errors)
+
+	count, err := s.CountURLs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, total, count)
+}
+
+func TestImporter_StorageError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	s := mocks.NewMockStorage(ctrl)
+	s.EXPECT().Import(gomock.Any(), gomock.Len(1)).Return(nil, errors.New("database is down"))
+
+	result, err := New(s, nil).Import(context.Background(), strings.NewReader("original_url\nhttps://example.com\n"), FormatCSV)
+	assert.EqualError(t, err, "database is down")
+	assert.Equal(t, 1, result.Total)
+	assert.Zero(t, result.Imported)
+}
@@ -0,0 +1,223 @@
+package keygen
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Стратегии генерации коротких ключей.
+const (
+	// StrategyRandom — случайный ключ из криптографически стойкого генератора.
+	StrategyRandom = "random"
+	// StrategySequential — последовательный ключ на основе времени, номера узла и счётчика (по схеме Snowflake).
+	StrategySequential = "sequential"
+	// StrategyHash — ключ на основе хеша исходного URL.
+	StrategyHash = "hash"
+)
+
+const (
+	// epoch — начало отсчёта времени для последовательных ключей (2024-01-01 UTC), в миллисекундах.
+	epoch = 1704067200000
+	// nodeBits — количество бит под номер узла в последовательном ключе.
+	nodeBits = 10
+	// sequenceBits — количество бит под счётчик в пределах одной миллисекунды.
+	sequenceBits = 12
+	// maxNodeID — максимальный номер узла.
+	maxNodeID = 1<<nodeBits - 1
+	// maxSequence — максимальное значение счётчика в пределах одной миллисекунды.
+	maxSequence = 1<<sequenceBits - 1
+)
+
+// Ошибки создания генератора.
+var (
+	// ErrUnknownStrategy возвращается для неизвестной стратегии генерации.
+	ErrUnknownStrategy = errors.New("unknown key generation strategy")
+	// ErrInvalidAlphabet возвращается, если алфавит короче двух символов, содержит повторы или не-ASCII символы.
+	ErrInvalidAlphabet = errors.New("invalid key alphabet")
+	// ErrInvalidLength возвращается для неположительной длины ключа.
+	ErrInvalidLength = errors.New("invalid key length")
+	// ErrInvalidNodeID возвращается, если номер узла выходит за допустимые границы.
+	ErrInvalidNodeID = errors.New("invalid node id")
+)
+
+// KeyGenerator генерирует короткие ключи для ссылок.
+type KeyGenerator interface {
+	// Generate возвращает короткий ключ для URL. attempt — номер попытки, начиная с нуля:
+	// при коллизии ключа хранилище повторяет генерацию с увеличенным attempt,
+	// что позволяет детерминированным стратегиям получить другой ключ.
+	Generate(url string, attempt int) (string, error)
+}
+
+// New создает генератор ключей по названию стратегии.
+// length задаёт длину ключа (для последовательной стратегии — минимальную длину),
+// nodeID используется только последовательной стратегией.
+func New(strategy string, alphabet string, length int, nodeID int) (KeyGenerator, error) {
+	switch strategy {
+	case StrategyRandom:
+		return NewRandom(alphabet, length)
+	case StrategySequential:
+		return NewSequential(alphabet, length, nodeID)
+	case StrategyHash:
+		return NewHash(alphabet, length)
+	default:
+		return nil, ErrUnknownStrategy
+	}
+}
+
+// Random генерирует случайные ключи с помощью crypto/rand.
+type Random struct {
+	alphabet string
+	length   int
+}
+
+// NewRandom создает генератор случайных ключей заданной длины из символов alphabet.
+func NewRandom(alphabet string, length int) (*Random, error) {
+	if err := validate(alphabet, length); err != nil {
+		return nil, err
+	}
+
+	return &Random{alphabet: alphabet, length: length}, nil
+}
+
+// Generate возвращает случайный ключ. Символы выбираются равновероятно.
+func (g *Random) Generate(url string, attempt int) (string, error) {
+	max := big.NewInt(int64(len(g.alphabet)))
+
+	b := make([]byte, g.length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = g.alphabet[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// Sequential генерирует возрастающие ключи из 64-битных идентификаторов:
+// миллисекунды от epoch, номер узла и счётчик в пределах миллисекунды.
+// Идентификатор кодируется в системе счисления с основанием len(alphabet).
+type Sequential struct {
+	mx       sync.Mutex
+	alphabet string
+	length   int
+	nodeID   int64
+	lastTime int64
+	sequence int64
+	now      func() time.Time
+}
+
+// NewSequential создает генератор последовательных ключей для узла nodeID (0..1023).
+// Ключ дополняется слева до length символов, но может быть длиннее.
+func NewSequential(alphabet string, length int, nodeID int) (*Sequential, error) {
+	if err := validate(alphabet, length); err != nil {
+		return nil, err
+	}
+	if nodeID < 0 || nodeID > maxNodeID {
+		return nil, ErrInvalidNodeID
+	}
+
+	return &Sequential{alphabet: alphabet, length: length, nodeID: int64(nodeID), now: time.Now}, nil
+}
+
+// Generate возвращает следующий ключ. При исчерпании счётчика генератор переходит к следующей миллисекунде.
+func (g *Sequential) Generate(url string, attempt int) (string, error) {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	ms := g.now().UnixMilli() - epoch
+	if ms < g.lastTime {
+		// Часы отстали — продолжаем от последнего известного момента, чтобы ключи не повторялись
+		ms = g.lastTime
+	}
+	if ms == g.lastTime {
+		g.sequence = (g.sequence + 1) & maxSequence
+		if g.sequence == 0 {
+			ms++
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.lastTime = ms
+
+	id := ms<<(nodeBits+sequenceBits) | g.nodeID<<sequenceBits | g.sequence
+
+	return encode(big.NewInt(id), g.alphabet, g.length), nil
+}
+
+// Hash генерирует ключи из SHA-256 хеша исходного URL,
+// поэтому один и тот же URL получает один и тот же ключ.
+type Hash struct {
+	alphabet string
+	length   int
+}
+
+// NewHash создает генератор ключей на основе хеша URL.
+func NewHash(alphabet string, length int) (*Hash, error) {
+	if err := validate(alphabet, length); err != nil {
+		return nil, err
+	}
+
+	return &Hash{alphabet: alphabet, length: length}, nil
+}
+
+// Generate возвращает последние length разрядов хеша URL, записанного в алфавите генератора.
+// При повторных попытках к URL добавляется номер попытки.
+func (g *Hash) Generate(url string, attempt int) (string, error) {
+	data := url
+	if attempt > 0 {
+		data += "#" + strconv.Itoa(attempt)
+	}
+	sum := sha256.Sum256([]byte(data))
+
+	key := encode(new(big.Int).SetBytes(sum[:]), g.alphabet, g.length)
+	return key[len(key)-g.length:], nil
+}
+
+// encode записывает число n в системе счисления с основанием len(alphabet),
+// дополняя результат слева первым символом алфавита до length символов.
+func encode(n *big.Int, alphabet string, length int) string {
+	base := big.NewInt(int64(len(alphabet)))
+	mod := new(big.Int)
+
+	var b []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		b = append(b, alphabet[mod.Int64()])
+	}
+	for len(b) < length {
+		b = append(b, alphabet[0])
+	}
+
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b)
+}
+
+// validate проверяет алфавит и длину ключа.
+func validate(alphabet string, length int) error {
+	if length <= 0 {
+		return ErrInvalidLength
+	}
+	if len(alphabet) < 2 {
+		return ErrInvalidAlphabet
+	}
+
+	seen := make(map[byte]bool, len(alphabet))
+	for i := 0; i < len(alphabet); i++ {
+		if seen[alphabet[i]] || alphabet[i] >= 0x80 {
+			return ErrInvalidAlphabet
+		}
+		seen[alphabet[i]] = true
+	}
+
+	return nil
+}
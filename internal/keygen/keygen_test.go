@@ -0,0 +1,96 @@
+package keygen
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		alphabet string
+		length   int
+		nodeID   int
+		wantErr  error
+	}{
+		{name: "random", strategy: StrategyRandom, alphabet: testAlphabet, length: 8},
+		{name: "sequential", strategy: StrategySequential, alphabet: testAlphabet, length: 8, nodeID: 1},
+		{name: "hash", strategy: StrategyHash, alphabet: testAlphabet, length: 8},
+		{name: "unknown strategy", strategy: "uuid", alphabet: testAlphabet, length: 8, wantErr: ErrUnknownStrategy},
+		{name: "zero length", strategy: StrategyRandom, alphabet: testAlphabet, wantErr: ErrInvalidLength},
+		{name: "short alphabet", strategy: StrategyRandom, alphabet: "a", length: 8, wantErr: ErrInvalidAlphabet},
+		{name: "duplicate characters", strategy: StrategyHash, alphabet: "abca", length: 8, wantErr: ErrInvalidAlphabet},
+		{name: "invalid node id", strategy: StrategySequential, alphabet: testAlphabet, length: 8, nodeID: 1024, wantErr: ErrInvalidNodeID},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			generator, err := New(test.strategy, test.alphabet, test.length, test.nodeID)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, generator)
+		})
+	}
+}
+
+func TestRandom_Generate(t *testing.T) {
+	generator, err := NewRandom("ab", 16)
+	assert.NoError(t, err)
+
+	key, err := generator.Generate("https://example.com", 0)
+	assert.NoError(t, err)
+	assert.Len(t, key, 16)
+	assert.Empty(t, strings.Trim(key, "ab"), "Key should contain only alphabet characters")
+}
+
+func TestSequential_Generate(t *testing.T) {
+	generator, err := NewSequential(testAlphabet, 8, 5)
+	assert.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	generator.now = func() time.Time { return now }
+
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		key, err := generator.Generate("https://example.com", 0)
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, len(key), 8)
+		assert.False(t, seen[key], "Keys should not repeat")
+		seen[key] = true
+	}
+
+	// Отставание часов не приводит к повтору ключей
+	now = now.Add(-time.Hour)
+	key, err := generator.Generate("https://example.com", 0)
+	assert.NoError(t, err)
+	assert.False(t, seen[key])
+}
+
+func TestHash_Generate(t *testing.T) {
+	generator, err := NewHash(testAlphabet, 8)
+	assert.NoError(t, err)
+
+	first, err := generator.Generate("https://example.com", 0)
+	assert.NoError(t, err)
+	assert.Len(t, first, 8)
+
+	again, err := generator.Generate("https://example.com", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, first, again, "Same URL should produce the same key")
+
+	retry, err := generator.Generate("https://example.com", 1)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, retry, "Retry should produce another key")
+
+	other, err := generator.Generate("https://example.org", 0)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, other)
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/cache"
+)
+
+// statsTimeout ограничивает время чтения показателей из хранилища при сборе метрик.
+const statsTimeout = 5 * time.Second
+
+// Counts — количество сохранённых ссылок и их владельцев.
+type Counts struct {
+	URLs  int // Количество неудалённых ссылок
+	Users int // Количество уникальных владельцев ссылок
+}
+
+// ReadCounts читает из хранилища количество ссылок и пользователей.
+// Используется и метриками, и внутренней статистикой сервиса, чтобы показатели совпадали.
+func ReadCounts(ctx context.Context, s storage.Storage) (Counts, error) {
+	urls, err := s.CountURLs(ctx)
+	if err != nil {
+		return Counts{}, err
+	}
+	users, err := s.CountUsers(ctx)
+	if err != nil {
+		return Counts{}, err
+	}
+
+	return Counts{URLs: urls, Users: users}, nil
+}
+
+// statsCollector отдаёт количество ссылок и пользователей, читая их из хранилища при каждом сборе метрик.
+type statsCollector struct {
+	storage storage.Storage
+	urls    *prometheus.Desc
+	users   *prometheus.Desc
+}
+
+// NewStatsCollector создаёт сборщик метрик shorturl_links и shorturl_users для хранилища s.
+// Если прочитать показатели не удалось, ошибка возвращается в ответе на запрос метрик.
+func NewStatsCollector(s storage.Storage) prometheus.Collector {
+	return &statsCollector{
+		storage: s,
+		urls:    prometheus.NewDesc(Namespace+"_links", "Number of stored links that are not deleted.", nil, nil),
+		users:   prometheus.NewDesc(Namespace+"_users", "Number of distinct link owners.", nil, nil),
+	}
+}
+
+// Describe передаёт описания метрик сборщика.
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.urls
+	ch <- c.users
+}
+
+// Collect читает показатели из хранилища и передаёт их значения.
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), statsTimeout)
+	defer cancel()
+
+	counts, err := ReadCounts(ctx, c.storage)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.urls, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.urls, prometheus.GaugeValue, float64(counts.URLs))
+	ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(counts.Users))
+}
+
+// cacheCollector отдаёт счётчики кеша ссылок.
+type cacheCollector struct {
+	cache  *cache.StorageCache
+	hits   *prometheus.Desc
+	misses *prometheus.Desc
+	size   *prometheus.Desc
+}
+
+// NewCacheCollector создаёт сборщик счётчиков обращений к кешу ссылок c.
+func NewCacheCollector(c *cache.StorageCache) prometheus.Collector {
+	return &cacheCollector{
+		cache:  c,
+		hits:   prometheus.NewDesc(Namespace+"_cache_hits_total", "Number of link lookups served from cache.", nil, nil),
+		misses: prometheus.NewDesc(Namespace+"_cache_misses_total", "Number of link lookups passed to storage.", nil, nil),
+		size:   prometheus.NewDesc(Namespace+"_cache_entries", "Number of entries in the link cache.", nil, nil),
+	}
+}
+
+// Describe передаёт описания метрик сборщика.
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.size
+}
+
+// Collect передаёт текущие значения счётчиков кеша.
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+}
+
+// NewDBStatsCollector создаёт сборщик статистики пула соединений db (sql.DB.Stats).
+func NewDBStatsCollector(db *sql.DB) prometheus.Collector {
+	return collectors.NewDBStatsCollector(db, Namespace)
+}
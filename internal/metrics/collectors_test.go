@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage/cache"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/dsemenov12/shorturl/internal/storage/mocks"
+)
+
+func TestReadCounts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+	ctx := context.Background()
+
+	m.EXPECT().CountURLs(gomock.Any()).Return(10, nil)
+	m.EXPECT().CountUsers(gomock.Any()).Return(3, nil)
+	counts, err := ReadCounts(ctx, m)
+	require.NoError(t, err)
+	assert.Equal(t, Counts{URLs: 10, Users: 3}, counts)
+
+	m.EXPECT().CountURLs(gomock.Any()).Return(0, errors.New("connection reset"))
+	_, err = ReadCounts(ctx, m)
+	assert.Error(t, err)
+}
+
+func TestStatsCollector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+
+	m.EXPECT().CountURLs(gomock.Any()).Return(10, nil)
+	m.EXPECT().CountUsers(gomock.Any()).Return(3, nil)
+	err := testutil.CollectAndCompare(NewStatsCollector(m), strings.NewReader(`
+# HELP shorturl_links Number of stored links that are not deleted.
+# TYPE shorturl_links gauge
+shorturl_links 10
+# HELP shorturl_users Number of distinct link owners.
+# TYPE shorturl_users gauge
+shorturl_users 3
+`))
+	assert.NoError(t, err)
+
+	// Ошибка хранилища возвращается в ответе на запрос метрик
+	m.EXPECT().CountURLs(gomock.Any()).Return(0, errors.New("connection reset"))
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewStatsCollector(m))
+	_, err = registry.Gather()
+	assert.ErrorContains(t, err, "connection reset")
+}
+
+func TestCacheCollector(t *testing.T) {
+	linkCache := cache.NewStorage(memory.NewStorage(), cache.Options{Size: 10, TTL: time.Minute})
+	ctx := context.Background()
+
+	_, err := linkCache.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com"})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = linkCache.Get(ctx, "short1")
+		require.NoError(t, err)
+	}
+
+	err = testutil.CollectAndCompare(NewCacheCollector(linkCache), strings.NewReader(`
+# HELP shorturl_cache_entries Number of entries in the link cache.
+# TYPE shorturl_cache_entries gauge
+shorturl_cache_entries 1
+# HELP shorturl_cache_hits_total Number of link lookups served from cache.
+# TYPE shorturl_cache_hits_total counter
+shorturl_cache_hits_total 2
+# HELP shorturl_cache_misses_total Number of link lookups passed to storage.
+# TYPE shorturl_cache_misses_total counter
+shorturl_cache_misses_total 1
+`))
+	assert.NoError(t, err)
+}
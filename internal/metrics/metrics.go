@@ -0,0 +1,83 @@
+// Package metrics содержит метрики Prometheus сервиса и обработчик, отдающий их по HTTP.
+//
+// Метрики регистрируются в собственном реестре Registry, а не в глобальном реестре Prometheus,
+// поэтому в выдачу /metrics попадают только метрики сервиса, Go runtime и процесса.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace — общий префикс имён метрик сервиса.
+const Namespace = "shorturl"
+
+// Registry — реестр метрик сервиса.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+// Метрики HTTP-запросов. Маршрут — шаблон chi, например /api/user/urls/{id}, чтобы количество
+// рядов не зависело от количества ссылок.
+var (
+	// HTTPRequests — количество обработанных HTTP-запросов по методу, маршруту и коду ответа.
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of handled HTTP requests.",
+	}, []string{"method", "route", "status"})
+	// HTTPDuration — время обработки HTTP-запросов по методу и маршруту.
+	HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request handling latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+// Метрики gRPC-вызовов. Метод — полное имя вида /shorturl.ShortenerService/ShortenURL.
+var (
+	// GRPCRequests — количество обработанных gRPC-вызовов по методу и коду ответа.
+	GRPCRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Number of handled gRPC calls.",
+	}, []string{"method", "code"})
+	// GRPCDuration — время обработки gRPC-вызовов по методу.
+	GRPCDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "gRPC call handling latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// StorageDuration — время выполнения операций хранилища по имени метода storage.Storage
+// и результату: ok, rejected (ошибка предметной области, например ErrNotFound) или error.
+var StorageDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: Namespace,
+	Subsystem: "storage",
+	Name:      "operation_duration_seconds",
+	Help:      "Storage operation latency.",
+	Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+}, []string{"operation", "result"})
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler возвращает HTTP-обработчик, отдающий метрики реестра Registry в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
+	"go.uber.org/zap"
 )
 
 // AuthCookieHandle является middleware-функцией для обработки авторизации пользователей
@@ -31,7 +33,8 @@ func AuthCookieHandle(handlerFunc http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		r = r.WithContext(context.WithValue(context.Background(), auth.UserIDKey, userID))
+		r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+		logger.With(r.Context(), zap.String("user_id", userID))
 
 		handlerFunc(w, r)
 	})
@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 // AuthHandle является middleware-функцией для обработки авторизации пользователей.
@@ -46,6 +48,7 @@ func AuthHandle(handlerFunc http.HandlerFunc) http.HandlerFunc {
 		}
 
 		r = r.WithContext(context.WithValue(r.Context(), auth.UserIDKey, userID))
+		logger.With(r.Context(), zap.String("user_id", userID))
 
 		handlerFunc(w, r)
 	})
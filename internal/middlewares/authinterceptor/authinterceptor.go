@@ -7,10 +7,12 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
 )
 
 // AuthUnaryInterceptor является gRPC Unary Interceptor-ом для обработки авторизации пользователей по JWT-токену из cookie.
@@ -27,70 +29,119 @@ func AuthUnaryInterceptor() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		md, _ := metadata.FromIncomingContext(ctx)
-
-		var jwtToken string
-
-		// Извлекаем JWT из cookie заголовков (metadata)
-		if cookieHeaders := md.Get("cookie"); len(cookieHeaders) > 0 {
-			for _, cookieHeader := range cookieHeaders {
-				parts := strings.Split(cookieHeader, ";")
-				for _, part := range parts {
-					kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
-					if len(kv) == 2 && kv[0] == "JWT" {
-						jwtToken = kv[1]
-						break
-					}
+		ctx, trailer, err := authorize(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// Добавляем Set-Cookie в trailing metadata (может быть перехвачено grpc-gateway)
+		if trailer != nil {
+			grpc.SetTrailer(ctx, trailer)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// AuthStreamInterceptor является gRPC Stream Interceptor-ом с той же логикой авторизации, что и AuthUnaryInterceptor.
+// Идентификатор пользователя добавляется в контекст потока, а новый токен передаётся в trailing metadata потока.
+func AuthStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, trailer, err := authorize(stream.Context())
+		if err != nil {
+			return err
+		}
+
+		if trailer != nil {
+			stream.SetTrailer(trailer)
+		}
+
+		return handler(srv, &authStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authStream подменяет контекст потока контекстом с идентификатором пользователя.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потока с идентификатором пользователя.
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize извлекает идентификатор пользователя из JWT в cookie входящего запроса или создаёт нового пользователя.
+// Возвращает контекст с идентификатором пользователя и, если выпущен новый токен, metadata с Set-Cookie.
+func authorize(ctx context.Context) (context.Context, metadata.MD, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var jwtToken string
+
+	// Извлекаем JWT из cookie заголовков (metadata)
+	if cookieHeaders := md.Get("cookie"); len(cookieHeaders) > 0 {
+		for _, cookieHeader := range cookieHeaders {
+			parts := strings.Split(cookieHeader, ";")
+			for _, part := range parts {
+				kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+				if len(kv) == 2 && kv[0] == "JWT" {
+					jwtToken = kv[1]
+					break
 				}
 			}
 		}
+	}
 
-		var userID string
-		var err error
-		var newToken string
-		needNewToken := false
+	var userID string
+	var err error
+	var newToken string
+	needNewToken := false
 
-		if jwtToken == "" {
-			// Генерация нового токена
+	if jwtToken == "" {
+		// Генерация нового токена
+		id := uuid.New()
+		userID = id.String()
+		newToken, err = auth.BuildJWTString(userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		needNewToken = true
+	} else {
+		// Проверка JWT
+		userID, err = auth.GetUserID(jwtToken)
+		if err != nil || userID == "" {
+			// Некорректный токен — как в HTTP middleware, авторизуем заново
 			id := uuid.New()
 			userID = id.String()
 			newToken, err = auth.BuildJWTString(userID)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			needNewToken = true
-		} else {
-			// Проверка JWT
-			userID, err = auth.GetUserID(jwtToken)
-			if err != nil || userID == "" {
-				// Некорректный токен — как в HTTP middleware, авторизуем заново
-				id := uuid.New()
-				userID = id.String()
-				newToken, err = auth.BuildJWTString(userID)
-				if err != nil {
-					return nil, err
-				}
-				needNewToken = true
-			}
 		}
+	}
 
-		// Добавляем userID в context
-		ctx = context.WithValue(ctx, auth.UserIDKey, userID)
+	// Добавляем userID в context
+	ctx = context.WithValue(ctx, auth.UserIDKey, userID)
+	logger.With(ctx, zap.String("user_id", userID))
 
-		// Добавляем Set-Cookie в trailing metadata (может быть перехвачено grpc-gateway)
-		if needNewToken {
-			setCookie := &http.Cookie{
-				Name:     "JWT",
-				Value:    newToken,
-				Path:     "/",
-				Expires:  time.Now().Add(24 * time.Hour),
-				HttpOnly: true,
-				SameSite: http.SameSiteLaxMode,
-			}
-			trailer := metadata.Pairs("Set-Cookie", setCookie.String())
-			grpc.SetTrailer(ctx, trailer)
-		}
+	if !needNewToken {
+		return ctx, nil, nil
+	}
 
-		return handler(ctx, req)
+	setCookie := &http.Cookie{
+		Name:     "JWT",
+		Value:    newToken,
+		Path:     "/",
+		Expires:  time.Now().Add(24 * time.Hour),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
 	}
+
+	return ctx, metadata.Pairs("Set-Cookie", setCookie.String()), nil
 }
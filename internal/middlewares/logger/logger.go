@@ -1,10 +1,15 @@
 package logger
 
 import (
+	"context"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
 )
 
 // Log является глобальной переменной для логгера, инициализированного с помощью пакета zap.
@@ -57,8 +62,49 @@ func Initialize(level string) error {
 	return nil
 }
 
+// ctxKey — ключ контекста, под которым хранится логгер запроса.
+type ctxKey struct{}
+
+// requestLog хранит логгер запроса. Поля добавляются к нему на всём пути обработки запроса,
+// в том числе вложенными middleware, поэтому логгер хранится в контексте по указателю.
+type requestLog struct {
+	logger atomic.Pointer[zap.Logger]
+}
+
+// NewContext возвращает контекст с логгером запроса l.
+func NewContext(ctx context.Context, l *zap.Logger) context.Context {
+	rl := &requestLog{}
+	rl.logger.Store(l)
+	return context.WithValue(ctx, ctxKey{}, rl)
+}
+
+// FromContext возвращает логгер запроса из контекста, а если его нет — глобальный логгер Log.
+func FromContext(ctx context.Context) *zap.Logger {
+	if rl, ok := ctx.Value(ctxKey{}).(*requestLog); ok {
+		return rl.logger.Load()
+	}
+	return Log
+}
+
+// With добавляет поля к логгеру запроса из контекста ctx. Поля попадают во все последующие записи
+// этого запроса, включая итоговую запись RequestLogger. Без логгера запроса в контексте ничего не делает.
+func With(ctx context.Context, fields ...zap.Field) {
+	rl, ok := ctx.Value(ctxKey{}).(*requestLog)
+	if !ok {
+		return
+	}
+	for {
+		current := rl.logger.Load()
+		if rl.logger.CompareAndSwap(current, current.With(fields...)) {
+			return
+		}
+	}
+}
+
 // RequestLogger является middleware для логирования информации о входящих HTTP-запросах и их ответах.
-// Логируются метод, путь запроса, продолжительность запроса, код статуса ответа и размер ответа.
+// По завершении запроса в логгер запроса записывается одна запись с методом, путём и шаблоном маршрута,
+// кодом статуса, размером ответа и продолжительностью запроса. Шаблон маршрута добавляется к логгеру запроса
+// до вызова обработчика, поэтому попадает и в записи самого обработчика.
 // handlerFunc: обработчик HTTP-запроса, который будет обернут в логирование.
 //
 // Возвращаемое значение: возвращает обработчик HTTP-запроса, который логирует информацию о запросах и ответах.
@@ -66,6 +112,13 @@ func RequestLogger(handlerFunc http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		if _, ok := r.Context().Value(ctxKey{}).(*requestLog); !ok {
+			r = r.WithContext(NewContext(r.Context(), Log))
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			With(r.Context(), zap.String("route", rctx.RoutePattern()))
+		}
+
 		responseData := &responseData{
 			status: 0,
 			size:   0,
@@ -77,16 +130,85 @@ func RequestLogger(handlerFunc http.HandlerFunc) http.HandlerFunc {
 
 		handlerFunc(&lw, r)
 
-		duration := time.Since(start)
+		status := responseData.status
+		if status == 0 {
+			status = http.StatusOK
+		}
 
-		Log.Info("got incoming HTTP request",
+		FromContext(r.Context()).Info("HTTP request",
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
-			zap.Duration("duration", duration),
-		)
-		Log.Info("got response",
-			zap.Int("status", responseData.status),
+			zap.Int("status", status),
 			zap.Int("size", responseData.size),
+			zap.Duration("duration", time.Since(start)),
 		)
 	})
 }
+
+// UnaryCallLogger является gRPC Unary Interceptor-ом, который по завершении вызова записывает в логгер запроса
+// одну запись с кодом ответа и продолжительностью вызова. Полное имя метода добавляется к логгеру запроса
+// до вызова обработчика.
+func UnaryCallLogger() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+		ctx = withCallLogger(ctx, info.FullMethod)
+
+		resp, err := handler(ctx, req)
+		logCall(ctx, start, err)
+		return resp, err
+	}
+}
+
+// StreamCallLogger является gRPC Stream Interceptor-ом с той же логикой, что и UnaryCallLogger.
+func StreamCallLogger() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+		ctx := withCallLogger(stream.Context(), info.FullMethod)
+
+		err := handler(srv, &loggingStream{ServerStream: stream, ctx: ctx})
+		logCall(ctx, start, err)
+		return err
+	}
+}
+
+// loggingStream подменяет контекст потока контекстом с логгером запроса.
+type loggingStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потока с логгером запроса.
+func (s *loggingStream) Context() context.Context {
+	return s.ctx
+}
+
+// withCallLogger добавляет к логгеру запроса имя метода, при необходимости создавая логгер запроса.
+func withCallLogger(ctx context.Context, method string) context.Context {
+	if _, ok := ctx.Value(ctxKey{}).(*requestLog); !ok {
+		ctx = NewContext(ctx, Log)
+	}
+	With(ctx, zap.String("route", method))
+	return ctx
+}
+
+// logCall записывает итоговую запись gRPC-вызова, начатого в момент start и завершившегося ошибкой err.
+func logCall(ctx context.Context, start time.Time, err error) {
+	fields := []zap.Field{
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	FromContext(ctx).Info("gRPC call", fields...)
+}
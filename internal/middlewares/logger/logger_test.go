@@ -1,13 +1,20 @@
 package logger
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestInitialize(t *testing.T) {
@@ -95,3 +102,99 @@ func TestRequestLoggerLogsCorrectly(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.Equal(t, "OK", rr.Body.String())
 }
+
+func TestFromContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	Log = zap.New(core)
+	defer func() { Log = zap.NewNop() }()
+
+	// Без логгера запроса используется глобальный логгер, а With ничего не делает
+	ctx := context.Background()
+	With(ctx, zap.String("user_id", "user1"))
+	assert.Same(t, Log, FromContext(ctx))
+
+	ctx = NewContext(ctx, Log.With(zap.String("request_id", "req1")))
+	With(ctx, zap.String("user_id", "user1"))
+	FromContext(ctx).Info("message")
+
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]interface{}{"request_id": "req1", "user_id": "user1"}, logs.All()[0].ContextMap())
+}
+
+func TestRequestLoggerFields(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	Log = zap.New(core)
+	defer func() { Log = zap.NewNop() }()
+
+	router := chi.NewRouter()
+	router.Get("/api/user/urls/{id}/history", RequestLogger(func(w http.ResponseWriter, r *http.Request) {
+		With(r.Context(), zap.String("user_id", "user1"))
+		FromContext(r.Context()).Info("handler")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/user/urls/abc/history", nil))
+
+	// Запись обработчика и итоговая запись запроса содержат поля логгера запроса
+	entries := logs.All()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "handler", entries[0].Message)
+	assert.Equal(t, "/api/user/urls/{id}/history", entries[0].ContextMap()["route"])
+
+	fields := entries[1].ContextMap()
+	assert.Equal(t, "HTTP request", entries[1].Message)
+	assert.Equal(t, "/api/user/urls/{id}/history", fields["route"])
+	assert.Equal(t, "user1", fields["user_id"])
+	assert.Equal(t, "/api/user/urls/abc/history", fields["path"])
+	assert.Equal(t, int64(http.StatusNotFound), fields["status"])
+}
+
+func TestUnaryCallLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	Log = zap.New(core)
+	defer func() { Log = zap.NewNop() }()
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/shorturl.ShortenerService/RollbackURL"}
+	_, err := UnaryCallLogger()(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		With(ctx, zap.String("user_id", "user1"))
+		return nil, status.Error(codes.FailedPrecondition, "short url has no history")
+	})
+	assert.Error(t, err)
+
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, "gRPC call", logs.All()[0].Message)
+	assert.Equal(t, info.FullMethod, fields["route"])
+	assert.Equal(t, "user1", fields["user_id"])
+	assert.Equal(t, "FailedPrecondition", fields["code"])
+}
+
+func TestStreamCallLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	Log = zap.New(core)
+	defer func() { Log = zap.NewNop() }()
+
+	info := &grpc.StreamServerInfo{FullMethod: "/shorturl.ShortenerService/ExportUserUrls"}
+	err := StreamCallLogger()(nil, &testStream{ctx: context.Background()}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		FromContext(stream.Context()).Info("streaming")
+		return nil
+	})
+	assert.NoError(t, err)
+
+	require.Equal(t, 2, logs.Len())
+	for _, entry := range logs.All() {
+		assert.Equal(t, info.FullMethod, entry.ContextMap()["route"])
+	}
+	assert.Equal(t, "OK", logs.All()[1].ContextMap()["code"])
+}
+
+// testStream — поток gRPC с заданным контекстом.
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потока.
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,43 @@
+package metricshandler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/dsemenov12/shorturl/internal/metrics"
+)
+
+// unmatchedRoute — метка маршрута для запросов, не совпавших ни с одним маршрутом роутера.
+const unmatchedRoute = "unmatched"
+
+// MetricsHandle является middleware-функцией, которая записывает количество и время обработки HTTP-запросов
+// в метрики metrics.HTTPRequests и metrics.HTTPDuration.
+// Запросы группируются по шаблону маршрута chi, поэтому middleware подключается к роутеру через Use.
+//
+// next: следующий обработчик в цепочке.
+//
+// Возвращаемое значение: обработчик HTTP-запроса, который вызывает next и записывает метрики запроса.
+func MetricsHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		metrics.HTTPDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
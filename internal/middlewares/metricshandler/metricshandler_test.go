@@ -0,0 +1,43 @@
+package metricshandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dsemenov12/shorturl/internal/metrics"
+)
+
+func TestMetricsHandle(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(MetricsHandle)
+	router.Get("/api/stats/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	router.Post("/api/user/urls/{id}/rollback", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "conflict", http.StatusConflict)
+	})
+
+	requests := []struct {
+		method string
+		target string
+	}{
+		{http.MethodGet, "/api/stats/abc"},
+		{http.MethodGet, "/api/stats/def"},
+		{http.MethodPost, "/api/user/urls/abc/rollback"},
+		{http.MethodGet, "/missing"},
+	}
+	for _, r := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(r.method, r.target, nil))
+	}
+
+	// Запросы к разным ссылкам учитываются в одном ряду по шаблону маршрута
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/api/stats/{id}", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodPost, "/api/user/urls/{id}/rollback", "409")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(metrics.HTTPDuration))
+}
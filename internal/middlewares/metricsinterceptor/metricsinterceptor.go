@@ -0,0 +1,50 @@
+package metricsinterceptor
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/dsemenov12/shorturl/internal/metrics"
+)
+
+// MetricsUnaryInterceptor является gRPC Unary Interceptor-ом, который записывает количество и время обработки
+// вызовов в метрики metrics.GRPCRequests и metrics.GRPCDuration по полному имени метода и коду ответа.
+// Чтобы учитывались и отклонённые авторизацией вызовы, интерцептор ставится первым в цепочке.
+func MetricsUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observe(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// MetricsStreamInterceptor является gRPC Stream Interceptor-ом с той же логикой, что и MetricsUnaryInterceptor.
+// Временем обработки потокового вызова считается время до его завершения.
+func MetricsStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		start := time.Now()
+		err := handler(srv, stream)
+		observe(info.FullMethod, start, err)
+		return err
+	}
+}
+
+// observe записывает метрики вызова method, начатого в момент start и завершившегося ошибкой err.
+func observe(method string, start time.Time, err error) {
+	metrics.GRPCRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	metrics.GRPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,44 @@
+package metricsinterceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dsemenov12/shorturl/internal/metrics"
+)
+
+func TestMetricsUnaryInterceptor(t *testing.T) {
+	interceptor := MetricsUnaryInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/shorturl.ShortenerService/GetURL"}
+
+	resp, err := interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "resp", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "resp", resp)
+
+	_, err = interceptor(context.Background(), "req", info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "short url not found")
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(info.FullMethod, "OK")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(info.FullMethod, "NotFound")))
+}
+
+func TestMetricsStreamInterceptor(t *testing.T) {
+	interceptor := MetricsStreamInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/shorturl.ShortenerService/ExportURLs"}
+
+	err := interceptor(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return status.Error(codes.Unauthenticated, "no token")
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(info.FullMethod, "Unauthenticated")))
+}
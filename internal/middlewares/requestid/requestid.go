@@ -0,0 +1,132 @@
+// Package requestid присваивает каждому HTTP-запросу и gRPC-вызову идентификатор запроса.
+//
+// Идентификатор принимается от клиента в заголовке X-Request-ID (metadata x-request-id для gRPC),
+// а если он не передан или некорректен, создаётся новый. Идентификатор возвращается клиенту
+// и добавляется полем request_id к логгеру запроса, который middleware помещает в контекст.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
+)
+
+const (
+	// Header — HTTP-заголовок с идентификатором запроса.
+	Header = "X-Request-ID"
+	// MetadataKey — ключ gRPC metadata с идентификатором запроса.
+	MetadataKey = "x-request-id"
+	// maxLength — максимальная длина принимаемого от клиента идентификатора.
+	maxLength = 128
+)
+
+// ctxKey — ключ контекста, под которым хранится идентификатор запроса.
+type ctxKey struct{}
+
+// FromContext возвращает идентификатор запроса из контекста или пустую строку.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// RequestIDHandle является middleware-функцией, которая присваивает HTTP-запросу идентификатор,
+// возвращает его в заголовке ответа X-Request-ID и помещает в контекст запроса вместе с логгером запроса.
+//
+// next: следующий обработчик в цепочке.
+//
+// Возвращаемое значение: обработчик HTTP-запроса, вызывающий next с идентификатором запроса в контексте.
+func RequestIDHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := accept(r.Header.Get(Header))
+		w.Header().Set(Header, id)
+
+		next.ServeHTTP(w, r.WithContext(newContext(r.Context(), id)))
+	})
+}
+
+// RequestIDUnaryInterceptor является gRPC Unary Interceptor-ом, который присваивает вызову идентификатор запроса,
+// помещает его в контекст вместе с логгером запроса и возвращает клиенту в header и trailing metadata.
+func RequestIDUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		id := accept(incomingID(ctx))
+		md := metadata.Pairs(MetadataKey, id)
+		grpc.SetHeader(ctx, md)
+		grpc.SetTrailer(ctx, md)
+
+		return handler(newContext(ctx, id), req)
+	}
+}
+
+// RequestIDStreamInterceptor является gRPC Stream Interceptor-ом с той же логикой, что и RequestIDUnaryInterceptor.
+func RequestIDStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		id := accept(incomingID(stream.Context()))
+		md := metadata.Pairs(MetadataKey, id)
+		stream.SetHeader(md)
+		stream.SetTrailer(md)
+
+		return handler(srv, &requestIDStream{ServerStream: stream, ctx: newContext(stream.Context(), id)})
+	}
+}
+
+// requestIDStream подменяет контекст потока контекстом с идентификатором запроса.
+type requestIDStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потока с идентификатором запроса.
+func (s *requestIDStream) Context() context.Context {
+	return s.ctx
+}
+
+// newContext возвращает контекст с идентификатором запроса id и логгером запроса с полем request_id.
+func newContext(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, ctxKey{}, id)
+	return logger.NewContext(ctx, logger.Log.With(zap.String("request_id", id)))
+}
+
+// incomingID возвращает идентификатор запроса из входящей metadata gRPC-вызова.
+func incomingID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(MetadataKey); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// accept возвращает идентификатор клиента, если он корректен, иначе создаёт новый.
+// Корректный идентификатор не длиннее maxLength и состоит из букв и цифр ASCII и символов «-_.:».
+func accept(id string) string {
+	if id == "" || len(id) > maxLength {
+		return uuid.NewString()
+	}
+	for _, c := range id {
+		if !isIDChar(c) {
+			return uuid.NewString()
+		}
+	}
+	return id
+}
+
+// isIDChar сообщает, допустим ли символ c в идентификаторе запроса.
+func isIDChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == ':'
+}
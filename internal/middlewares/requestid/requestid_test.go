@@ -0,0 +1,138 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dsemenov12/shorturl/internal/middlewares/logger"
+)
+
+func TestRequestIDHandle(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger.Log = zap.New(core)
+	defer func() { logger.Log = zap.NewNop() }()
+
+	var gotID string
+	handler := RequestIDHandle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = FromContext(r.Context())
+		logger.FromContext(r.Context()).Info("handler")
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		accepted bool
+	}{
+		{name: "client id", incoming: "0f8fad5b-d9cb-469f-a165-70867728950e", accepted: true},
+		{name: "client id with separators", incoming: "web:req_42.1", accepted: true},
+		{name: "no id"},
+		{name: "invalid characters", incoming: "id with spaces\n"},
+		{name: "too long", incoming: strings.Repeat("a", maxLength+1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			logs.TakeAll()
+
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.incoming != "" {
+				request.Header.Set(Header, test.incoming)
+			}
+			response := httptest.NewRecorder()
+			handler.ServeHTTP(response, request)
+
+			id := response.Header().Get(Header)
+			require.NotEmpty(t, id)
+			assert.Equal(t, id, gotID)
+			if test.accepted {
+				assert.Equal(t, test.incoming, id)
+			} else {
+				assert.NotEqual(t, test.incoming, id)
+			}
+
+			require.Equal(t, 1, logs.Len())
+			assert.Equal(t, id, logs.All()[0].ContextMap()["request_id"])
+		})
+	}
+}
+
+func TestRequestIDUnaryInterceptor(t *testing.T) {
+	stream := &transportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataKey, "req-1"))
+
+	var gotID string
+	_, err := RequestIDUnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		gotID = FromContext(ctx)
+		return nil, nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "req-1", gotID)
+	assert.Equal(t, []string{"req-1"}, stream.header.Get(MetadataKey))
+	assert.Equal(t, []string{"req-1"}, stream.trailer.Get(MetadataKey))
+}
+
+func TestRequestIDStreamInterceptor(t *testing.T) {
+	stream := &serverStream{ctx: context.Background()}
+
+	var gotID string
+	err := RequestIDStreamInterceptor()(nil, stream, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+		gotID = FromContext(stream.Context())
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Без идентификатора клиента создаётся новый
+	require.NotEmpty(t, gotID)
+	assert.Equal(t, []string{gotID}, stream.header.Get(MetadataKey))
+	assert.Equal(t, []string{gotID}, stream.trailer.Get(MetadataKey))
+}
+
+// transportStream запоминает metadata, установленную обработчиком unary-вызова.
+type transportStream struct {
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *transportStream) Method() string { return "" }
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// serverStream запоминает metadata, установленную обработчиком потокового вызова.
+type serverStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func (s *serverStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *serverStream) SetTrailer(md metadata.MD) {
+	s.trailer = metadata.Join(s.trailer, md)
+}
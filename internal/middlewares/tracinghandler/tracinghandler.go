@@ -0,0 +1,36 @@
+package tracinghandler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingHandle является middleware-функцией, которая создаёт спан OpenTelemetry для каждого HTTP-запроса.
+// Контекст трассировки извлекается из заголовков traceparent и tracestate запроса, если они переданы.
+// После маршрутизации спан получает имя вида «GET /api/user/urls/{id}» по шаблону маршрута chi,
+// поэтому middleware подключается к роутеру через Use.
+//
+// next: следующий обработчик в цепочке.
+//
+// Возвращаемое значение: обработчик HTTP-запроса, выполняющий next внутри спана запроса.
+func TracingHandle(next http.Handler) http.Handler {
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+	})
+
+	return otelhttp.NewHandler(routed, "http.request",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
@@ -1,8 +1,46 @@
 package models
 
+import "time"
+
 // InputData представляет входные данные с URL для сокращения.
 type InputData struct {
-	URL string `json:"url"`
+	URL       string     `json:"url"`                  // Исходный URL
+	Alias     string     `json:"alias,omitempty"`      // Желаемый короткий ключ (необязательно)
+	TTL       int64      `json:"ttl,omitempty"`        // Время жизни ссылки в секундах (необязательно)
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Момент окончания действия ссылки (необязательно)
+	MaxClicks int64      `json:"max_clicks,omitempty"` // Максимальное количество переходов (необязательно)
+	Password  string     `json:"password,omitempty"`   // Пароль для перехода по ссылке (необязательно)
+	Tags      []string   `json:"tags,omitempty"`       // Метки ссылки (необязательно)
+}
+
+// Link представляет сокращённую ссылку и её параметры в хранилище.
+type Link struct {
+	ShortKey     string    // Короткий ключ
+	OriginalURL  string    // Исходный URL
+	UserID       string    // Идентификатор владельца; пустая строка — владелец неизвестен
+	CreatedAt    time.Time // Момент создания ссылки
+	IsDeleted    bool      // Признак удаления
+	DeletedAt    time.Time // Момент удаления; нулевое значение — ссылка не удалена
+	ExpiresAt    time.Time // Момент окончания действия; нулевое значение — ссылка бессрочная
+	MaxClicks    int64     // Максимальное количество переходов; 0 — без ограничений
+	ClicksLeft   int64     // Оставшееся количество переходов при MaxClicks > 0
+	PasswordHash string    // bcrypt-хеш пароля; пустая строка — ссылка без пароля
+	Tags         []string  // Нормализованные метки ссылки без повторов
+}
+
+// Expired сообщает, истёк ли срок действия ссылки к моменту now.
+func (l Link) Expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
+
+// Protected сообщает, защищена ли ссылка паролем.
+func (l Link) Protected() bool {
+	return l.PasswordHash != ""
+}
+
+// Exhausted сообщает, исчерпан ли лимит переходов по ссылке.
+func (l Link) Exhausted() bool {
+	return l.MaxClicks > 0 && l.ClicksLeft <= 0
 }
 
 // ResultJSON содержит результат операции по сокращению URL.
@@ -16,16 +54,79 @@ type BatchItem struct {
 	OriginalURL   string `json:"original_url"`   // Исходный URL
 }
 
+// BatchStatus — результат обработки элемента пакета.
+type BatchStatus string
+
+// Результаты обработки элемента пакета.
+const (
+	// BatchStatusCreated — создана новая короткая ссылка.
+	BatchStatusCreated BatchStatus = "created"
+	// BatchStatusExists — URL уже сокращён, возвращается существующая ссылка.
+	BatchStatusExists BatchStatus = "exists"
+	// BatchStatusInvalid — элемент не прошёл проверку и не сохранён.
+	BatchStatusInvalid BatchStatus = "invalid"
+)
+
 // BatchResultItem содержит результат пакетной обработки URL.
 type BatchResultItem struct {
-	CorrelationID string `json:"correlation_id"` // Уникальный идентификатор корреляции
-	ShortURL      string `json:"short_url"`      // Сокращенный URL
+	CorrelationID string      `json:"correlation_id"`      // Уникальный идентификатор корреляции
+	ShortURL      string      `json:"short_url,omitempty"` // Сокращенный URL
+	Status        BatchStatus `json:"status,omitempty"`    // Результат обработки элемента
+	Error         string      `json:"error,omitempty"`     // Причина, по которой элемент не сохранён
 }
 
 // ShortURLItem представляет связь между сокращенным и исходным URL.
 type ShortURLItem struct {
-	ShortURL    string `json:"short_url"`    // Сокращенный URL
-	OriginalURL string `json:"original_url"` // Исходный URL
+	ShortURL    string     `json:"short_url"`             // Сокращенный URL
+	OriginalURL string     `json:"original_url"`          // Исходный URL
+	ClicksLeft  *int64     `json:"clicks_left,omitempty"` // Оставшееся количество переходов, если оно ограничено
+	CreatedAt   *time.Time `json:"created_at,omitempty"`  // Момент создания ссылки (в постраничном списке)
+	Clicks      *int64     `json:"clicks,omitempty"`      // Количество переходов (в постраничном списке)
+	IsDeleted   bool       `json:"is_deleted,omitempty"`  // Признак удаления ссылки
+	Tags        []string   `json:"tags,omitempty"`        // Метки ссылки (в постраничном списке)
+}
+
+// UserLink — ссылка пользователя вместе с количеством переходов по ней.
+type UserLink struct {
+	Link   Link  // Ссылка
+	Clicks int64 // Количество переходов по ссылке
+}
+
+// ListSort — поле сортировки списка ссылок пользователя.
+type ListSort string
+
+// Поля сортировки списка ссылок пользователя.
+const (
+	ListSortCreated ListSort = "created" // По времени создания
+	ListSortClicks  ListSort = "clicks"  // По количеству переходов
+)
+
+// DeletedFilter определяет, попадают ли в список ссылок пользователя удалённые ссылки.
+type DeletedFilter string
+
+// Фильтры удалённых ссылок.
+const (
+	DeletedExclude DeletedFilter = "exclude" // Только неудалённые ссылки
+	DeletedOnly    DeletedFilter = "only"    // Только удалённые ссылки
+	DeletedInclude DeletedFilter = "include" // Все ссылки
+)
+
+// ListQuery задаёт параметры постраничного списка ссылок пользователя.
+type ListQuery struct {
+	Limit   int           // Размер страницы; 0 — размер по умолчанию
+	Cursor  string        // Курсор из ShortURLPage.NextCursor; пустая строка — первая страница
+	Sort    ListSort      // Поле сортировки; пустое значение — ListSortCreated
+	Desc    bool          // Сортировка по убыванию
+	Search  string        // Подстрока оригинального URL без учёта регистра
+	Tag     string        // Метка, которая должна быть у ссылки; пустая строка — без фильтра по метке
+	Deleted DeletedFilter // Фильтр удалённых ссылок; пустое значение — DeletedExclude
+}
+
+// ShortURLPage представляет страницу списка ссылок пользователя.
+type ShortURLPage struct {
+	Items      []ShortURLItem // Ссылки страницы
+	Total      int            // Количество ссылок на всех страницах с учётом фильтров
+	NextCursor string         // Курсор следующей страницы; пустая строка — страница последняя
 }
 
 // StatsResponse представляет JSON-ответ для эндпоинта /api/internal/stats.
@@ -33,3 +134,31 @@ type StatsResponse struct {
 	URLs  int `json:"urls"`
 	Users int `json:"users"`
 }
+
+// ClickEvent описывает один переход по короткой ссылке.
+type ClickEvent struct {
+	ShortKey  string    // Короткий ключ ссылки
+	Timestamp time.Time // Время перехода
+	Referrer  string    // Значение заголовка Referer
+	UserAgent string    // Значение заголовка User-Agent
+}
+
+// StatsBucket содержит количество переходов за один интервал временного ряда.
+type StatsBucket struct {
+	Time   time.Time `json:"time"`   // Начало интервала
+	Clicks int64     `json:"clicks"` // Количество переходов за интервал
+}
+
+// LinkStats представляет JSON-ответ для эндпоинта /api/stats/{id}.
+type LinkStats struct {
+	ShortURL    string        `json:"short_url"`    // Сокращенный URL
+	OriginalURL string        `json:"original_url"` // Исходный URL
+	Clicks      int64         `json:"clicks"`       // Общее количество переходов
+	Series      []StatsBucket `json:"series"`       // Переходы, сгруппированные по интервалам
+}
+
+// HistoryItem представляет предыдущее значение оригинального URL короткой ссылки.
+type HistoryItem struct {
+	OriginalURL string    `json:"original_url"` // Исходный URL до изменения
+	ChangedAt   time.Time `json:"changed_at"`   // Время изменения
+}
@@ -0,0 +1,294 @@
+// Package cache реализует кеширующую обёртку над любой реализацией storage.Storage.
+//
+// StorageCache хранит результаты Get в ограниченном LRU-кеше с временем жизни записей,
+// в том числе отрицательные результаты для несуществующих ключей. Одновременные промахи
+// по одному ключу объединяются в один запрос к хранилищу. Записи сбрасываются при любом
+// изменении ссылки через эту обёртку; изменения, сделанные другими экземплярами сервиса,
+// становятся видны не позже окончания времени жизни записи.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+)
+
+// Options задаёт параметры кеша.
+type Options struct {
+	Size        int           // Максимальное количество записей
+	TTL         time.Duration // Время жизни найденной ссылки
+	NegativeTTL time.Duration // Время жизни записи о несуществующем ключе; 0 — не кешировать
+}
+
+// Stats — счётчики обращений к кешу.
+type Stats struct {
+	Hits   uint64 // Количество запросов, обслуженных из кеша
+	Misses uint64 // Количество запросов, переданных хранилищу
+	Size   int    // Текущее количество записей
+}
+
+// entry — запись кеша: ссылка или ошибка ErrNotFound для ключа key.
+type entry struct {
+	key       string
+	link      models.Link
+	err       error
+	expiresAt time.Time
+}
+
+// StorageCache кеширует чтение ссылок по короткому ключу.
+// Остальные методы выполняются встроенным хранилищем, а изменяющие ссылки методы
+// дополнительно сбрасывают соответствующие записи кеша.
+type StorageCache struct {
+	storage.Storage
+
+	opts  Options
+	now   func() time.Time
+	group singleflight.Group
+
+	mx      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Записи от недавно использованных к давно использованным
+	// gen увеличивается при каждом сбросе записей, чтобы не сохранять результаты чтений,
+	// начатых до изменения ссылки
+	gen uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewStorage создаёт кеш размером opts.Size записей поверх хранилища s.
+func NewStorage(s storage.Storage, opts Options) *StorageCache {
+	return &StorageCache{
+		Storage: s,
+		opts:    opts,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Stats возвращает текущие значения счётчиков кеша.
+func (s *StorageCache) Stats() Stats {
+	s.mx.Lock()
+	size := s.order.Len()
+	s.mx.Unlock()
+
+	return Stats{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+		Size:   size,
+	}
+}
+
+// Get возвращает ссылку из кеша, а при промахе читает её из хранилища и сохраняет в кеш.
+// Одновременные промахи по одному ключу выполняются одним запросом к хранилищу;
+// отмена контекста прерывает ожидание, но не общий запрос.
+func (s *StorageCache) Get(ctx context.Context, shortKey string) (models.Link, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Link{}, err
+	}
+
+	if e, ok := s.lookup(shortKey); ok {
+		s.hits.Add(1)
+		return e.link, e.err
+	}
+	s.misses.Add(1)
+
+	ch := s.group.DoChan(shortKey, func() (any, error) {
+		gen := s.generation()
+		link, err := s.Storage.Get(context.WithoutCancel(ctx), shortKey)
+		if err == nil || errors.Is(err, storage.ErrNotFound) {
+			s.store(gen, shortKey, link, err)
+		}
+		return link, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return models.Link{}, ctx.Err()
+	case result := <-ch:
+		link, _ := result.Val.(models.Link)
+		return link, result.Err
+	}
+}
+
+// Set сохраняет ссылку и сбрасывает запись о её ключе, в том числе отрицательную.
+func (s *StorageCache) Set(ctx context.Context, link models.Link) (string, error) {
+	defer s.invalidate(link.ShortKey)
+	return s.Storage.Set(ctx, link)
+}
+
+// SetBatch сохраняет пакет ссылок и сбрасывает записи об их ключах.
+func (s *StorageCache) SetBatch(ctx context.Context, links []models.Link, atomic bool) ([]storage.BatchResult, error) {
+	defer s.invalidate(linkKeys(links)...)
+	return s.Storage.SetBatch(ctx, links, atomic)
+}
+
+// Import сохраняет пакет ссылок и сбрасывает записи об их ключах.
+func (s *StorageCache) Import(ctx context.Context, links []models.Link) ([]error, error) {
+	defer s.invalidate(linkKeys(links)...)
+	return s.Storage.Import(ctx, links)
+}
+
+// Delete помечает ссылку удалённой и сбрасывает её запись.
+func (s *StorageCache) Delete(ctx context.Context, shortKey string) error {
+	defer s.invalidate(shortKey)
+	return s.Storage.Delete(ctx, shortKey)
+}
+
+// DeleteBatch помечает ссылки удалёнными и сбрасывает их записи.
+func (s *StorageCache) DeleteBatch(ctx context.Context, shortKeys []string) error {
+	defer s.invalidate(shortKeys...)
+	return s.Storage.DeleteBatch(ctx, shortKeys)
+}
+
+// Update заменяет URL ссылки и сбрасывает её запись.
+func (s *StorageCache) Update(ctx context.Context, shortKey string, url string) error {
+	defer s.invalidate(shortKey)
+	return s.Storage.Update(ctx, shortKey, url)
+}
+
+// Rollback откатывает URL ссылки к предыдущему значению и сбрасывает её запись.
+func (s *StorageCache) Rollback(ctx context.Context, shortKey string) (string, error) {
+	defer s.invalidate(shortKey)
+	return s.Storage.Rollback(ctx, shortKey)
+}
+
+// Restore снимает пометку удаления со ссылки и сбрасывает её запись.
+func (s *StorageCache) Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (string, error) {
+	defer s.invalidate(shortKey)
+	return s.Storage.Restore(ctx, shortKey, deletedAfter)
+}
+
+// Consume уменьшает остаток переходов по ссылке и сбрасывает её запись.
+func (s *StorageCache) Consume(ctx context.Context, shortKey string) error {
+	defer s.invalidate(shortKey)
+	return s.Storage.Consume(ctx, shortKey)
+}
+
+// DeleteExpired удаляет ссылки с истёкшим сроком действия и, если такие нашлись, очищает кеш.
+func (s *StorageCache) DeleteExpired(ctx context.Context, now time.Time, purge bool) (int64, error) {
+	count, err := s.Storage.DeleteExpired(ctx, now, purge)
+	if count > 0 {
+		s.clear()
+	}
+	return count, err
+}
+
+// PurgeDeleted окончательно удаляет ссылки и, если такие нашлись, очищает кеш.
+func (s *StorageCache) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	count, err := s.Storage.PurgeDeleted(ctx, before)
+	if count > 0 {
+		s.clear()
+	}
+	return count, err
+}
+
+// lookup возвращает действующую запись кеша о ключе и отмечает её как недавно использованную.
+// Записи не изменяются после создания, поэтому их можно читать без блокировки.
+func (s *StorageCache) lookup(shortKey string) (*entry, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	elem, ok := s.entries[shortKey]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if !s.now().Before(e.expiresAt) {
+		s.remove(elem)
+		return nil, false
+	}
+
+	s.order.MoveToFront(elem)
+	return e, true
+}
+
+// generation возвращает номер текущего поколения записей кеша.
+func (s *StorageCache) generation() uint64 {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.gen
+}
+
+// store сохраняет результат чтения, начатого в поколении gen, вытесняя давно использованные записи.
+// Если с начала чтения записи сбрасывались, результат не сохраняется.
+func (s *StorageCache) store(gen uint64, shortKey string, link models.Link, err error) {
+	ttl := s.opts.TTL
+	if err != nil {
+		ttl = s.opts.NegativeTTL
+	}
+	if ttl <= 0 || s.opts.Size <= 0 {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if gen != s.gen {
+		return
+	}
+
+	e := &entry{key: shortKey, link: link, err: err, expiresAt: s.now().Add(ttl)}
+	if elem, ok := s.entries[shortKey]; ok {
+		elem.Value = e
+		s.order.MoveToFront(elem)
+		return
+	}
+
+	s.entries[shortKey] = s.order.PushFront(e)
+	for s.order.Len() > s.opts.Size {
+		s.remove(s.order.Back())
+	}
+}
+
+// invalidate сбрасывает записи о ключах shortKeys.
+func (s *StorageCache) invalidate(shortKeys ...string) {
+	s.mx.Lock()
+	s.gen++
+	for _, shortKey := range shortKeys {
+		if elem, ok := s.entries[shortKey]; ok {
+			s.remove(elem)
+		}
+	}
+	s.mx.Unlock()
+
+	// Последующие чтения не должны присоединяться к запросам, начатым до изменения
+	for _, shortKey := range shortKeys {
+		s.group.Forget(shortKey)
+	}
+}
+
+// clear сбрасывает все записи кеша.
+func (s *StorageCache) clear() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.gen++
+	s.entries = make(map[string]*list.Element)
+	s.order.Init()
+}
+
+// remove удаляет запись из кеша. Вызывается под блокировкой.
+func (s *StorageCache) remove(elem *list.Element) {
+	s.order.Remove(elem)
+	delete(s.entries, elem.Value.(*entry).key)
+}
+
+// linkKeys возвращает короткие ключи ссылок.
+func linkKeys(links []models.Link) []string {
+	keys := make([]string, 0, len(links))
+	for _, link := range links {
+		keys = append(keys, link.ShortKey)
+	}
+	return keys
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/dsemenov12/shorturl/internal/storage/mocks"
+	"github.com/dsemenov12/shorturl/internal/storage/storagetest"
+)
+
+var testOptions = Options{Size: 100, TTL: time.Minute, NegativeTTL: time.Minute}
+
+func TestStorageCache_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return NewStorage(memory.NewStorage(), testOptions)
+	})
+}
+
+func TestStorageCache_Get(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+	s := NewStorage(m, testOptions)
+	ctx := context.Background()
+
+	link := models.Link{ShortKey: "short1", OriginalURL: "http://example.com"}
+	m.EXPECT().Get(gomock.Any(), "short1").Return(link, nil).Times(1)
+	m.EXPECT().Get(gomock.Any(), "missing").Return(models.Link{}, storage.ErrNotFound).Times(1)
+	m.EXPECT().Get(gomock.Any(), "broken").Return(models.Link{}, errors.New("connection reset")).Times(2)
+
+	for i := 0; i < 2; i++ {
+		got, err := s.Get(ctx, "short1")
+		require.NoError(t, err)
+		assert.Equal(t, link, got)
+
+		_, err = s.Get(ctx, "missing")
+		assert.ErrorIs(t, err, storage.ErrNotFound, "Unknown keys should be cached")
+
+		_, err = s.Get(ctx, "broken")
+		assert.Error(t, err, "Storage errors should not be cached")
+	}
+
+	assert.Equal(t, Stats{Hits: 2, Misses: 4, Size: 2}, s.Stats())
+}
+
+func TestStorageCache_TTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+	s := NewStorage(m, Options{Size: 10, TTL: time.Minute, NegativeTTL: time.Second})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	m.EXPECT().Get(gomock.Any(), "short1").Return(models.Link{ShortKey: "short1"}, nil).Times(2)
+	m.EXPECT().Get(gomock.Any(), "missing").Return(models.Link{}, storage.ErrNotFound).Times(2)
+
+	for _, shortKey := range []string{"short1", "missing", "short1", "missing"} {
+		s.Get(ctx, shortKey)
+	}
+	assert.Equal(t, uint64(2), s.Stats().Hits)
+
+	// Отрицательная запись устаревает раньше
+	now = now.Add(2 * time.Second)
+	s.Get(ctx, "short1")
+	s.Get(ctx, "missing")
+
+	now = now.Add(time.Minute)
+	s.Get(ctx, "short1")
+	assert.Equal(t, Stats{Hits: 3, Misses: 4, Size: 2}, s.Stats())
+}
+
+func TestStorageCache_Eviction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+	s := NewStorage(m, Options{Size: 2, TTL: time.Minute})
+	ctx := context.Background()
+
+	m.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, shortKey string) (models.Link, error) {
+		return models.Link{ShortKey: shortKey}, nil
+	}).Times(4)
+
+	// b вытесняется как давно использованная запись
+	for _, shortKey := range []string{"a", "b", "a", "c", "a", "b"} {
+		link, err := s.Get(ctx, shortKey)
+		require.NoError(t, err)
+		assert.Equal(t, shortKey, link.ShortKey)
+	}
+	assert.Equal(t, Stats{Hits: 2, Misses: 4, Size: 2}, s.Stats())
+}
+
+func TestStorageCache_Invalidate(t *testing.T) {
+	s := NewStorage(memory.NewStorage(), testOptions)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	_, err := s.Get(ctx, "short1")
+	require.ErrorIs(t, err, storage.ErrNotFound)
+
+	_, err = s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+	_, err = s.Get(ctx, "short1")
+	require.NoError(t, err, "Set should drop the negative entry")
+
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/2"))
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/2", link.OriginalURL)
+
+	require.NoError(t, s.DeleteBatch(ctx, []string{"short1"}))
+	link, err = s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.True(t, link.IsDeleted)
+
+	count, err := s.PurgeDeleted(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	_, err = s.Get(ctx, "short1")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestStorageCache_Singleflight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+	s := NewStorage(m, testOptions)
+
+	release := make(chan struct{})
+	m.EXPECT().Get(gomock.Any(), "short1").DoAndReturn(func(context.Context, string) (models.Link, error) {
+		<-release
+		return models.Link{ShortKey: "short1"}, nil
+	}).Times(1)
+
+	const readers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			link, err := s.Get(context.Background(), "short1")
+			assert.NoError(t, err)
+			assert.Equal(t, "short1", link.ShortKey)
+		}()
+	}
+
+	// Отменённый запрос перестаёт ждать, не прерывая общий запрос
+	ctx, cancel := context.WithCancel(context.Background())
+	go cancel()
+	_, err := s.Get(ctx, "short1")
+	assert.ErrorIs(t, err, context.Canceled)
+
+	require.Eventually(t, func() bool { return s.Stats().Misses >= readers }, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestStorageCache_StaleRead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+	s := NewStorage(m, testOptions)
+	ctx := context.Background()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	gomock.InOrder(
+		m.EXPECT().Get(gomock.Any(), "short1").DoAndReturn(func(context.Context, string) (models.Link, error) {
+			close(started)
+			<-release
+			return models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"}, nil
+		}),
+		m.EXPECT().Get(gomock.Any(), "short1").Return(models.Link{ShortKey: "short1", OriginalURL: "http://example.com/2"}, nil),
+	)
+	m.EXPECT().Update(gomock.Any(), "short1", "http://example.com/2").Return(nil)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Get(ctx, "short1")
+	}()
+	<-started
+
+	// Чтение, начатое до изменения, не должно попасть в кеш
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/2"))
+	close(release)
+	<-done
+
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/2", link.OriginalURL)
+}
@@ -0,0 +1,276 @@
+// Package file реализует storage.Storage в памяти с сохранением изменений в журнал на диске.
+//
+// Ссылки хранятся в memory.StorageMemory, а каждое изменение дописывается в журнал
+// filestorage.Journal и воспроизводится из него при запуске, включая остаток переходов по ссылкам
+// с ограничением. История изменений URL переносится в снимок при сжатии журнала и сохраняется
+// после перезапуска; статистика переходов в файл не сохраняется.
+package file
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/filestorage"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+)
+
+// StorageFile представляет собой хранилище в памяти, сохраняющее изменения в журнал на диске.
+// Чтение выполняется встроенным memory.StorageMemory, изменения дополнительно записываются в журнал.
+type StorageFile struct {
+	*memory.StorageMemory
+
+	// mx упорядочивает изменения, чтобы порядок записей в журнале совпадал с порядком изменений в памяти
+	mx      sync.Mutex
+	journal *filestorage.Journal
+}
+
+// NewStorage создает хранилище, сохраняющее изменения в журнал journal.
+// Журнал закрывается методом Close.
+func NewStorage(journal *filestorage.Journal) *StorageFile {
+	return &StorageFile{
+		StorageMemory: memory.NewStorage(),
+		journal:       journal,
+	}
+}
+
+// Bootstrap загружает в память ссылки из журнала.
+func (s *StorageFile) Bootstrap(ctx context.Context) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return filestorage.Load(s.journal, s.StorageMemory)
+}
+
+// Set сохраняет ссылку в памяти и дописывает запись о её создании в журнал.
+func (s *StorageFile) Set(ctx context.Context, link models.Link) (string, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	shortKey, err := s.StorageMemory.Set(ctx, link)
+	if err != nil {
+		return shortKey, err
+	}
+
+	return shortKey, s.appendLink(ctx, filestorage.OpSet, shortKey)
+}
+
+// SetBatch сохраняет пакет ссылок в памяти и дописывает записи о создании сохранённых ссылок
+// в журнал одной операцией записи. Отменённый пакет в журнал не записывается.
+func (s *StorageFile) SetBatch(ctx context.Context, links []models.Link, atomic bool) ([]storage.BatchResult, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	results, err := s.StorageMemory.SetBatch(ctx, links, atomic)
+	if err != nil {
+		return results, err
+	}
+
+	saved := make([]models.Link, 0, len(links))
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+		link, err := s.StorageMemory.Get(context.WithoutCancel(ctx), result.ShortKey)
+		if err != nil {
+			return nil, err
+		}
+		saved = append(saved, link)
+	}
+
+	return results, s.journal.Append(filestorage.OpSet, saved...)
+}
+
+// Import сохраняет пакет ссылок в памяти и дописывает записи о создании сохранённых ссылок
+// в журнал одной операцией записи.
+func (s *StorageFile) Import(ctx context.Context, links []models.Link) ([]error, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	errs, err := s.StorageMemory.Import(ctx, links)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := make([]models.Link, 0, len(links))
+	for i, link := range links {
+		if errs[i] != nil {
+			continue
+		}
+		if link, err = s.StorageMemory.Get(context.WithoutCancel(ctx), link.ShortKey); err != nil {
+			return nil, err
+		}
+		saved = append(saved, link)
+	}
+
+	return errs, s.journal.Append(filestorage.OpSet, saved...)
+}
+
+// Delete помечает ссылку текущего пользователя как удалённую и дописывает запись об удалении в журнал.
+// Если ссылка не изменилась, журнал не изменяется.
+func (s *StorageFile) Delete(ctx context.Context, shortKey string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	before, err := s.StorageMemory.Get(ctx, shortKey)
+	if err != nil || before.IsDeleted {
+		return s.StorageMemory.Delete(ctx, shortKey)
+	}
+	if err = s.StorageMemory.Delete(ctx, shortKey); err != nil {
+		return err
+	}
+
+	after, err := s.StorageMemory.Get(context.WithoutCancel(ctx), shortKey)
+	if err != nil || !after.IsDeleted {
+		return err
+	}
+
+	return s.journal.Append(filestorage.OpDelete, after)
+}
+
+// DeleteBatch помечает ссылки как удалённые и дописывает в журнал записи об удалении изменённых ссылок.
+func (s *StorageFile) DeleteBatch(ctx context.Context, shortKeys []string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var pending []string
+	for _, shortKey := range shortKeys {
+		if before, err := s.StorageMemory.Get(ctx, shortKey); err == nil && !before.IsDeleted {
+			pending = append(pending, shortKey)
+		}
+	}
+	if err := s.StorageMemory.DeleteBatch(ctx, shortKeys); err != nil {
+		return err
+	}
+
+	for _, shortKey := range pending {
+		after, err := s.StorageMemory.Get(context.WithoutCancel(ctx), shortKey)
+		if err != nil || !after.IsDeleted {
+			continue
+		}
+		if err = s.journal.Append(filestorage.OpDelete, after); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Update заменяет оригинальный URL ссылки и дописывает запись об изменении в журнал.
+func (s *StorageFile) Update(ctx context.Context, shortKey string, url string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	before, err := s.StorageMemory.Get(ctx, shortKey)
+	if err != nil {
+		return s.StorageMemory.Update(ctx, shortKey, url)
+	}
+	if err = s.StorageMemory.Update(ctx, shortKey, url); err != nil || before.OriginalURL == url {
+		return err
+	}
+
+	return s.appendLink(ctx, filestorage.OpUpdate, shortKey)
+}
+
+// Rollback восстанавливает предыдущее значение URL ссылки и дописывает запись об откате в журнал.
+func (s *StorageFile) Rollback(ctx context.Context, shortKey string) (string, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	url, err := s.StorageMemory.Rollback(ctx, shortKey)
+	if err != nil {
+		return "", err
+	}
+
+	return url, s.appendLink(ctx, filestorage.OpRollback, shortKey)
+}
+
+// Consume уменьшает остаток переходов по ссылке и дописывает в журнал запись с новым остатком,
+// чтобы исчерпанный лимит переходов сохранялся после перезапуска.
+func (s *StorageFile) Consume(ctx context.Context, shortKey string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err := s.StorageMemory.Consume(ctx, shortKey); err != nil {
+		return err
+	}
+
+	link, err := s.StorageMemory.Get(context.WithoutCancel(ctx), shortKey)
+	if err != nil || link.MaxClicks == 0 {
+		return err
+	}
+
+	return s.journal.Append(filestorage.OpConsume, link)
+}
+
+// DeleteExpired удаляет ссылки с истёкшим сроком действия и заменяет журнал снимком оставшихся ссылок.
+func (s *StorageFile) DeleteExpired(ctx context.Context, now time.Time, purge bool) (int64, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	count, err := s.StorageMemory.DeleteExpired(ctx, now, purge)
+	if err != nil || count == 0 {
+		return count, err
+	}
+
+	return count, s.compact()
+}
+
+// Restore восстанавливает удалённую ссылку и дописывает запись о восстановлении в журнал.
+func (s *StorageFile) Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (string, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	url, err := s.StorageMemory.Restore(ctx, shortKey, deletedAfter)
+	if err != nil {
+		return "", err
+	}
+
+	return url, s.appendLink(ctx, filestorage.OpRestore, shortKey)
+}
+
+// PurgeDeleted удаляет давно удалённые ссылки и заменяет журнал снимком оставшихся ссылок.
+func (s *StorageFile) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	count, err := s.StorageMemory.PurgeDeleted(ctx, before)
+	if err != nil || count == 0 {
+		return count, err
+	}
+
+	return count, s.compact()
+}
+
+// Compact заменяет журнал снимком текущего состояния, оставляя по одной записи на ссылку
+// вместе с историей изменений её URL.
+func (s *StorageFile) Compact() error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.compact()
+}
+
+// compact заменяет журнал снимком ссылок вместе с историей изменений их URL.
+// Вызывается под блокировкой хранилища.
+func (s *StorageFile) compact() error {
+	return s.journal.Compact(s.StorageMemory.Histories(), s.StorageMemory.Links()...)
+}
+
+// Close сбрасывает журнал на диск и закрывает его.
+func (s *StorageFile) Close() error {
+	return s.journal.Close()
+}
+
+// appendLink дописывает в журнал запись типа op с текущим состоянием ссылки.
+// Вызывается под блокировкой хранилища после изменения ссылки, поэтому отмена ctx не учитывается.
+func (s *StorageFile) appendLink(ctx context.Context, op filestorage.Op, shortKey string) error {
+	link, err := s.StorageMemory.Get(context.WithoutCancel(ctx), shortKey)
+	if err != nil {
+		return err
+	}
+
+	return s.journal.Append(op, link)
+}
@@ -0,0 +1,185 @@
+package file
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/filestorage"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/storagetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T, path string) *StorageFile {
+	journal, err := filestorage.Open(path, filestorage.Options{})
+	require.NoError(t, err)
+
+	s := NewStorage(journal)
+	t.Cleanup(func() {
+		s.Close()
+	})
+	require.NoError(t, s.Bootstrap(context.Background()))
+
+	return s
+}
+
+func TestStorageFile_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestStorage(t, filepath.Join(t.TempDir(), "storage.json"))
+	})
+}
+
+func TestStorageFile_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	s := newTestStorage(t, path)
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2", Tags: []string{"promo"}})
+	require.NoError(t, err)
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/3"))
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/4"))
+	_, err = s.Rollback(ctx, "short1")
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, "short2"))
+	require.NoError(t, s.Close())
+
+	reloaded := newTestStorage(t, path)
+
+	link, err := reloaded.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/3", link.OriginalURL, "Updates and rollbacks should survive a restart")
+	assert.Equal(t, "user1", link.UserID, "Owner should survive a restart")
+	history, err := reloaded.GetHistory(ctx, "short1")
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	link, err = reloaded.Get(ctx, "short2")
+	require.NoError(t, err)
+	assert.True(t, link.IsDeleted, "Deletion should survive a restart")
+	assert.Equal(t, []string{"promo"}, link.Tags, "Tags should survive a restart")
+
+	result, err := reloaded.GetUserURL(ctx)
+	require.NoError(t, err)
+	assert.Len(t, result, 1)
+}
+
+func TestStorageFile_IgnoredChangesNotJournaled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	otherCtx := context.WithValue(context.Background(), auth.UserIDKey, "user2")
+
+	s := newTestStorage(t, path)
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+	_, err = s.Set(otherCtx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/1"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	require.NoError(t, s.Delete(otherCtx, "short1"))
+	assert.ErrorIs(t, s.Update(otherCtx, "short1", "http://example.com/2"), storage.ErrForbidden)
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/1"))
+
+	records, err := s.journal.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 1, "Only the successful creation should be journaled")
+}
+
+func TestStorageFile_Compact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	now := time.Now()
+
+	s := newTestStorage(t, path)
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "expired", OriginalURL: "http://example.com/2", ExpiresAt: now.Add(time.Minute)})
+	require.NoError(t, err)
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/3"))
+
+	count, err := s.DeleteExpired(ctx, now.Add(time.Hour), true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	records, err := s.journal.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1, "Purge should compact the journal")
+	assert.Equal(t, "http://example.com/3", records[0].OriginalURL)
+
+	_, err = s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/4"})
+	require.NoError(t, err)
+	require.NoError(t, s.Compact())
+
+	records, err = s.journal.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+}
+
+func TestStorageFile_CompactKeepsHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	s := newTestStorage(t, path)
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/2"))
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/3"))
+	history, err := s.GetHistory(ctx, "short1")
+	require.NoError(t, err)
+	require.NoError(t, s.Compact())
+	require.NoError(t, s.Close())
+
+	reloaded := newTestStorage(t, path)
+
+	restored, err := reloaded.GetHistory(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, history, restored, "History should survive compaction and a restart")
+
+	url, err := reloaded.Rollback(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/2", url)
+	url, err = reloaded.Rollback(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/1", url)
+	_, err = reloaded.Rollback(ctx, "short1")
+	assert.ErrorIs(t, err, storage.ErrNoHistory)
+}
+
+func TestStorageFile_ClicksLeft(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	s := newTestStorage(t, path)
+	_, err := s.Set(ctx, models.Link{ShortKey: "once", OriginalURL: "http://example.com/1", MaxClicks: 1})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "twice", OriginalURL: "http://example.com/2", MaxClicks: 2})
+	require.NoError(t, err)
+	require.NoError(t, s.Consume(ctx, "once"))
+	require.NoError(t, s.Consume(ctx, "twice"))
+	require.NoError(t, s.Close())
+
+	// Исчерпанный лимит переходов сохраняется после перезапуска
+	reloaded := newTestStorage(t, path)
+	assert.ErrorIs(t, reloaded.Consume(ctx, "once"), storage.ErrExhausted)
+	link, err := reloaded.Get(ctx, "twice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), link.ClicksLeft)
+
+	// и после сжатия журнала
+	require.NoError(t, reloaded.Consume(ctx, "twice"))
+	require.NoError(t, reloaded.Compact())
+	require.NoError(t, reloaded.Close())
+
+	compacted := newTestStorage(t, path)
+	assert.ErrorIs(t, compacted.Consume(ctx, "once"), storage.ErrExhausted)
+	assert.ErrorIs(t, compacted.Consume(ctx, "twice"), storage.ErrExhausted)
+
+	records, err := compacted.journal.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, records, 2, "Compaction should keep one record per link")
+}
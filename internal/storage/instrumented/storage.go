@@ -0,0 +1,205 @@
+// Package instrumented реализует обёртку над любой реализацией storage.Storage,
+// измеряющую время выполнения операций хранилища.
+//
+// Длительность каждого вызова записывается в гистограмму metrics.StorageDuration с именем метода
+// и результатом: ok, rejected для ошибок предметной области (ErrNotFound, ErrConflict и т. п.)
+// или error для остальных ошибок.
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/metrics"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+)
+
+// Результаты операций хранилища.
+const (
+	resultOK       = "ok"
+	resultRejected = "rejected"
+	resultError    = "error"
+)
+
+// rejections — ошибки хранилища, которые означают отказ в операции, а не сбой.
+var rejections = []error{
+	storage.ErrNotFound,
+	storage.ErrForbidden,
+	storage.ErrDeleted,
+	storage.ErrConflict,
+	storage.ErrKeyExists,
+	storage.ErrNoHistory,
+	storage.ErrExhausted,
+	storage.ErrNotDeleted,
+	storage.ErrRestoreExpired,
+	storage.ErrInvalidQuery,
+}
+
+// StorageInstrumented измеряет время выполнения методов встроенного хранилища.
+type StorageInstrumented struct {
+	storage.Storage
+}
+
+// NewStorage создаёт обёртку над хранилищем s, записывающую метрики его операций.
+func NewStorage(s storage.Storage) *StorageInstrumented {
+	return &StorageInstrumented{Storage: s}
+}
+
+// Bootstrap инициализирует хранилище.
+func (s *StorageInstrumented) Bootstrap(ctx context.Context) (err error) {
+	defer observe("Bootstrap", time.Now(), &err)
+	return s.Storage.Bootstrap(ctx)
+}
+
+// Set сохраняет ссылку.
+func (s *StorageInstrumented) Set(ctx context.Context, link models.Link) (_ string, err error) {
+	defer observe("Set", time.Now(), &err)
+	return s.Storage.Set(ctx, link)
+}
+
+// SetBatch сохраняет пакет ссылок.
+func (s *StorageInstrumented) SetBatch(ctx context.Context, links []models.Link, atomic bool) (_ []storage.BatchResult, err error) {
+	defer observe("SetBatch", time.Now(), &err)
+	return s.Storage.SetBatch(ctx, links, atomic)
+}
+
+// Import сохраняет пакет импортируемых ссылок.
+func (s *StorageInstrumented) Import(ctx context.Context, links []models.Link) (_ []error, err error) {
+	defer observe("Import", time.Now(), &err)
+	return s.Storage.Import(ctx, links)
+}
+
+// Get получает ссылку по короткому ключу.
+func (s *StorageInstrumented) Get(ctx context.Context, shortKey string) (_ models.Link, err error) {
+	defer observe("Get", time.Now(), &err)
+	return s.Storage.Get(ctx, shortKey)
+}
+
+// GetUserURL возвращает URL текущего пользователя.
+func (s *StorageInstrumented) GetUserURL(ctx context.Context) (_ []models.ShortURLItem, err error) {
+	defer observe("GetUserURL", time.Now(), &err)
+	return s.Storage.GetUserURL(ctx)
+}
+
+// ListUserURLs возвращает страницу ссылок текущего пользователя.
+func (s *StorageInstrumented) ListUserURLs(ctx context.Context, query models.ListQuery) (_ models.ShortURLPage, err error) {
+	defer observe("ListUserURLs", time.Now(), &err)
+	return s.Storage.ListUserURLs(ctx, query)
+}
+
+// UserLinks возвращает итератор по ссылкам текущего пользователя.
+// Измеряется время от начала до окончания итерации.
+func (s *StorageInstrumented) UserLinks(ctx context.Context) storage.UserLinkSeq {
+	links := s.Storage.UserLinks(ctx)
+	return func(yield func(models.UserLink, error) bool) {
+		var err error
+		defer observe("UserLinks", time.Now(), &err)
+
+		for link, linkErr := range links {
+			if linkErr != nil {
+				err = linkErr
+			}
+			if !yield(link, linkErr) {
+				return
+			}
+		}
+	}
+}
+
+// Delete помечает ссылку удалённой.
+func (s *StorageInstrumented) Delete(ctx context.Context, shortKey string) (err error) {
+	defer observe("Delete", time.Now(), &err)
+	return s.Storage.Delete(ctx, shortKey)
+}
+
+// DeleteBatch помечает ссылки удалёнными.
+func (s *StorageInstrumented) DeleteBatch(ctx context.Context, shortKeys []string) (err error) {
+	defer observe("DeleteBatch", time.Now(), &err)
+	return s.Storage.DeleteBatch(ctx, shortKeys)
+}
+
+// CountURLs возвращает количество неудалённых ссылок.
+func (s *StorageInstrumented) CountURLs(ctx context.Context) (_ int, err error) {
+	defer observe("CountURLs", time.Now(), &err)
+	return s.Storage.CountURLs(ctx)
+}
+
+// CountUsers возвращает количество владельцев ссылок.
+func (s *StorageInstrumented) CountUsers(ctx context.Context) (_ int, err error) {
+	defer observe("CountUsers", time.Now(), &err)
+	return s.Storage.CountUsers(ctx)
+}
+
+// SaveClicks сохраняет пакет событий перехода.
+func (s *StorageInstrumented) SaveClicks(ctx context.Context, events []models.ClickEvent) (err error) {
+	defer observe("SaveClicks", time.Now(), &err)
+	return s.Storage.SaveClicks(ctx, events)
+}
+
+// GetStats возвращает статистику переходов по ссылке.
+func (s *StorageInstrumented) GetStats(ctx context.Context, shortKey string, from, to time.Time, step time.Duration) (_ int64, _ []models.StatsBucket, err error) {
+	defer observe("GetStats", time.Now(), &err)
+	return s.Storage.GetStats(ctx, shortKey, from, to, step)
+}
+
+// Update заменяет URL ссылки.
+func (s *StorageInstrumented) Update(ctx context.Context, shortKey string, url string) (err error) {
+	defer observe("Update", time.Now(), &err)
+	return s.Storage.Update(ctx, shortKey, url)
+}
+
+// GetHistory возвращает историю изменений ссылки.
+func (s *StorageInstrumented) GetHistory(ctx context.Context, shortKey string) (_ []models.HistoryItem, err error) {
+	defer observe("GetHistory", time.Now(), &err)
+	return s.Storage.GetHistory(ctx, shortKey)
+}
+
+// Rollback откатывает URL ссылки к предыдущему значению.
+func (s *StorageInstrumented) Rollback(ctx context.Context, shortKey string) (_ string, err error) {
+	defer observe("Rollback", time.Now(), &err)
+	return s.Storage.Rollback(ctx, shortKey)
+}
+
+// DeleteExpired удаляет ссылки с истёкшим сроком действия.
+func (s *StorageInstrumented) DeleteExpired(ctx context.Context, now time.Time, purge bool) (_ int64, err error) {
+	defer observe("DeleteExpired", time.Now(), &err)
+	return s.Storage.DeleteExpired(ctx, now, purge)
+}
+
+// Restore снимает пометку удаления со ссылки.
+func (s *StorageInstrumented) Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (_ string, err error) {
+	defer observe("Restore", time.Now(), &err)
+	return s.Storage.Restore(ctx, shortKey, deletedAfter)
+}
+
+// PurgeDeleted окончательно удаляет ссылки, удалённые раньше before.
+func (s *StorageInstrumented) PurgeDeleted(ctx context.Context, before time.Time) (_ int64, err error) {
+	defer observe("PurgeDeleted", time.Now(), &err)
+	return s.Storage.PurgeDeleted(ctx, before)
+}
+
+// Consume уменьшает остаток переходов по ссылке.
+func (s *StorageInstrumented) Consume(ctx context.Context, shortKey string) (err error) {
+	defer observe("Consume", time.Now(), &err)
+	return s.Storage.Consume(ctx, shortKey)
+}
+
+// observe записывает длительность операции, начатой в момент start и завершившейся ошибкой *err.
+func observe(operation string, start time.Time, err *error) {
+	metrics.StorageDuration.WithLabelValues(operation, result(*err)).Observe(time.Since(start).Seconds())
+}
+
+// result возвращает метку результата операции по её ошибке.
+func result(err error) string {
+	if err == nil {
+		return resultOK
+	}
+	for _, rejection := range rejections {
+		if errors.Is(err, rejection) {
+			return resultRejected
+		}
+	}
+	return resultError
+}
@@ -0,0 +1,87 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/dsemenov12/shorturl/internal/metrics"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/dsemenov12/shorturl/internal/storage/mocks"
+	"github.com/dsemenov12/shorturl/internal/storage/storagetest"
+)
+
+func TestStorageInstrumented_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return NewStorage(memory.NewStorage())
+	})
+}
+
+func TestStorageInstrumented_Results(t *testing.T) {
+	metrics.StorageDuration.Reset()
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+	s := NewStorage(m)
+	ctx := context.Background()
+
+	m.EXPECT().Get(gomock.Any(), "short1").Return(models.Link{ShortKey: "short1"}, nil)
+	m.EXPECT().Get(gomock.Any(), "missing").Return(models.Link{}, storage.ErrNotFound)
+	m.EXPECT().Get(gomock.Any(), "broken").Return(models.Link{}, errors.New("connection reset"))
+	m.EXPECT().Update(gomock.Any(), "short1", "http://example.com").Return(storage.ErrForbidden)
+
+	link, err := s.Get(ctx, "short1")
+	assert.NoError(t, err)
+	assert.Equal(t, "short1", link.ShortKey)
+	_, err = s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.Get(ctx, "broken")
+	assert.Error(t, err)
+	assert.ErrorIs(t, s.Update(ctx, "short1", "http://example.com"), storage.ErrForbidden)
+
+	for _, labels := range [][]string{{"Get", "ok"}, {"Get", "rejected"}, {"Get", "error"}, {"Update", "rejected"}} {
+		assert.Equal(t, uint64(1), sampleCount(t, labels...), labels)
+	}
+	assert.Equal(t, 4, testutil.CollectAndCount(metrics.StorageDuration))
+}
+
+func TestStorageInstrumented_UserLinks(t *testing.T) {
+	metrics.StorageDuration.Reset()
+	ctrl := gomock.NewController(t)
+	m := mocks.NewMockStorage(ctrl)
+	s := NewStorage(m)
+
+	readErr := errors.New("connection reset")
+	m.EXPECT().UserLinks(gomock.Any()).Return(func(yield func(models.UserLink, error) bool) {
+		if !yield(models.UserLink{}, nil) {
+			return
+		}
+		yield(models.UserLink{}, readErr)
+	})
+
+	var got []error
+	for _, err := range s.UserLinks(context.Background()) {
+		got = append(got, err)
+	}
+
+	assert.Equal(t, []error{nil, readErr}, got)
+	assert.Equal(t, uint64(1), sampleCount(t, "UserLinks", "error"))
+}
+
+// sampleCount возвращает количество измерений операции хранилища с метками labels.
+func sampleCount(t *testing.T, labels ...string) uint64 {
+	t.Helper()
+
+	metric := &dto.Metric{}
+	if err := metrics.StorageDuration.WithLabelValues(labels...).(prometheus.Histogram).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
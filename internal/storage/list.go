@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/tags"
+)
+
+// Ограничения размера страницы списка ссылок пользователя.
+const (
+	// DefaultListLimit — размер страницы, если он не задан.
+	DefaultListLimit = 100
+	// MaxListLimit — максимальный размер страницы; больший размер уменьшается до него.
+	MaxListLimit = 1000
+)
+
+// ErrInvalidQuery возвращается для некорректных параметров списка ссылок или курсора.
+var ErrInvalidQuery = errors.New("invalid list query")
+
+// ListCursor — позиция в списке ссылок пользователя: значения полей сортировки последней ссылки страницы.
+// Курсор действителен только для того же поля и направления сортировки, с которыми он получен.
+type ListCursor struct {
+	Sort      models.ListSort `json:"s"`           // Поле сортировки
+	Desc      bool            `json:"d,omitempty"` // Сортировка по убыванию
+	CreatedAt time.Time       `json:"c"`           // Момент создания ссылки
+	Clicks    int64           `json:"n"`           // Количество переходов по ссылке
+	ShortKey  string          `json:"k"`           // Короткий ключ ссылки
+}
+
+// NewListCursor возвращает позицию ссылки в списке, упорядоченном согласно query.
+func NewListCursor(query models.ListQuery, link models.Link, clicks int64) ListCursor {
+	return ListCursor{
+		Sort:      query.Sort,
+		Desc:      query.Desc,
+		CreatedAt: link.CreatedAt,
+		Clicks:    clicks,
+		ShortKey:  link.ShortKey,
+	}
+}
+
+// Encode кодирует курсор в непрозрачную строку для передачи клиенту.
+func (c ListCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Compare сравнивает позиции c и other в порядке сортировки курсора c:
+// отрицательное значение — c в списке раньше other, ноль — позиции совпадают.
+// При равенстве значений поля сортировки ссылки упорядочиваются по короткому ключу.
+func (c ListCursor) Compare(other ListCursor) int {
+	var result int
+	if c.Sort == models.ListSortClicks {
+		result = cmp.Compare(c.Clicks, other.Clicks)
+	} else {
+		result = c.CreatedAt.Compare(other.CreatedAt)
+	}
+	if result == 0 {
+		result = strings.Compare(c.ShortKey, other.ShortKey)
+	}
+	if c.Desc {
+		result = -result
+	}
+
+	return result
+}
+
+// NormalizeListQuery проверяет параметры списка, подставляет значения по умолчанию,
+// нормализует метку фильтра и декодирует курсор. Для первой страницы возвращает курсор nil.
+// Для некорректных параметров возвращает ErrInvalidQuery.
+func NormalizeListQuery(query models.ListQuery) (models.ListQuery, *ListCursor, error) {
+	switch {
+	case query.Limit < 0:
+		return query, nil, fmt.Errorf("%w: negative limit", ErrInvalidQuery)
+	case query.Limit == 0:
+		query.Limit = DefaultListLimit
+	case query.Limit > MaxListLimit:
+		query.Limit = MaxListLimit
+	}
+
+	switch query.Sort {
+	case "":
+		query.Sort = models.ListSortCreated
+	case models.ListSortCreated, models.ListSortClicks:
+	default:
+		return query, nil, fmt.Errorf("%w: unknown sort %q", ErrInvalidQuery, query.Sort)
+	}
+
+	switch query.Deleted {
+	case "":
+		query.Deleted = models.DeletedExclude
+	case models.DeletedExclude, models.DeletedOnly, models.DeletedInclude:
+	default:
+		return query, nil, fmt.Errorf("%w: unknown deleted filter %q", ErrInvalidQuery, query.Deleted)
+	}
+
+	if query.Tag != "" {
+		tag, err := tags.Normalize(query.Tag)
+		if err != nil {
+			return query, nil, fmt.Errorf("%w: %w", ErrInvalidQuery, err)
+		}
+		query.Tag = tag
+	}
+
+	if query.Cursor == "" {
+		return query, nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return query, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var cursor ListCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return query, nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if cursor.Sort != query.Sort || cursor.Desc != query.Desc {
+		return query, nil, fmt.Errorf("%w: cursor belongs to another sort order", ErrInvalidQuery)
+	}
+
+	return query, &cursor, nil
+}
+
+// ParseListOrder разбирает направление сортировки "asc" или "desc" (пустая строка — "asc")
+// и сообщает, нужна ли сортировка по убыванию.
+func ParseListOrder(order string) (bool, error) {
+	switch order {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: unknown order %q", ErrInvalidQuery, order)
+	}
+}
+
+// MatchesListQuery сообщает, подходит ли ссылка под фильтры списка query.
+// Используется хранилищами, которые фильтруют ссылки без помощи базы данных.
+func MatchesListQuery(query models.ListQuery, link models.Link) bool {
+	switch query.Deleted {
+	case models.DeletedOnly:
+		if !link.IsDeleted {
+			return false
+		}
+	case models.DeletedInclude:
+	default:
+		if link.IsDeleted {
+			return false
+		}
+	}
+
+	if query.Tag != "" && !slices.Contains(link.Tags, query.Tag) {
+		return false
+	}
+
+	return query.Search == "" || strings.Contains(strings.ToLower(link.OriginalURL), strings.ToLower(query.Search))
+}
+
+// NewListItem преобразует ссылку с количеством переходов clicks в элемент списка ссылок пользователя.
+func NewListItem(link models.Link, clicks int64) models.ShortURLItem {
+	createdAt := link.CreatedAt
+	item := models.ShortURLItem{
+		OriginalURL: link.OriginalURL,
+		ShortURL:    config.FlagBaseAddr + "/" + link.ShortKey,
+		CreatedAt:   &createdAt,
+		Clicks:      &clicks,
+		IsDeleted:   link.IsDeleted,
+		Tags:        link.Tags,
+	}
+	if link.MaxClicks > 0 {
+		left := link.ClicksLeft
+		item.ClicksLeft = &left
+	}
+
+	return item
+}
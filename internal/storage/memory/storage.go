@@ -2,66 +2,726 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
-	"github.com/dsemenov12/shorturl/internal/filestorage"
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/config"
 	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
 )
 
 // StorageMemory представляет собой структуру для хранения данных в памяти.
+// Помимо ссылок по короткому ключу хранит индексы по URL и по владельцу,
+// чтобы проверять уникальность URL и выбирать ссылки пользователя без полного перебора.
 type StorageMemory struct {
-	mx   sync.RWMutex
-	Data map[string]string
+	mx      sync.RWMutex
+	Data    map[string]models.Link
+	urls    map[string]string
+	users   map[string]map[string]struct{}
+	clicks  map[string][]models.ClickEvent
+	history map[string][]models.HistoryItem
 }
 
 // NewStorage создает новый экземпляр StorageMemory с инициализацией пустой карты для хранения данных.
 func NewStorage() *StorageMemory {
-	StorageObj := StorageMemory{Data: make(map[string]string)}
+	StorageObj := StorageMemory{
+		Data:    make(map[string]models.Link),
+		urls:    make(map[string]string),
+		users:   make(map[string]map[string]struct{}),
+		clicks:  make(map[string][]models.ClickEvent),
+		history: make(map[string][]models.HistoryItem),
+	}
 	return &StorageObj
 }
 
-// Get извлекает из памяти значение для заданного ключа (сокращённого URL).
-func (s *StorageMemory) Get(ctx context.Context, key string) (string, string, bool, error) {
+// Get извлекает из памяти ссылку для заданного ключа (сокращённого URL).
+func (s *StorageMemory) Get(ctx context.Context, key string) (models.Link, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Link{}, err
+	}
+
 	s.mx.RLock()
 	defer s.mx.RUnlock()
-	return s.Data[key], key, false, nil
+
+	link, ok := s.Data[key]
+	if !ok {
+		return models.Link{}, storage.ErrNotFound
+	}
+
+	return link, nil
+}
+
+// Set сохраняет ссылку в память и возвращает её короткий ключ.
+// Владельцем становится link.UserID, а если он не задан — пользователь из контекста.
+// Если URL уже сокращён, возвращает существующий ключ и storage.ErrConflict,
+// если ключ уже занят другой ссылкой — storage.ErrKeyExists.
+func (s *StorageMemory) Set(ctx context.Context, link models.Link) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return s.set(ctx, link)
 }
 
-// Set сохраняет пару ключ-значение в память.
-func (s *StorageMemory) Set(ctx context.Context, key string, value string) (string, error) {
+// SetBatch сохраняет пакет ссылок в память под одной блокировкой.
+// При atomic=true и занятом ключе у какой-либо ссылки уже сохранённые ссылки пакета удаляются.
+func (s *StorageMemory) SetBatch(ctx context.Context, links []models.Link, atomic bool) ([]storage.BatchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	s.mx.Lock()
 	defer s.mx.Unlock()
-	s.Data[key] = value
 
-	return value, nil
+	results := make([]storage.BatchResult, len(links))
+	failed := false
+	for i, link := range links {
+		results[i].ShortKey, results[i].Err = s.set(ctx, link)
+		failed = failed || errors.Is(results[i].Err, storage.ErrKeyExists)
+	}
+	if !atomic || !failed {
+		return results, nil
+	}
+
+	for i, result := range results {
+		if result.Err == nil {
+			s.unset(result.ShortKey)
+			results[i].ShortKey = ""
+		}
+	}
+
+	return results, storage.ErrKeyExists
+}
+
+// Import сохраняет пакет ссылок в память под одной блокировкой.
+func (s *StorageMemory) Import(ctx context.Context, links []models.Link) ([]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	errs := make([]error, len(links))
+	for i, link := range links {
+		_, errs[i] = s.set(ctx, link)
+	}
+
+	return errs, nil
+}
+
+// set сохраняет ссылку в память и обновляет индексы. Вызывается под блокировкой хранилища.
+func (s *StorageMemory) set(ctx context.Context, link models.Link) (string, error) {
+	if shortKey, ok := s.urls[link.OriginalURL]; ok {
+		return shortKey, storage.ErrConflict
+	}
+	if _, ok := s.Data[link.ShortKey]; ok {
+		return "", storage.ErrKeyExists
+	}
+	if link.UserID == "" {
+		link.UserID = auth.UserIDFromContext(ctx)
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC()
+	}
+	link.ClicksLeft = link.MaxClicks
+	s.Data[link.ShortKey] = link
+	s.urls[link.OriginalURL] = link.ShortKey
+	if link.UserID != "" {
+		if s.users[link.UserID] == nil {
+			s.users[link.UserID] = make(map[string]struct{})
+		}
+		s.users[link.UserID][link.ShortKey] = struct{}{}
+	}
+
+	return link.ShortKey, nil
+}
+
+// unset удаляет только что сохранённую ссылку и её индексы. Вызывается под блокировкой хранилища.
+func (s *StorageMemory) unset(shortKey string) {
+	link, ok := s.Data[shortKey]
+	if !ok {
+		return
+	}
+
+	delete(s.Data, shortKey)
+	delete(s.urls, link.OriginalURL)
+	delete(s.users[link.UserID], shortKey)
+	if len(s.users[link.UserID]) == 0 {
+		delete(s.users, link.UserID)
+	}
 }
 
-// Bootstrap загружает данные из внешнего хранилища в память, используя функционал файла.
+// Bootstrap ничего не делает: хранилище в памяти не требует инициализации.
 func (s *StorageMemory) Bootstrap(ctx context.Context) error {
-	filestorage.Load(s)
 	return nil
 }
 
-// GetUserURL извлекает данные о всех сокращённых URL для текущего пользователя.
+// GetUserURL извлекает неудалённые сокращённые URL текущего пользователя в порядке их создания.
 func (s *StorageMemory) GetUserURL(ctx context.Context) (result []models.ShortURLItem, err error) {
-	return nil, nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	userID := auth.UserIDFromContext(ctx)
+	if userID == "" {
+		return nil, nil
+	}
+
+	links := make([]models.Link, 0, len(s.users[userID]))
+	for shortKey := range s.users[userID] {
+		if link := s.Data[shortKey]; !link.IsDeleted {
+			links = append(links, link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].ShortKey < links[j].ShortKey
+		}
+		return links[i].CreatedAt.Before(links[j].CreatedAt)
+	})
+
+	for _, link := range links {
+		item := models.ShortURLItem{
+			OriginalURL: link.OriginalURL,
+			ShortURL:    config.FlagBaseAddr + "/" + link.ShortKey,
+		}
+		if link.MaxClicks > 0 {
+			left := link.ClicksLeft
+			item.ClicksLeft = &left
+		}
+		result = append(result, item)
+	}
+
+	return result, nil
 }
 
-// Delete удаляет запись по ключу (сокращённому URL) из памяти.
+// ListUserURLs возвращает страницу ссылок текущего пользователя.
+// Ссылки пользователя отбираются по индексу владельцев, фильтруются и упорядочиваются в памяти.
+func (s *StorageMemory) ListUserURLs(ctx context.Context, query models.ListQuery) (models.ShortURLPage, error) {
+	if err := ctx.Err(); err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	query, after, err := storage.NormalizeListQuery(query)
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	userID := auth.UserIDFromContext(ctx)
+	if userID == "" {
+		return models.ShortURLPage{}, nil
+	}
+
+	type entry struct {
+		link   models.Link
+		clicks int64
+		cursor storage.ListCursor
+	}
+	entries := make([]entry, 0, len(s.users[userID]))
+	for shortKey := range s.users[userID] {
+		link := s.Data[shortKey]
+		if !storage.MatchesListQuery(query, link) {
+			continue
+		}
+		clicks := int64(len(s.clicks[shortKey]))
+		entries = append(entries, entry{link: link, clicks: clicks, cursor: storage.NewListCursor(query, link, clicks)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].cursor.Compare(entries[j].cursor) < 0
+	})
+
+	page := models.ShortURLPage{Total: len(entries)}
+	if after != nil {
+		start := sort.Search(len(entries), func(i int) bool {
+			return entries[i].cursor.Compare(*after) > 0
+		})
+		entries = entries[start:]
+	}
+	if len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+		page.NextCursor = entries[len(entries)-1].cursor.Encode()
+	}
+
+	for _, e := range entries {
+		page.Items = append(page.Items, storage.NewListItem(e.link, e.clicks))
+	}
+
+	return page, nil
+}
+
+// UserLinks возвращает итератор по ссылкам текущего пользователя.
+// Итератор обходит снимок ссылок, сделанный при начале итерации, и не удерживает блокировку хранилища.
+func (s *StorageMemory) UserLinks(ctx context.Context) storage.UserLinkSeq {
+	return func(yield func(models.UserLink, error) bool) {
+		if err := ctx.Err(); err != nil {
+			yield(models.UserLink{}, err)
+			return
+		}
+
+		for _, item := range s.userLinks(auth.UserIDFromContext(ctx)) {
+			if err := ctx.Err(); err != nil {
+				yield(models.UserLink{}, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// userLinks возвращает ссылки пользователя userID в порядке создания вместе с количеством переходов.
+func (s *StorageMemory) userLinks(userID string) []models.UserLink {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	if userID == "" {
+		return nil
+	}
+
+	items := make([]models.UserLink, 0, len(s.users[userID]))
+	for shortKey := range s.users[userID] {
+		items = append(items, models.UserLink{Link: s.Data[shortKey], Clicks: int64(len(s.clicks[shortKey]))})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Link.CreatedAt.Equal(items[j].Link.CreatedAt) {
+			return items[i].Link.CreatedAt.Before(items[j].Link.CreatedAt)
+		}
+		return items[i].Link.ShortKey < items[j].Link.ShortKey
+	})
+
+	return items
+}
+
+// Delete помечает ссылку текущего пользователя как удалённую.
+// Ссылки других пользователей и уже удалённые ссылки не изменяются.
 func (s *StorageMemory) Delete(ctx context.Context, shortKey string) (err error) {
-	delete(s.Data, shortKey)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	link, ok := s.Data[shortKey]
+	if !ok || link.IsDeleted || link.UserID == "" || link.UserID != auth.UserIDFromContext(ctx) {
+		return nil
+	}
+
+	link.IsDeleted = true
+	link.DeletedAt = time.Now().UTC()
+	s.Data[shortKey] = link
+
 	return nil
 }
 
-// CountURLs возвращает количество уникальных URL.
+// DeleteBatch помечает как удалённые ссылки shortKeys текущего пользователя.
+func (s *StorageMemory) DeleteBatch(ctx context.Context, shortKeys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	owner := auth.UserIDFromContext(ctx)
+	now := time.Now().UTC()
+	for _, shortKey := range shortKeys {
+		link, ok := s.Data[shortKey]
+		if !ok || link.IsDeleted || link.UserID == "" || link.UserID != owner {
+			continue
+		}
+
+		link.IsDeleted = true
+		link.DeletedAt = now
+		s.Data[shortKey] = link
+	}
+
+	return nil
+}
+
+// CountURLs возвращает количество неудалённых ссылок.
 func (s *StorageMemory) CountURLs(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
 	s.mx.RLock()
 	defer s.mx.RUnlock()
-	return len(s.Data), nil
+
+	var count int
+	for _, link := range s.Data {
+		if !link.IsDeleted {
+			count++
+		}
+	}
+	return count, nil
 }
 
-// CountUsers возвращает количество уникальных пользователей в базе данных.
-// В Memory-хранилище нет user_id, поэтому метод всегда возвращает 0.
+// CountUsers возвращает количество уникальных владельцев ссылок.
 func (s *StorageMemory) CountUsers(ctx context.Context) (int, error) {
-	return 0, nil
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return len(s.users), nil
+}
+
+// SaveClicks сохраняет события перехода в памяти.
+func (s *StorageMemory) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, event := range events {
+		s.clicks[event.ShortKey] = append(s.clicks[event.ShortKey], event)
+	}
+
+	return nil
+}
+
+// GetStats подсчитывает переходы по короткой ссылке и группирует их по интервалам длиной step.
+func (s *StorageMemory) GetStats(ctx context.Context, shortKey string, from, to time.Time, step time.Duration) (int64, []models.StatsBucket, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	events := s.clicks[shortKey]
+	buckets := make(map[time.Time]int64)
+	for _, event := range events {
+		if event.Timestamp.Before(from) || !event.Timestamp.Before(to) {
+			continue
+		}
+		buckets[storage.BucketStart(event.Timestamp, step)]++
+	}
+
+	series := make([]models.StatsBucket, 0, len(buckets))
+	for bucket, clicks := range buckets {
+		series = append(series, models.StatsBucket{Time: bucket, Clicks: clicks})
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Time.Before(series[j].Time)
+	})
+
+	return int64(len(events)), series, nil
+}
+
+// Update заменяет оригинальный URL ссылки текущего пользователя и сохраняет предыдущее значение в истории.
+func (s *StorageMemory) Update(ctx context.Context, shortKey string, url string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	link, err := s.ownedLink(ctx, shortKey)
+	if err != nil {
+		return err
+	}
+	if link.OriginalURL == url {
+		return nil
+	}
+	if _, ok := s.urls[url]; ok {
+		return storage.ErrConflict
+	}
+
+	s.history[shortKey] = append(s.history[shortKey], models.HistoryItem{
+		OriginalURL: link.OriginalURL,
+		ChangedAt:   time.Now().UTC(),
+	})
+	delete(s.urls, link.OriginalURL)
+	link.OriginalURL = url
+	s.Data[shortKey] = link
+	s.urls[url] = shortKey
+
+	return nil
+}
+
+// GetHistory возвращает историю изменений ссылки текущего пользователя, начиная с самого позднего изменения.
+func (s *StorageMemory) GetHistory(ctx context.Context, shortKey string) ([]models.HistoryItem, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	link, ok := s.Data[shortKey]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if link.UserID != auth.UserIDFromContext(ctx) {
+		return nil, storage.ErrForbidden
+	}
+
+	history := s.history[shortKey]
+	result := make([]models.HistoryItem, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		result = append(result, history[i])
+	}
+
+	return result, nil
+}
+
+// Rollback восстанавливает последнее значение из истории изменений ссылки текущего пользователя.
+func (s *StorageMemory) Rollback(ctx context.Context, shortKey string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	link, err := s.ownedLink(ctx, shortKey)
+	if err != nil {
+		return "", err
+	}
+
+	history := s.history[shortKey]
+	if len(history) == 0 {
+		return "", storage.ErrNoHistory
+	}
+
+	previous := history[len(history)-1]
+	if _, ok := s.urls[previous.OriginalURL]; ok {
+		return "", storage.ErrConflict
+	}
+
+	s.history[shortKey] = history[:len(history)-1]
+	delete(s.urls, link.OriginalURL)
+	link.OriginalURL = previous.OriginalURL
+	s.Data[shortKey] = link
+	s.urls[link.OriginalURL] = shortKey
+
+	return previous.OriginalURL, nil
+}
+
+// Consume уменьшает оставшееся количество переходов по ссылке под блокировкой хранилища.
+func (s *StorageMemory) Consume(ctx context.Context, shortKey string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	link, ok := s.Data[shortKey]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if link.MaxClicks == 0 {
+		return nil
+	}
+	if link.ClicksLeft <= 0 {
+		return storage.ErrExhausted
+	}
+
+	link.ClicksLeft--
+	s.Data[shortKey] = link
+
+	return nil
+}
+
+// DeleteExpired помечает удалёнными ссылки, срок действия которых истёк к моменту now,
+// а при purge=true вытесняет их из памяти вместе со статистикой и историей изменений.
+func (s *StorageMemory) DeleteExpired(ctx context.Context, now time.Time, purge bool) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var count int64
+	for key, link := range s.Data {
+		if !link.Expired(now) {
+			continue
+		}
+		if purge {
+			s.remove(link)
+			count++
+			continue
+		}
+		if !link.IsDeleted {
+			link.IsDeleted = true
+			link.DeletedAt = now.UTC()
+			s.Data[key] = link
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Restore снимает пометку удаления со ссылки текущего пользователя, удалённой не раньше deletedAfter.
+func (s *StorageMemory) Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	link, ok := s.Data[shortKey]
+	switch {
+	case !ok:
+		return "", storage.ErrNotFound
+	case link.UserID != auth.UserIDFromContext(ctx):
+		return "", storage.ErrForbidden
+	case !link.IsDeleted:
+		return "", storage.ErrNotDeleted
+	case link.DeletedAt.Before(deletedAfter):
+		return "", storage.ErrRestoreExpired
+	}
+
+	link.IsDeleted = false
+	link.DeletedAt = time.Time{}
+	s.Data[shortKey] = link
+
+	return link.OriginalURL, nil
+}
+
+// PurgeDeleted удаляет из памяти ссылки, помеченные удалёнными раньше момента before.
+func (s *StorageMemory) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	var count int64
+	for _, link := range s.Data {
+		if link.IsDeleted && link.DeletedAt.Before(before) {
+			s.remove(link)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// SetDeletedAt заменяет момент удаления удалённой ссылки. Используется при загрузке ссылок из журнала,
+// чтобы сроки восстановления и хранения отсчитывались от исходного удаления.
+func (s *StorageMemory) SetDeletedAt(shortKey string, deletedAt time.Time) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if link, ok := s.Data[shortKey]; ok && link.IsDeleted {
+		link.DeletedAt = deletedAt
+		s.Data[shortKey] = link
+	}
+}
+
+// SetClicksLeft заменяет остаток переходов по ссылке с ограничением количества переходов.
+// Используется при загрузке ссылок из журнала, чтобы исчерпанный лимит сохранялся после перезапуска.
+func (s *StorageMemory) SetClicksLeft(shortKey string, clicksLeft int64) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if link, ok := s.Data[shortKey]; ok && link.MaxClicks > 0 {
+		link.ClicksLeft = clicksLeft
+		s.Data[shortKey] = link
+	}
+}
+
+// SetHistory заменяет историю изменений URL ссылки; history упорядочена от ранних значений к поздним.
+// Используется при загрузке ссылок из снимка журнала, чтобы изменения можно было откатить после перезапуска.
+func (s *StorageMemory) SetHistory(shortKey string, history []models.HistoryItem) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.Data[shortKey]; ok {
+		s.history[shortKey] = slices.Clone(history)
+	}
+}
+
+// Histories возвращает копию истории изменений URL всех ссылок, у которых она есть,
+// от ранних значений к поздним.
+func (s *StorageMemory) Histories() map[string][]models.HistoryItem {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	histories := make(map[string][]models.HistoryItem, len(s.history))
+	for shortKey, history := range s.history {
+		if len(history) > 0 {
+			histories[shortKey] = slices.Clone(history)
+		}
+	}
+
+	return histories
+}
+
+// Links возвращает снимок всех ссылок, включая удалённые, в порядке их создания.
+func (s *StorageMemory) Links() []models.Link {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	links := make([]models.Link, 0, len(s.Data))
+	for _, link := range s.Data {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].ShortKey < links[j].ShortKey
+		}
+		return links[i].CreatedAt.Before(links[j].CreatedAt)
+	})
+
+	return links
+}
+
+// ownedLink возвращает ссылку, если она принадлежит текущему пользователю и не удалена.
+// Вызывается под блокировкой хранилища.
+func (s *StorageMemory) ownedLink(ctx context.Context, shortKey string) (models.Link, error) {
+	link, ok := s.Data[shortKey]
+	if !ok {
+		return models.Link{}, storage.ErrNotFound
+	}
+	if link.UserID != auth.UserIDFromContext(ctx) {
+		return models.Link{}, storage.ErrForbidden
+	}
+	if link.IsDeleted {
+		return models.Link{}, storage.ErrDeleted
+	}
+
+	return link, nil
+}
+
+// remove полностью удаляет ссылку и связанные с ней данные из памяти и индексов.
+// Вызывается под блокировкой хранилища.
+func (s *StorageMemory) remove(link models.Link) {
+	delete(s.Data, link.ShortKey)
+	delete(s.urls, link.OriginalURL)
+	delete(s.clicks, link.ShortKey)
+	delete(s.history, link.ShortKey)
+	if keys, ok := s.users[link.UserID]; ok {
+		delete(keys, link.ShortKey)
+		if len(keys) == 0 {
+			delete(s.users, link.UserID)
+		}
+	}
 }
@@ -2,7 +2,16 @@ package memory
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/storagetest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -14,52 +23,246 @@ func TestStorageMemory_SetAndGet(t *testing.T) {
 	// Test Set method
 	key := "short1"
 	value := "http://example.com"
-	storedValue, err := storage.Set(ctx, key, value)
+	storedValue, err := storage.Set(ctx, models.Link{ShortKey: key, OriginalURL: value})
 	assert.NoError(t, err, "Set should not return an error")
-	assert.Equal(t, value, storedValue, "The stored value should match the expected value")
+	assert.Equal(t, key, storedValue, "Set should return the short key")
 
 	// Test Get method
-	gotValue, gotKey, _, err := storage.Get(ctx, key)
+	link, err := storage.Get(ctx, key)
 	assert.NoError(t, err, "Get should not return an error")
-	assert.Equal(t, value, gotValue, "The retrieved value should match the expected value")
-	assert.Equal(t, key, gotKey, "The retrieved key should match the expected key")
+	assert.Equal(t, value, link.OriginalURL, "The retrieved value should match the expected value")
+	assert.Equal(t, key, link.ShortKey, "The retrieved key should match the expected key")
+}
+
+func TestStorageMemory_SetExistingKey(t *testing.T) {
+	s := NewStorage()
+	ctx := context.Background()
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	assert.NoError(t, err, "Set should not return an error")
+
+	shortKey, err := s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/1"})
+	assert.ErrorIs(t, err, storage.ErrConflict, "Set of an already shortened URL should report a conflict")
+	assert.Equal(t, "short1", shortKey, "Set should return the existing key on conflict")
+
+	_, err = s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/2"})
+	assert.ErrorIs(t, err, storage.ErrKeyExists, "Set should not overwrite an existing key")
+
+	link, err := s.Get(ctx, "short1")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/1", link.OriginalURL)
 }
 
 func TestStorageMemory_Delete(t *testing.T) {
 	storage := NewStorage()
-	ctx := context.Background()
+	ownerCtx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	otherCtx := context.WithValue(context.Background(), auth.UserIDKey, "user2")
 
 	// Set a value
 	key := "short1"
 	value := "http://example.com"
-	_, err := storage.Set(ctx, key, value)
+	_, err := storage.Set(ownerCtx, models.Link{ShortKey: key, OriginalURL: value})
 	assert.NoError(t, err, "Set should not return an error")
 
+	// Another user can't delete the key
+	err = storage.Delete(otherCtx, key)
+	assert.NoError(t, err, "Delete should not return an error")
+	link, err := storage.Get(ownerCtx, key)
+	assert.NoError(t, err)
+	assert.False(t, link.IsDeleted, "Link of another user should not be deleted")
+
 	// Delete the key
-	err = storage.Delete(ctx, key)
+	err = storage.Delete(ownerCtx, key)
 	assert.NoError(t, err, "Delete should not return an error")
 
-	// Check if the key is deleted
-	_, _, found, err := storage.Get(ctx, key)
-	assert.NoError(t, err, "Get after delete should not return an error")
-	assert.False(t, found, "Key should be deleted")
+	// Check if the key is marked as deleted
+	link, err = storage.Get(ownerCtx, key)
+	assert.NoError(t, err)
+	assert.True(t, link.IsDeleted, "Key should be marked as deleted")
+
+	count, err := storage.CountURLs(ownerCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count, "Deleted links should not be counted")
 }
 
 func TestStorageMemory_Bootstrap(t *testing.T) {
 	storage := NewStorage()
 	ctx := context.Background()
 
-	// Test Bootstrap method (assuming filestorage.Load doesn't throw an error)
 	err := storage.Bootstrap(ctx)
 	assert.NoError(t, err, "Bootstrap should not return an error")
 }
 
 func TestStorageMemory_GetUserURL(t *testing.T) {
 	storage := NewStorage()
-	ctx := context.Background()
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	otherCtx := context.WithValue(context.Background(), auth.UserIDKey, "user2")
 
-	// Test GetUserURL method (which currently returns nil)
 	result, err := storage.GetUserURL(ctx)
 	assert.NoError(t, err, "GetUserURL should not return an error")
 	assert.Nil(t, result, "GetUserURL should return nil as there is no data yet")
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err = storage.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2", CreatedAt: createdAt.Add(time.Minute), MaxClicks: 3})
+	assert.NoError(t, err)
+	_, err = storage.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1", CreatedAt: createdAt})
+	assert.NoError(t, err)
+	_, err = storage.Set(ctx, models.Link{ShortKey: "short3", OriginalURL: "http://example.com/3"})
+	assert.NoError(t, err)
+	_, err = storage.Set(otherCtx, models.Link{ShortKey: "other", OriginalURL: "http://example.com/4"})
+	assert.NoError(t, err)
+	assert.NoError(t, storage.Delete(ctx, "short3"))
+
+	result, err = storage.GetUserURL(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, result, 2, "Only non-deleted links of the current user should be returned") {
+		assert.Equal(t, "http://example.com/1", result[0].OriginalURL, "Links should be ordered by creation time")
+		assert.Nil(t, result[0].ClicksLeft)
+		assert.Equal(t, "http://example.com/2", result[1].OriginalURL)
+		if assert.NotNil(t, result[1].ClicksLeft) {
+			assert.Equal(t, int64(3), *result[1].ClicksLeft)
+		}
+	}
+
+	users, err := storage.CountUsers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, users)
+}
+
+func TestStorageMemory_GetStats(t *testing.T) {
+	storage := NewStorage()
+	ctx := context.Background()
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := storage.SaveClicks(ctx, []models.ClickEvent{
+		{ShortKey: "short1", Timestamp: day.Add(time.Hour)},
+		{ShortKey: "short1", Timestamp: day.Add(2 * time.Hour)},
+		{ShortKey: "short1", Timestamp: day.Add(26 * time.Hour)},
+		{ShortKey: "short1", Timestamp: day.Add(-time.Hour)},
+		{ShortKey: "short2", Timestamp: day.Add(time.Hour)},
+	})
+	assert.NoError(t, err, "SaveClicks should not return an error")
+
+	total, series, err := storage.GetStats(ctx, "short1", day, day.Add(48*time.Hour), 24*time.Hour)
+	assert.NoError(t, err, "GetStats should not return an error")
+	assert.Equal(t, int64(4), total, "Total should include clicks outside of the range")
+	assert.Equal(t, []models.StatsBucket{
+		{Time: day, Clicks: 2},
+		{Time: day.Add(24 * time.Hour), Clicks: 1},
+	}, series)
+}
+
+func TestStorageMemory_UpdateAndRollback(t *testing.T) {
+	s := NewStorage()
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+	otherCtx := context.WithValue(context.Background(), auth.UserIDKey, "user2")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	assert.NoError(t, err, "Set should not return an error")
+
+	err = s.Update(ctx, "short1", "http://example.com/2")
+	assert.NoError(t, err, "Update should not return an error")
+	err = s.Update(ctx, "short1", "http://example.com/3")
+	assert.NoError(t, err, "Update should not return an error")
+
+	history, err := s.GetHistory(ctx, "short1")
+	assert.NoError(t, err, "GetHistory should not return an error")
+	assert.Len(t, history, 2)
+	assert.Equal(t, "http://example.com/2", history[0].OriginalURL, "The latest change should come first")
+
+	url, err := s.Rollback(ctx, "short1")
+	assert.NoError(t, err, "Rollback should not return an error")
+	assert.Equal(t, "http://example.com/2", url)
+
+	link, err := s.Get(ctx, "short1")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://example.com/2", link.OriginalURL)
+
+	err = s.Update(ctx, "missing", "http://example.com")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	err = s.Update(otherCtx, "short1", "http://example.com/4")
+	assert.ErrorIs(t, err, storage.ErrForbidden)
+	_, err = s.GetHistory(otherCtx, "short1")
+	assert.ErrorIs(t, err, storage.ErrForbidden)
+	_, err = s.Rollback(otherCtx, "short1")
+	assert.ErrorIs(t, err, storage.ErrForbidden)
+
+	_, err = s.Rollback(ctx, "short1")
+	assert.NoError(t, err)
+	_, err = s.Rollback(ctx, "short1")
+	assert.ErrorIs(t, err, storage.ErrNoHistory)
+
+	assert.NoError(t, s.Delete(ctx, "short1"))
+	err = s.Update(ctx, "short1", "http://example.com/4")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+}
+
+func TestStorageMemory_DeleteExpired(t *testing.T) {
+	s := NewStorage()
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "expired", OriginalURL: "http://example.com/1", ExpiresAt: now.Add(-time.Minute)})
+	assert.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "active", OriginalURL: "http://example.com/2", ExpiresAt: now.Add(time.Hour)})
+	assert.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "forever", OriginalURL: "http://example.com/3"})
+	assert.NoError(t, err)
+
+	count, err := s.DeleteExpired(ctx, now, false)
+	assert.NoError(t, err, "DeleteExpired should not return an error")
+	assert.Equal(t, int64(1), count)
+
+	link, err := s.Get(ctx, "expired")
+	assert.NoError(t, err)
+	assert.True(t, link.IsDeleted, "Expired link should be marked as deleted")
+	link, err = s.Get(ctx, "active")
+	assert.NoError(t, err)
+	assert.False(t, link.IsDeleted)
+	_, err = s.Get(ctx, "forever")
+	assert.NoError(t, err)
+
+	count, err = s.DeleteExpired(ctx, now, false)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count, "Already deleted links should not be counted again")
+
+	count, err = s.DeleteExpired(ctx, now, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, err = s.Get(ctx, "expired")
+	assert.ErrorIs(t, err, storage.ErrNotFound, "Purged link should be evicted")
+}
+
+func TestStorageMemory_Consume(t *testing.T) {
+	s := NewStorage()
+	ctx := context.Background()
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "once", OriginalURL: "http://example.com", MaxClicks: 2})
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var exhausted atomic.Int64
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errors.Is(s.Consume(ctx, "once"), storage.ErrExhausted) {
+				exhausted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(8), exhausted.Load(), "Only max_clicks redirects should succeed")
+
+	link, err := s.Get(ctx, "once")
+	assert.NoError(t, err)
+	assert.True(t, link.Exhausted())
+}
+
+func TestStorageMemory_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return NewStorage()
+	})
 }
@@ -7,8 +7,10 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/dsemenov12/shorturl/internal/models"
+	storage "github.com/dsemenov12/shorturl/internal/storage"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -49,6 +51,20 @@ func (mr *MockStorageMockRecorder) Bootstrap(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bootstrap", reflect.TypeOf((*MockStorage)(nil).Bootstrap), ctx)
 }
 
+// Consume mocks base method.
+func (m *MockStorage) Consume(ctx context.Context, shortKey string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Consume", ctx, shortKey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Consume indicates an expected call of Consume.
+func (mr *MockStorageMockRecorder) Consume(ctx, shortKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Consume", reflect.TypeOf((*MockStorage)(nil).Consume), ctx, shortKey)
+}
+
 // CountURLs mocks base method.
 func (m *MockStorage) CountURLs(ctx context.Context) (int, error) {
 	m.ctrl.T.Helper()
@@ -93,15 +109,42 @@ func (mr *MockStorageMockRecorder) Delete(ctx, shortKey interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockStorage)(nil).Delete), ctx, shortKey)
 }
 
+// DeleteBatch mocks base method.
+func (m *MockStorage) DeleteBatch(ctx context.Context, shortKeys []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBatch", ctx, shortKeys)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBatch indicates an expected call of DeleteBatch.
+func (mr *MockStorageMockRecorder) DeleteBatch(ctx, shortKeys interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBatch", reflect.TypeOf((*MockStorage)(nil).DeleteBatch), ctx, shortKeys)
+}
+
+// DeleteExpired mocks base method.
+func (m *MockStorage) DeleteExpired(ctx context.Context, now time.Time, purge bool) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", ctx, now, purge)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockStorageMockRecorder) DeleteExpired(ctx, now, purge interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockStorage)(nil).DeleteExpired), ctx, now, purge)
+}
+
 // Get mocks base method.
-func (m *MockStorage) Get(ctx context.Context, shortKey string) (string, string, bool, error) {
+func (m *MockStorage) Get(ctx context.Context, shortKey string) (models.Link, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", ctx, shortKey)
-	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(string)
-	ret2, _ := ret[2].(bool)
-	ret3, _ := ret[3].(error)
-	return ret0, ret1, ret2, ret3
+	ret0, _ := ret[0].(models.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Get indicates an expected call of Get.
@@ -110,6 +153,37 @@ func (mr *MockStorageMockRecorder) Get(ctx, shortKey interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockStorage)(nil).Get), ctx, shortKey)
 }
 
+// GetHistory mocks base method.
+func (m *MockStorage) GetHistory(ctx context.Context, shortKey string) ([]models.HistoryItem, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHistory", ctx, shortKey)
+	ret0, _ := ret[0].([]models.HistoryItem)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHistory indicates an expected call of GetHistory.
+func (mr *MockStorageMockRecorder) GetHistory(ctx, shortKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHistory", reflect.TypeOf((*MockStorage)(nil).GetHistory), ctx, shortKey)
+}
+
+// GetStats mocks base method.
+func (m *MockStorage) GetStats(ctx context.Context, shortKey string, from, to time.Time, step time.Duration) (int64, []models.StatsBucket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", ctx, shortKey, from, to, step)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].([]models.StatsBucket)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockStorageMockRecorder) GetStats(ctx, shortKey, from, to, step interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockStorage)(nil).GetStats), ctx, shortKey, from, to, step)
+}
+
 // GetUserURL mocks base method.
 func (m *MockStorage) GetUserURL(ctx context.Context) ([]models.ShortURLItem, error) {
 	m.ctrl.T.Helper()
@@ -125,17 +199,149 @@ func (mr *MockStorageMockRecorder) GetUserURL(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserURL", reflect.TypeOf((*MockStorage)(nil).GetUserURL), ctx)
 }
 
+// Import mocks base method.
+func (m *MockStorage) Import(ctx context.Context, links []models.Link) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", ctx, links)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockStorageMockRecorder) Import(ctx, links interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockStorage)(nil).Import), ctx, links)
+}
+
+// ListUserURLs mocks base method.
+func (m *MockStorage) ListUserURLs(ctx context.Context, query models.ListQuery) (models.ShortURLPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserURLs", ctx, query)
+	ret0, _ := ret[0].(models.ShortURLPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserURLs indicates an expected call of ListUserURLs.
+func (mr *MockStorageMockRecorder) ListUserURLs(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserURLs", reflect.TypeOf((*MockStorage)(nil).ListUserURLs), ctx, query)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockStorage) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockStorageMockRecorder) PurgeDeleted(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockStorage)(nil).PurgeDeleted), ctx, before)
+}
+
+// Restore mocks base method.
+func (m *MockStorage) Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, shortKey, deletedAfter)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockStorageMockRecorder) Restore(ctx, shortKey, deletedAfter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockStorage)(nil).Restore), ctx, shortKey, deletedAfter)
+}
+
+// Rollback mocks base method.
+func (m *MockStorage) Rollback(ctx context.Context, shortKey string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rollback", ctx, shortKey)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rollback indicates an expected call of Rollback.
+func (mr *MockStorageMockRecorder) Rollback(ctx, shortKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rollback", reflect.TypeOf((*MockStorage)(nil).Rollback), ctx, shortKey)
+}
+
+// SaveClicks mocks base method.
+func (m *MockStorage) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveClicks", ctx, events)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveClicks indicates an expected call of SaveClicks.
+func (mr *MockStorageMockRecorder) SaveClicks(ctx, events interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveClicks", reflect.TypeOf((*MockStorage)(nil).SaveClicks), ctx, events)
+}
+
 // Set mocks base method.
-func (m *MockStorage) Set(ctx context.Context, shortKey, url string) (string, error) {
+func (m *MockStorage) Set(ctx context.Context, link models.Link) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Set", ctx, shortKey, url)
+	ret := m.ctrl.Call(m, "Set", ctx, link)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Set indicates an expected call of Set.
-func (mr *MockStorageMockRecorder) Set(ctx, shortKey, url interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) Set(ctx, link interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockStorage)(nil).Set), ctx, link)
+}
+
+// SetBatch mocks base method.
+func (m *MockStorage) SetBatch(ctx context.Context, links []models.Link, atomic bool) ([]storage.BatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetBatch", ctx, links, atomic)
+	ret0, _ := ret[0].([]storage.BatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetBatch indicates an expected call of SetBatch.
+func (mr *MockStorageMockRecorder) SetBatch(ctx, links, atomic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetBatch", reflect.TypeOf((*MockStorage)(nil).SetBatch), ctx, links, atomic)
+}
+
+// Update mocks base method.
+func (m *MockStorage) Update(ctx context.Context, shortKey, url string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, shortKey, url)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockStorageMockRecorder) Update(ctx, shortKey, url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockStorage)(nil).Update), ctx, shortKey, url)
+}
+
+// UserLinks mocks base method.
+func (m *MockStorage) UserLinks(ctx context.Context) storage.UserLinkSeq {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserLinks", ctx)
+	ret0, _ := ret[0].(storage.UserLinkSeq)
+	return ret0
+}
+
+// UserLinks indicates an expected call of UserLinks.
+func (mr *MockStorageMockRecorder) UserLinks(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockStorage)(nil).Set), ctx, shortKey, url)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserLinks", reflect.TypeOf((*MockStorage)(nil).UserLinks), ctx)
 }
@@ -0,0 +1,266 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// undefinedTable — код ошибки PostgreSQL при обращении к несуществующей таблице.
+const undefinedTable = "42P01"
+
+// migrationLockID — ключ рекомендательной блокировки PostgreSQL, под которой применяются миграции.
+// Блокировка не даёт нескольким экземплярам сервиса одновременно изменять схему.
+const migrationLockID int64 = 7_305_512_604
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrInvalidMigration возвращается, если набор файлов миграций некорректен.
+var ErrInvalidMigration = errors.New("invalid migration")
+
+// Migration описывает одну версию схемы базы данных.
+// Файлы миграции называются <версия>_<название>.up.sql и <версия>_<название>.down.sql.
+type Migration struct {
+	Version int    // Номер версии схемы
+	Name    string // Название миграции
+	Up      string // SQL для перехода на версию
+	Down    string // SQL для отката версии
+}
+
+// MigrationState описывает состояние миграции в базе данных.
+type MigrationState struct {
+	Migration
+	AppliedAt time.Time // Момент применения; нулевое значение — миграция не применена
+}
+
+// Applied сообщает, применена ли миграция.
+func (m MigrationState) Applied() bool {
+	return !m.AppliedAt.IsZero()
+}
+
+// Migrations возвращает встроенные миграции схемы в порядке возрастания версий.
+func Migrations() ([]Migration, error) {
+	return LoadMigrations(migrationFiles, "migrations")
+}
+
+// LoadMigrations читает миграции из каталога dir файловой системы fsys.
+// У каждой версии должны быть оба файла — up и down, версии не должны повторяться.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		ext := path.Ext(base)
+		base = strings.TrimSuffix(base, ext)
+		versionPart, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionPart)
+		if !ok || err != nil || version <= 0 || (ext != ".up" && ext != ".down") {
+			return nil, fmt.Errorf("%w: unexpected file name %q", ErrInvalidMigration, entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("%w: version %d has different names %q and %q", ErrInvalidMigration, version, migration.Name, name)
+		}
+		if ext == ".up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: version %d must have both up and down files", ErrInvalidMigration, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// MigrateUp применяет все ещё не применённые миграции по возрастанию версий
+// и возвращает количество применённых миграций.
+// Каждая миграция выполняется в отдельной транзакции вместе с записью в schema_migrations.
+func (s StorageDB) MigrateUp(ctx context.Context) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = s.withMigrationLock(ctx, func(conn *sql.Conn, applied map[int]time.Time) error {
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := applyMigration(ctx, conn, migration.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+			if err != nil {
+				return fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// MigrateDown откатывает steps последних применённых миграций по убыванию версий
+// и возвращает количество откаченных миграций.
+func (s StorageDB) MigrateDown(ctx context.Context, steps int) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = s.withMigrationLock(ctx, func(conn *sql.Conn, applied map[int]time.Time) error {
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			err := applyMigration(ctx, conn, migration.Down,
+				"DELETE FROM schema_migrations WHERE version=$1", migration.Version)
+			if err != nil {
+				return fmt.Errorf("revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			count++
+		}
+		return nil
+	})
+
+	return count, err
+}
+
+// MigrationStatus возвращает все встроенные миграции с отметкой о применении.
+// Таблица schema_migrations читается без блокировки миграций, поэтому состояние можно узнать
+// и во время применения миграций другим экземпляром; если таблицы ещё нет, ни одна миграция не применена.
+func (s StorageDB) MigrationStatus(ctx context.Context) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(ctx, s.conn)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == undefinedTable {
+		applied, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		result = append(result, MigrationState{Migration: migration, AppliedAt: applied[migration.Version]})
+	}
+
+	return result, nil
+}
+
+// withMigrationLock выполняет fn на отдельном соединении под рекомендательной блокировкой
+// и передаёт ему применённые версии схемы из таблицы schema_migrations.
+func (s StorageDB) withMigrationLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int]time.Time) error) error {
+	conn, err := s.conn.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations(
+			version integer PRIMARY KEY,
+			name text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// appliedMigrations возвращает применённые версии схемы из таблицы schema_migrations с моментами применения.
+func appliedMigrations(ctx context.Context, q queryer) (map[int]time.Time, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// applyMigration выполняет SQL миграции и изменяет schema_migrations запросом record в одной транзакции.
+func applyMigration(ctx context.Context, conn *sql.Conn, migration string, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, migration); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS storage(
+	user_id varchar(36),
+	short_key varchar(128),
+	url text UNIQUE,
+	is_deleted boolean DEFAULT false
+);
+CREATE UNIQUE INDEX IF NOT EXISTS short_key_idx ON storage (short_key);
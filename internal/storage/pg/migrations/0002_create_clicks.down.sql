@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS clicks;
+ALTER TABLE storage DROP COLUMN IF EXISTS clicks;
@@ -0,0 +1,9 @@
+ALTER TABLE storage ADD COLUMN IF NOT EXISTS clicks bigint NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS clicks(
+	id bigserial PRIMARY KEY,
+	short_key varchar(128) NOT NULL,
+	created_at timestamptz NOT NULL,
+	referrer text,
+	user_agent text
+);
+CREATE INDEX IF NOT EXISTS clicks_short_key_created_at_idx ON clicks (short_key, created_at);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS storage_history(
+	id bigserial PRIMARY KEY,
+	short_key varchar(128) NOT NULL,
+	url text NOT NULL,
+	changed_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS storage_history_short_key_idx ON storage_history (short_key);
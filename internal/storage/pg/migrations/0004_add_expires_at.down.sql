@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS storage_expires_at_idx;
+ALTER TABLE storage DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,2 @@
+ALTER TABLE storage ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+CREATE INDEX IF NOT EXISTS storage_expires_at_idx ON storage (expires_at) WHERE expires_at IS NOT NULL;
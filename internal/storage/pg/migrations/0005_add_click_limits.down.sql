@@ -0,0 +1,3 @@
+ALTER TABLE storage
+	DROP COLUMN IF EXISTS clicks_left,
+	DROP COLUMN IF EXISTS max_clicks;
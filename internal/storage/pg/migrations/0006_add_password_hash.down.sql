@@ -0,0 +1 @@
+ALTER TABLE storage DROP COLUMN IF EXISTS password_hash;
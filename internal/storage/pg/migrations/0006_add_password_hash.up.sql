@@ -0,0 +1 @@
+ALTER TABLE storage ADD COLUMN IF NOT EXISTS password_hash text NOT NULL DEFAULT '';
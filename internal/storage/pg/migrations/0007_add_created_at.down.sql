@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS storage_user_id_created_at_idx;
+ALTER TABLE storage DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,2 @@
+ALTER TABLE storage ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT now();
+CREATE INDEX IF NOT EXISTS storage_user_id_created_at_idx ON storage (user_id, created_at);
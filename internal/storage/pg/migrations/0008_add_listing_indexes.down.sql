@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS storage_user_id_created_at_idx ON storage (user_id, created_at);
+DROP INDEX IF EXISTS storage_user_id_clicks_short_key_idx;
+DROP INDEX IF EXISTS storage_user_id_created_at_short_key_idx;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS storage_tags_idx;
+ALTER TABLE storage DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,2 @@
+ALTER TABLE storage ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS storage_tags_idx ON storage USING GIN (tags);
@@ -0,0 +1,3 @@
+ALTER TABLE storage ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+UPDATE storage SET deleted_at = now() WHERE is_deleted AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS storage_deleted_at_idx ON storage (deleted_at) WHERE is_deleted;
@@ -3,10 +3,28 @@ package pg
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/dsemenov12/shorturl/internal/auth"
 	"github.com/dsemenov12/shorturl/internal/config"
 	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/tags"
+)
+
+const (
+	// uniqueViolation — код ошибки PostgreSQL при нарушении ограничения уникальности.
+	uniqueViolation = "23505"
+	// shortKeyIndex — имя уникального индекса по короткому ключу.
+	shortKeyIndex = "short_key_idx"
+	// importChunkSize — количество ссылок в одном многострочном INSERT при импорте.
+	// Ограничивает количество параметров запроса (8 на ссылку) пределом протокола PostgreSQL.
+	importChunkSize = 1000
 )
 
 // StorageItem представляет структуру для хранения данных в базе данных (PostgreSQL).
@@ -27,73 +45,245 @@ func NewStorage(conn *sql.DB) *StorageDB {
 	return &StorageDB{conn: conn}
 }
 
-// Bootstrap создает необходимые таблицы и индексы в базе данных при старте приложения.
+// Bootstrap приводит схему базы данных к последней версии, применяя встроенные миграции.
 func (s StorageDB) Bootstrap(ctx context.Context) error {
+	_, err := s.MigrateUp(ctx)
+	return err
+}
+
+// Ping проверяет соединение с базой данных.
+func (s StorageDB) Ping(ctx context.Context) error {
+	return s.conn.PingContext(ctx)
+}
+
+// Set сохраняет ссылку в базе данных.
+// Владельцем становится link.UserID, а если он не задан — пользователь из контекста.
+// При нарушении уникальности короткого ключа возвращает storage.ErrKeyExists,
+// при нарушении уникальности URL — существующий короткий ключ и storage.ErrConflict.
+func (s StorageDB) Set(ctx context.Context, link models.Link) (shortKeyResult string, err error) {
+	if link.UserID == "" {
+		link.UserID = auth.UserIDFromContext(ctx)
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC()
+	}
+
+	_, err = s.conn.ExecContext(ctx, "INSERT INTO storage (short_key, url, user_id, created_at, expires_at, max_clicks, clicks_left, password_hash, tags) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $6, $7, string_to_array($8, ','))",
+		link.ShortKey, link.OriginalURL, nullString(link.UserID), link.CreatedAt, nullTime(link.ExpiresAt), link.MaxClicks, link.PasswordHash, tags.Join(link.Tags))
+	if err == nil {
+		return link.ShortKey, nil
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return "", err
+	}
+	if pgErr.ConstraintName == shortKeyIndex {
+		return "", storage.ErrKeyExists
+	}
+
+	row := s.conn.QueryRowContext(ctx, "SELECT short_key FROM storage WHERE url=$1", link.OriginalURL)
+	if err = row.Scan(&shortKeyResult); err != nil {
+		return "", err
+	}
+
+	return shortKeyResult, storage.ErrConflict
+}
+
+// SetBatch сохраняет пакет ссылок в одной транзакции многострочными запросами
+// INSERT ... ON CONFLICT DO NOTHING по importChunkSize ссылок.
+func (s StorageDB) SetBatch(ctx context.Context, links []models.Link, atomic bool) ([]storage.BatchResult, error) {
 	tx, err := s.conn.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, `
-		CREATE TABLE IF NOT EXISTS storage(
-			user_id varchar(36),
-			short_key varchar(128),
-			url text UNIQUE,
-			is_deleted boolean DEFAULT false
-		)
-    `)
+	results := make([]storage.BatchResult, len(links))
+	for start := 0; start < len(links); start += importChunkSize {
+		end := min(start+importChunkSize, len(links))
+		if err = insertChunk(ctx, tx, links[start:end], results[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	if atomic {
+		for _, result := range results {
+			if errors.Is(result.Err, storage.ErrKeyExists) {
+				return results, storage.ErrKeyExists
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Import сохраняет пакет ссылок многострочными запросами INSERT ... ON CONFLICT DO NOTHING
+// по importChunkSize ссылок вместо отдельного запроса на каждую ссылку.
+// Причина, по которой ссылка не вставлена, определяется одним запросом существующих URL на весь фрагмент.
+func (s StorageDB) Import(ctx context.Context, links []models.Link) ([]error, error) {
+	results := make([]storage.BatchResult, len(links))
+	for start := 0; start < len(links); start += importChunkSize {
+		end := min(start+importChunkSize, len(links))
+		if err := insertChunk(ctx, s.conn, links[start:end], results[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	errs := make([]error, len(links))
+	for i, result := range results {
+		errs[i] = result.Err
+	}
+
+	return errs, nil
+}
+
+// queryer выполняет запросы в базе данных или в транзакции.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// insertChunk вставляет ссылки одним запросом и записывает в results результаты по каждой ссылке.
+// Для невставленных ссылок один запрос на весь фрагмент находит уже сокращённые URL и их ключи.
+func insertChunk(ctx context.Context, q queryer, links []models.Link, results []storage.BatchResult) error {
+	owner := auth.UserIDFromContext(ctx)
+	now := time.Now().UTC()
+
+	values := make([]string, 0, len(links))
+	args := make([]any, 0, len(links)*8)
+	for _, link := range links {
+		if link.UserID == "" {
+			link.UserID = owner
+		}
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+		}
+		n := len(args)
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, string_to_array($%d, ','))", n+1, n+2, n+3, n+4, n+5, n+6, n+6, n+7, n+8))
+		args = append(args, link.ShortKey, link.OriginalURL, nullString(link.UserID), link.CreatedAt, nullTime(link.ExpiresAt), link.MaxClicks, link.PasswordHash, tags.Join(link.Tags))
+	}
+
+	rows, err := q.QueryContext(ctx, "INSERT INTO storage (short_key, url, user_id, created_at, expires_at, max_clicks, clicks_left, password_hash, tags) VALUES "+
+		strings.Join(values, ", ")+" ON CONFLICT DO NOTHING RETURNING short_key, url", args...)
 	if err != nil {
 		return err
 	}
-	_, err = tx.ExecContext(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS short_key_idx ON storage (short_key)`)
-	if err != nil {
+	defer rows.Close()
+
+	inserted := make(map[[2]string]bool, len(links))
+	for rows.Next() {
+		var shortKey, url string
+		if err = rows.Scan(&shortKey, &url); err != nil {
+			return err
+		}
+		inserted[[2]string{shortKey, url}] = true
+	}
+	if err = rows.Err(); err != nil {
 		return err
 	}
+	rows.Close()
 
-	return tx.Commit()
-}
+	var failedURLs []string
+	for i, link := range links {
+		// Вставленной считается только первая из одинаковых ссылок пакета
+		key := [2]string{link.ShortKey, link.OriginalURL}
+		if inserted[key] {
+			delete(inserted, key)
+			results[i] = storage.BatchResult{ShortKey: link.ShortKey}
+			continue
+		}
+		results[i] = storage.BatchResult{Err: storage.ErrKeyExists}
+		failedURLs = append(failedURLs, link.OriginalURL)
+	}
+	if len(failedURLs) == 0 {
+		return nil
+	}
 
-// Set сохраняет пару сокращённый URL и оригинальный URL в базе данных.
-func (s StorageDB) Set(ctx context.Context, shortKey string, url string) (shortKeyResult string, err error) {
-	_, err = s.conn.ExecContext(ctx, "INSERT INTO storage (short_key, url, user_id) VALUES ($1, $2, $3)", shortKey, url, ctx.Value(auth.UserIDKey))
+	rows, err = q.QueryContext(ctx, "SELECT url, short_key FROM storage WHERE url = ANY($1)", failedURLs)
 	if err != nil {
-		row := s.conn.QueryRowContext(ctx, "SELECT short_key FROM storage WHERE url=$1", url)
-		row.Scan(&shortKeyResult)
-	} else {
-		shortKeyResult = shortKey
+		return err
 	}
+	defer rows.Close()
 
-	return shortKeyResult, err
+	existing := make(map[string]string, len(failedURLs))
+	for rows.Next() {
+		var url, shortKey string
+		if err = rows.Scan(&url, &shortKey); err != nil {
+			return err
+		}
+		existing[url] = shortKey
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i, link := range links {
+		if shortKey, ok := existing[link.OriginalURL]; ok && results[i].Err != nil {
+			results[i] = storage.BatchResult{ShortKey: shortKey, Err: storage.ErrConflict}
+		}
+	}
+
+	return nil
 }
 
-// Get извлекает оригинальный URL по сокращённому URL из базы данных.
-func (s StorageDB) Get(ctx context.Context, shortKey string) (redirectLink string, shortKeyRes string, isDeleted bool, err error) {
-	row := s.conn.QueryRowContext(ctx, "SELECT url, short_key, is_deleted FROM storage WHERE short_key=$1", shortKey)
-	err = row.Scan(&redirectLink, &shortKeyRes, &isDeleted)
-	return
+// Get извлекает ссылку по сокращённому URL из базы данных.
+func (s StorageDB) Get(ctx context.Context, shortKey string) (link models.Link, err error) {
+	var userID sql.NullString
+	var expiresAt, deletedAt sql.NullTime
+	var linkTags string
+
+	row := s.conn.QueryRowContext(ctx, "SELECT url, short_key, user_id, created_at, is_deleted, deleted_at, expires_at, max_clicks, clicks_left, password_hash, array_to_string(tags, ',') "+
+		"FROM storage WHERE short_key=$1", shortKey)
+	err = row.Scan(&link.OriginalURL, &link.ShortKey, &userID, &link.CreatedAt, &link.IsDeleted, &deletedAt, &expiresAt, &link.MaxClicks, &link.ClicksLeft, &link.PasswordHash, &linkTags)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Link{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Link{}, err
+	}
+	link.UserID = userID.String
+	link.Tags = tags.Split(linkTags)
+	if expiresAt.Valid {
+		link.ExpiresAt = expiresAt.Time
+	}
+	if deletedAt.Valid {
+		link.DeletedAt = deletedAt.Time
+	}
+
+	return link, nil
 }
 
-// GetUserURL извлекает список всех сокращённых URL для текущего пользователя.
+// GetUserURL извлекает неудалённые сокращённые URL текущего пользователя в порядке их создания.
 func (s StorageDB) GetUserURL(ctx context.Context) (result []models.ShortURLItem, err error) {
 	var shortKey string
 	var originalURL string
+	var maxClicks, clicksLeft int64
 
-	rows, err := s.conn.QueryContext(ctx, "SELECT short_key, url FROM storage WHERE user_id=$1", ctx.Value(auth.UserIDKey))
+	rows, err := s.conn.QueryContext(ctx, "SELECT short_key, url, max_clicks, clicks_left FROM storage WHERE user_id=$1 AND is_deleted=false ORDER BY created_at, short_key", ctx.Value(auth.UserIDKey))
 	if err != nil {
 		return nil, err
 	}
 
 	for rows.Next() {
-		err = rows.Scan(&shortKey, &originalURL)
+		err = rows.Scan(&shortKey, &originalURL, &maxClicks, &clicksLeft)
 		if err != nil {
 			continue
 		}
 
-		result = append(result, models.ShortURLItem{
+		item := models.ShortURLItem{
 			OriginalURL: originalURL,
 			ShortURL:    config.FlagBaseAddr + "/" + shortKey,
-		})
+		}
+		if maxClicks > 0 {
+			left := clicksLeft
+			item.ClicksLeft = &left
+		}
+		result = append(result, item)
 	}
 
 	err = rows.Err()
@@ -104,15 +294,158 @@ func (s StorageDB) GetUserURL(ctx context.Context) (result []models.ShortURLItem
 	return result, nil
 }
 
+// ListUserURLs возвращает страницу ссылок текущего пользователя.
+// Страница выбирается по ключу (keyset pagination): вместо OFFSET запрос начинается
+// со значений полей сортировки из курсора, поэтому использует индексы (user_id, created_at, short_key)
+// и (user_id, clicks, short_key), и его стоимость не зависит от номера страницы.
+// Фильтр по метке использует GIN-индекс по столбцу tags.
+func (s StorageDB) ListUserURLs(ctx context.Context, query models.ListQuery) (models.ShortURLPage, error) {
+	query, after, err := storage.NormalizeListQuery(query)
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	userID := auth.UserIDFromContext(ctx)
+	if userID == "" {
+		return models.ShortURLPage{}, ctx.Err()
+	}
+
+	conditions := []string{"user_id=$1"}
+	args := []any{userID}
+	switch query.Deleted {
+	case models.DeletedExclude:
+		conditions = append(conditions, "is_deleted=false")
+	case models.DeletedOnly:
+		conditions = append(conditions, "is_deleted=true")
+	}
+	if query.Search != "" {
+		args = append(args, query.Search)
+		conditions = append(conditions, fmt.Sprintf("strpos(lower(url), lower($%d)) > 0", len(args)))
+	}
+	if query.Tag != "" {
+		args = append(args, query.Tag)
+		conditions = append(conditions, fmt.Sprintf("tags @> ARRAY[$%d]::text[]", len(args)))
+	}
+
+	var page models.ShortURLPage
+	err = s.conn.QueryRowContext(ctx, "SELECT count(*) FROM storage WHERE "+strings.Join(conditions, " AND "), args...).Scan(&page.Total)
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	column, direction, comparison := "created_at", "ASC", ">"
+	if query.Sort == models.ListSortClicks {
+		column = "clicks"
+	}
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if after != nil {
+		var value any = after.CreatedAt
+		if query.Sort == models.ListSortClicks {
+			value = after.Clicks
+		}
+		args = append(args, value, after.ShortKey)
+		conditions = append(conditions, fmt.Sprintf("(%s, short_key) %s ($%d, $%d)", column, comparison, len(args)-1, len(args)))
+	}
+	args = append(args, query.Limit+1)
+
+	rows, err := s.conn.QueryContext(ctx, fmt.Sprintf(
+		"SELECT short_key, url, created_at, is_deleted, clicks, max_clicks, clicks_left, array_to_string(tags, ',') FROM storage WHERE %s ORDER BY %s %s, short_key %s LIMIT $%d",
+		strings.Join(conditions, " AND "), column, direction, direction, len(args),
+	), args...)
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+	defer rows.Close()
+
+	var last storage.ListCursor
+	for rows.Next() {
+		var link models.Link
+		var clicks int64
+		var linkTags string
+		if err = rows.Scan(&link.ShortKey, &link.OriginalURL, &link.CreatedAt, &link.IsDeleted, &clicks, &link.MaxClicks, &link.ClicksLeft, &linkTags); err != nil {
+			return models.ShortURLPage{}, err
+		}
+		link.Tags = tags.Split(linkTags)
+		if len(page.Items) == query.Limit {
+			page.NextCursor = last.Encode()
+			break
+		}
+		page.Items = append(page.Items, storage.NewListItem(link, clicks))
+		last = storage.NewListCursor(query, link, clicks)
+	}
+	if err = rows.Err(); err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	return page, nil
+}
+
+// UserLinks возвращает итератор по ссылкам текущего пользователя.
+// Ссылки читаются из курсора запроса по мере итерации, не загружаясь в память целиком.
+func (s StorageDB) UserLinks(ctx context.Context) storage.UserLinkSeq {
+	return func(yield func(models.UserLink, error) bool) {
+		userID := auth.UserIDFromContext(ctx)
+		if userID == "" {
+			if err := ctx.Err(); err != nil {
+				yield(models.UserLink{}, err)
+			}
+			return
+		}
+
+		rows, err := s.conn.QueryContext(ctx, "SELECT short_key, url, created_at, is_deleted, expires_at, max_clicks, clicks_left, clicks, array_to_string(tags, ',') "+
+			"FROM storage WHERE user_id=$1 ORDER BY created_at, short_key", userID)
+		if err != nil {
+			yield(models.UserLink{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			item := models.UserLink{Link: models.Link{UserID: userID}}
+			var expiresAt sql.NullTime
+			var linkTags string
+			err = rows.Scan(&item.Link.ShortKey, &item.Link.OriginalURL, &item.Link.CreatedAt, &item.Link.IsDeleted,
+				&expiresAt, &item.Link.MaxClicks, &item.Link.ClicksLeft, &item.Clicks, &linkTags)
+			if err != nil {
+				yield(models.UserLink{}, err)
+				return
+			}
+			item.Link.Tags = tags.Split(linkTags)
+			if expiresAt.Valid {
+				item.Link.ExpiresAt = expiresAt.Time
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(models.UserLink{}, err)
+		}
+	}
+}
+
 // Delete помечает запись как удалённую в базе данных по сокращённому URL.
 func (s StorageDB) Delete(ctx context.Context, shortKey string) error {
-	_, err := s.conn.ExecContext(ctx, "UPDATE storage SET is_deleted=true WHERE short_key=$1 AND user_id=$2", shortKey, ctx.Value(auth.UserIDKey))
+	_, err := s.conn.ExecContext(ctx, "UPDATE storage SET is_deleted=true, deleted_at=now() WHERE short_key=$1 AND user_id=$2 AND is_deleted=false", shortKey, ctx.Value(auth.UserIDKey))
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// DeleteBatch помечает ссылки пользователя как удалённые одним запросом UPDATE ... WHERE short_key = ANY($1).
+func (s StorageDB) DeleteBatch(ctx context.Context, shortKeys []string) error {
+	if len(shortKeys) == 0 {
+		return nil
+	}
+
+	_, err := s.conn.ExecContext(ctx, "UPDATE storage SET is_deleted=true, deleted_at=now() WHERE short_key = ANY($1) AND user_id=$2 AND is_deleted=false",
+		shortKeys, auth.UserIDFromContext(ctx))
+	return err
+}
+
 // CountURLs возвращает количество уникальных URL в базе данных.
 func (s StorageDB) CountURLs(ctx context.Context) (int, error) {
 	var count int
@@ -133,3 +466,370 @@ func (s StorageDB) CountUsers(ctx context.Context) (int, error) {
 	}
 	return count, nil
 }
+
+// SaveClicks сохраняет события перехода в таблицу clicks и увеличивает счётчики переходов
+// в таблице storage. Все изменения выполняются в одной транзакции.
+func (s StorageDB) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO clicks (short_key, created_at, referrer, user_agent) VALUES ($1, $2, $3, $4)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	counts := make(map[string]int64)
+	keys := make([]string, 0)
+	for _, event := range events {
+		_, err = stmt.ExecContext(ctx, event.ShortKey, event.Timestamp, event.Referrer, event.UserAgent)
+		if err != nil {
+			return err
+		}
+		if _, ok := counts[event.ShortKey]; !ok {
+			keys = append(keys, event.ShortKey)
+		}
+		counts[event.ShortKey]++
+	}
+
+	for _, key := range keys {
+		_, err = tx.ExecContext(ctx, "UPDATE storage SET clicks = clicks + $1 WHERE short_key=$2", counts[key], key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetStats возвращает счётчик переходов по короткой ссылке и временной ряд переходов,
+// сгруппированный на стороне базы данных по интервалам длиной step.
+func (s StorageDB) GetStats(ctx context.Context, shortKey string, from, to time.Time, step time.Duration) (total int64, series []models.StatsBucket, err error) {
+	row := s.conn.QueryRowContext(ctx, "SELECT clicks FROM storage WHERE short_key=$1", shortKey)
+	if err = row.Scan(&total); err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := s.conn.QueryContext(ctx, `
+		SELECT to_timestamp(floor(extract(epoch FROM created_at) / $2) * $2) AS bucket, COUNT(*)
+		FROM clicks
+		WHERE short_key=$1 AND created_at >= $3 AND created_at < $4
+		GROUP BY bucket
+		ORDER BY bucket
+	`, shortKey, int64(step/time.Second), from, to)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket models.StatsBucket
+		if err = rows.Scan(&bucket.Time, &bucket.Clicks); err != nil {
+			return 0, nil, err
+		}
+		bucket.Time = bucket.Time.UTC()
+		series = append(series, bucket)
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return total, series, nil
+}
+
+// Update заменяет оригинальный URL короткой ссылки текущего пользователя.
+// Предыдущее значение сохраняется в таблице storage_history в той же транзакции.
+func (s StorageDB) Update(ctx context.Context, shortKey string, url string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockOwnedURL(ctx, tx, shortKey)
+	if err != nil {
+		return err
+	}
+	if current == url {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO storage_history (short_key, url) VALUES ($1, $2)", shortKey, current)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE storage SET url=$1 WHERE short_key=$2", url, shortKey)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return tx.Commit()
+}
+
+// GetHistory возвращает историю изменений короткой ссылки текущего пользователя.
+func (s StorageDB) GetHistory(ctx context.Context, shortKey string) ([]models.HistoryItem, error) {
+	var userID sql.NullString
+
+	err := s.conn.QueryRowContext(ctx, "SELECT user_id FROM storage WHERE short_key=$1", shortKey).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userID.String != ctx.Value(auth.UserIDKey) {
+		return nil, storage.ErrForbidden
+	}
+
+	rows, err := s.conn.QueryContext(ctx, "SELECT url, changed_at FROM storage_history WHERE short_key=$1 ORDER BY id DESC", shortKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.HistoryItem, 0)
+	for rows.Next() {
+		var item models.HistoryItem
+		if err = rows.Scan(&item.OriginalURL, &item.ChangedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Rollback восстанавливает последнее значение из истории изменений короткой ссылки
+// текущего пользователя и удаляет его из истории.
+func (s StorageDB) Rollback(ctx context.Context, shortKey string) (string, error) {
+	var historyID int64
+	var previous string
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err = lockOwnedURL(ctx, tx, shortKey); err != nil {
+		return "", err
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT id, url FROM storage_history WHERE short_key=$1 ORDER BY id DESC LIMIT 1", shortKey)
+	err = row.Scan(&historyID, &previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrNoHistory
+	}
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE storage SET url=$1 WHERE short_key=$2", previous, shortKey)
+	if err != nil {
+		return "", convertError(err)
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM storage_history WHERE id=$1", historyID)
+	if err != nil {
+		return "", err
+	}
+
+	return previous, tx.Commit()
+}
+
+// DeleteExpired удаляет ссылки, срок действия которых истёк к моменту now.
+// При purge=true строки удаляются вместе с переходами и историей изменений,
+// иначе ссылки помечаются как удалённые.
+func (s StorageDB) DeleteExpired(ctx context.Context, now time.Time, purge bool) (int64, error) {
+	if !purge {
+		result, err := s.conn.ExecContext(ctx, "UPDATE storage SET is_deleted=true, deleted_at=$1 WHERE expires_at <= $1 AND is_deleted=false", now)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM clicks WHERE short_key IN (SELECT short_key FROM storage WHERE expires_at <= $1)", now)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM storage_history WHERE short_key IN (SELECT short_key FROM storage WHERE expires_at <= $1)", now)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM storage WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// Restore снимает пометку удаления со ссылки, заблокировав её строку до конца транзакции.
+func (s StorageDB) Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (string, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var url string
+	var userID sql.NullString
+	var isDeleted bool
+	var deletedAt sql.NullTime
+
+	row := tx.QueryRowContext(ctx, "SELECT url, user_id, is_deleted, deleted_at FROM storage WHERE short_key=$1 FOR UPDATE", shortKey)
+	err = row.Scan(&url, &userID, &isDeleted, &deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case userID.String != auth.UserIDFromContext(ctx):
+		return "", storage.ErrForbidden
+	case !isDeleted:
+		return "", storage.ErrNotDeleted
+	case !deletedAt.Valid || deletedAt.Time.Before(deletedAfter):
+		return "", storage.ErrRestoreExpired
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE storage SET is_deleted=false, deleted_at=NULL WHERE short_key=$1", shortKey); err != nil {
+		return "", err
+	}
+
+	return url, tx.Commit()
+}
+
+// PurgeDeleted удаляет строки ссылок, помеченных удалёнными раньше момента before,
+// вместе с переходами и историей изменений в одной транзакции.
+func (s StorageDB) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM clicks WHERE short_key IN (SELECT short_key FROM storage WHERE is_deleted AND deleted_at < $1)", before)
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM storage_history WHERE short_key IN (SELECT short_key FROM storage WHERE is_deleted AND deleted_at < $1)", before)
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM storage WHERE is_deleted AND deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// Consume уменьшает оставшееся количество переходов по ссылке одним условным запросом UPDATE,
+// поэтому параллельные переходы не могут превысить лимит.
+func (s StorageDB) Consume(ctx context.Context, shortKey string) error {
+	result, err := s.conn.ExecContext(ctx, "UPDATE storage SET clicks_left = clicks_left - 1 WHERE short_key=$1 AND max_clicks > 0 AND clicks_left > 0", shortKey)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return s.consumeFailure(ctx, shortKey)
+	}
+
+	return nil
+}
+
+// consumeFailure определяет, почему Consume не уменьшил остаток переходов по ссылке:
+// ссылки нет, у неё нет ограничения или лимит исчерпан.
+func (s StorageDB) consumeFailure(ctx context.Context, shortKey string) error {
+	var maxClicks int64
+	err := s.conn.QueryRowContext(ctx, "SELECT max_clicks FROM storage WHERE short_key=$1", shortKey).Scan(&maxClicks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if maxClicks == 0 {
+		return nil
+	}
+
+	return storage.ErrExhausted
+}
+
+// nullTime преобразует нулевое время в NULL для записи в базу данных.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// nullString преобразует пустую строку в NULL для записи в базу данных.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// lockOwnedURL блокирует строку короткой ссылки до конца транзакции и возвращает текущий URL.
+// Возвращает ошибку, если ссылка не найдена, удалена или принадлежит другому пользователю.
+func lockOwnedURL(ctx context.Context, tx *sql.Tx, shortKey string) (string, error) {
+	var url string
+	var userID sql.NullString
+	var isDeleted bool
+
+	row := tx.QueryRowContext(ctx, "SELECT url, user_id, is_deleted FROM storage WHERE short_key=$1 FOR UPDATE", shortKey)
+	err := row.Scan(&url, &userID, &isDeleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if userID.String != ctx.Value(auth.UserIDKey) {
+		return "", storage.ErrForbidden
+	}
+	if isDeleted {
+		return "", storage.ErrDeleted
+	}
+
+	return url, nil
+}
+
+// convertError преобразует ошибку нарушения уникальности URL в storage.ErrConflict.
+func convertError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return storage.ErrConflict
+	}
+	return err
+}
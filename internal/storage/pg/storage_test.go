@@ -2,11 +2,23 @@ package pg
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"os"
+	"regexp"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/dsemenov12/shorturl/internal/auth"
 	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/storagetest"
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -18,12 +30,20 @@ func TestStorageDB_Bootstrap(t *testing.T) {
 	storage := NewStorage(db)
 	ctx := context.Background()
 
-	mock.ExpectBegin()
-	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS storage`).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec(`CREATE UNIQUE INDEX IF NOT EXISTS short_key_idx`).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectCommit()
+	migrations, err := Migrations()
+	assert.NoError(t, err)
+
+	expectMigrationLock(mock)
+	for _, migration := range migrations {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(migration.Up)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(migration.Version, migration.Name).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	expectMigrationUnlock(mock)
 
 	err = storage.Bootstrap(ctx)
 	assert.NoError(t, err)
@@ -31,6 +51,177 @@ func TestStorageDB_Bootstrap(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestStorageDB_MigrateUp(t *testing.T) {
+	migrations, err := Migrations()
+	assert.NoError(t, err)
+	last := migrations[len(migrations)-1]
+
+	t.Run("applied migrations are skipped", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		applied := make([]int, 0, len(migrations)-1)
+		for _, migration := range migrations[:len(migrations)-1] {
+			applied = append(applied, migration.Version)
+		}
+
+		expectMigrationLock(mock, applied...)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(last.Up)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(last.Version, last.Name).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		expectMigrationUnlock(mock)
+
+		count, err := NewStorage(db).MigrateUp(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed migration is rolled back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+
+		expectMigrationLock(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(migrations[0].Up)).
+			WillReturnError(errors.New("syntax error"))
+		mock.ExpectRollback()
+		expectMigrationUnlock(mock)
+
+		count, err := NewStorage(db).MigrateUp(context.Background())
+		assert.ErrorContains(t, err, "apply migration 1_create_storage")
+		assert.Equal(t, 0, count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestStorageDB_MigrateDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	migrations, err := Migrations()
+	assert.NoError(t, err)
+
+	applied := make([]int, 0, len(migrations))
+	for _, migration := range migrations {
+		applied = append(applied, migration.Version)
+	}
+
+	expectMigrationLock(mock, applied...)
+	for _, migration := range []Migration{migrations[len(migrations)-1], migrations[len(migrations)-2]} {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(migration.Down)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM schema_migrations").
+			WithArgs(migration.Version).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+	expectMigrationUnlock(mock)
+
+	count, err := NewStorage(db).MigrateDown(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_MigrationStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	t.Run("applied migrations", func(t *testing.T) {
+		mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+		status, err := NewStorage(db).MigrationStatus(context.Background())
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, status) {
+			assert.True(t, status[0].Applied())
+			assert.False(t, status[len(status)-1].Applied())
+		}
+		assert.NoError(t, mock.ExpectationsWereMet(), "Status should not take the migration lock")
+	})
+
+	t.Run("missing schema_migrations", func(t *testing.T) {
+		mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+			WillReturnError(&pgconn.PgError{Code: undefinedTable})
+
+		status, err := NewStorage(db).MigrationStatus(context.Background())
+		assert.NoError(t, err)
+		if assert.NotEmpty(t, status) {
+			assert.False(t, status[0].Applied())
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	assert.NoError(t, err)
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "Embedded migrations should have consecutive versions")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{"missing down", fstest.MapFS{
+			"m/0001_init.up.sql": {Data: []byte("SELECT 1")},
+		}},
+		{"bad version", fstest.MapFS{
+			"m/init.up.sql":   {Data: []byte("SELECT 1")},
+			"m/init.down.sql": {Data: []byte("SELECT 1")},
+		}},
+		{"bad direction", fstest.MapFS{
+			"m/0001_init.sideways.sql": {Data: []byte("SELECT 1")},
+		}},
+		{"different names", fstest.MapFS{
+			"m/0001_init.up.sql":  {Data: []byte("SELECT 1")},
+			"m/0001_other.up.sql": {Data: []byte("SELECT 1")},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadMigrations(test.files, "m")
+			assert.ErrorIs(t, err, ErrInvalidMigration)
+		})
+	}
+}
+
+// expectMigrationLock ожидает захват блокировки миграций и чтение применённых версий.
+func expectMigrationLock(mock sqlmock.Sqlmock, applied ...int) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range applied {
+		rows.AddRow(version, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(rows)
+}
+
+// expectMigrationUnlock ожидает освобождение блокировки миграций.
+func expectMigrationUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).
+		WithArgs(migrationLockID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
 func TestStorageDB_Set(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -43,16 +234,50 @@ func TestStorageDB_Set(t *testing.T) {
 	originalURL := "https://example.com"
 
 	mock.ExpectExec("INSERT INTO storage").
-		WithArgs(shortKey, originalURL, "test-user").
+		WithArgs(shortKey, originalURL, "test-user", sqlmock.AnyArg(), sql.NullTime{}, int64(0), "", "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	result, err := storage.Set(ctx, shortKey, originalURL)
+	result, err := storage.Set(ctx, models.Link{ShortKey: shortKey, OriginalURL: originalURL})
 	assert.NoError(t, err)
 	assert.Equal(t, shortKey, result)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestStorageDB_SetConflicts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+
+	t.Run("url already shortened", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO storage").
+			WithArgs("short123", "https://example.com", "test-user", sqlmock.AnyArg(), sql.NullTime{}, int64(0), "", "").
+			WillReturnError(&pgconn.PgError{Code: uniqueViolation, ConstraintName: "storage_url_key"})
+		mock.ExpectQuery("SELECT short_key FROM storage WHERE url").
+			WithArgs("https://example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"short_key"}).AddRow("existing"))
+
+		result, err := s.Set(ctx, models.Link{ShortKey: "short123", OriginalURL: "https://example.com"})
+		assert.ErrorIs(t, err, storage.ErrConflict)
+		assert.Equal(t, "existing", result)
+	})
+
+	t.Run("short key taken", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO storage").
+			WithArgs("short123", "https://example.com/2", "test-user", sqlmock.AnyArg(), sql.NullTime{}, int64(0), "", "").
+			WillReturnError(&pgconn.PgError{Code: uniqueViolation, ConstraintName: shortKeyIndex})
+
+		result, err := s.Set(ctx, models.Link{ShortKey: "short123", OriginalURL: "https://example.com/2"})
+		assert.ErrorIs(t, err, storage.ErrKeyExists)
+		assert.Empty(t, result)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestStorageDB_Get(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -64,16 +289,26 @@ func TestStorageDB_Get(t *testing.T) {
 	shortKey := "short123"
 	originalURL := "https://example.com"
 
-	mock.ExpectQuery("SELECT url, short_key, is_deleted FROM storage").
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT url, short_key, user_id, created_at, is_deleted, deleted_at, expires_at, max_clicks, clicks_left, password_hash, array_to_string(tags, ',') FROM storage")).
 		WithArgs(shortKey).
-		WillReturnRows(sqlmock.NewRows([]string{"url", "short_key", "is_deleted"}).
-			AddRow(originalURL, shortKey, false))
+		WillReturnRows(sqlmock.NewRows([]string{"url", "short_key", "user_id", "created_at", "is_deleted", "deleted_at", "expires_at", "max_clicks", "clicks_left", "password_hash", "tags"}).
+			AddRow(originalURL, shortKey, "test-user", createdAt, false, nil, expiresAt, 5, 3, "", "promo,news"))
 
-	url, key, deleted, err := storage.Get(ctx, shortKey)
+	link, err := storage.Get(ctx, shortKey)
 	assert.NoError(t, err)
-	assert.Equal(t, originalURL, url)
-	assert.Equal(t, shortKey, key)
-	assert.False(t, deleted)
+	assert.Equal(t, originalURL, link.OriginalURL)
+	assert.Equal(t, shortKey, link.ShortKey)
+	assert.Equal(t, "test-user", link.UserID)
+	assert.Equal(t, createdAt, link.CreatedAt)
+	assert.False(t, link.IsDeleted)
+	assert.Equal(t, expiresAt, link.ExpiresAt)
+	assert.Equal(t, int64(5), link.MaxClicks)
+	assert.Equal(t, int64(3), link.ClicksLeft)
+	assert.Equal(t, []string{"promo", "news"}, link.Tags)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -98,6 +333,145 @@ func TestStorageDB_Delete(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestStorageDB_DeleteBatch(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	storage := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE storage SET is_deleted=true, deleted_at=now() WHERE short_key = ANY($1) AND user_id=$2 AND is_deleted=false")).
+		WithArgs([]string{"short1", "short2"}, "test-user").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	assert.NoError(t, storage.DeleteBatch(ctx, []string{"short1", "short2"}))
+	assert.NoError(t, storage.DeleteBatch(ctx, nil), "Empty batch should not query the database")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// arrayConverter пропускает срезы строк без преобразования, как драйвер pgx, передающий их массивами PostgreSQL.
+type arrayConverter struct{}
+
+func (arrayConverter) ConvertValue(v any) (driver.Value, error) {
+	if values, ok := v.([]string); ok {
+		return values, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+func TestStorageDB_Import(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+
+	links := []models.Link{
+		{ShortKey: "short1", OriginalURL: "https://example.com/1"},
+		{ShortKey: "short2", OriginalURL: "https://example.com/2"},
+		{ShortKey: "short3", OriginalURL: "https://example.com/3", UserID: "owner"},
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO storage (short_key, url, user_id, created_at, expires_at, max_clicks, clicks_left, password_hash, tags) VALUES "+
+		"($1, $2, $3, $4, $5, $6, $6, $7, string_to_array($8, ',')), ($9, $10, $11, $12, $13, $14, $14, $15, string_to_array($16, ',')), "+
+		"($17, $18, $19, $20, $21, $22, $22, $23, string_to_array($24, ',')) "+
+		"ON CONFLICT DO NOTHING RETURNING short_key, url")).
+		WithArgs(
+			"short1", "https://example.com/1", sql.NullString{String: "test-user", Valid: true}, sqlmock.AnyArg(), sql.NullTime{}, int64(0), "", "",
+			"short2", "https://example.com/2", sql.NullString{String: "test-user", Valid: true}, sqlmock.AnyArg(), sql.NullTime{}, int64(0), "", "",
+			"short3", "https://example.com/3", sql.NullString{String: "owner", Valid: true}, sqlmock.AnyArg(), sql.NullTime{}, int64(0), "", "",
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"short_key", "url"}).AddRow("short1", "https://example.com/1"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT url, short_key FROM storage WHERE url = ANY($1)")).
+		WithArgs([]string{"https://example.com/2", "https://example.com/3"}).
+		WillReturnRows(sqlmock.NewRows([]string{"url", "short_key"}).AddRow("https://example.com/2", "existing2"))
+
+	errs, err := s.Import(ctx, links)
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil, storage.ErrConflict, storage.ErrKeyExists}, errs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_ListUserURLs(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := models.ListQuery{Limit: 2, Sort: models.ListSortClicks, Desc: true, Search: "example", Tag: "Promo"}
+	cursor := storage.ListCursor{Sort: models.ListSortClicks, Desc: true, Clicks: 10, ShortKey: "short0"}
+	query.Cursor = cursor.Encode()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM storage WHERE user_id=$1 AND is_deleted=false AND strpos(lower(url), lower($2)) > 0 AND tags @> ARRAY[$3]::text[]")).
+		WithArgs("test-user", "example", "promo").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT short_key, url, created_at, is_deleted, clicks, max_clicks, clicks_left, array_to_string(tags, ',') FROM storage "+
+		"WHERE user_id=$1 AND is_deleted=false AND strpos(lower(url), lower($2)) > 0 AND tags @> ARRAY[$3]::text[] AND (clicks, short_key) < ($4, $5) "+
+		"ORDER BY clicks DESC, short_key DESC LIMIT $6")).
+		WithArgs("test-user", "example", "promo", int64(10), "short0", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"short_key", "url", "created_at", "is_deleted", "clicks", "max_clicks", "clicks_left", "tags"}).
+			AddRow("short1", "https://example.com/1", createdAt, false, 7, 0, 0, "promo").
+			AddRow("short2", "https://example.com/2", createdAt, false, 5, 3, 1, "news,promo").
+			AddRow("short3", "https://example.com/3", createdAt, false, 5, 0, 0, "promo"))
+
+	page, err := s.ListUserURLs(ctx, query)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, page.Total)
+	if assert.Len(t, page.Items, 2) {
+		assert.Equal(t, "https://example.com/1", page.Items[0].OriginalURL)
+		assert.Equal(t, int64(7), *page.Items[0].Clicks)
+		assert.Equal(t, int64(1), *page.Items[1].ClicksLeft)
+		assert.Equal(t, []string{"news", "promo"}, page.Items[1].Tags)
+	}
+
+	next := storage.ListCursor{Sort: models.ListSortClicks, Desc: true, CreatedAt: createdAt, Clicks: 5, ShortKey: "short2"}
+	assert.Equal(t, next.Encode(), page.NextCursor, "Next cursor should point at the last returned link")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_UserLinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT short_key, url, created_at, is_deleted, expires_at, max_clicks, clicks_left, clicks, array_to_string(tags, ',') " +
+		"FROM storage WHERE user_id=$1 ORDER BY created_at, short_key")).
+		WithArgs("test-user").
+		WillReturnRows(sqlmock.NewRows([]string{"short_key", "url", "created_at", "is_deleted", "expires_at", "max_clicks", "clicks_left", "clicks", "tags"}).
+			AddRow("short1", "https://example.com/1", createdAt, false, nil, 0, 0, 7, "promo").
+			AddRow("short2", "https://example.com/2", createdAt, true, createdAt.Add(time.Hour), 3, 1, 2, "").
+			RowError(1, errors.New("connection reset")))
+
+	var items []models.UserLink
+	var iterErr error
+	for item, err := range s.UserLinks(ctx) {
+		if err != nil {
+			iterErr = err
+			break
+		}
+		items = append(items, item)
+	}
+
+	assert.EqualError(t, iterErr, "connection reset")
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, "short1", items[0].Link.ShortKey)
+		assert.Equal(t, "test-user", items[0].Link.UserID)
+		assert.Equal(t, int64(7), items[0].Clicks)
+		assert.True(t, items[0].Link.ExpiresAt.IsZero())
+		assert.Equal(t, []string{"promo"}, items[0].Link.Tags)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestStorageDB_GetUserURL(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	assert.NoError(t, err)
@@ -111,11 +485,11 @@ func TestStorageDB_GetUserURL(t *testing.T) {
 	shortKey2 := "short456"
 	originalURL2 := "https://example.com/2"
 
-	mock.ExpectQuery(`SELECT short_key, url FROM storage WHERE user_id=\$1`).
+	mock.ExpectQuery(`SELECT short_key, url, max_clicks, clicks_left FROM storage WHERE user_id=\$1 AND is_deleted=false ORDER BY created_at`).
 		WithArgs("test-user").
-		WillReturnRows(sqlmock.NewRows([]string{"short_key", "url"}).
-			AddRow(shortKey1, originalURL1).
-			AddRow(shortKey2, originalURL2))
+		WillReturnRows(sqlmock.NewRows([]string{"short_key", "url", "max_clicks", "clicks_left"}).
+			AddRow(shortKey1, originalURL1, 0, 0).
+			AddRow(shortKey2, originalURL2, 10, 4))
 
 	result, err := storage.GetUserURL(ctx)
 	assert.NoError(t, err)
@@ -125,6 +499,385 @@ func TestStorageDB_GetUserURL(t *testing.T) {
 	assert.Equal(t, result[0].OriginalURL, originalURL1)
 	assert.Equal(t, result[1].ShortURL, config.FlagBaseAddr+"/"+shortKey2)
 	assert.Equal(t, result[1].OriginalURL, originalURL2)
+	assert.Nil(t, result[0].ClicksLeft, "Unlimited link should not report remaining clicks")
+	if assert.NotNil(t, result[1].ClicksLeft) {
+		assert.Equal(t, int64(4), *result[1].ClicksLeft)
+	}
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestStorageDB_SaveClicks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	storage := NewStorage(db)
+	ctx := context.Background()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []models.ClickEvent{
+		{ShortKey: "short123", Timestamp: now, Referrer: "https://ref.example", UserAgent: "curl/8.0"},
+		{ShortKey: "short123", Timestamp: now.Add(time.Minute)},
+		{ShortKey: "short456", Timestamp: now},
+	}
+
+	mock.ExpectBegin()
+	prep := mock.ExpectPrepare("INSERT INTO clicks")
+	prep.ExpectExec().WithArgs("short123", now, "https://ref.example", "curl/8.0").WillReturnResult(sqlmock.NewResult(1, 1))
+	prep.ExpectExec().WithArgs("short123", now.Add(time.Minute), "", "").WillReturnResult(sqlmock.NewResult(2, 1))
+	prep.ExpectExec().WithArgs("short456", now, "", "").WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectExec("UPDATE storage SET clicks = clicks").WithArgs(int64(2), "short123").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE storage SET clicks = clicks").WithArgs(int64(1), "short456").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = storage.SaveClicks(ctx, events)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_GetStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	storage := NewStorage(db)
+	ctx := context.Background()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(48 * time.Hour)
+
+	mock.ExpectQuery("SELECT clicks FROM storage").
+		WithArgs("short123").
+		WillReturnRows(sqlmock.NewRows([]string{"clicks"}).AddRow(5))
+	mock.ExpectQuery("SELECT to_timestamp").
+		WithArgs("short123", int64(86400), from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "count"}).
+			AddRow(from, 3).
+			AddRow(from.Add(24*time.Hour), 2))
+
+	total, series, err := storage.GetStats(ctx, "short123", from, to, 24*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Equal(t, []models.StatsBucket{
+		{Time: from, Clicks: 3},
+		{Time: from.Add(24 * time.Hour), Clicks: 2},
+	}, series)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT url, user_id, is_deleted FROM storage").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "user_id", "is_deleted"}).
+				AddRow("https://example.com/old", "test-user", false))
+		mock.ExpectExec("INSERT INTO storage_history").
+			WithArgs("short123", "https://example.com/old").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE storage SET url").
+			WithArgs("https://example.com/new", "short123").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err = s.Update(ctx, "short123", "https://example.com/new")
+		assert.NoError(t, err)
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT url, user_id, is_deleted FROM storage").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "user_id", "is_deleted"}).
+				AddRow("https://example.com/old", "other-user", false))
+		mock.ExpectRollback()
+
+		err = s.Update(ctx, "short123", "https://example.com/new")
+		assert.ErrorIs(t, err, storage.ErrForbidden)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_Rollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT url, user_id, is_deleted FROM storage").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "user_id", "is_deleted"}).
+				AddRow("https://example.com/new", "test-user", false))
+		mock.ExpectQuery("SELECT id, url FROM storage_history").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "url"}).AddRow(7, "https://example.com/old"))
+		mock.ExpectExec("UPDATE storage SET url").
+			WithArgs("https://example.com/old", "short123").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM storage_history").
+			WithArgs(int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		url, err := s.Rollback(ctx, "short123")
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/old", url)
+	})
+
+	t.Run("no history", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT url, user_id, is_deleted FROM storage").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "user_id", "is_deleted"}).
+				AddRow("https://example.com/new", "test-user", false))
+		mock.ExpectQuery("SELECT id, url FROM storage_history").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "url"}))
+		mock.ExpectRollback()
+
+		_, err := s.Rollback(ctx, "short123")
+		assert.ErrorIs(t, err, storage.ErrNoHistory)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_Restore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+	deletedAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	t.Run("success", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT url, user_id, is_deleted, deleted_at FROM storage").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "user_id", "is_deleted", "deleted_at"}).
+				AddRow("https://example.com", "test-user", true, deletedAt))
+		mock.ExpectExec("UPDATE storage SET is_deleted=false, deleted_at=NULL").
+			WithArgs("short123").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		url, err := s.Restore(ctx, "short123", deletedAt.Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com", url)
+	})
+
+	t.Run("restore window expired", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT url, user_id, is_deleted, deleted_at FROM storage").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "user_id", "is_deleted", "deleted_at"}).
+				AddRow("https://example.com", "test-user", true, deletedAt))
+		mock.ExpectRollback()
+
+		_, err := s.Restore(ctx, "short123", deletedAt.Add(time.Hour))
+		assert.ErrorIs(t, err, storage.ErrRestoreExpired)
+	})
+
+	t.Run("not deleted", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT url, user_id, is_deleted, deleted_at FROM storage").
+			WithArgs("short123").
+			WillReturnRows(sqlmock.NewRows([]string{"url", "user_id", "is_deleted", "deleted_at"}).
+				AddRow("https://example.com", "test-user", false, nil))
+		mock.ExpectRollback()
+
+		_, err := s.Restore(ctx, "short123", deletedAt)
+		assert.ErrorIs(t, err, storage.ErrNotDeleted)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_PurgeDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM clicks WHERE short_key IN").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec("DELETE FROM storage_history WHERE short_key IN").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM storage WHERE is_deleted AND deleted_at < \\$1").
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	count, err := s.PurgeDeleted(context.Background(), before)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_DeleteExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("soft delete", func(t *testing.T) {
+		mock.ExpectExec("UPDATE storage SET is_deleted=true, deleted_at=\\$1 WHERE expires_at").
+			WithArgs(now).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		count, err := s.DeleteExpired(ctx, now, false)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM clicks").
+			WithArgs(now).
+			WillReturnResult(sqlmock.NewResult(0, 5))
+		mock.ExpectExec("DELETE FROM storage_history").
+			WithArgs(now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM storage WHERE expires_at").
+			WithArgs(now).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		count, err := s.DeleteExpired(ctx, now, true)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), count)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorageDB_Consume(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.Background()
+
+	mock.ExpectExec("UPDATE storage SET clicks_left = clicks_left - 1").
+		WithArgs("short123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE storage SET clicks_left = clicks_left - 1").
+		WithArgs("short123").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT max_clicks FROM storage WHERE short_key=$1")).
+		WithArgs("short123").
+		WillReturnRows(sqlmock.NewRows([]string{"max_clicks"}).AddRow(1))
+	mock.ExpectExec("UPDATE storage SET clicks_left = clicks_left - 1").
+		WithArgs("missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT max_clicks FROM storage WHERE short_key=$1")).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	assert.NoError(t, s.Consume(ctx, "short123"))
+	assert.ErrorIs(t, s.Consume(ctx, "short123"), storage.ErrExhausted)
+	assert.ErrorIs(t, s.Consume(ctx, "missing"), storage.ErrNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestStorageDB_Conformance прогоняет общий набор тестов хранилища на реальной базе данных.
+// Адрес базы задаётся переменной окружения TEST_DATABASE_DSN; без неё тест пропускается.
+// Все данные в таблицах хранилища удаляются перед каждым подтестом.
+func TestStorageDB_Conformance(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := Open(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	s := NewStorage(db)
+	if err = s.Bootstrap(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		_, err := db.ExecContext(context.Background(), "TRUNCATE storage, clicks, storage_history")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+func TestStorageDB_SetBatch(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(arrayConverter{}))
+	assert.NoError(t, err)
+	defer db.Close()
+
+	s := NewStorage(db)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "test-user")
+
+	links := []models.Link{
+		{ShortKey: "short1", OriginalURL: "https://example.com/1"},
+		{ShortKey: "short2", OriginalURL: "https://example.com/2"},
+	}
+	insert := regexp.QuoteMeta("INSERT INTO storage (short_key, url, user_id, created_at, expires_at, max_clicks, clicks_left, password_hash, tags) VALUES " +
+		"($1, $2, $3, $4, $5, $6, $6, $7, string_to_array($8, ',')), ($9, $10, $11, $12, $13, $14, $14, $15, string_to_array($16, ',')) " +
+		"ON CONFLICT DO NOTHING RETURNING short_key, url")
+
+	t.Run("commit", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).
+			WillReturnRows(sqlmock.NewRows([]string{"short_key", "url"}).AddRow("short1", "https://example.com/1"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT url, short_key FROM storage WHERE url = ANY($1)")).
+			WithArgs([]string{"https://example.com/2"}).
+			WillReturnRows(sqlmock.NewRows([]string{"url", "short_key"}).AddRow("https://example.com/2", "existing2"))
+		mock.ExpectCommit()
+
+		results, err := s.SetBatch(ctx, links, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []storage.BatchResult{{ShortKey: "short1"}, {ShortKey: "existing2", Err: storage.ErrConflict}}, results)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("atomic rollback", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(insert).
+			WillReturnRows(sqlmock.NewRows([]string{"short_key", "url"}).AddRow("short1", "https://example.com/1"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT url, short_key FROM storage WHERE url = ANY($1)")).
+			WithArgs([]string{"https://example.com/2"}).
+			WillReturnRows(sqlmock.NewRows([]string{"url", "short_key"}))
+		mock.ExpectRollback()
+
+		results, err := s.SetBatch(ctx, links, true)
+		assert.ErrorIs(t, err, storage.ErrKeyExists)
+		assert.Equal(t, []storage.BatchResult{{ShortKey: "short1"}, {Err: storage.ErrKeyExists}}, results)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,238 @@
+package redis
+
+import goredis "github.com/redis/go-redis/v9"
+
+// Изменения, затрагивающие несколько ключей, выполняются Lua-скриптами, поэтому они атомарны
+// относительно всех экземпляров сервиса. Ключи, известные до вызова, передаются в KEYS: первые четыре —
+// индексы links, owners, expires и deleted (см. scriptKeys), за ними ключи ссылок, перечисленные
+// в описании скрипта. Первый аргумент каждого скрипта — префикс ключей; из него внутри скрипта строятся
+// ключи, зависящие от сохранённых данных (url:<URL>, clicks:<ключ>, ссылки из индексов). Префикс содержит
+// хеш-тег, поэтому все ключи находятся в одном слоте Redis Cluster.
+//
+// Статусы результатов скриптов преобразуются в ошибки storage по таблице scriptErrors.
+
+// scriptPrelude содержит общие для всех скриптов функции построения ключей и удаления ссылки.
+const scriptPrelude = `
+local prefix = ARGV[1]
+local linksKey, ownersKey, expiresKey, deletedKey = KEYS[1], KEYS[2], KEYS[3], KEYS[4]
+local function linkKey(k) return prefix .. 'link:' .. k end
+local function urlKey(u) return prefix .. 'url:' .. u end
+local function userKey(u) return prefix .. 'user:' .. u end
+local function historyKey(k) return prefix .. 'history:' .. k end
+local function clicksKey(k) return prefix .. 'clicks:' .. k end
+
+local function remove(k)
+	local fields = redis.call('HMGET', linkKey(k), 'url', 'user_id')
+	if not fields[1] then
+		return
+	end
+	redis.call('DEL', linkKey(k), historyKey(k), clicksKey(k))
+	if redis.call('GET', urlKey(fields[1])) == k then
+		redis.call('DEL', urlKey(fields[1]))
+	end
+	redis.call('SREM', linksKey, k)
+	redis.call('ZREM', expiresKey, k)
+	redis.call('ZREM', deletedKey, k)
+	if fields[2] ~= '' then
+		redis.call('SREM', userKey(fields[2]), k)
+		if redis.call('SCARD', userKey(fields[2])) == 0 then
+			redis.call('SREM', ownersKey, fields[2])
+		end
+	end
+end
+
+local function owned(key, owner)
+	local fields = redis.call('HMGET', key, 'url', 'user_id', 'is_deleted')
+	if not fields[1] then
+		return nil, 'notfound'
+	end
+	if fields[2] ~= owner then
+		return nil, 'forbidden'
+	end
+	if fields[3] == '1' then
+		return nil, 'deleted'
+	end
+	return fields[1]
+end
+`
+
+// setScript сохраняет пакет ссылок. Ключи: индексы и по два ключа на каждую ссылку — link:<ключ>
+// и user:<владелец>. Аргументы: префикс, признак атомарности ('1' или '0') и по linkArgs значений
+// на каждую ссылку: ключ, URL, владелец, время создания, время окончания действия,
+// лимит переходов, хеш пароля и метки, объединённые tags.Join. Для каждой ссылки возвращает пару «статус, ключ»:
+// ok, conflict с ключом существующей ссылки или exists. В атомарном режиме при статусе exists
+// сохранённые ссылки пакета удаляются.
+var setScript = goredis.NewScript(scriptPrelude + `
+local results = {}
+local created = {}
+local failed = false
+for n = 5, #KEYS, 2 do
+	local key, ownerKey, base = KEYS[n], KEYS[n + 1], 3 + (n - 5) / 2 * 8
+	local k, url, user = ARGV[base], ARGV[base + 1], ARGV[base + 2]
+	local existing = redis.call('GET', urlKey(url))
+	if existing then
+		table.insert(results, 'conflict')
+		table.insert(results, existing)
+	elseif redis.call('EXISTS', key) == 1 then
+		table.insert(results, 'exists')
+		table.insert(results, '')
+		failed = true
+	else
+		redis.call('HSET', key,
+			'url', url, 'user_id', user, 'created_at', ARGV[base + 3], 'expires_at', ARGV[base + 4],
+			'max_clicks', ARGV[base + 5], 'clicks_left', ARGV[base + 5], 'password_hash', ARGV[base + 6],
+			'tags', ARGV[base + 7], 'is_deleted', '0', 'deleted_at', '0')
+		redis.call('SET', urlKey(url), k)
+		redis.call('SADD', linksKey, k)
+		if user ~= '' then
+			redis.call('SADD', ownerKey, k)
+			redis.call('SADD', ownersKey, user)
+		end
+		if ARGV[base + 4] ~= '0' then
+			redis.call('ZADD', expiresKey, ARGV[base + 4], k)
+		end
+		table.insert(results, 'ok')
+		table.insert(results, k)
+		table.insert(created, k)
+	end
+end
+if failed and ARGV[2] == '1' then
+	for _, k in ipairs(created) do
+		remove(k)
+	end
+end
+return results
+`)
+
+// linkArgs — количество аргументов setScript на одну ссылку.
+const linkArgs = 8
+
+// deleteScript помечает удалёнными неудалённые ссылки владельца. Ключи: индексы и link:<ключ>
+// каждой ссылки. Аргументы: префикс, владелец, момент удаления и ключи ссылок в том же порядке.
+var deleteScript = goredis.NewScript(scriptPrelude + `
+local owner, now = ARGV[2], ARGV[3]
+if owner == '' then
+	return 0
+end
+for i = 4, #ARGV do
+	local key = KEYS[i + 1]
+	local fields = redis.call('HMGET', key, 'user_id', 'is_deleted')
+	if fields[1] == owner and fields[2] == '0' then
+		redis.call('HSET', key, 'is_deleted', '1', 'deleted_at', now)
+		redis.call('ZADD', deletedKey, now, ARGV[i])
+	end
+end
+return 0
+`)
+
+// expireScript удаляет ссылки, срок действия которых истёк к моменту now. Ключи: индексы.
+// Ключи ссылок берутся из индекса expires. Аргументы: префикс, now и признак физического удаления ('1' или '0'). Возвращает количество удалённых ссылок.
+var expireScript = goredis.NewScript(scriptPrelude + `
+local now, purge = ARGV[2], ARGV[3]
+local count = 0
+for _, k in ipairs(redis.call('ZRANGEBYSCORE', expiresKey, '-inf', now)) do
+	if purge == '1' then
+		remove(k)
+		count = count + 1
+	elseif redis.call('HGET', linkKey(k), 'is_deleted') == '0' then
+		redis.call('HSET', linkKey(k), 'is_deleted', '1', 'deleted_at', now)
+		redis.call('ZADD', deletedKey, now, k)
+		count = count + 1
+	end
+end
+return count
+`)
+
+// purgeScript физически удаляет ссылки, помеченные удалёнными раньше момента before.
+// Ключи: индексы; ключи ссылок берутся из индекса deleted. Аргументы: префикс и before. Возвращает количество удалённых ссылок.
+var purgeScript = goredis.NewScript(scriptPrelude + `
+local keys = redis.call('ZRANGEBYSCORE', deletedKey, '-inf', '(' .. ARGV[2])
+for _, k in ipairs(keys) do
+	remove(k)
+end
+return #keys
+`)
+
+// restoreScript снимает пометку удаления со ссылки владельца, удалённой не раньше deletedAfter.
+// Ключи: индексы и link:<ключ>. Аргументы: префикс, ключ, владелец и deletedAfter. Возвращает статус и URL ссылки.
+var restoreScript = goredis.NewScript(scriptPrelude + `
+local fields = redis.call('HMGET', KEYS[5], 'url', 'user_id', 'is_deleted', 'deleted_at')
+if not fields[1] then
+	return {'notfound'}
+end
+if fields[2] ~= ARGV[3] then
+	return {'forbidden'}
+end
+if fields[3] ~= '1' then
+	return {'notdeleted'}
+end
+if tonumber(fields[4]) < tonumber(ARGV[4]) then
+	return {'expired'}
+end
+redis.call('HSET', KEYS[5], 'is_deleted', '0', 'deleted_at', '0')
+redis.call('ZREM', deletedKey, ARGV[2])
+return {'ok', fields[1]}
+`)
+
+// updateScript заменяет URL ссылки владельца и дописывает предыдущее значение в историю
+// в виде «момент изменения, пробел, URL». Ключи: индексы, link:<ключ> и history:<ключ>.
+// Аргументы: префикс, ключ, владелец, новый URL и момент изменения.
+var updateScript = goredis.NewScript(scriptPrelude + `
+local k, url = ARGV[2], ARGV[4]
+local current, status = owned(KEYS[5], ARGV[3])
+if not current then
+	return {status}
+end
+if current == url then
+	return {'ok'}
+end
+if redis.call('EXISTS', urlKey(url)) == 1 then
+	return {'conflict'}
+end
+redis.call('RPUSH', KEYS[6], ARGV[5] .. ' ' .. current)
+redis.call('DEL', urlKey(current))
+redis.call('SET', urlKey(url), k)
+redis.call('HSET', KEYS[5], 'url', url)
+return {'ok'}
+`)
+
+// rollbackScript восстанавливает последнее значение из истории изменений ссылки владельца.
+// Ключи: индексы, link:<ключ> и history:<ключ>. Аргументы: префикс, ключ и владелец.
+// Возвращает статус и восстановленный URL.
+var rollbackScript = goredis.NewScript(scriptPrelude + `
+local k = ARGV[2]
+local current, status = owned(KEYS[5], ARGV[3])
+if not current then
+	return {status}
+end
+local last = redis.call('LINDEX', KEYS[6], -1)
+if not last then
+	return {'nohistory'}
+end
+local previous = string.sub(last, string.find(last, ' ', 1, true) + 1)
+if redis.call('EXISTS', urlKey(previous)) == 1 then
+	return {'conflict'}
+end
+redis.call('RPOP', KEYS[6])
+redis.call('DEL', urlKey(current))
+redis.call('SET', urlKey(previous), k)
+redis.call('HSET', KEYS[5], 'url', previous)
+return {'ok', previous}
+`)
+
+// consumeScript уменьшает оставшееся количество переходов по ссылке с ограничением.
+// Ключи: индексы и link:<ключ>. Аргументы: префикс и ключ. Возвращает статус.
+var consumeScript = goredis.NewScript(scriptPrelude + `
+local fields = redis.call('HMGET', KEYS[5], 'max_clicks', 'clicks_left')
+if not fields[1] then
+	return {'notfound'}
+end
+if fields[1] == '0' then
+	return {'ok'}
+end
+if tonumber(fields[2]) <= 0 then
+	return {'exhausted'}
+end
+redis.call('HINCRBY', KEYS[5], 'clicks_left', -1)
+return {'ok'}
+`)
@@ -0,0 +1,541 @@
+// Package redis реализует storage.Storage в Redis для нескольких экземпляров сервиса с общим хранилищем.
+//
+// Каждая ссылка хранится в хеше link:<ключ>, уникальность URL обеспечивается строкой url:<URL>
+// с ключом ссылки, а ссылки владельца перечислены во множестве user:<владелец>. Множества links и owners
+// содержат все ключи и всех владельцев, а упорядоченные множества expires и deleted — ссылки
+// по моменту окончания действия и моменту удаления. История изменений URL хранится в списке
+// history:<ключ>, переходы — в упорядоченном множестве clicks:<ключ> по моменту перехода.
+// Все ключи начинаются с префикса keyPrefix с хеш-тегом {shorturl}, поэтому в Redis Cluster
+// они находятся в одном слоте и доступны многоключевым скриптам; моменты времени хранятся в микросекундах от начала эпохи Unix,
+// 0 — момент не задан.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/tags"
+)
+
+// keyPrefix — префикс всех ключей хранилища в Redis. Хеш-тег {shorturl} помещает все ключи в один слот Redis Cluster.
+const keyPrefix = "{shorturl}:"
+
+// Индексы хранилища.
+const (
+	linksKey   = keyPrefix + "links"   // Ключи всех ссылок
+	ownersKey  = keyPrefix + "owners"  // Все владельцы ссылок
+	expiresKey = keyPrefix + "expires" // Ссылки по моменту окончания действия
+	deletedKey = keyPrefix + "deleted" // Удалённые ссылки по моменту удаления
+)
+
+// scriptErrors сопоставляет статусы результатов Lua-скриптов с ошибками storage.
+var scriptErrors = map[string]error{
+	"notfound":   storage.ErrNotFound,
+	"forbidden":  storage.ErrForbidden,
+	"deleted":    storage.ErrDeleted,
+	"conflict":   storage.ErrConflict,
+	"exists":     storage.ErrKeyExists,
+	"nohistory":  storage.ErrNoHistory,
+	"exhausted":  storage.ErrExhausted,
+	"notdeleted": storage.ErrNotDeleted,
+	"expired":    storage.ErrRestoreExpired,
+}
+
+// StorageRedis представляет собой хранилище сокращённых URL в Redis.
+type StorageRedis struct {
+	client *goredis.Client
+}
+
+// Open создаёт клиент Redis по строке подключения вида redis://[[user]:password@]host:port[/db]
+// или rediss://... для подключения по TLS.
+func Open(dsn string) (*goredis.Client, error) {
+	opts, err := goredis.ParseURL(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return goredis.NewClient(opts), nil
+}
+
+// NewStorage создает новый экземпляр StorageRedis с заданным клиентом Redis.
+func NewStorage(client *goredis.Client) *StorageRedis {
+	return &StorageRedis{client: client}
+}
+
+// Bootstrap проверяет доступность Redis. Структуры данных создаются при первой записи.
+func (s StorageRedis) Bootstrap(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Ping проверяет соединение с сервером Redis.
+func (s StorageRedis) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Get получает ссылку по её короткому ключу.
+func (s StorageRedis) Get(ctx context.Context, shortKey string) (models.Link, error) {
+	fields, err := s.client.HGetAll(ctx, linkKey(shortKey)).Result()
+	if err != nil {
+		return models.Link{}, err
+	}
+	if len(fields) == 0 {
+		return models.Link{}, storage.ErrNotFound
+	}
+
+	return decodeLink(shortKey, fields), nil
+}
+
+// Set сохраняет ссылку одним вызовом Lua-скрипта, который проверяет уникальность URL и ключа
+// и обновляет индексы атомарно.
+func (s StorageRedis) Set(ctx context.Context, link models.Link) (string, error) {
+	results, err := s.setLinks(ctx, []models.Link{link}, false)
+	if err != nil {
+		return "", err
+	}
+	return results[0].ShortKey, results[0].Err
+}
+
+// SetBatch сохраняет пакет ссылок одним вызовом Lua-скрипта.
+// При atomic=true и занятом ключе у какой-либо ссылки сохранённые ссылки пакета удаляются тем же скриптом.
+func (s StorageRedis) SetBatch(ctx context.Context, links []models.Link, atomic bool) ([]storage.BatchResult, error) {
+	results, err := s.setLinks(ctx, links, atomic)
+	if err != nil {
+		return nil, err
+	}
+	if !atomic {
+		return results, nil
+	}
+
+	failed := false
+	for _, result := range results {
+		failed = failed || errors.Is(result.Err, storage.ErrKeyExists)
+	}
+	if !failed {
+		return results, nil
+	}
+
+	for i, result := range results {
+		if result.Err == nil {
+			results[i].ShortKey = ""
+		}
+	}
+	return results, storage.ErrKeyExists
+}
+
+// Import сохраняет пакет ссылок одним вызовом Lua-скрипта.
+func (s StorageRedis) Import(ctx context.Context, links []models.Link) ([]error, error) {
+	results, err := s.setLinks(ctx, links, false)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(results))
+	for i, result := range results {
+		errs[i] = result.Err
+	}
+	return errs, nil
+}
+
+// setLinks сохраняет ссылки скриптом setScript и возвращает результаты в порядке links.
+// Владелец и время создания назначаются так же, как в Set.
+func (s StorageRedis) setLinks(ctx context.Context, links []models.Link, atomic bool) ([]storage.BatchResult, error) {
+	if len(links) == 0 {
+		return []storage.BatchResult{}, ctx.Err()
+	}
+
+	keys := make([]string, 0, 2*len(links))
+	args := make([]any, 0, 2+len(links)*linkArgs)
+	args = append(args, keyPrefix, atomic)
+	now := time.Now().UTC()
+	for _, link := range links {
+		if link.UserID == "" {
+			link.UserID = auth.UserIDFromContext(ctx)
+		}
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+		}
+		keys = append(keys, linkKey(link.ShortKey), userKey(link.UserID))
+		args = append(args, link.ShortKey, link.OriginalURL, link.UserID, encodeTime(link.CreatedAt),
+			encodeTime(link.ExpiresAt), link.MaxClicks, link.PasswordHash, tags.Join(link.Tags))
+	}
+
+	reply, err := setScript.Run(ctx, s.client, scriptKeys(keys...), args...).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]storage.BatchResult, len(links))
+	for i := range results {
+		results[i].ShortKey, results[i].Err = reply[2*i+1], scriptErrors[reply[2*i]]
+	}
+	return results, nil
+}
+
+// GetUserURL возвращает неудалённые сокращённые URL текущего пользователя в порядке их создания.
+func (s StorageRedis) GetUserURL(ctx context.Context) (result []models.ShortURLItem, err error) {
+	items, err := s.userLinks(ctx, auth.UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		if item.Link.IsDeleted {
+			continue
+		}
+		shortURL := models.ShortURLItem{
+			OriginalURL: item.Link.OriginalURL,
+			ShortURL:    config.FlagBaseAddr + "/" + item.Link.ShortKey,
+		}
+		if item.Link.MaxClicks > 0 {
+			left := item.Link.ClicksLeft
+			shortURL.ClicksLeft = &left
+		}
+		result = append(result, shortURL)
+	}
+
+	return result, nil
+}
+
+// ListUserURLs возвращает страницу ссылок текущего пользователя.
+// Ссылки пользователя читаются по индексу владельцев, фильтруются и упорядочиваются на стороне сервиса.
+func (s StorageRedis) ListUserURLs(ctx context.Context, query models.ListQuery) (models.ShortURLPage, error) {
+	query, after, err := storage.NormalizeListQuery(query)
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	items, err := s.userLinks(ctx, auth.UserIDFromContext(ctx))
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	type entry struct {
+		item   models.UserLink
+		cursor storage.ListCursor
+	}
+	entries := make([]entry, 0, len(items))
+	for _, item := range items {
+		if storage.MatchesListQuery(query, item.Link) {
+			entries = append(entries, entry{item: item, cursor: storage.NewListCursor(query, item.Link, item.Clicks)})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].cursor.Compare(entries[j].cursor) < 0
+	})
+
+	page := models.ShortURLPage{Total: len(entries)}
+	if after != nil {
+		start := sort.Search(len(entries), func(i int) bool {
+			return entries[i].cursor.Compare(*after) > 0
+		})
+		entries = entries[start:]
+	}
+	if len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+		page.NextCursor = entries[len(entries)-1].cursor.Encode()
+	}
+
+	for _, e := range entries {
+		page.Items = append(page.Items, storage.NewListItem(e.item.Link, e.item.Clicks))
+	}
+
+	return page, nil
+}
+
+// UserLinks возвращает итератор по ссылкам текущего пользователя.
+// Ссылки читаются одним конвейером запросов при начале итерации.
+func (s StorageRedis) UserLinks(ctx context.Context) storage.UserLinkSeq {
+	return func(yield func(models.UserLink, error) bool) {
+		items, err := s.userLinks(ctx, auth.UserIDFromContext(ctx))
+		if err != nil {
+			yield(models.UserLink{}, err)
+			return
+		}
+
+		for _, item := range items {
+			if err := ctx.Err(); err != nil {
+				yield(models.UserLink{}, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// userLinks возвращает ссылки пользователя userID в порядке создания вместе с количеством переходов.
+func (s StorageRedis) userLinks(ctx context.Context, userID string) ([]models.UserLink, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, nil
+	}
+
+	shortKeys, err := s.client.SMembers(ctx, userKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := s.client.Pipeline()
+	links := make([]*goredis.MapStringStringCmd, len(shortKeys))
+	clicks := make([]*goredis.IntCmd, len(shortKeys))
+	for i, shortKey := range shortKeys {
+		links[i] = pipe.HGetAll(ctx, linkKey(shortKey))
+		clicks[i] = pipe.ZCard(ctx, clicksKey(shortKey))
+	}
+	if _, err = pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	items := make([]models.UserLink, 0, len(shortKeys))
+	for i, shortKey := range shortKeys {
+		fields := links[i].Val()
+		if len(fields) == 0 {
+			continue
+		}
+		items = append(items, models.UserLink{Link: decodeLink(shortKey, fields), Clicks: clicks[i].Val()})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Link.CreatedAt.Equal(items[j].Link.CreatedAt) {
+			return items[i].Link.CreatedAt.Before(items[j].Link.CreatedAt)
+		}
+		return items[i].Link.ShortKey < items[j].Link.ShortKey
+	})
+
+	return items, nil
+}
+
+// Delete помечает ссылку текущего пользователя как удалённую.
+func (s StorageRedis) Delete(ctx context.Context, shortKey string) error {
+	return s.DeleteBatch(ctx, []string{shortKey})
+}
+
+// DeleteBatch помечает как удалённые ссылки shortKeys текущего пользователя одним вызовом Lua-скрипта.
+func (s StorageRedis) DeleteBatch(ctx context.Context, shortKeys []string) error {
+	if len(shortKeys) == 0 {
+		return ctx.Err()
+	}
+
+	keys := make([]string, 0, len(shortKeys))
+	args := make([]any, 0, 3+len(shortKeys))
+	args = append(args, keyPrefix, auth.UserIDFromContext(ctx), encodeTime(time.Now()))
+	for _, shortKey := range shortKeys {
+		keys = append(keys, linkKey(shortKey))
+		args = append(args, shortKey)
+	}
+
+	return deleteScript.Run(ctx, s.client, scriptKeys(keys...), args...).Err()
+}
+
+// CountURLs возвращает количество неудалённых ссылок.
+func (s StorageRedis) CountURLs(ctx context.Context) (int, error) {
+	pipe := s.client.TxPipeline()
+	links := pipe.SCard(ctx, linksKey)
+	deleted := pipe.ZCard(ctx, deletedKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return int(links.Val() - deleted.Val()), nil
+}
+
+// CountUsers возвращает количество уникальных владельцев ссылок.
+func (s StorageRedis) CountUsers(ctx context.Context) (int, error) {
+	count, err := s.client.SCard(ctx, ownersKey).Result()
+	return int(count), err
+}
+
+// clickEvent — элемент упорядоченного множества переходов; идентификатор делает элементы уникальными.
+type clickEvent struct {
+	ID        string `json:"id"`
+	Referrer  string `json:"referrer,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// SaveClicks сохраняет события перехода одним конвейером запросов.
+func (s StorageRedis) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	if len(events) == 0 {
+		return ctx.Err()
+	}
+
+	pipe := s.client.Pipeline()
+	for _, event := range events {
+		member, err := json.Marshal(clickEvent{ID: uuid.NewString(), Referrer: event.Referrer, UserAgent: event.UserAgent})
+		if err != nil {
+			return err
+		}
+		pipe.ZAdd(ctx, clicksKey(event.ShortKey), goredis.Z{Score: float64(event.Timestamp.UnixMicro()), Member: member})
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetStats подсчитывает переходы по короткой ссылке и группирует их по интервалам длиной step.
+func (s StorageRedis) GetStats(ctx context.Context, shortKey string, from, to time.Time, step time.Duration) (int64, []models.StatsBucket, error) {
+	pipe := s.client.Pipeline()
+	total := pipe.ZCard(ctx, clicksKey(shortKey))
+	clicks := pipe.ZRangeByScoreWithScores(ctx, clicksKey(shortKey), &goredis.ZRangeBy{
+		Min: strconv.FormatInt(from.UnixMicro(), 10),
+		Max: "(" + strconv.FormatInt(to.UnixMicro(), 10),
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, nil, err
+	}
+
+	// Переходы упорядочены по времени, поэтому интервалы следуют подряд
+	var series []models.StatsBucket
+	for _, click := range clicks.Val() {
+		bucket := storage.BucketStart(time.UnixMicro(int64(click.Score)).UTC(), step)
+		if len(series) > 0 && series[len(series)-1].Time.Equal(bucket) {
+			series[len(series)-1].Clicks++
+			continue
+		}
+		series = append(series, models.StatsBucket{Time: bucket, Clicks: 1})
+	}
+
+	return total.Val(), series, nil
+}
+
+// Update заменяет оригинальный URL ссылки текущего пользователя и сохраняет предыдущее значение в истории.
+func (s StorageRedis) Update(ctx context.Context, shortKey string, url string) error {
+	_, err := s.runScript(ctx, updateScript, []string{linkKey(shortKey), historyKey(shortKey)}, shortKey, auth.UserIDFromContext(ctx), url, encodeTime(time.Now()))
+	return err
+}
+
+// GetHistory возвращает историю изменений ссылки текущего пользователя, начиная с самого позднего изменения.
+func (s StorageRedis) GetHistory(ctx context.Context, shortKey string) ([]models.HistoryItem, error) {
+	owner, err := s.client.HGet(ctx, linkKey(shortKey), "user_id").Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if owner != auth.UserIDFromContext(ctx) {
+		return nil, storage.ErrForbidden
+	}
+
+	entries, err := s.client.LRange(ctx, historyKey(shortKey), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]models.HistoryItem, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		changedAt, url, _ := strings.Cut(entries[i], " ")
+		micros, err := strconv.ParseInt(changedAt, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, models.HistoryItem{OriginalURL: url, ChangedAt: decodeTime(micros)})
+	}
+
+	return history, nil
+}
+
+// Rollback восстанавливает последнее значение из истории изменений ссылки текущего пользователя.
+func (s StorageRedis) Rollback(ctx context.Context, shortKey string) (string, error) {
+	return s.runScript(ctx, rollbackScript, []string{linkKey(shortKey), historyKey(shortKey)}, shortKey, auth.UserIDFromContext(ctx))
+}
+
+// Consume уменьшает оставшееся количество переходов по ссылке одним вызовом Lua-скрипта,
+// поэтому параллельные переходы не могут превысить лимит.
+func (s StorageRedis) Consume(ctx context.Context, shortKey string) error {
+	_, err := s.runScript(ctx, consumeScript, []string{linkKey(shortKey)}, shortKey)
+	return err
+}
+
+// DeleteExpired помечает удалёнными ссылки, срок действия которых истёк к моменту now,
+// а при purge=true удаляет их вместе со статистикой и историей изменений.
+func (s StorageRedis) DeleteExpired(ctx context.Context, now time.Time, purge bool) (int64, error) {
+	return expireScript.Run(ctx, s.client, scriptKeys(), keyPrefix, encodeTime(now), purge).Int64()
+}
+
+// Restore снимает пометку удаления со ссылки текущего пользователя, удалённой не раньше deletedAfter.
+func (s StorageRedis) Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (string, error) {
+	return s.runScript(ctx, restoreScript, []string{linkKey(shortKey)}, shortKey, auth.UserIDFromContext(ctx), deletedAfter.UnixMicro())
+}
+
+// PurgeDeleted удаляет ссылки, помеченные удалёнными раньше момента before,
+// вместе с переходами и историей изменений.
+func (s StorageRedis) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	return purgeScript.Run(ctx, s.client, scriptKeys(), keyPrefix, before.UnixMicro()).Int64()
+}
+
+// runScript выполняет скрипт с ключами ссылки keys, возвращающий статус и необязательное значение,
+// и преобразует статус в ошибку storage.
+func (s StorageRedis) runScript(ctx context.Context, script *goredis.Script, keys []string, args ...any) (string, error) {
+	reply, err := script.Run(ctx, s.client, scriptKeys(keys...), append([]any{keyPrefix}, args...)...).StringSlice()
+	if err != nil {
+		return "", err
+	}
+	if err = scriptErrors[reply[0]]; err != nil {
+		return "", err
+	}
+	if len(reply) < 2 {
+		return "", nil
+	}
+	return reply[1], nil
+}
+
+// decodeLink собирает ссылку из полей хеша link:<ключ>.
+func decodeLink(shortKey string, fields map[string]string) models.Link {
+	parseInt := func(field string) int64 {
+		value, _ := strconv.ParseInt(fields[field], 10, 64)
+		return value
+	}
+
+	return models.Link{
+		ShortKey:     shortKey,
+		OriginalURL:  fields["url"],
+		UserID:       fields["user_id"],
+		CreatedAt:    decodeTime(parseInt("created_at")),
+		IsDeleted:    fields["is_deleted"] == "1",
+		DeletedAt:    decodeTime(parseInt("deleted_at")),
+		ExpiresAt:    decodeTime(parseInt("expires_at")),
+		MaxClicks:    parseInt("max_clicks"),
+		ClicksLeft:   parseInt("clicks_left"),
+		PasswordHash: fields["password_hash"],
+		Tags:         tags.Split(fields["tags"]),
+	}
+}
+
+// encodeTime преобразует момент времени в микросекунды от начала эпохи Unix, а нулевое время — в 0.
+func encodeTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMicro()
+}
+
+// decodeTime преобразует микросекунды от начала эпохи Unix в момент времени, а 0 — в нулевое время.
+func decodeTime(micros int64) time.Time {
+	if micros == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(micros).UTC()
+}
+
+// scriptKeys возвращает KEYS скрипта: индексы хранилища и ключи keys.
+func scriptKeys(keys ...string) []string {
+	return append([]string{linksKey, ownersKey, expiresKey, deletedKey}, keys...)
+}
+
+func linkKey(shortKey string) string    { return keyPrefix + "link:" + shortKey }
+func userKey(userID string) string      { return keyPrefix + "user:" + userID }
+func historyKey(shortKey string) string { return keyPrefix + "history:" + shortKey }
+func clicksKey(shortKey string) string  { return keyPrefix + "clicks:" + shortKey }
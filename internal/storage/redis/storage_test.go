@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/storagetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T, server *miniredis.Miniredis) *StorageRedis {
+	client, err := Open("redis://" + server.Addr())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+	})
+
+	s := NewStorage(client)
+	require.NoError(t, s.Bootstrap(context.Background()))
+
+	return s
+}
+
+func TestStorageRedis_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestStorage(t, miniredis.RunT(t))
+	})
+}
+
+func TestStorageRedis_Open(t *testing.T) {
+	_, err := Open("postgres://localhost/db")
+	assert.Error(t, err)
+
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+	client, err := Open("redis://:secret@" + server.Addr() + "/0")
+	require.NoError(t, err)
+	defer client.Close()
+	assert.NoError(t, NewStorage(client).Bootstrap(context.Background()))
+}
+
+func TestStorageRedis_SharedState(t *testing.T) {
+	server := miniredis.RunT(t)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	// Два экземпляра сервиса работают с одним сервером Redis
+	first := newTestStorage(t, server)
+	second := newTestStorage(t, server)
+
+	_, err := first.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1", MaxClicks: 1})
+	require.NoError(t, err)
+	_, err = second.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/1"})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+
+	require.NoError(t, second.Consume(ctx, "short1"))
+	assert.ErrorIs(t, first.Consume(ctx, "short1"), storage.ErrExhausted)
+
+	require.NoError(t, first.Delete(ctx, "short1"))
+	link, err := second.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.True(t, link.IsDeleted)
+
+	assert.Equal(t, "http://example.com/1", server.HGet(keyPrefix+"link:short1", "url"))
+	assert.True(t, server.Exists(keyPrefix+"user:user1"))
+}
+
+func TestStorageRedis_PurgeIndexes(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestStorage(t, server)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1", ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+	require.NoError(t, s.SaveClicks(ctx, []models.ClickEvent{{ShortKey: "short1", Timestamp: time.Now()}}))
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/2"))
+
+	count, err := s.DeleteExpired(ctx, time.Now(), true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// После физического удаления не должно остаться ни данных ссылки, ни индексов
+	assert.Empty(t, server.Keys())
+}
+
+func TestStorageRedis_Ping(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestStorage(t, server)
+
+	var _ storage.Pinger = s
+	assert.NoError(t, s.Ping(context.Background()))
+
+	server.Close()
+	assert.Error(t, s.Ping(context.Background()))
+}
+
+func TestStorageRedis_HashTag(t *testing.T) {
+	server := miniredis.RunT(t)
+	s := newTestStorage(t, server)
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1", ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/2"))
+	require.NoError(t, s.SaveClicks(ctx, []models.ClickEvent{{ShortKey: "short1", Timestamp: time.Now()}}))
+	require.NoError(t, s.Delete(ctx, "short1"))
+
+	// Все ключи содержат хеш-тег и в Redis Cluster попадают в один слот
+	keys := server.Keys()
+	assert.NotEmpty(t, keys)
+	for _, key := range keys {
+		assert.True(t, strings.HasPrefix(key, "{shorturl}:"), key)
+	}
+}
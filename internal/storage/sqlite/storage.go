@@ -0,0 +1,892 @@
+// Package sqlite реализует storage.Storage поверх встроенной базы данных SQLite.
+//
+// Используется чистый Go-драйвер modernc.org/sqlite, поэтому сборка не требует cgo.
+// Схема и поведение хранилища совпадают с pg.StorageDB; моменты времени хранятся
+// в виде количества микросекунд от начала эпохи Unix.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/config"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/tags"
+)
+
+// DriverName — имя драйвера database/sql для SQLite.
+const DriverName = "sqlite"
+
+// connParams — параметры подключения: журнал WAL, ожидание блокировки записи
+// и захват блокировки записи в начале каждой транзакции.
+const connParams = "_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)&_txlock=immediate"
+
+// StorageSQLite представляет собой хранилище сокращённых URL в базе данных SQLite.
+type StorageSQLite struct {
+	conn *sql.DB
+}
+
+// Open открывает базу данных SQLite в файле path в режиме WAL.
+// path может содержать собственные параметры подключения после '?'; connParams добавляются к ним.
+func Open(path string) (*sql.DB, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return sql.Open(DriverName, path+separator+connParams)
+}
+
+// NewStorage создает новый экземпляр StorageSQLite с заданным подключением к базе данных.
+func NewStorage(conn *sql.DB) *StorageSQLite {
+	return &StorageSQLite{conn: conn}
+}
+
+// Bootstrap создает необходимые таблицы и индексы в базе данных при старте приложения.
+func (s StorageSQLite) Bootstrap(ctx context.Context) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS storage(
+			user_id text,
+			short_key text NOT NULL,
+			url text NOT NULL UNIQUE,
+			is_deleted integer NOT NULL DEFAULT 0,
+			deleted_at integer,
+			clicks integer NOT NULL DEFAULT 0,
+			created_at integer NOT NULL,
+			expires_at integer,
+			max_clicks integer NOT NULL DEFAULT 0,
+			clicks_left integer NOT NULL DEFAULT 0,
+			password_hash text NOT NULL DEFAULT '',
+			tags text NOT NULL DEFAULT ''
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS short_key_idx ON storage (short_key)`,
+		`DROP INDEX IF EXISTS storage_user_id_created_at_idx`,
+		`CREATE INDEX IF NOT EXISTS storage_user_id_created_at_short_key_idx ON storage (user_id, created_at, short_key)`,
+		`CREATE INDEX IF NOT EXISTS storage_user_id_clicks_short_key_idx ON storage (user_id, clicks, short_key)`,
+		`CREATE INDEX IF NOT EXISTS storage_expires_at_idx ON storage (expires_at) WHERE expires_at IS NOT NULL`,
+		`CREATE TABLE IF NOT EXISTS clicks(
+			id integer PRIMARY KEY AUTOINCREMENT,
+			short_key text NOT NULL,
+			created_at integer NOT NULL,
+			referrer text,
+			user_agent text
+		)`,
+		`CREATE INDEX IF NOT EXISTS clicks_short_key_created_at_idx ON clicks (short_key, created_at)`,
+		`CREATE TABLE IF NOT EXISTS storage_history(
+			id integer PRIMARY KEY AUTOINCREMENT,
+			short_key text NOT NULL,
+			url text NOT NULL,
+			changed_at integer NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS storage_history_short_key_idx ON storage_history (short_key)`,
+	}
+	for _, statement := range statements {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	// Столбец deleted_at появился позже остальных: добавляем его в базы, созданные раньше,
+	// и считаем ранее удалённые ссылки удалёнными в момент обновления схемы
+	if err = addColumn(ctx, tx, "storage", "deleted_at", "integer"); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE storage SET deleted_at=? WHERE is_deleted=1 AND deleted_at IS NULL", time.Now().UnixMicro())
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS storage_deleted_at_idx ON storage (deleted_at) WHERE is_deleted=1")
+	if err != nil {
+		return err
+	}
+
+	// Метки хранятся строкой, полученной tags.Join
+	if err = addColumn(ctx, tx, "storage", "tags", "text NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Ping проверяет соединение с базой данных.
+func (s StorageSQLite) Ping(ctx context.Context) error {
+	return s.conn.PingContext(ctx)
+}
+
+// addColumn добавляет в таблицу table столбец column типа columnType, если его ещё нет.
+func addColumn(ctx context.Context, tx *sql.Tx, table, column, columnType string) error {
+	var count int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name=?", table, column).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "ALTER TABLE "+table+" ADD COLUMN "+column+" "+columnType)
+	return err
+}
+
+// Set сохраняет ссылку в базе данных.
+// Владельцем становится link.UserID, а если он не задан — пользователь из контекста.
+// При нарушении уникальности короткого ключа возвращает storage.ErrKeyExists,
+// при нарушении уникальности URL — существующий короткий ключ и storage.ErrConflict.
+func (s StorageSQLite) Set(ctx context.Context, link models.Link) (shortKeyResult string, err error) {
+	if link.UserID == "" {
+		link.UserID = auth.UserIDFromContext(ctx)
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC()
+	}
+
+	_, err = s.conn.ExecContext(ctx, "INSERT INTO storage (short_key, url, user_id, created_at, expires_at, max_clicks, clicks_left, password_hash, tags) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		link.ShortKey, link.OriginalURL, nullString(link.UserID), link.CreatedAt.UnixMicro(), nullTime(link.ExpiresAt), link.MaxClicks, link.MaxClicks, link.PasswordHash, tags.Join(link.Tags))
+	if err == nil {
+		return link.ShortKey, nil
+	}
+
+	if !isUniqueViolation(err) {
+		return "", err
+	}
+	if strings.Contains(err.Error(), "storage.short_key") {
+		return "", storage.ErrKeyExists
+	}
+
+	row := s.conn.QueryRowContext(ctx, "SELECT short_key FROM storage WHERE url=?", link.OriginalURL)
+	if err = row.Scan(&shortKeyResult); err != nil {
+		return "", err
+	}
+
+	return shortKeyResult, storage.ErrConflict
+}
+
+// SetBatch сохраняет пакет ссылок в одной транзакции подготовленным запросом INSERT ... ON CONFLICT DO NOTHING.
+func (s StorageSQLite) SetBatch(ctx context.Context, links []models.Link, atomic bool) ([]storage.BatchResult, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results, err := insertLinks(ctx, tx, links)
+	if err != nil {
+		return nil, err
+	}
+
+	if atomic {
+		for _, result := range results {
+			if errors.Is(result.Err, storage.ErrKeyExists) {
+				return results, storage.ErrKeyExists
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Import сохраняет пакет ссылок в одной транзакции подготовленным запросом INSERT ... ON CONFLICT DO NOTHING.
+func (s StorageSQLite) Import(ctx context.Context, links []models.Link) ([]error, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results, err := insertLinks(ctx, tx, links)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, len(links))
+	for i, result := range results {
+		errs[i] = result.Err
+	}
+
+	return errs, nil
+}
+
+// insertLinks вставляет ссылки в транзакции tx и возвращает результаты по каждой ссылке.
+// Для невставленной ссылки причина определяется поиском существующей ссылки с тем же URL.
+func insertLinks(ctx context.Context, tx *sql.Tx, links []models.Link) ([]storage.BatchResult, error) {
+	owner := auth.UserIDFromContext(ctx)
+	now := time.Now().UTC()
+
+	insert, err := tx.PrepareContext(ctx, "INSERT INTO storage (short_key, url, user_id, created_at, expires_at, max_clicks, clicks_left, password_hash, tags) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING")
+	if err != nil {
+		return nil, err
+	}
+	defer insert.Close()
+
+	results := make([]storage.BatchResult, len(links))
+	for i, link := range links {
+		if link.UserID == "" {
+			link.UserID = owner
+		}
+		if link.CreatedAt.IsZero() {
+			link.CreatedAt = now
+		}
+
+		result, err := insert.ExecContext(ctx, link.ShortKey, link.OriginalURL, nullString(link.UserID), link.CreatedAt.UnixMicro(), nullTime(link.ExpiresAt), link.MaxClicks, link.MaxClicks, link.PasswordHash, tags.Join(link.Tags))
+		if err != nil {
+			return nil, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected > 0 {
+			results[i] = storage.BatchResult{ShortKey: link.ShortKey}
+			continue
+		}
+
+		var shortKey string
+		err = tx.QueryRowContext(ctx, "SELECT short_key FROM storage WHERE url=?", link.OriginalURL).Scan(&shortKey)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			results[i] = storage.BatchResult{Err: storage.ErrKeyExists}
+		case err != nil:
+			return nil, err
+		default:
+			results[i] = storage.BatchResult{ShortKey: shortKey, Err: storage.ErrConflict}
+		}
+	}
+
+	return results, nil
+}
+
+// Get извлекает ссылку по сокращённому URL из базы данных.
+func (s StorageSQLite) Get(ctx context.Context, shortKey string) (link models.Link, err error) {
+	var userID sql.NullString
+	var createdAt int64
+	var expiresAt, deletedAt sql.NullInt64
+	var linkTags string
+
+	row := s.conn.QueryRowContext(ctx, "SELECT url, short_key, user_id, created_at, is_deleted, deleted_at, expires_at, max_clicks, clicks_left, password_hash, tags FROM storage WHERE short_key=?", shortKey)
+	err = row.Scan(&link.OriginalURL, &link.ShortKey, &userID, &createdAt, &link.IsDeleted, &deletedAt, &expiresAt, &link.MaxClicks, &link.ClicksLeft, &link.PasswordHash, &linkTags)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Link{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Link{}, err
+	}
+	link.UserID = userID.String
+	link.CreatedAt = time.UnixMicro(createdAt).UTC()
+	link.Tags = tags.Split(linkTags)
+	if expiresAt.Valid {
+		link.ExpiresAt = time.UnixMicro(expiresAt.Int64).UTC()
+	}
+	if deletedAt.Valid {
+		link.DeletedAt = time.UnixMicro(deletedAt.Int64).UTC()
+	}
+
+	return link, nil
+}
+
+// GetUserURL извлекает неудалённые сокращённые URL текущего пользователя в порядке их создания.
+func (s StorageSQLite) GetUserURL(ctx context.Context) (result []models.ShortURLItem, err error) {
+	var shortKey string
+	var originalURL string
+	var maxClicks, clicksLeft int64
+
+	rows, err := s.conn.QueryContext(ctx, "SELECT short_key, url, max_clicks, clicks_left FROM storage WHERE user_id=? AND is_deleted=0 ORDER BY created_at, short_key", auth.UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = rows.Scan(&shortKey, &originalURL, &maxClicks, &clicksLeft); err != nil {
+			return nil, err
+		}
+
+		item := models.ShortURLItem{
+			OriginalURL: originalURL,
+			ShortURL:    config.FlagBaseAddr + "/" + shortKey,
+		}
+		if maxClicks > 0 {
+			left := clicksLeft
+			item.ClicksLeft = &left
+		}
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ListUserURLs возвращает страницу ссылок текущего пользователя.
+// Как и в pg.StorageDB, страница выбирается по значениям полей сортировки из курсора (keyset pagination).
+func (s StorageSQLite) ListUserURLs(ctx context.Context, query models.ListQuery) (models.ShortURLPage, error) {
+	query, after, err := storage.NormalizeListQuery(query)
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	userID := auth.UserIDFromContext(ctx)
+	if userID == "" {
+		return models.ShortURLPage{}, ctx.Err()
+	}
+
+	conditions := []string{"user_id=?"}
+	args := []any{userID}
+	switch query.Deleted {
+	case models.DeletedExclude:
+		conditions = append(conditions, "is_deleted=0")
+	case models.DeletedOnly:
+		conditions = append(conditions, "is_deleted=1")
+	}
+	if query.Search != "" {
+		conditions = append(conditions, "instr(lower(url), lower(?)) > 0")
+		args = append(args, query.Search)
+	}
+	if query.Tag != "" {
+		conditions = append(conditions, "instr(',' || tags || ',', ',' || ? || ',') > 0")
+		args = append(args, query.Tag)
+	}
+
+	var page models.ShortURLPage
+	err = s.conn.QueryRowContext(ctx, "SELECT count(*) FROM storage WHERE "+strings.Join(conditions, " AND "), args...).Scan(&page.Total)
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	column, direction, comparison := "created_at", "ASC", ">"
+	if query.Sort == models.ListSortClicks {
+		column = "clicks"
+	}
+	if query.Desc {
+		direction, comparison = "DESC", "<"
+	}
+	if after != nil {
+		value := after.CreatedAt.UnixMicro()
+		if query.Sort == models.ListSortClicks {
+			value = after.Clicks
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, short_key) %s (?, ?)", column, comparison))
+		args = append(args, value, after.ShortKey)
+	}
+	args = append(args, query.Limit+1)
+
+	rows, err := s.conn.QueryContext(ctx, fmt.Sprintf(
+		"SELECT short_key, url, created_at, is_deleted, clicks, max_clicks, clicks_left, tags FROM storage WHERE %s ORDER BY %s %s, short_key %s LIMIT ?",
+		strings.Join(conditions, " AND "), column, direction, direction,
+	), args...)
+	if err != nil {
+		return models.ShortURLPage{}, err
+	}
+	defer rows.Close()
+
+	var last storage.ListCursor
+	for rows.Next() {
+		var link models.Link
+		var createdAt, clicks int64
+		var linkTags string
+		if err = rows.Scan(&link.ShortKey, &link.OriginalURL, &createdAt, &link.IsDeleted, &clicks, &link.MaxClicks, &link.ClicksLeft, &linkTags); err != nil {
+			return models.ShortURLPage{}, err
+		}
+		link.CreatedAt = time.UnixMicro(createdAt).UTC()
+		link.Tags = tags.Split(linkTags)
+		if len(page.Items) == query.Limit {
+			page.NextCursor = last.Encode()
+			break
+		}
+		page.Items = append(page.Items, storage.NewListItem(link, clicks))
+		last = storage.NewListCursor(query, link, clicks)
+	}
+	if err = rows.Err(); err != nil {
+		return models.ShortURLPage{}, err
+	}
+
+	return page, nil
+}
+
+// UserLinks возвращает итератор по ссылкам текущего пользователя.
+// Ссылки читаются из курсора запроса по мере итерации, не загружаясь в память целиком.
+func (s StorageSQLite) UserLinks(ctx context.Context) storage.UserLinkSeq {
+	return func(yield func(models.UserLink, error) bool) {
+		userID := auth.UserIDFromContext(ctx)
+		if userID == "" {
+			if err := ctx.Err(); err != nil {
+				yield(models.UserLink{}, err)
+			}
+			return
+		}
+
+		rows, err := s.conn.QueryContext(ctx, "SELECT short_key, url, created_at, is_deleted, expires_at, max_clicks, clicks_left, clicks, tags FROM storage WHERE user_id=? ORDER BY created_at, short_key", userID)
+		if err != nil {
+			yield(models.UserLink{}, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			item := models.UserLink{Link: models.Link{UserID: userID}}
+			var createdAt int64
+			var expiresAt sql.NullInt64
+			var linkTags string
+			err = rows.Scan(&item.Link.ShortKey, &item.Link.OriginalURL, &createdAt, &item.Link.IsDeleted,
+				&expiresAt, &item.Link.MaxClicks, &item.Link.ClicksLeft, &item.Clicks, &linkTags)
+			if err != nil {
+				yield(models.UserLink{}, err)
+				return
+			}
+			item.Link.CreatedAt = time.UnixMicro(createdAt).UTC()
+			item.Link.Tags = tags.Split(linkTags)
+			if expiresAt.Valid {
+				item.Link.ExpiresAt = time.UnixMicro(expiresAt.Int64).UTC()
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			yield(models.UserLink{}, err)
+		}
+	}
+}
+
+// Delete помечает ссылку текущего пользователя как удалённую.
+func (s StorageSQLite) Delete(ctx context.Context, shortKey string) error {
+	_, err := s.conn.ExecContext(ctx, "UPDATE storage SET is_deleted=1, deleted_at=? WHERE short_key=? AND user_id=? AND is_deleted=0",
+		time.Now().UnixMicro(), shortKey, auth.UserIDFromContext(ctx))
+	return err
+}
+
+// DeleteBatch помечает ссылки пользователя как удалённые одним запросом UPDATE ... WHERE short_key IN (...).
+func (s StorageSQLite) DeleteBatch(ctx context.Context, shortKeys []string) error {
+	if len(shortKeys) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(shortKeys)+2)
+	args = append(args, time.Now().UnixMicro())
+	for _, shortKey := range shortKeys {
+		args = append(args, shortKey)
+	}
+	args = append(args, auth.UserIDFromContext(ctx))
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(shortKeys)), ", ")
+	_, err := s.conn.ExecContext(ctx, "UPDATE storage SET is_deleted=1, deleted_at=? WHERE short_key IN ("+placeholders+") AND user_id=? AND is_deleted=0", args...)
+	return err
+}
+
+// CountURLs возвращает количество неудалённых ссылок.
+func (s StorageSQLite) CountURLs(ctx context.Context) (int, error) {
+	var count int
+	err := s.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM storage WHERE is_deleted=0").Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountUsers возвращает количество уникальных владельцев ссылок.
+func (s StorageSQLite) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	err := s.conn.QueryRowContext(ctx, "SELECT COUNT(DISTINCT user_id) FROM storage").Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// SaveClicks сохраняет события перехода в таблицу clicks и увеличивает счётчики переходов
+// в таблице storage. Все изменения выполняются в одной транзакции.
+func (s StorageSQLite) SaveClicks(ctx context.Context, events []models.ClickEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO clicks (short_key, created_at, referrer, user_agent) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	counts := make(map[string]int64)
+	keys := make([]string, 0)
+	for _, event := range events {
+		_, err = stmt.ExecContext(ctx, event.ShortKey, event.Timestamp.UnixMicro(), event.Referrer, event.UserAgent)
+		if err != nil {
+			return err
+		}
+		if _, ok := counts[event.ShortKey]; !ok {
+			keys = append(keys, event.ShortKey)
+		}
+		counts[event.ShortKey]++
+	}
+
+	for _, key := range keys {
+		_, err = tx.ExecContext(ctx, "UPDATE storage SET clicks = clicks + ? WHERE short_key=?", counts[key], key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetStats возвращает счётчик переходов по короткой ссылке и временной ряд переходов,
+// сгруппированный на стороне базы данных по интервалам длиной step.
+func (s StorageSQLite) GetStats(ctx context.Context, shortKey string, from, to time.Time, step time.Duration) (total int64, series []models.StatsBucket, err error) {
+	row := s.conn.QueryRowContext(ctx, "SELECT clicks FROM storage WHERE short_key=?", shortKey)
+	if err = row.Scan(&total); err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := s.conn.QueryContext(ctx, `
+		SELECT created_at / 1000000 / ? * ? AS bucket, COUNT(*)
+		FROM clicks
+		WHERE short_key=? AND created_at >= ? AND created_at < ?
+		GROUP BY bucket
+		ORDER BY bucket
+	`, int64(step/time.Second), int64(step/time.Second), shortKey, from.UnixMicro(), to.UnixMicro())
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket int64
+		var clicks int64
+		if err = rows.Scan(&bucket, &clicks); err != nil {
+			return 0, nil, err
+		}
+		series = append(series, models.StatsBucket{Time: time.Unix(bucket, 0).UTC(), Clicks: clicks})
+	}
+
+	if err = rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return total, series, nil
+}
+
+// Update заменяет оригинальный URL короткой ссылки текущего пользователя.
+// Предыдущее значение сохраняется в таблице storage_history в той же транзакции.
+func (s StorageSQLite) Update(ctx context.Context, shortKey string, url string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockOwnedURL(ctx, tx, shortKey)
+	if err != nil {
+		return err
+	}
+	if current == url {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO storage_history (short_key, url, changed_at) VALUES (?, ?, ?)", shortKey, current, time.Now().UnixMicro())
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE storage SET url=? WHERE short_key=?", url, shortKey)
+	if err != nil {
+		return convertError(err)
+	}
+
+	return tx.Commit()
+}
+
+// GetHistory возвращает историю изменений короткой ссылки текущего пользователя.
+func (s StorageSQLite) GetHistory(ctx context.Context, shortKey string) ([]models.HistoryItem, error) {
+	var userID sql.NullString
+
+	err := s.conn.QueryRowContext(ctx, "SELECT user_id FROM storage WHERE short_key=?", shortKey).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userID.String != auth.UserIDFromContext(ctx) {
+		return nil, storage.ErrForbidden
+	}
+
+	rows, err := s.conn.QueryContext(ctx, "SELECT url, changed_at FROM storage_history WHERE short_key=? ORDER BY id DESC", shortKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]models.HistoryItem, 0)
+	for rows.Next() {
+		var item models.HistoryItem
+		var changedAt int64
+		if err = rows.Scan(&item.OriginalURL, &changedAt); err != nil {
+			return nil, err
+		}
+		item.ChangedAt = time.UnixMicro(changedAt).UTC()
+		result = append(result, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Rollback восстанавливает последнее значение из истории изменений короткой ссылки
+// текущего пользователя и удаляет его из истории.
+func (s StorageSQLite) Rollback(ctx context.Context, shortKey string) (string, error) {
+	var historyID int64
+	var previous string
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	if _, err = lockOwnedURL(ctx, tx, shortKey); err != nil {
+		return "", err
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT id, url FROM storage_history WHERE short_key=? ORDER BY id DESC LIMIT 1", shortKey)
+	err = row.Scan(&historyID, &previous)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrNoHistory
+	}
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE storage SET url=? WHERE short_key=?", previous, shortKey)
+	if err != nil {
+		return "", convertError(err)
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM storage_history WHERE id=?", historyID)
+	if err != nil {
+		return "", err
+	}
+
+	return previous, tx.Commit()
+}
+
+// DeleteExpired удаляет ссылки, срок действия которых истёк к моменту now.
+// При purge=true строки удаляются вместе с переходами и историей изменений,
+// иначе ссылки помечаются как удалённые.
+func (s StorageSQLite) DeleteExpired(ctx context.Context, now time.Time, purge bool) (int64, error) {
+	if !purge {
+		result, err := s.conn.ExecContext(ctx, "UPDATE storage SET is_deleted=1, deleted_at=? WHERE expires_at <= ? AND is_deleted=0", now.UnixMicro(), now.UnixMicro())
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM clicks WHERE short_key IN (SELECT short_key FROM storage WHERE expires_at <= ?)", now.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM storage_history WHERE short_key IN (SELECT short_key FROM storage WHERE expires_at <= ?)", now.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM storage WHERE expires_at <= ?", now.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// Restore снимает пометку удаления со ссылки в транзакции, удерживающей блокировку записи.
+func (s StorageSQLite) Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (string, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var url string
+	var userID sql.NullString
+	var isDeleted bool
+	var deletedAt sql.NullInt64
+
+	row := tx.QueryRowContext(ctx, "SELECT url, user_id, is_deleted, deleted_at FROM storage WHERE short_key=?", shortKey)
+	err = row.Scan(&url, &userID, &isDeleted, &deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case userID.String != auth.UserIDFromContext(ctx):
+		return "", storage.ErrForbidden
+	case !isDeleted:
+		return "", storage.ErrNotDeleted
+	case !deletedAt.Valid || time.UnixMicro(deletedAt.Int64).Before(deletedAfter):
+		return "", storage.ErrRestoreExpired
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE storage SET is_deleted=0, deleted_at=NULL WHERE short_key=?", shortKey); err != nil {
+		return "", err
+	}
+
+	return url, tx.Commit()
+}
+
+// PurgeDeleted удаляет строки ссылок, помеченных удалёнными раньше момента before,
+// вместе с переходами и историей изменений в одной транзакции.
+func (s StorageSQLite) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM clicks WHERE short_key IN (SELECT short_key FROM storage WHERE is_deleted=1 AND deleted_at < ?)", before.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM storage_history WHERE short_key IN (SELECT short_key FROM storage WHERE is_deleted=1 AND deleted_at < ?)", before.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, "DELETE FROM storage WHERE is_deleted=1 AND deleted_at < ?", before.UnixMicro())
+	if err != nil {
+		return 0, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return count, tx.Commit()
+}
+
+// Consume уменьшает оставшееся количество переходов по ссылке одним условным запросом UPDATE,
+// поэтому параллельные переходы не могут превысить лимит.
+func (s StorageSQLite) Consume(ctx context.Context, shortKey string) error {
+	result, err := s.conn.ExecContext(ctx, "UPDATE storage SET clicks_left = clicks_left - 1 WHERE short_key=? AND max_clicks > 0 AND clicks_left > 0", shortKey)
+	if err != nil {
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return s.consumeFailure(ctx, shortKey)
+	}
+
+	return nil
+}
+
+// consumeFailure определяет, почему Consume не уменьшил остаток переходов по ссылке:
+// ссылки нет, у неё нет ограничения или лимит исчерпан.
+func (s StorageSQLite) consumeFailure(ctx context.Context, shortKey string) error {
+	var maxClicks int64
+	err := s.conn.QueryRowContext(ctx, "SELECT max_clicks FROM storage WHERE short_key=?", shortKey).Scan(&maxClicks)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if maxClicks == 0 {
+		return nil
+	}
+
+	return storage.ErrExhausted
+}
+
+// nullTime преобразует момент времени в микросекунды от начала эпохи Unix,
+// а нулевое время — в NULL.
+func nullTime(t time.Time) sql.NullInt64 {
+	return sql.NullInt64{Int64: t.UnixMicro(), Valid: !t.IsZero()}
+}
+
+// nullString преобразует пустую строку в NULL для записи в базу данных.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// lockOwnedURL возвращает текущий URL короткой ссылки в транзакции, которая уже удерживает
+// блокировку записи (_txlock=immediate).
+// Возвращает ошибку, если ссылка не найдена, удалена или принадлежит другому пользователю.
+func lockOwnedURL(ctx context.Context, tx *sql.Tx, shortKey string) (string, error) {
+	var url string
+	var userID sql.NullString
+	var isDeleted bool
+
+	row := tx.QueryRowContext(ctx, "SELECT url, user_id, is_deleted FROM storage WHERE short_key=?", shortKey)
+	err := row.Scan(&url, &userID, &isDeleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", storage.ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	if userID.String != auth.UserIDFromContext(ctx) {
+		return "", storage.ErrForbidden
+	}
+	if isDeleted {
+		return "", storage.ErrDeleted
+	}
+
+	return url, nil
+}
+
+// isUniqueViolation сообщает, вызвана ли ошибка нарушением уникальности.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlitedriver.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+// convertError преобразует ошибку нарушения уникальности URL в storage.ErrConflict.
+func convertError(err error) error {
+	if isUniqueViolation(err) {
+		return storage.ErrConflict
+	}
+	return err
+}
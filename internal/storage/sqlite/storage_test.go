@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/storagetest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T, path string) *StorageSQLite {
+	conn, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	s := NewStorage(conn)
+	require.NoError(t, s.Bootstrap(context.Background()))
+
+	return s
+}
+
+func TestStorageSQLite_Conformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		return newTestStorage(t, filepath.Join(t.TempDir(), "storage.db"))
+	})
+}
+
+func TestStorageSQLite_WALMode(t *testing.T) {
+	s := newTestStorage(t, filepath.Join(t.TempDir(), "storage.db"))
+
+	var mode string
+	err := s.conn.QueryRowContext(context.Background(), "PRAGMA journal_mode").Scan(&mode)
+	require.NoError(t, err)
+	assert.Equal(t, "wal", mode)
+}
+
+func TestOpen_DSNWithParams(t *testing.T) {
+	path := "file:" + filepath.Join(t.TempDir(), "storage.db") + "?_pragma=foreign_keys(1)"
+	s := newTestStorage(t, path)
+
+	var mode string
+	err := s.conn.QueryRowContext(context.Background(), "PRAGMA journal_mode").Scan(&mode)
+	require.NoError(t, err)
+	assert.Equal(t, "wal", mode, "Own connection parameters should not replace the default ones")
+
+	var foreignKeys int
+	err = s.conn.QueryRowContext(context.Background(), "PRAGMA foreign_keys").Scan(&foreignKeys)
+	require.NoError(t, err)
+	assert.Equal(t, 1, foreignKeys, "Own connection parameters should be kept")
+}
+
+func TestStorageSQLite_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.db")
+	ctx := context.WithValue(context.Background(), auth.UserIDKey, "user1")
+
+	s := newTestStorage(t, path)
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1", MaxClicks: 2})
+	require.NoError(t, err)
+	require.NoError(t, s.Consume(ctx, "short1"))
+	require.NoError(t, s.conn.Close())
+
+	reopened := newTestStorage(t, path)
+	link, err := reopened.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/1", link.OriginalURL)
+	assert.Equal(t, "user1", link.UserID)
+	assert.Equal(t, int64(1), link.ClicksLeft)
+}
+
+func TestStorageSQLite_Ping(t *testing.T) {
+	s := newTestStorage(t, filepath.Join(t.TempDir(), "storage.db"))
+
+	var _ storage.Pinger = s
+	assert.NoError(t, s.Ping(context.Background()))
+
+	require.NoError(t, s.conn.Close())
+	assert.Error(t, s.Ping(context.Background()))
+}
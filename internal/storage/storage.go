@@ -2,26 +2,216 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"iter"
+	"time"
 
+	"github.com/dsemenov12/shorturl/internal/keygen"
 	"github.com/dsemenov12/shorturl/internal/models"
 )
 
+// BatchResult — результат сохранения одной ссылки пакета методом SetBatch.
+type BatchResult struct {
+	ShortKey string // Ключ сохранённой ссылки, а при ErrConflict — ключ существующей ссылки с тем же URL
+	Err      error  // nil, ErrConflict или ErrKeyExists
+}
+
+// UserLinkSeq — итератор по ссылкам пользователя; второй элемент пары — ошибка чтения.
+type UserLinkSeq = iter.Seq2[models.UserLink, error]
+
+// MaxKeyAttempts — максимальное количество попыток подобрать свободный короткий ключ.
+const MaxKeyAttempts = 5
+
+// Ошибки, возвращаемые реализациями Storage.
+var (
+	// ErrNotFound возвращается, если короткая ссылка не найдена.
+	ErrNotFound = errors.New("short url not found")
+	// ErrForbidden возвращается при попытке изменить ссылку другого пользователя.
+	ErrForbidden = errors.New("short url belongs to another user")
+	// ErrDeleted возвращается при попытке изменить удалённую ссылку.
+	ErrDeleted = errors.New("short url was deleted")
+	// ErrConflict возвращается, если такой URL уже сокращён.
+	ErrConflict = errors.New("url already shortened")
+	// ErrKeyExists возвращается, если короткий ключ уже занят другой ссылкой.
+	ErrKeyExists = errors.New("short key already exists")
+	// ErrNoHistory возвращается при откате ссылки, у которой нет предыдущих значений.
+	ErrNoHistory = errors.New("short url has no history")
+	// ErrExhausted возвращается, если лимит переходов по ссылке исчерпан.
+	ErrExhausted = errors.New("short url click limit exhausted")
+	// ErrNotDeleted возвращается при восстановлении ссылки, которая не удалена.
+	ErrNotDeleted = errors.New("short url is not deleted")
+	// ErrRestoreExpired возвращается при восстановлении ссылки, удалённой раньше допустимого срока.
+	ErrRestoreExpired = errors.New("short url restore period has expired")
+)
+
 // Storage определяет интерфейс для работы с хранилищем сокращенных URL-адресов.
 type Storage interface {
 	// Bootstrap инициализирует хранилище (например, создает таблицы в БД или загружает данные из файла).
 	Bootstrap(ctx context.Context) error
-	// Set сохраняет URL под заданным коротким ключом.
-	// Если ключ уже существует, возвращает существующий ключ и ошибку.
-	Set(ctx context.Context, shortKey string, url string) (string, error)
-	// Get получает оригинальный URL по его короткому ключу.
-	Get(ctx context.Context, shortKey string) (string, string, bool, error)
-	// GetUserURL возвращает список всех URL, сохраненных пользователем.
+	// Set сохраняет ссылку под её коротким ключом.
+	// Владельцем ссылки становится link.UserID, а если он не задан — пользователь из контекста.
+	// Если такой URL уже сокращён, возвращает существующий ключ и ErrConflict.
+	// Если ключ уже занят другой ссылкой, возвращает ErrKeyExists.
+	Set(ctx context.Context, link models.Link) (string, error)
+	// SetBatch сохраняет пакет ссылок в одной транзакции и возвращает результаты по каждой ссылке в порядке links:
+	// без ошибки — ссылка сохранена, ErrConflict — такой URL уже сокращён (ShortKey содержит ключ существующей ссылки),
+	// ErrKeyExists — ключ уже занят. Владелец и время создания назначаются так же, как в Set.
+	// При atomic=true пакет сохраняется, только если ни одна ссылка не получила ErrKeyExists;
+	// иначе изменения отменяются и вместе с результатами возвращается ErrKeyExists.
+	SetBatch(ctx context.Context, links []models.Link, atomic bool) ([]BatchResult, error)
+	// Import сохраняет пакет ссылок под их короткими ключами, не прерываясь на ссылках, которые сохранить нельзя.
+	// Владелец и время создания назначаются так же, как в Set. Возвращает ошибки по каждой ссылке
+	// в порядке links: nil — ссылка сохранена, ErrConflict — такой URL уже сокращён,
+	// ErrKeyExists — ключ уже занят. Ошибка второго результата означает, что пакет не обработан.
+	Import(ctx context.Context, links []models.Link) ([]error, error)
+	// Get получает ссылку по её короткому ключу.
+	// Если ссылка не найдена, возвращает ErrNotFound.
+	Get(ctx context.Context, shortKey string) (models.Link, error)
+	// GetUserURL возвращает неудалённые URL текущего пользователя в порядке создания.
 	GetUserURL(ctx context.Context) (result []models.ShortURLItem, err error)
+	// ListUserURLs возвращает страницу ссылок текущего пользователя, отобранных и упорядоченных согласно query,
+	// а также общее количество подходящих ссылок и курсор следующей страницы.
+	// Для некорректных параметров или курсора возвращает ErrInvalidQuery.
+	ListUserURLs(ctx context.Context, query models.ListQuery) (models.ShortURLPage, error)
+	// UserLinks возвращает итератор по всем ссылкам текущего пользователя, включая удалённые,
+	// в порядке создания вместе с количеством переходов по ним. Ссылки читаются по мере итерации;
+	// при ошибке итератор возвращает её последним элементом.
+	UserLinks(ctx context.Context) UserLinkSeq
 	// Delete помечает сокращенный URL как удаленный (soft delete).
-	// Реальное удаление может происходить асинхронно.
+	// Ссылки других пользователей не изменяются.
 	Delete(ctx context.Context, shortKey string) error
-	// Возвращает количество уникальных URL.
+	// DeleteBatch помечает как удалённые все ссылки shortKeys текущего пользователя одним обращением к хранилищу.
+	// Отсутствующие, уже удалённые и чужие ссылки пропускаются.
+	DeleteBatch(ctx context.Context, shortKeys []string) error
+	// Возвращает количество неудалённых URL.
 	CountURLs(ctx context.Context) (int, error)
-	// CountUsers возвращает количество уникальных пользователей в базе данных.
+	// CountUsers возвращает количество уникальных владельцев ссылок.
 	CountUsers(ctx context.Context) (int, error)
+	// SaveClicks сохраняет пакет событий перехода по коротким ссылкам
+	// и увеличивает счётчики переходов соответствующих ссылок.
+	SaveClicks(ctx context.Context, events []models.ClickEvent) error
+	// GetStats возвращает общее количество переходов по короткой ссылке и временной ряд
+	// переходов за период [from, to), сгруппированный по интервалам длиной step.
+	// Интервалы без переходов в ряд не попадают.
+	GetStats(ctx context.Context, shortKey string, from, to time.Time, step time.Duration) (int64, []models.StatsBucket, error)
+	// Update заменяет оригинальный URL короткой ссылки текущего пользователя.
+	// Предыдущее значение сохраняется в истории изменений.
+	Update(ctx context.Context, shortKey string, url string) error
+	// GetHistory возвращает предыдущие значения оригинального URL короткой ссылки
+	// текущего пользователя, начиная с самого позднего.
+	GetHistory(ctx context.Context, shortKey string) ([]models.HistoryItem, error)
+	// Rollback восстанавливает последнее значение из истории изменений короткой ссылки
+	// текущего пользователя и возвращает восстановленный URL.
+	Rollback(ctx context.Context, shortKey string) (string, error)
+	// DeleteExpired удаляет ссылки, срок действия которых истёк к моменту now, и возвращает их количество.
+	// При purge=true записи удаляются физически, иначе помечаются как удалённые, если хранилище это поддерживает.
+	DeleteExpired(ctx context.Context, now time.Time, purge bool) (int64, error)
+	// Restore снимает пометку удаления с короткой ссылки текущего пользователя и возвращает её URL.
+	// Восстановить можно только ссылку, удалённую не раньше deletedAfter, иначе возвращается ErrRestoreExpired;
+	// для неудалённой ссылки возвращается ErrNotDeleted.
+	Restore(ctx context.Context, shortKey string, deletedAfter time.Time) (string, error)
+	// PurgeDeleted физически удаляет ссылки, помеченные удалёнными раньше момента before,
+	// вместе с их переходами и историей изменений и возвращает их количество.
+	// Ключи и URL удалённых ссылок становятся доступны для повторного использования.
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	// Consume атомарно уменьшает оставшееся количество переходов по ссылке с ограничением max_clicks.
+	// Вызывается только для ссылок с MaxClicks > 0; для ссылки без ограничения ничего не делает.
+	// Если лимит уже исчерпан, возвращает ErrExhausted, если ссылки нет — ErrNotFound.
+	Consume(ctx context.Context, shortKey string) error
+}
+
+// Pinger реализуют хранилища, работающие с внешним сервером, доступность которого можно проверить.
+type Pinger interface {
+	// Ping проверяет соединение с сервером хранилища.
+	Ping(ctx context.Context) error
+}
+
+// SetGenerated сохраняет ссылку под ключом, полученным от генератора keys.
+// Если ключ уже занят другой ссылкой, генерация повторяется, но не более MaxKeyAttempts раз,
+// после чего возвращается ErrKeyExists.
+// Возвращает ключ сохранённой ссылки, а если URL уже сокращён — существующий ключ и ErrConflict.
+func SetGenerated(ctx context.Context, s Storage, keys keygen.KeyGenerator, link models.Link) (string, error) {
+	for attempt := 0; attempt < MaxKeyAttempts; attempt++ {
+		shortKey, err := keys.Generate(link.OriginalURL, attempt)
+		if err != nil {
+			return "", err
+		}
+
+		link.ShortKey = shortKey
+		result, err := s.Set(ctx, link)
+		if errors.Is(err, ErrKeyExists) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		return shortKey, nil
+	}
+
+	return "", ErrKeyExists
+}
+
+// SetBatchGenerated сохраняет пакет ссылок методом SetBatch под ключами, полученными от генератора keys.
+// Ссылки, ключи которых оказались заняты, сохраняются повторно с новыми ключами, но не более MaxKeyAttempts раз;
+// при atomic=true повторно сохраняется весь пакет. Если свободные ключи подобрать не удалось, возвращается ErrKeyExists.
+// Результаты возвращаются в порядке links.
+func SetBatchGenerated(ctx context.Context, s Storage, keys keygen.KeyGenerator, links []models.Link, atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(links))
+	pending := make([]int, len(links))
+	for i := range links {
+		pending[i] = i
+	}
+
+	for attempt := 0; attempt < MaxKeyAttempts && len(pending) > 0; attempt++ {
+		batch := make([]models.Link, len(pending))
+		for i, n := range pending {
+			shortKey, err := keys.Generate(links[n].OriginalURL, attempt)
+			if err != nil {
+				return nil, err
+			}
+			batch[i] = links[n]
+			batch[i].ShortKey = shortKey
+		}
+
+		batchResults, err := s.SetBatch(ctx, batch, atomic)
+		if atomic && errors.Is(err, ErrKeyExists) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var retry []int
+		for i, n := range pending {
+			if errors.Is(batchResults[i].Err, ErrKeyExists) {
+				retry = append(retry, n)
+				continue
+			}
+			results[n] = batchResults[i]
+		}
+		pending = retry
+	}
+	if len(pending) > 0 {
+		return nil, ErrKeyExists
+	}
+
+	return results, nil
+}
+
+// BucketStart возвращает начало интервала длиной step, в который попадает момент t.
+// Интервалы отсчитываются от начала эпохи Unix, что совпадает с группировкой в PostgreSQL.
+func BucketStart(t time.Time, step time.Duration) time.Time {
+	seconds := int64(step / time.Second)
+	if seconds <= 0 {
+		return t.UTC()
+	}
+
+	unix := t.Unix()
+	bucket := unix - unix%seconds
+	if unix < 0 && unix%seconds != 0 {
+		bucket -= seconds
+	}
+
+	return time.Unix(bucket, 0).UTC()
 }
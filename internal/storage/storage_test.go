@@ -0,0 +1,152 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+	"github.com/dsemenov12/shorturl/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+// sequenceKeys возвращает ключи из заранее заданного списка.
+type sequenceKeys []string
+
+func (k sequenceKeys) Generate(url string, attempt int) (string, error) {
+	return k[attempt%len(k)], nil
+}
+
+func TestSetGenerated(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStorage()
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "taken", OriginalURL: "https://example.com/1"})
+	assert.NoError(t, err)
+
+	t.Run("retry on collision", func(t *testing.T) {
+		shortKey, err := storage.SetGenerated(ctx, s, sequenceKeys{"taken", "free"}, models.Link{OriginalURL: "https://example.com/2"})
+		assert.NoError(t, err)
+		assert.Equal(t, "free", shortKey)
+	})
+
+	t.Run("url already shortened", func(t *testing.T) {
+		shortKey, err := storage.SetGenerated(ctx, s, sequenceKeys{"other"}, models.Link{OriginalURL: "https://example.com/1"})
+		assert.ErrorIs(t, err, storage.ErrConflict)
+		assert.Equal(t, "taken", shortKey)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		_, err := storage.SetGenerated(ctx, s, sequenceKeys{"taken"}, models.Link{OriginalURL: "https://example.com/3"})
+		assert.ErrorIs(t, err, storage.ErrKeyExists)
+	})
+}
+
+// urlKeys возвращает для каждого URL ключи из заданного списка по номеру попытки.
+type urlKeys map[string][]string
+
+func (k urlKeys) Generate(url string, attempt int) (string, error) {
+	keys := k[url]
+	return keys[min(attempt, len(keys)-1)], nil
+}
+
+func TestSetBatchGenerated(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStorage()
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "taken", OriginalURL: "https://example.com/1"})
+	assert.NoError(t, err)
+
+	keys := urlKeys{
+		"https://example.com/1": {"other"},
+		"https://example.com/2": {"key2"},
+		"https://example.com/3": {"taken", "key3"},
+		"https://example.com/4": {"key4"},
+		"https://example.com/5": {"taken", "key5"},
+		"https://example.com/6": {"taken"},
+	}
+
+	t.Run("retry on collision", func(t *testing.T) {
+		links := []models.Link{
+			{OriginalURL: "https://example.com/1"},
+			{OriginalURL: "https://example.com/2"},
+			{OriginalURL: "https://example.com/3"},
+		}
+		results, err := storage.SetBatchGenerated(ctx, s, keys, links, false)
+		assert.NoError(t, err)
+		assert.Equal(t, []storage.BatchResult{
+			{ShortKey: "taken", Err: storage.ErrConflict},
+			{ShortKey: "key2"},
+			{ShortKey: "key3"},
+		}, results)
+	})
+
+	t.Run("atomic retry", func(t *testing.T) {
+		links := []models.Link{
+			{OriginalURL: "https://example.com/4"},
+			{OriginalURL: "https://example.com/5"},
+		}
+		results, err := storage.SetBatchGenerated(ctx, s, keys, links, true)
+		assert.NoError(t, err)
+		assert.Equal(t, []storage.BatchResult{{ShortKey: "key4"}, {ShortKey: "key5"}}, results)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		_, err := storage.SetBatchGenerated(ctx, s, keys, []models.Link{{OriginalURL: "https://example.com/6"}}, false)
+		assert.ErrorIs(t, err, storage.ErrKeyExists)
+	})
+}
+
+func TestNormalizeListQuery(t *testing.T) {
+	query, cursor, err := storage.NormalizeListQuery(models.ListQuery{})
+	assert.NoError(t, err)
+	assert.Nil(t, cursor)
+	assert.Equal(t, models.ListQuery{Limit: storage.DefaultListLimit, Sort: models.ListSortCreated, Deleted: models.DeletedExclude}, query)
+
+	query, _, err = storage.NormalizeListQuery(models.ListQuery{Limit: storage.MaxListLimit + 1})
+	assert.NoError(t, err)
+	assert.Equal(t, storage.MaxListLimit, query.Limit, "Limit should be capped")
+
+	query, _, err = storage.NormalizeListQuery(models.ListQuery{Tag: " Promo "})
+	assert.NoError(t, err)
+	assert.Equal(t, "promo", query.Tag, "Tag should be normalized")
+
+	encoded := storage.ListCursor{Sort: models.ListSortClicks, Desc: true, Clicks: 3, ShortKey: "short1"}
+	_, cursor, err = storage.NormalizeListQuery(models.ListQuery{Sort: models.ListSortClicks, Desc: true, Cursor: encoded.Encode()})
+	assert.NoError(t, err)
+	if assert.NotNil(t, cursor) {
+		assert.Equal(t, encoded, *cursor)
+	}
+
+	invalid := []models.ListQuery{
+		{Limit: -1},
+		{Sort: "url"},
+		{Deleted: "maybe"},
+		{Tag: "bad tag"},
+		{Cursor: "%%%"},
+		{Cursor: "bm90IGpzb24"},
+		{Sort: models.ListSortCreated, Cursor: encoded.Encode()},
+	}
+	for _, query := range invalid {
+		_, _, err = storage.NormalizeListQuery(query)
+		assert.ErrorIs(t, err, storage.ErrInvalidQuery, "%+v", query)
+	}
+}
+
+func TestListCursor_Compare(t *testing.T) {
+	now := time.Now()
+	first := storage.ListCursor{Sort: models.ListSortCreated, CreatedAt: now, Clicks: 5, ShortKey: "b"}
+	second := storage.ListCursor{Sort: models.ListSortCreated, CreatedAt: now.Add(time.Second), Clicks: 1, ShortKey: "a"}
+	tie := storage.ListCursor{Sort: models.ListSortCreated, CreatedAt: now, Clicks: 5, ShortKey: "c"}
+
+	assert.Negative(t, first.Compare(second))
+	assert.Negative(t, first.Compare(tie), "Ties should be broken by short key")
+	assert.Zero(t, first.Compare(first))
+
+	first.Sort = models.ListSortClicks
+	assert.Positive(t, first.Compare(second))
+
+	first.Desc = true
+	assert.Negative(t, first.Compare(second))
+}
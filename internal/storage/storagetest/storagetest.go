@@ -0,0 +1,829 @@
+// Package storagetest содержит общий набор тестов, которому должна соответствовать
+// любая реализация storage.Storage.
+//
+// Реализация подключает набор в своём пакете тестов:
+//
+//	func TestStorageMemory_Conformance(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Storage {
+//			return NewStorage()
+//		})
+//	}
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dsemenov12/shorturl/internal/auth"
+	"github.com/dsemenov12/shorturl/internal/models"
+	"github.com/dsemenov12/shorturl/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory создаёт пустое инициализированное хранилище для одного теста.
+// Освобождение ресурсов регистрируется через t.Cleanup.
+type Factory func(t *testing.T) storage.Storage
+
+// precision — допустимое расхождение сохранённого времени из-за точности хранилища.
+const precision = time.Millisecond
+
+// Run запускает набор тестов для хранилища, создаваемого newStorage.
+// Каждый подтест получает отдельное пустое хранилище.
+func Run(t *testing.T, newStorage Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s storage.Storage)
+	}{
+		{"SetAndGet", testSetAndGet},
+		{"Conflicts", testConflicts},
+		{"SetBatch", testSetBatch},
+		{"Import", testImport},
+		{"Ownership", testOwnership},
+		{"SoftDelete", testSoftDelete},
+		{"DeleteBatch", testDeleteBatch},
+		{"Restore", testRestore},
+		{"PurgeDeleted", testPurgeDeleted},
+		{"UserURLs", testUserURLs},
+		{"ListUserURLs", testListUserURLs},
+		{"UserLinks", testUserLinks},
+		{"Counts", testCounts},
+		{"History", testHistory},
+		{"Stats", testStats},
+		{"DeleteExpired", testDeleteExpired},
+		{"ConcurrentSet", testConcurrentSet},
+		{"Consume", testConsume},
+		{"ConcurrentConsume", testConcurrentConsume},
+		{"ContextCancellation", testContextCancellation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.fn(t, newStorage(t))
+		})
+	}
+}
+
+// userContext возвращает контекст запроса пользователя userID.
+func userContext(userID string) context.Context {
+	return context.WithValue(context.Background(), auth.UserIDKey, userID)
+}
+
+func testSetAndGet(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+	expiresAt := time.Now().Add(time.Hour).UTC()
+
+	shortKey, err := s.Set(ctx, models.Link{
+		ShortKey:     "short1",
+		OriginalURL:  "http://example.com/1",
+		ExpiresAt:    expiresAt,
+		MaxClicks:    3,
+		PasswordHash: "hash",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "short1", shortKey, "Set should return the short key")
+
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "short1", link.ShortKey)
+	assert.Equal(t, "http://example.com/1", link.OriginalURL)
+	assert.Equal(t, "user1", link.UserID, "Owner should be taken from the context")
+	assert.WithinDuration(t, time.Now(), link.CreatedAt, time.Minute, "CreatedAt should be set on Set")
+	assert.False(t, link.IsDeleted)
+	assert.WithinDuration(t, expiresAt, link.ExpiresAt, precision)
+	assert.Equal(t, int64(3), link.MaxClicks)
+	assert.Equal(t, int64(3), link.ClicksLeft, "ClicksLeft should start at MaxClicks")
+	assert.Equal(t, "hash", link.PasswordHash)
+
+	_, err = s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2", UserID: "user2"})
+	require.NoError(t, err)
+	link, err = s.Get(ctx, "short2")
+	require.NoError(t, err)
+	assert.Equal(t, "user2", link.UserID, "Explicit owner should take precedence over the context")
+
+	_, err = s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testConflicts(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+
+	shortKey, err := s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/1"})
+	assert.ErrorIs(t, err, storage.ErrConflict, "Set of an already shortened URL should report a conflict")
+	assert.Equal(t, "short1", shortKey, "Set should return the existing key on conflict")
+
+	shortKey, err = s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/2"})
+	assert.ErrorIs(t, err, storage.ErrKeyExists, "Set should not overwrite an existing key")
+	assert.Empty(t, shortKey)
+
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/1", link.OriginalURL)
+
+	_, err = s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2"})
+	require.NoError(t, err)
+	err = s.Update(ctx, "short2", "http://example.com/1")
+	assert.ErrorIs(t, err, storage.ErrConflict, "Update to a URL of another link should report a conflict")
+}
+
+func testSetBatch(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short0", OriginalURL: "http://example.com/0"})
+	require.NoError(t, err)
+
+	results, err := s.SetBatch(ctx, []models.Link{
+		{ShortKey: "short1", OriginalURL: "http://example.com/1", MaxClicks: 3},
+		{ShortKey: "short2", OriginalURL: "http://example.com/0"},
+		{ShortKey: "short0", OriginalURL: "http://example.com/3"},
+		{ShortKey: "short4", OriginalURL: "http://example.com/1"},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.BatchResult{
+		{ShortKey: "short1"},
+		{ShortKey: "short0", Err: storage.ErrConflict},
+		{Err: storage.ErrKeyExists},
+		{ShortKey: "short1", Err: storage.ErrConflict},
+	}, results)
+
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "user1", link.UserID, "Owner should be taken from the context")
+	assert.Equal(t, int64(3), link.ClicksLeft)
+
+	results, err = s.SetBatch(ctx, []models.Link{
+		{ShortKey: "short5", OriginalURL: "http://example.com/5"},
+		{ShortKey: "short0", OriginalURL: "http://example.com/6"},
+	}, true)
+	assert.ErrorIs(t, err, storage.ErrKeyExists)
+	if assert.Len(t, results, 2) {
+		assert.ErrorIs(t, results[1].Err, storage.ErrKeyExists)
+	}
+	_, err = s.Get(ctx, "short5")
+	assert.ErrorIs(t, err, storage.ErrNotFound, "Atomic batch should be rolled back")
+
+	results, err = s.SetBatch(ctx, []models.Link{
+		{ShortKey: "short5", OriginalURL: "http://example.com/5"},
+		{ShortKey: "short6", OriginalURL: "http://example.com/0"},
+	}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.BatchResult{{ShortKey: "short5"}, {ShortKey: "short0", Err: storage.ErrConflict}}, results,
+		"Already shortened URLs should not roll back an atomic batch")
+
+	items, err := s.GetUserURL(ctx)
+	require.NoError(t, err)
+	assert.Len(t, items, 3)
+}
+
+func testImport(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "existing", OriginalURL: "http://example.com/existing"})
+	require.NoError(t, err)
+
+	errs, err := s.Import(ctx, []models.Link{
+		{ShortKey: "short1", OriginalURL: "http://example.com/1", CreatedAt: createdAt},
+		{ShortKey: "existing", OriginalURL: "http://example.com/2"},
+		{ShortKey: "short3", OriginalURL: "http://example.com/existing"},
+		{ShortKey: "short1", OriginalURL: "http://example.com/4"},
+		{ShortKey: "short5", OriginalURL: "http://example.com/1"},
+		{ShortKey: "short6", OriginalURL: "http://example.com/6", UserID: "user2", MaxClicks: 2},
+	})
+	require.NoError(t, err)
+	require.Len(t, errs, 6)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], storage.ErrKeyExists, "Existing key")
+	assert.ErrorIs(t, errs[2], storage.ErrConflict, "Existing URL")
+	assert.ErrorIs(t, errs[3], storage.ErrKeyExists, "Key repeated within the batch")
+	assert.ErrorIs(t, errs[4], storage.ErrConflict, "URL repeated within the batch")
+	assert.NoError(t, errs[5])
+
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/1", link.OriginalURL)
+	assert.Equal(t, "user1", link.UserID, "Owner should default to the current user")
+	assert.WithinDuration(t, createdAt, link.CreatedAt, precision, "Given creation time should be preserved")
+
+	link, err = s.Get(ctx, "short6")
+	require.NoError(t, err)
+	assert.Equal(t, "user2", link.UserID, "Given owner should be preserved")
+	assert.Equal(t, int64(2), link.ClicksLeft)
+	assert.False(t, link.CreatedAt.IsZero())
+
+	_, err = s.Get(ctx, "short3")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	urls, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, urls)
+
+	errs, err = s.Import(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+}
+
+func testOwnership(t *testing.T, s storage.Storage) {
+	ownerCtx := userContext("user1")
+	otherCtx := userContext("user2")
+
+	_, err := s.Set(ownerCtx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+
+	err = s.Update(otherCtx, "short1", "http://example.com/2")
+	assert.ErrorIs(t, err, storage.ErrForbidden)
+	_, err = s.GetHistory(otherCtx, "short1")
+	assert.ErrorIs(t, err, storage.ErrForbidden)
+	_, err = s.Rollback(otherCtx, "short1")
+	assert.ErrorIs(t, err, storage.ErrForbidden)
+
+	assert.NoError(t, s.Delete(otherCtx, "short1"), "Delete of another user's link should be ignored")
+	link, err := s.Get(ownerCtx, "short1")
+	require.NoError(t, err)
+	assert.False(t, link.IsDeleted, "Link of another user should not be deleted")
+	assert.Equal(t, "http://example.com/1", link.OriginalURL)
+
+	err = s.Update(ownerCtx, "missing", "http://example.com/3")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.GetHistory(ownerCtx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.Rollback(ownerCtx, "missing")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func testDeleteBatch(t *testing.T, s storage.Storage) {
+	ownerCtx := userContext("user1")
+	otherCtx := userContext("user2")
+
+	for i := 1; i <= 3; i++ {
+		_, err := s.Set(ownerCtx, models.Link{ShortKey: fmt.Sprintf("short%d", i), OriginalURL: fmt.Sprintf("http://example.com/%d", i)})
+		require.NoError(t, err)
+	}
+	_, err := s.Set(otherCtx, models.Link{ShortKey: "other", OriginalURL: "http://example.com/other"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteBatch(ownerCtx, []string{"short1", "short2", "short2", "other", "missing"}))
+	require.NoError(t, s.DeleteBatch(ownerCtx, []string{"short1"}), "Repeated DeleteBatch should not fail")
+	require.NoError(t, s.DeleteBatch(ownerCtx, nil))
+
+	for shortKey, deleted := range map[string]bool{"short1": true, "short2": true, "short3": false, "other": false} {
+		link, err := s.Get(ownerCtx, shortKey)
+		require.NoError(t, err)
+		assert.Equal(t, deleted, link.IsDeleted, shortKey)
+	}
+}
+
+func testRestore(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+
+	_, err = s.Restore(ctx, "short1", time.Time{})
+	assert.ErrorIs(t, err, storage.ErrNotDeleted)
+
+	before := time.Now().Add(-time.Minute)
+	require.NoError(t, s.Delete(ctx, "short1"))
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.False(t, link.DeletedAt.Before(before), "Deletion time should be recorded")
+
+	_, err = s.Restore(userContext("user2"), "short1", before)
+	assert.ErrorIs(t, err, storage.ErrForbidden)
+	_, err = s.Restore(ctx, "missing", before)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.Restore(ctx, "short1", time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, storage.ErrRestoreExpired)
+
+	url, err := s.Restore(ctx, "short1", before)
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/1", url)
+
+	link, err = s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.False(t, link.IsDeleted)
+	assert.True(t, link.DeletedAt.IsZero())
+
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/2"), "Restored link should be editable")
+}
+
+func testPurgeDeleted(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+
+	for i := 1; i <= 2; i++ {
+		_, err := s.Set(ctx, models.Link{ShortKey: fmt.Sprintf("short%d", i), OriginalURL: fmt.Sprintf("http://example.com/%d", i)})
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.SaveClicks(ctx, []models.ClickEvent{{ShortKey: "short1", Timestamp: time.Now()}}))
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/3"))
+	require.NoError(t, s.Delete(ctx, "short1"))
+
+	count, err := s.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, count, "Recently deleted links should be kept")
+
+	count, err = s.PurgeDeleted(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, err = s.Get(ctx, "short1")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	_, err = s.Get(ctx, "short2")
+	assert.NoError(t, err, "Links that are not deleted should be kept")
+
+	ctx2 := userContext("user2")
+	_, err = s.Set(ctx2, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/3"})
+	require.NoError(t, err, "Key and URL of a purged link should be reusable")
+
+	_, series, err := s.GetStats(ctx2, "short1", time.Now().Add(-time.Hour), time.Now().Add(time.Hour), time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, series, "Clicks of purged links should be removed")
+	history, err := s.GetHistory(ctx2, "short1")
+	require.NoError(t, err)
+	assert.Empty(t, history, "History of purged links should be removed")
+}
+
+func testSoftDelete(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Delete(ctx, "short1"))
+	require.NoError(t, s.Delete(ctx, "short1"), "Repeated Delete should not fail")
+	require.NoError(t, s.Delete(ctx, "missing"), "Delete of a missing link should not fail")
+
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err, "Deleted link should still be readable")
+	assert.True(t, link.IsDeleted)
+
+	err = s.Update(ctx, "short1", "http://example.com/2")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+	_, err = s.Rollback(ctx, "short1")
+	assert.ErrorIs(t, err, storage.ErrDeleted)
+
+	shortKey, err := s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/1"})
+	assert.ErrorIs(t, err, storage.ErrConflict, "URL of a deleted link should stay reserved")
+	assert.Equal(t, "short1", shortKey)
+}
+
+func testUserURLs(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	result, err := s.GetUserURL(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, result)
+
+	_, err = s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2", CreatedAt: createdAt.Add(time.Minute), MaxClicks: 5})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1", CreatedAt: createdAt})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "short3", OriginalURL: "http://example.com/3", CreatedAt: createdAt.Add(2 * time.Minute)})
+	require.NoError(t, err)
+	_, err = s.Set(userContext("user2"), models.Link{ShortKey: "other", OriginalURL: "http://example.com/4"})
+	require.NoError(t, err)
+	_, err = s.Set(context.Background(), models.Link{ShortKey: "anonymous", OriginalURL: "http://example.com/5"})
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, "short3"))
+	require.NoError(t, s.Consume(ctx, "short2"))
+
+	result, err = s.GetUserURL(ctx)
+	require.NoError(t, err)
+	require.Len(t, result, 2, "Only non-deleted links of the current user should be returned")
+	assert.Equal(t, "http://example.com/1", result[0].OriginalURL, "Links should be ordered by creation time")
+	assert.Nil(t, result[0].ClicksLeft, "Unlimited link should not report remaining clicks")
+	assert.Equal(t, "http://example.com/2", result[1].OriginalURL)
+	if assert.NotNil(t, result[1].ClicksLeft) {
+		assert.Equal(t, int64(4), *result[1].ClicksLeft)
+	}
+
+	result, err = s.GetUserURL(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, result, "Anonymous user should not see any links")
+}
+
+func testListUserURLs(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	page, err := s.ListUserURLs(ctx, models.ListQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+	assert.Zero(t, page.Total)
+	assert.Empty(t, page.NextCursor)
+
+	// Ссылки short0..short4 создаются с интервалом в минуту; у short1 и short3 одинаковое время создания
+	created := []time.Duration{0, time.Minute, 2 * time.Minute, time.Minute, 3 * time.Minute}
+	linkTags := [][]string{{"promo"}, nil, {"news", "promo"}, {"news"}, {"promo"}}
+	for i, offset := range created {
+		_, err = s.Set(ctx, models.Link{
+			ShortKey:    fmt.Sprintf("short%d", i),
+			OriginalURL: fmt.Sprintf("http://Example.com/%d", i),
+			CreatedAt:   createdAt.Add(offset),
+			Tags:        linkTags[i],
+		})
+		require.NoError(t, err)
+	}
+	_, err = s.Set(userContext("user2"), models.Link{ShortKey: "other", OriginalURL: "http://example.com/other", Tags: []string{"promo"}})
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, "short4"))
+
+	var events []models.ClickEvent
+	for shortKey, clicks := range map[string]int{"short0": 2, "short2": 3, "short3": 2} {
+		for i := 0; i < clicks; i++ {
+			events = append(events, models.ClickEvent{ShortKey: shortKey, Timestamp: createdAt})
+		}
+	}
+	require.NoError(t, s.SaveClicks(ctx, events))
+
+	// list собирает все страницы списка и возвращает исходные URL в порядке списка
+	list := func(query models.ListQuery) ([]string, int) {
+		var urls []string
+		for {
+			page, err := s.ListUserURLs(ctx, query)
+			require.NoError(t, err)
+			for _, item := range page.Items {
+				urls = append(urls, item.OriginalURL)
+			}
+			if page.NextCursor == "" {
+				return urls, page.Total
+			}
+			require.Len(t, page.Items, query.Limit, "Only the last page may be shorter than the limit")
+			query.Cursor = page.NextCursor
+		}
+	}
+
+	urls, total := list(models.ListQuery{Limit: 2})
+	assert.Equal(t, 4, total)
+	assert.Equal(t, []string{"http://Example.com/0", "http://Example.com/1", "http://Example.com/3", "http://Example.com/2"}, urls,
+		"Links should be ordered by creation time and then by short key")
+
+	urls, _ = list(models.ListQuery{Limit: 3, Desc: true})
+	assert.Equal(t, []string{"http://Example.com/2", "http://Example.com/3", "http://Example.com/1", "http://Example.com/0"}, urls)
+
+	urls, _ = list(models.ListQuery{Limit: 1, Sort: models.ListSortClicks, Desc: true})
+	assert.Equal(t, []string{"http://Example.com/2", "http://Example.com/3", "http://Example.com/0", "http://Example.com/1"}, urls,
+		"Links should be ordered by clicks and then by short key")
+
+	urls, total = list(models.ListQuery{Limit: 10, Search: "example.COM/3"})
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{"http://Example.com/3"}, urls, "Search should be case-insensitive")
+
+	urls, total = list(models.ListQuery{Limit: 10, Deleted: models.DeletedOnly})
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{"http://Example.com/4"}, urls)
+
+	_, total = list(models.ListQuery{Limit: 10, Deleted: models.DeletedInclude})
+	assert.Equal(t, 5, total)
+
+	urls, total = list(models.ListQuery{Limit: 1, Tag: "PROMO"})
+	assert.Equal(t, 2, total)
+	assert.Equal(t, []string{"http://Example.com/0", "http://Example.com/2"}, urls, "Tag filter should be case-insensitive")
+
+	urls, total = list(models.ListQuery{Limit: 10, Tag: "news", Search: "/3"})
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{"http://Example.com/3"}, urls)
+
+	_, total = list(models.ListQuery{Limit: 10, Tag: "promo", Deleted: models.DeletedInclude})
+	assert.Equal(t, 3, total)
+
+	_, total = list(models.ListQuery{Limit: 10, Tag: "new"})
+	assert.Zero(t, total, "Tag filter should match whole tags only")
+
+	link, err := s.Get(ctx, "short2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"news", "promo"}, link.Tags)
+
+	page, err = s.ListUserURLs(ctx, models.ListQuery{Limit: 1, Sort: models.ListSortClicks})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "http://Example.com/1", page.Items[0].OriginalURL)
+	assert.Empty(t, page.Items[0].Tags)
+	if assert.NotNil(t, page.Items[0].Clicks) {
+		assert.Zero(t, *page.Items[0].Clicks)
+	}
+	if assert.NotNil(t, page.Items[0].CreatedAt) {
+		assert.WithinDuration(t, createdAt.Add(time.Minute), *page.Items[0].CreatedAt, precision)
+	}
+
+	_, err = s.ListUserURLs(ctx, models.ListQuery{Cursor: page.NextCursor})
+	assert.ErrorIs(t, err, storage.ErrInvalidQuery, "Cursor should not be accepted for another sort order")
+	_, err = s.ListUserURLs(ctx, models.ListQuery{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, storage.ErrInvalidQuery)
+	_, err = s.ListUserURLs(ctx, models.ListQuery{Sort: "url"})
+	assert.ErrorIs(t, err, storage.ErrInvalidQuery)
+	_, err = s.ListUserURLs(ctx, models.ListQuery{Tag: "not a tag"})
+	assert.ErrorIs(t, err, storage.ErrInvalidQuery)
+
+	page, err = s.ListUserURLs(context.Background(), models.ListQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items, "Anonymous user should not see any links")
+}
+
+func testUserLinks(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiresAt := time.Now().Add(time.Hour).UTC()
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2", CreatedAt: createdAt.Add(time.Minute), MaxClicks: 5, ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1", CreatedAt: createdAt})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "short3", OriginalURL: "http://example.com/3", CreatedAt: createdAt.Add(2 * time.Minute)})
+	require.NoError(t, err)
+	_, err = s.Set(userContext("user2"), models.Link{ShortKey: "other", OriginalURL: "http://example.com/4"})
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(ctx, "short3"))
+	require.NoError(t, s.SaveClicks(ctx, []models.ClickEvent{
+		{ShortKey: "short2", Timestamp: createdAt.Add(time.Hour)},
+		{ShortKey: "short2", Timestamp: createdAt.Add(2 * time.Hour)},
+	}))
+
+	var items []models.UserLink
+	for item, err := range s.UserLinks(ctx) {
+		require.NoError(t, err)
+		items = append(items, item)
+	}
+	require.Len(t, items, 3, "All links of the current user should be returned, including deleted ones")
+	assert.Equal(t, "short1", items[0].Link.ShortKey, "Links should be ordered by creation time")
+	assert.Equal(t, "short2", items[1].Link.ShortKey)
+	assert.Equal(t, "short3", items[2].Link.ShortKey)
+	assert.Equal(t, "http://example.com/2", items[1].Link.OriginalURL)
+	assert.Equal(t, "user1", items[1].Link.UserID)
+	assert.WithinDuration(t, createdAt.Add(time.Minute), items[1].Link.CreatedAt, precision)
+	assert.WithinDuration(t, expiresAt, items[1].Link.ExpiresAt, precision)
+	assert.Equal(t, int64(5), items[1].Link.MaxClicks)
+	assert.Equal(t, int64(2), items[1].Clicks)
+	assert.Zero(t, items[0].Clicks)
+	assert.True(t, items[2].Link.IsDeleted)
+
+	var count int
+	for range s.UserLinks(ctx) {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count, "Iteration should stop when the consumer breaks")
+
+	for range s.UserLinks(context.Background()) {
+		t.Fatal("Anonymous user should not see any links")
+	}
+}
+
+func testCounts(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	urls, err := s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, urls)
+	users, err := s.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, users)
+
+	for i, userID := range []string{"user1", "user1", "user2", ""} {
+		_, err = s.Set(userContext(userID), models.Link{
+			ShortKey:    fmt.Sprintf("short%d", i),
+			OriginalURL: fmt.Sprintf("http://example.com/%d", i),
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, s.Delete(userContext("user2"), "short2"))
+
+	urls, err = s.CountURLs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, urls, "Deleted links should not be counted")
+	users, err = s.CountUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, users, "Links without an owner should not be counted as a user")
+}
+
+func testHistory(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+
+	history, err := s.GetHistory(ctx, "short1")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/2"))
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/2"), "Update to the same URL should be a no-op")
+	require.NoError(t, s.Update(ctx, "short1", "http://example.com/3"))
+
+	history, err = s.GetHistory(ctx, "short1")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "http://example.com/2", history[0].OriginalURL, "The latest change should come first")
+	assert.Equal(t, "http://example.com/1", history[1].OriginalURL)
+
+	url, err := s.Rollback(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/2", url)
+
+	link, err := s.Get(ctx, "short1")
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/2", link.OriginalURL)
+
+	_, err = s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/3"})
+	require.NoError(t, err, "URL released by Update should be available again")
+
+	_, err = s.Rollback(ctx, "short1")
+	require.NoError(t, err)
+	_, err = s.Rollback(ctx, "short1")
+	assert.ErrorIs(t, err, storage.ErrNoHistory)
+}
+
+func testStats(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2"})
+	require.NoError(t, err)
+
+	err = s.SaveClicks(ctx, []models.ClickEvent{
+		{ShortKey: "short1", Timestamp: day.Add(time.Hour), Referrer: "https://ref.example", UserAgent: "test"},
+		{ShortKey: "short1", Timestamp: day.Add(2 * time.Hour)},
+		{ShortKey: "short1", Timestamp: day.Add(26 * time.Hour)},
+		{ShortKey: "short1", Timestamp: day.Add(-time.Hour)},
+		{ShortKey: "short2", Timestamp: day.Add(time.Hour)},
+	})
+	require.NoError(t, err)
+
+	total, series, err := s.GetStats(ctx, "short1", day, day.Add(48*time.Hour), 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total, "Total should include clicks outside of the range")
+	require.Len(t, series, 2)
+	assert.True(t, day.Equal(series[0].Time))
+	assert.Equal(t, int64(2), series[0].Clicks)
+	assert.True(t, day.Add(24*time.Hour).Equal(series[1].Time))
+	assert.Equal(t, int64(1), series[1].Clicks)
+}
+
+func testDeleteExpired(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+	now := time.Now().UTC()
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "expired", OriginalURL: "http://example.com/1", ExpiresAt: now.Add(-time.Minute)})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "active", OriginalURL: "http://example.com/2", ExpiresAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "forever", OriginalURL: "http://example.com/3"})
+	require.NoError(t, err)
+
+	count, err := s.DeleteExpired(ctx, now, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	link, err := s.Get(ctx, "expired")
+	require.NoError(t, err)
+	assert.True(t, link.IsDeleted, "Expired link should be marked as deleted")
+
+	count, err = s.DeleteExpired(ctx, now, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count, "Already deleted links should not be counted again")
+
+	count, err = s.DeleteExpired(ctx, now, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	_, err = s.Get(ctx, "expired")
+	assert.ErrorIs(t, err, storage.ErrNotFound, "Purged link should be removed")
+	for _, key := range []string{"active", "forever"} {
+		link, err = s.Get(ctx, key)
+		require.NoError(t, err)
+		assert.False(t, link.IsDeleted)
+	}
+}
+
+func testConcurrentSet(t *testing.T, s storage.Storage) {
+	const workers = 10
+	ctx := userContext("user1")
+
+	var wg sync.WaitGroup
+	var created atomic.Int64
+	keys := make([]string, workers)
+	errs := make([]error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			keys[i], errs[i] = s.Set(ctx, models.Link{ShortKey: fmt.Sprintf("short%d", i), OriginalURL: "http://example.com"})
+			if errs[i] == nil {
+				created.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(1), created.Load(), "Only one link should be created for the same URL")
+
+	var winner string
+	for i, err := range errs {
+		if err == nil {
+			winner = keys[i]
+		}
+	}
+	for i, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, storage.ErrConflict)
+			assert.Equal(t, winner, keys[i], "Conflicting Set should return the winning key")
+		}
+	}
+}
+
+func testConsume(t *testing.T, s storage.Storage) {
+	ctx := userContext("user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "limited", OriginalURL: "http://example.com/1", MaxClicks: 1})
+	require.NoError(t, err)
+	_, err = s.Set(ctx, models.Link{ShortKey: "unlimited", OriginalURL: "http://example.com/2"})
+	require.NoError(t, err)
+
+	assert.NoError(t, s.Consume(ctx, "limited"))
+	assert.ErrorIs(t, s.Consume(ctx, "limited"), storage.ErrExhausted)
+	assert.NoError(t, s.Consume(ctx, "unlimited"), "Unlimited link should never be exhausted")
+	assert.ErrorIs(t, s.Consume(ctx, "missing"), storage.ErrNotFound)
+}
+
+func testConcurrentConsume(t *testing.T, s storage.Storage) {
+	const workers = 10
+	ctx := userContext("user1")
+
+	_, err := s.Set(ctx, models.Link{ShortKey: "once", OriginalURL: "http://example.com", MaxClicks: 3})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var consumed, exhausted atomic.Int64
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.Consume(ctx, "once")
+			switch {
+			case err == nil:
+				consumed.Add(1)
+			case errors.Is(err, storage.ErrExhausted):
+				exhausted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(3), consumed.Load(), "Only max_clicks redirects should succeed")
+	assert.Equal(t, int64(workers-3), exhausted.Load())
+
+	link, err := s.Get(ctx, "once")
+	require.NoError(t, err)
+	assert.True(t, link.Exhausted())
+}
+
+func testContextCancellation(t *testing.T, s storage.Storage) {
+	_, err := s.Set(userContext("user1"), models.Link{ShortKey: "short1", OriginalURL: "http://example.com/1"})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(userContext("user1"))
+	cancel()
+
+	_, err = s.Set(ctx, models.Link{ShortKey: "short2", OriginalURL: "http://example.com/2"})
+	assert.ErrorIs(t, err, context.Canceled, "Set")
+	_, err = s.Get(ctx, "short1")
+	assert.ErrorIs(t, err, context.Canceled, "Get")
+	_, err = s.GetUserURL(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "GetUserURL")
+	_, err = s.ListUserURLs(ctx, models.ListQuery{})
+	assert.ErrorIs(t, err, context.Canceled, "ListUserURLs")
+	err = nil
+	for _, err = range s.UserLinks(ctx) {
+	}
+	assert.ErrorIs(t, err, context.Canceled, "UserLinks")
+	assert.ErrorIs(t, s.Delete(ctx, "short1"), context.Canceled, "Delete")
+	assert.ErrorIs(t, s.DeleteBatch(ctx, []string{"short1"}), context.Canceled, "DeleteBatch")
+	_, err = s.Restore(ctx, "short1", time.Time{})
+	assert.ErrorIs(t, err, context.Canceled, "Restore")
+	_, err = s.PurgeDeleted(ctx, time.Now())
+	assert.ErrorIs(t, err, context.Canceled, "PurgeDeleted")
+	assert.ErrorIs(t, s.Update(ctx, "short1", "http://example.com/3"), context.Canceled, "Update")
+	_, err = s.CountURLs(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "CountURLs")
+	_, err = s.DeleteExpired(ctx, time.Now(), false)
+	assert.ErrorIs(t, err, context.Canceled, "DeleteExpired")
+
+	_, err = s.Get(context.Background(), "short2")
+	assert.ErrorIs(t, err, storage.ErrNotFound, "Cancelled Set should not store the link")
+	link, err := s.Get(context.Background(), "short1")
+	require.NoError(t, err)
+	assert.False(t, link.IsDeleted, "Cancelled Delete should not delete the link")
+	assert.Equal(t, "http://example.com/1", link.OriginalURL, "Cancelled Update should not change the link")
+}
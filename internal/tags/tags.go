@@ -0,0 +1,83 @@
+// Package tags проверяет и нормализует метки ссылок.
+package tags
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Ограничения меток ссылки.
+const (
+	// MaxCount — максимальное количество меток у одной ссылки.
+	MaxCount = 10
+	// MaxLength — максимальная длина метки в символах.
+	MaxLength = 32
+)
+
+// Ошибки проверки меток.
+var (
+	// ErrTooMany возвращается, если у ссылки больше MaxCount меток.
+	ErrTooMany = errors.New("too many tags")
+	// ErrInvalidLength возвращается для пустой метки или метки длиннее MaxLength.
+	ErrInvalidLength = errors.New("tag has invalid length")
+	// ErrInvalidChars возвращается, если метка содержит символы, отличные от букв, цифр, '-' и '_'.
+	ErrInvalidChars = errors.New("tag contains invalid characters")
+)
+
+// Normalize проверяет метку и приводит её к каноническому виду: без пробелов по краям и в нижнем регистре.
+func Normalize(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+
+	length := len([]rune(tag))
+	if length == 0 || length > MaxLength {
+		return "", ErrInvalidLength
+	}
+
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' {
+			return "", ErrInvalidChars
+		}
+	}
+
+	return tag, nil
+}
+
+// NormalizeAll нормализует метки ссылки и удаляет повторы, сохраняя порядок первого появления.
+// Для пустого списка возвращает nil.
+func NormalizeAll(tags []string) ([]string, error) {
+	var result []string
+	for _, tag := range tags {
+		tag, err := Normalize(tag)
+		if err != nil {
+			return nil, err
+		}
+
+		if !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	if len(result) > MaxCount {
+		return nil, ErrTooMany
+	}
+
+	return result, nil
+}
+
+// Separator разделяет метки в строковом представлении Join. Нормализованные метки его не содержат.
+const Separator = ","
+
+// Join объединяет нормализованные метки в одну строку для хранения в базе данных.
+func Join(tags []string) string {
+	return strings.Join(tags, Separator)
+}
+
+// Split разбирает строку, полученную Join. Для пустой строки возвращает nil.
+func Split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, Separator)
+}
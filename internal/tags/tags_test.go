@@ -0,0 +1,82 @@
+package tags
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		tag     string
+		want    string
+		wantErr error
+	}{
+		{
+			name: "valid tag",
+			tag:  "spring-sale_2024",
+			want: "spring-sale_2024",
+		},
+		{
+			name: "upper case and spaces",
+			tag:  "  Promo ",
+			want: "promo",
+		},
+		{
+			name: "non-latin letters",
+			tag:  "Скидка",
+			want: "скидка",
+		},
+		{
+			name:    "empty",
+			tag:     "   ",
+			wantErr: ErrInvalidLength,
+		},
+		{
+			name:    "too long",
+			tag:     strings.Repeat("a", MaxLength+1),
+			wantErr: ErrInvalidLength,
+		},
+		{
+			name:    "invalid characters",
+			tag:     "sale,2024",
+			wantErr: ErrInvalidChars,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Normalize(test.tag)
+			assert.ErrorIs(t, err, test.wantErr)
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestNormalizeAll(t *testing.T) {
+	got, err := NormalizeAll([]string{"Promo", "news", "promo "})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"promo", "news"}, got)
+
+	got, err = NormalizeAll(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = NormalizeAll([]string{"promo", "bad tag"})
+	assert.ErrorIs(t, err, ErrInvalidChars)
+
+	many := make([]string, MaxCount+1)
+	for i := range many {
+		many[i] = strings.Repeat("a", i+1)
+	}
+	_, err = NormalizeAll(many)
+	assert.ErrorIs(t, err, ErrTooMany)
+}
+
+func TestJoinSplit(t *testing.T) {
+	assert.Equal(t, "promo,news", Join([]string{"promo", "news"}))
+	assert.Equal(t, []string{"promo", "news"}, Split("promo,news"))
+	assert.Empty(t, Join(nil))
+	assert.Nil(t, Split(""))
+}
@@ -0,0 +1,89 @@
+// Package tracing настраивает распределённую трассировку OpenTelemetry.
+//
+// Setup устанавливает глобальные TracerProvider и пропагатор W3C Trace Context, которые используют
+// middleware HTTP-роутера, grpc-gateway, gRPC-сервер и хранилище PostgreSQL. Даже при отключённом
+// экспорте контекст трассировки из входящих запросов передаётся дальше по цепочке вызовов.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортёры трассировки.
+const (
+	// ExporterNone отключает экспорт; контекст трассировки по-прежнему передаётся.
+	ExporterNone = "none"
+	// ExporterOTLP отправляет трассировку в приёмник OTLP по gRPC.
+	ExporterOTLP = "otlp"
+	// ExporterStdout выводит трассировку в стандартный вывод; используется для отладки.
+	ExporterStdout = "stdout"
+)
+
+// ServiceName — имя сервиса в ресурсах трассировки.
+const ServiceName = "shorturl"
+
+// instrumentationName — имя библиотеки инструментирования в создаваемых сервисом спанах.
+const instrumentationName = "github.com/dsemenov12/shorturl"
+
+// Options задаёт параметры трассировки.
+type Options struct {
+	Exporter    string  // Экспортёр: ExporterNone, ExporterOTLP или ExporterStdout
+	Endpoint    string  // Адрес приёмника OTLP; пустое значение — адрес по умолчанию экспортёра
+	SampleRatio float64 // Доля трассируемых запросов, не продолжающих чужую трассировку
+}
+
+// Setup настраивает глобальную трассировку согласно opts и возвращает функцию, которая
+// отправляет накопленные спаны и останавливает экспорт.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var exporterOpts []otlptracegrpc.Option
+		if opts.Endpoint != "" {
+			exporterOpts = append(exporterOpts, otlptracegrpc.WithEndpoint(opts.Endpoint), otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, exporterOpts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewProvider(exporter, opts.SampleRatio)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewProvider создаёт TracerProvider, отправляющий спаны в exporter пакетами.
+// Запросы без родительского спана трассируются с долей sampleRatio, остальные — по решению родителя.
+func NewProvider(exporter sdktrace.SpanExporter, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))),
+	)
+}
+
+// Tracer возвращает трассировщик сервиса из глобального TracerProvider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
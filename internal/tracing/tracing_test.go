@@ -0,0 +1,59 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	for _, exporter := range []string{"", ExporterNone, ExporterStdout, ExporterOTLP} {
+		shutdown, err := Setup(ctx, Options{Exporter: exporter, Endpoint: "localhost:4317", SampleRatio: 1})
+		require.NoError(t, err, exporter)
+		assert.NoError(t, shutdown(ctx), exporter)
+	}
+
+	_, err := Setup(ctx, Options{Exporter: "jaeger"})
+	assert.Error(t, err)
+}
+
+func TestSetup_Propagation(t *testing.T) {
+	_, err := Setup(context.Background(), Options{Exporter: ExporterNone})
+	require.NoError(t, err)
+
+	// Контекст трассировки передаётся даже при отключённом экспорте
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{"traceparent": traceparent})
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	assert.Equal(t, traceparent, carrier["traceparent"])
+}
+
+func TestNewProvider(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, 0)
+	tracer := provider.Tracer(instrumentationName)
+
+	// Без родителя спан отбрасывается, а дочерний спан следует решению родителя
+	_, dropped := tracer.Start(context.Background(), "dropped")
+	dropped.End()
+
+	ctx := propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+	_, sampled := tracer.Start(ctx, "sampled")
+	sampled.End()
+
+	require.NoError(t, provider.ForceFlush(context.Background()))
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "sampled", spans[0].Name)
+	assert.Equal(t, ServiceName, spans[0].Resource.Attributes()[0].Value.AsString())
+}
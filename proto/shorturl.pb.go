@@ -26,6 +26,12 @@ const (
 type ShortenRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Alias         string                 `protobuf:"bytes,2,opt,name=alias,proto3" json:"alias,omitempty"`
+	Ttl           int64                  `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	ExpiresAt     string                 `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	MaxClicks     int64                  `protobuf:"varint,5,opt,name=max_clicks,json=maxClicks,proto3" json:"max_clicks,omitempty"`
+	Password      string                 `protobuf:"bytes,6,opt,name=password,proto3" json:"password,omitempty"`
+	Tags          []string               `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -67,6 +73,48 @@ func (x *ShortenRequest) GetUrl() string {
 	return ""
 }
 
+func (x *ShortenRequest) GetAlias() string {
+	if x != nil {
+		return x.Alias
+	}
+	return ""
+}
+
+func (x *ShortenRequest) GetTtl() int64 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+func (x *ShortenRequest) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+func (x *ShortenRequest) GetMaxClicks() int64 {
+	if x != nil {
+		return x.MaxClicks
+	}
+	return 0
+}
+
+func (x *ShortenRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+func (x *ShortenRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
 type ShortenResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
@@ -167,6 +215,8 @@ type ShortenBatchResponseItem struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	CorrelationId string                 `protobuf:"bytes,1,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
 	ShortUrl      string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -215,9 +265,24 @@ func (x *ShortenBatchResponseItem) GetShortUrl() string {
 	return ""
 }
 
+func (x *ShortenBatchResponseItem) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ShortenBatchResponseItem) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
 type ShortenBatchRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Items         []*ShortenBatchItem    `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	Atomic        bool                   `protobuf:"varint,2,opt,name=atomic,proto3" json:"atomic,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -259,6 +324,13 @@ func (x *ShortenBatchRequest) GetItems() []*ShortenBatchItem {
 	return nil
 }
 
+func (x *ShortenBatchRequest) GetAtomic() bool {
+	if x != nil {
+		return x.Atomic
+	}
+	return false
+}
+
 type ShortenBatchResponse struct {
 	state         protoimpl.MessageState      `protogen:"open.v1"`
 	Items         []*ShortenBatchResponseItem `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
@@ -307,6 +379,11 @@ type URL struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
 	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	ClicksLeft    *int64                 `protobuf:"varint,3,opt,name=clicks_left,json=clicksLeft,proto3,oneof" json:"clicks_left,omitempty"`
+	CreatedAt     string                 `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Clicks        *int64                 `protobuf:"varint,5,opt,name=clicks,proto3,oneof" json:"clicks,omitempty"`
+	IsDeleted     bool                   `protobuf:"varint,6,opt,name=is_deleted,json=isDeleted,proto3" json:"is_deleted,omitempty"`
+	Tags          []string               `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -355,16 +432,145 @@ func (x *URL) GetOriginalUrl() string {
 	return ""
 }
 
+func (x *URL) GetClicksLeft() int64 {
+	if x != nil && x.ClicksLeft != nil {
+		return *x.ClicksLeft
+	}
+	return 0
+}
+
+func (x *URL) GetCreatedAt() string {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return ""
+}
+
+func (x *URL) GetClicks() int64 {
+	if x != nil && x.Clicks != nil {
+		return *x.Clicks
+	}
+	return 0
+}
+
+func (x *URL) GetIsDeleted() bool {
+	if x != nil {
+		return x.IsDeleted
+	}
+	return false
+}
+
+func (x *URL) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type UserUrlsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	Cursor        string                 `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	Sort          string                 `protobuf:"bytes,3,opt,name=sort,proto3" json:"sort,omitempty"`
+	Order         string                 `protobuf:"bytes,4,opt,name=order,proto3" json:"order,omitempty"`
+	Search        string                 `protobuf:"bytes,5,opt,name=search,proto3" json:"search,omitempty"`
+	Deleted       string                 `protobuf:"bytes,6,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	Tag           string                 `protobuf:"bytes,7,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserUrlsRequest) Reset() {
+	*x = UserUrlsRequest{}
+	mi := &file_shorturl_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserUrlsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserUrlsRequest) ProtoMessage() {}
+
+func (x *UserUrlsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserUrlsRequest.ProtoReflect.Descriptor instead.
+func (*UserUrlsRequest) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UserUrlsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *UserUrlsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *UserUrlsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *UserUrlsRequest) GetOrder() string {
+	if x != nil {
+		return x.Order
+	}
+	return ""
+}
+
+func (x *UserUrlsRequest) GetSearch() string {
+	if x != nil {
+		return x.Search
+	}
+	return ""
+}
+
+func (x *UserUrlsRequest) GetDeleted() string {
+	if x != nil {
+		return x.Deleted
+	}
+	return ""
+}
+
+func (x *UserUrlsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
 type UserUrlsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Urls          []*URL                 `protobuf:"bytes,1,rep,name=urls,proto3" json:"urls,omitempty"`
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UserUrlsResponse) Reset() {
 	*x = UserUrlsResponse{}
-	mi := &file_shorturl_proto_msgTypes[7]
+	mi := &file_shorturl_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -376,7 +582,7 @@ func (x *UserUrlsResponse) String() string {
 func (*UserUrlsResponse) ProtoMessage() {}
 
 func (x *UserUrlsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_shorturl_proto_msgTypes[7]
+	mi := &file_shorturl_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -389,7 +595,7 @@ func (x *UserUrlsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UserUrlsResponse.ProtoReflect.Descriptor instead.
 func (*UserUrlsResponse) Descriptor() ([]byte, []int) {
-	return file_shorturl_proto_rawDescGZIP(), []int{7}
+	return file_shorturl_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *UserUrlsResponse) GetUrls() []*URL {
@@ -399,6 +605,20 @@ func (x *UserUrlsResponse) GetUrls() []*URL {
 	return nil
 }
 
+func (x *UserUrlsResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *UserUrlsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
 type DeleteUserUrlsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ShortUrls     []string               `protobuf:"bytes,1,rep,name=short_urls,json=shortUrls,proto3" json:"short_urls,omitempty"`
@@ -408,7 +628,7 @@ type DeleteUserUrlsRequest struct {
 
 func (x *DeleteUserUrlsRequest) Reset() {
 	*x = DeleteUserUrlsRequest{}
-	mi := &file_shorturl_proto_msgTypes[8]
+	mi := &file_shorturl_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -420,7 +640,7 @@ func (x *DeleteUserUrlsRequest) String() string {
 func (*DeleteUserUrlsRequest) ProtoMessage() {}
 
 func (x *DeleteUserUrlsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_shorturl_proto_msgTypes[8]
+	mi := &file_shorturl_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -433,7 +653,7 @@ func (x *DeleteUserUrlsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteUserUrlsRequest.ProtoReflect.Descriptor instead.
 func (*DeleteUserUrlsRequest) Descriptor() ([]byte, []int) {
-	return file_shorturl_proto_rawDescGZIP(), []int{8}
+	return file_shorturl_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *DeleteUserUrlsRequest) GetShortUrls() []string {
@@ -451,7 +671,7 @@ type Empty struct {
 
 func (x *Empty) Reset() {
 	*x = Empty{}
-	mi := &file_shorturl_proto_msgTypes[9]
+	mi := &file_shorturl_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -463,7 +683,7 @@ func (x *Empty) String() string {
 func (*Empty) ProtoMessage() {}
 
 func (x *Empty) ProtoReflect() protoreflect.Message {
-	mi := &file_shorturl_proto_msgTypes[9]
+	mi := &file_shorturl_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -476,7 +696,7 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Empty.ProtoReflect.Descriptor instead.
 func (*Empty) Descriptor() ([]byte, []int) {
-	return file_shorturl_proto_rawDescGZIP(), []int{9}
+	return file_shorturl_proto_rawDescGZIP(), []int{10}
 }
 
 type StatsResponse struct {
@@ -489,7 +709,7 @@ type StatsResponse struct {
 
 func (x *StatsResponse) Reset() {
 	*x = StatsResponse{}
-	mi := &file_shorturl_proto_msgTypes[10]
+	mi := &file_shorturl_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -501,7 +721,7 @@ func (x *StatsResponse) String() string {
 func (*StatsResponse) ProtoMessage() {}
 
 func (x *StatsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_shorturl_proto_msgTypes[10]
+	mi := &file_shorturl_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -514,7 +734,7 @@ func (x *StatsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
 func (*StatsResponse) Descriptor() ([]byte, []int) {
-	return file_shorturl_proto_rawDescGZIP(), []int{10}
+	return file_shorturl_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *StatsResponse) GetUrls() int64 {
@@ -534,13 +754,14 @@ func (x *StatsResponse) GetUsers() int64 {
 type RedirectRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *RedirectRequest) Reset() {
 	*x = RedirectRequest{}
-	mi := &file_shorturl_proto_msgTypes[11]
+	mi := &file_shorturl_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -552,7 +773,7 @@ func (x *RedirectRequest) String() string {
 func (*RedirectRequest) ProtoMessage() {}
 
 func (x *RedirectRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_shorturl_proto_msgTypes[11]
+	mi := &file_shorturl_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -565,7 +786,7 @@ func (x *RedirectRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RedirectRequest.ProtoReflect.Descriptor instead.
 func (*RedirectRequest) Descriptor() ([]byte, []int) {
-	return file_shorturl_proto_rawDescGZIP(), []int{11}
+	return file_shorturl_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *RedirectRequest) GetId() string {
@@ -575,6 +796,13 @@ func (x *RedirectRequest) GetId() string {
 	return ""
 }
 
+func (x *RedirectRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
 type RedirectResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
@@ -584,7 +812,7 @@ type RedirectResponse struct {
 
 func (x *RedirectResponse) Reset() {
 	*x = RedirectResponse{}
-	mi := &file_shorturl_proto_msgTypes[12]
+	mi := &file_shorturl_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -596,7 +824,7 @@ func (x *RedirectResponse) String() string {
 func (*RedirectResponse) ProtoMessage() {}
 
 func (x *RedirectResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_shorturl_proto_msgTypes[12]
+	mi := &file_shorturl_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -609,7 +837,7 @@ func (x *RedirectResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RedirectResponse.ProtoReflect.Descriptor instead.
 func (*RedirectResponse) Descriptor() ([]byte, []int) {
-	return file_shorturl_proto_rawDescGZIP(), []int{12}
+	return file_shorturl_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *RedirectResponse) GetUrl() string {
@@ -619,48 +847,808 @@ func (x *RedirectResponse) GetUrl() string {
 	return ""
 }
 
-var File_shorturl_proto protoreflect.FileDescriptor
+type UpdateURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Url           string                 `protobuf:"bytes,2,opt,name=url,proto3" json:"url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_shorturl_proto_rawDesc = "" +
-	"\n" +
-	"\x0eshorturl.proto\x12\bshorturl\x1a\x1cgoogle/api/annotations.proto\"\"\n" +
-	"\x0eShortenRequest\x12\x10\n" +
-	"\x03url\x18\x01 \x01(\tR\x03url\")\n" +
-	"\x0fShortenResponse\x12\x16\n" +
-	"\x06result\x18\x01 \x01(\tR\x06result\"\\\n" +
-	"\x10ShortenBatchItem\x12%\n" +
-	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12!\n" +
-	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"^\n" +
-	"\x18ShortenBatchResponseItem\x12%\n" +
-	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12\x1b\n" +
-	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\"G\n" +
-	"\x13ShortenBatchRequest\x120\n" +
-	"\x05items\x18\x01 \x03(\v2\x1a.shorturl.ShortenBatchItemR\x05items\"P\n" +
-	"\x14ShortenBatchResponse\x128\n" +
-	"\x05items\x18\x01 \x03(\v2\".shorturl.ShortenBatchResponseItemR\x05items\"E\n" +
-	"\x03URL\x12\x1b\n" +
-	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
-	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"5\n" +
-	"\x10UserUrlsResponse\x12!\n" +
-	"\x04urls\x18\x01 \x03(\v2\r.shorturl.URLR\x04urls\"6\n" +
-	"\x15DeleteUserUrlsRequest\x12\x1d\n" +
-	"\n" +
-	"short_urls\x18\x01 \x03(\tR\tshortUrls\"\a\n" +
-	"\x05Empty\"9\n" +
-	"\rStatsResponse\x12\x12\n" +
-	"\x04urls\x18\x01 \x01(\x03R\x04urls\x12\x14\n" +
-	"\x05users\x18\x02 \x01(\x03R\x05users\"!\n" +
-	"\x0fRedirectRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\tR\x02id\"$\n" +
-	"\x10RedirectResponse\x12\x10\n" +
-	"\x03url\x18\x01 \x01(\tR\x03url2\xbe\x04\n" +
-	"\x10ShortenerService\x12W\n" +
-	"\aPostURL\x12\x18.shorturl.ShortenRequest\x1a\x19.shorturl.ShortenResponse\"\x17\x82\xd3\xe4\x93\x02\x11:\x01*\"\f/api/shorten\x12p\n" +
-	"\x10ShortenBatchPost\x12\x1d.shorturl.ShortenBatchRequest\x1a\x1e.shorturl.ShortenBatchResponse\"\x1d\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/api/shorten/batch\x12P\n" +
-	"\bRedirect\x12\x19.shorturl.RedirectRequest\x1a\x1a.shorturl.RedirectResponse\"\r\x82\xd3\xe4\x93\x02\a\x12\x05/{id}\x12O\n" +
-	"\bUserUrls\x12\x0f.shorturl.Empty\x1a\x1a.shorturl.UserUrlsResponse\"\x16\x82\xd3\xe4\x93\x02\x10\x12\x0e/api/user/urls\x12d\n" +
-	"\x0eDeleteUserUrls\x12\x1f.shorturl.DeleteUserUrlsRequest\x1a\x0f.shorturl.Empty\" \x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/api/user/urls/delete\x12V\n" +
-	"\rInternalStats\x12\x0f.shorturl.Empty\x1a\x17.shorturl.StatsResponse\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/api/internal/statsB\x10Z\x0eshorturl/protob\x06proto3"
+func (x *UpdateURLRequest) Reset() {
+	*x = UpdateURLRequest{}
+	mi := &file_shorturl_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateURLRequest) ProtoMessage() {}
+
+func (x *UpdateURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateURLRequest.ProtoReflect.Descriptor instead.
+func (*UpdateURLRequest) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *UpdateURLRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateURLRequest) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+type URLHistoryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLHistoryRequest) Reset() {
+	*x = URLHistoryRequest{}
+	mi := &file_shorturl_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLHistoryRequest) ProtoMessage() {}
+
+func (x *URLHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLHistoryRequest.ProtoReflect.Descriptor instead.
+func (*URLHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *URLHistoryRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type URLHistoryItem struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OriginalUrl   string                 `protobuf:"bytes,1,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	ChangedAt     string                 `protobuf:"bytes,2,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLHistoryItem) Reset() {
+	*x = URLHistoryItem{}
+	mi := &file_shorturl_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLHistoryItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLHistoryItem) ProtoMessage() {}
+
+func (x *URLHistoryItem) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLHistoryItem.ProtoReflect.Descriptor instead.
+func (*URLHistoryItem) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *URLHistoryItem) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *URLHistoryItem) GetChangedAt() string {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return ""
+}
+
+type URLHistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*URLHistoryItem      `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *URLHistoryResponse) Reset() {
+	*x = URLHistoryResponse{}
+	mi := &file_shorturl_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *URLHistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*URLHistoryResponse) ProtoMessage() {}
+
+func (x *URLHistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use URLHistoryResponse.ProtoReflect.Descriptor instead.
+func (*URLHistoryResponse) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *URLHistoryResponse) GetItems() []*URLHistoryItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type RollbackURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RollbackURLRequest) Reset() {
+	*x = RollbackURLRequest{}
+	mi := &file_shorturl_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RollbackURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RollbackURLRequest) ProtoMessage() {}
+
+func (x *RollbackURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RollbackURLRequest.ProtoReflect.Descriptor instead.
+func (*RollbackURLRequest) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RollbackURLRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RestoreURLRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreURLRequest) Reset() {
+	*x = RestoreURLRequest{}
+	mi := &file_shorturl_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreURLRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreURLRequest) ProtoMessage() {}
+
+func (x *RestoreURLRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreURLRequest.ProtoReflect.Descriptor instead.
+func (*RestoreURLRequest) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RestoreURLRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type LinkStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	From          string                 `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To            string                 `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Interval      string                 `protobuf:"bytes,4,opt,name=interval,proto3" json:"interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkStatsRequest) Reset() {
+	*x = LinkStatsRequest{}
+	mi := &file_shorturl_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkStatsRequest) ProtoMessage() {}
+
+func (x *LinkStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkStatsRequest.ProtoReflect.Descriptor instead.
+func (*LinkStatsRequest) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *LinkStatsRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *LinkStatsRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *LinkStatsRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *LinkStatsRequest) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+type StatsBucket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Time          string                 `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	Clicks        int64                  `protobuf:"varint,2,opt,name=clicks,proto3" json:"clicks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsBucket) Reset() {
+	*x = StatsBucket{}
+	mi := &file_shorturl_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsBucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsBucket) ProtoMessage() {}
+
+func (x *StatsBucket) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsBucket.ProtoReflect.Descriptor instead.
+func (*StatsBucket) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *StatsBucket) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *StatsBucket) GetClicks() int64 {
+	if x != nil {
+		return x.Clicks
+	}
+	return 0
+}
+
+type LinkStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	Clicks        int64                  `protobuf:"varint,3,opt,name=clicks,proto3" json:"clicks,omitempty"`
+	Series        []*StatsBucket         `protobuf:"bytes,4,rep,name=series,proto3" json:"series,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkStatsResponse) Reset() {
+	*x = LinkStatsResponse{}
+	mi := &file_shorturl_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkStatsResponse) ProtoMessage() {}
+
+func (x *LinkStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkStatsResponse.ProtoReflect.Descriptor instead.
+func (*LinkStatsResponse) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *LinkStatsResponse) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *LinkStatsResponse) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *LinkStatsResponse) GetClicks() int64 {
+	if x != nil {
+		return x.Clicks
+	}
+	return 0
+}
+
+func (x *LinkStatsResponse) GetSeries() []*StatsBucket {
+	if x != nil {
+		return x.Series
+	}
+	return nil
+}
+
+type ImportUrlRecord struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ShortUrl      string                 `protobuf:"bytes,1,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	OriginalUrl   string                 `protobuf:"bytes,2,opt,name=original_url,json=originalUrl,proto3" json:"original_url,omitempty"`
+	ExpiresAt     string                 `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	MaxClicks     int64                  `protobuf:"varint,4,opt,name=max_clicks,json=maxClicks,proto3" json:"max_clicks,omitempty"`
+	Tags          []string               `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportUrlRecord) Reset() {
+	*x = ImportUrlRecord{}
+	mi := &file_shorturl_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportUrlRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportUrlRecord) ProtoMessage() {}
+
+func (x *ImportUrlRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportUrlRecord.ProtoReflect.Descriptor instead.
+func (*ImportUrlRecord) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ImportUrlRecord) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *ImportUrlRecord) GetOriginalUrl() string {
+	if x != nil {
+		return x.OriginalUrl
+	}
+	return ""
+}
+
+func (x *ImportUrlRecord) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+func (x *ImportUrlRecord) GetMaxClicks() int64 {
+	if x != nil {
+		return x.MaxClicks
+	}
+	return 0
+}
+
+func (x *ImportUrlRecord) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type ImportRowError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Line          int64                  `protobuf:"varint,1,opt,name=line,proto3" json:"line,omitempty"`
+	ShortUrl      string                 `protobuf:"bytes,2,opt,name=short_url,json=shortUrl,proto3" json:"short_url,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRowError) Reset() {
+	*x = ImportRowError{}
+	mi := &file_shorturl_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRowError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRowError) ProtoMessage() {}
+
+func (x *ImportRowError) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRowError.ProtoReflect.Descriptor instead.
+func (*ImportRowError) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ImportRowError) GetLine() int64 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *ImportRowError) GetShortUrl() string {
+	if x != nil {
+		return x.ShortUrl
+	}
+	return ""
+}
+
+func (x *ImportRowError) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ImportUrlsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         int64                  `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Imported      int64                  `protobuf:"varint,2,opt,name=imported,proto3" json:"imported,omitempty"`
+	Failed        int64                  `protobuf:"varint,3,opt,name=failed,proto3" json:"failed,omitempty"`
+	Errors        []*ImportRowError      `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportUrlsResponse) Reset() {
+	*x = ImportUrlsResponse{}
+	mi := &file_shorturl_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportUrlsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportUrlsResponse) ProtoMessage() {}
+
+func (x *ImportUrlsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shorturl_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportUrlsResponse.ProtoReflect.Descriptor instead.
+func (*ImportUrlsResponse) Descriptor() ([]byte, []int) {
+	return file_shorturl_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ImportUrlsResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ImportUrlsResponse) GetImported() int64 {
+	if x != nil {
+		return x.Imported
+	}
+	return 0
+}
+
+func (x *ImportUrlsResponse) GetFailed() int64 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *ImportUrlsResponse) GetErrors() []*ImportRowError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+var File_shorturl_proto protoreflect.FileDescriptor
+
+const file_shorturl_proto_rawDesc = "" +
+	"\n" +
+	"\x0eshorturl.proto\x12\bshorturl\x1a\x1cgoogle/api/annotations.proto\"\xb8\x01\n" +
+	"\x0eShortenRequest\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x14\n" +
+	"\x05alias\x18\x02 \x01(\tR\x05alias\x12\x10\n" +
+	"\x03ttl\x18\x03 \x01(\x03R\x03ttl\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\tR\texpiresAt\x12\x1d\n" +
+	"\n" +
+	"max_clicks\x18\x05 \x01(\x03R\tmaxClicks\x12\x1a\n" +
+	"\bpassword\x18\x06 \x01(\tR\bpassword\x12\x12\n" +
+	"\x04tags\x18\a \x03(\tR\x04tags\")\n" +
+	"\x0fShortenResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\"\\\n" +
+	"\x10ShortenBatchItem\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\"\x8c\x01\n" +
+	"\x18ShortenBatchResponseItem\x12%\n" +
+	"\x0ecorrelation_id\x18\x01 \x01(\tR\rcorrelationId\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"_\n" +
+	"\x13ShortenBatchRequest\x120\n" +
+	"\x05items\x18\x01 \x03(\v2\x1a.shorturl.ShortenBatchItemR\x05items\x12\x16\n" +
+	"\x06atomic\x18\x02 \x01(\bR\x06atomic\"P\n" +
+	"\x14ShortenBatchResponse\x128\n" +
+	"\x05items\x18\x01 \x03(\v2\".shorturl.ShortenBatchResponseItemR\x05items\"\xf5\x01\n" +
+	"\x03URL\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12$\n" +
+	"\vclicks_left\x18\x03 \x01(\x03H\x00R\n" +
+	"clicksLeft\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\tR\tcreatedAt\x12\x1b\n" +
+	"\x06clicks\x18\x05 \x01(\x03H\x01R\x06clicks\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"is_deleted\x18\x06 \x01(\bR\tisDeleted\x12\x12\n" +
+	"\x04tags\x18\a \x03(\tR\x04tagsB\x0e\n" +
+	"\f_clicks_leftB\t\n" +
+	"\a_clicks\"\xad\x01\n" +
+	"\x0fUserUrlsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x12\n" +
+	"\x04sort\x18\x03 \x01(\tR\x04sort\x12\x14\n" +
+	"\x05order\x18\x04 \x01(\tR\x05order\x12\x16\n" +
+	"\x06search\x18\x05 \x01(\tR\x06search\x12\x18\n" +
+	"\adeleted\x18\x06 \x01(\tR\adeleted\x12\x10\n" +
+	"\x03tag\x18\a \x01(\tR\x03tag\"l\n" +
+	"\x10UserUrlsResponse\x12!\n" +
+	"\x04urls\x18\x01 \x03(\v2\r.shorturl.URLR\x04urls\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x1f\n" +
+	"\vnext_cursor\x18\x03 \x01(\tR\n" +
+	"nextCursor\"6\n" +
+	"\x15DeleteUserUrlsRequest\x12\x1d\n" +
+	"\n" +
+	"short_urls\x18\x01 \x03(\tR\tshortUrls\"\a\n" +
+	"\x05Empty\"9\n" +
+	"\rStatsResponse\x12\x12\n" +
+	"\x04urls\x18\x01 \x01(\x03R\x04urls\x12\x14\n" +
+	"\x05users\x18\x02 \x01(\x03R\x05users\"=\n" +
+	"\x0fRedirectRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"$\n" +
+	"\x10RedirectResponse\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\"4\n" +
+	"\x10UpdateURLRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03url\x18\x02 \x01(\tR\x03url\"#\n" +
+	"\x11URLHistoryRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"R\n" +
+	"\x0eURLHistoryItem\x12!\n" +
+	"\foriginal_url\x18\x01 \x01(\tR\voriginalUrl\x12\x1d\n" +
+	"\n" +
+	"changed_at\x18\x02 \x01(\tR\tchangedAt\"D\n" +
+	"\x12URLHistoryResponse\x12.\n" +
+	"\x05items\x18\x01 \x03(\v2\x18.shorturl.URLHistoryItemR\x05items\"$\n" +
+	"\x12RollbackURLRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"#\n" +
+	"\x11RestoreURLRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"b\n" +
+	"\x10LinkStatsRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04from\x18\x02 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\x12\x1a\n" +
+	"\binterval\x18\x04 \x01(\tR\binterval\"9\n" +
+	"\vStatsBucket\x12\x12\n" +
+	"\x04time\x18\x01 \x01(\tR\x04time\x12\x16\n" +
+	"\x06clicks\x18\x02 \x01(\x03R\x06clicks\"\x9a\x01\n" +
+	"\x11LinkStatsResponse\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x16\n" +
+	"\x06clicks\x18\x03 \x01(\x03R\x06clicks\x12-\n" +
+	"\x06series\x18\x04 \x03(\v2\x15.shorturl.StatsBucketR\x06series\"\xa3\x01\n" +
+	"\x0fImportUrlRecord\x12\x1b\n" +
+	"\tshort_url\x18\x01 \x01(\tR\bshortUrl\x12!\n" +
+	"\foriginal_url\x18\x02 \x01(\tR\voriginalUrl\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\tR\texpiresAt\x12\x1d\n" +
+	"\n" +
+	"max_clicks\x18\x04 \x01(\x03R\tmaxClicks\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\"W\n" +
+	"\x0eImportRowError\x12\x12\n" +
+	"\x04line\x18\x01 \x01(\x03R\x04line\x12\x1b\n" +
+	"\tshort_url\x18\x02 \x01(\tR\bshortUrl\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"\x90\x01\n" +
+	"\x12ImportUrlsResponse\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x03R\x05total\x12\x1a\n" +
+	"\bimported\x18\x02 \x01(\x03R\bimported\x12\x16\n" +
+	"\x06failed\x18\x03 \x01(\x03R\x06failed\x120\n" +
+	"\x06errors\x18\x04 \x03(\v2\x18.shorturl.ImportRowErrorR\x06errors2\xb1\t\n" +
+	"\x10ShortenerService\x12W\n" +
+	"\aPostURL\x12\x18.shorturl.ShortenRequest\x1a\x19.shorturl.ShortenResponse\"\x17\x82\xd3\xe4\x93\x02\x11:\x01*\"\f/api/shorten\x12p\n" +
+	"\x10ShortenBatchPost\x12\x1d.shorturl.ShortenBatchRequest\x1a\x1e.shorturl.ShortenBatchResponse\"\x1d\x82\xd3\xe4\x93\x02\x17:\x01*\"\x12/api/shorten/batch\x12P\n" +
+	"\bRedirect\x12\x19.shorturl.RedirectRequest\x1a\x1a.shorturl.RedirectResponse\"\r\x82\xd3\xe4\x93\x02\a\x12\x05/{id}\x12Y\n" +
+	"\bUserUrls\x12\x19.shorturl.UserUrlsRequest\x1a\x1a.shorturl.UserUrlsResponse\"\x16\x82\xd3\xe4\x93\x02\x10\x12\x0e/api/user/urls\x12G\n" +
+	"\n" +
+	"ImportUrls\x12\x19.shorturl.ImportUrlRecord\x1a\x1c.shorturl.ImportUrlsResponse(\x01\x122\n" +
+	"\x0eExportUserUrls\x12\x0f.shorturl.Empty\x1a\r.shorturl.URL0\x01\x12d\n" +
+	"\x0eDeleteUserUrls\x12\x1f.shorturl.DeleteUserUrlsRequest\x1a\x0f.shorturl.Empty\" \x82\xd3\xe4\x93\x02\x1a:\x01*\"\x15/api/user/urls/delete\x12V\n" +
+	"\tUpdateURL\x12\x1a.shorturl.UpdateURLRequest\x1a\r.shorturl.URL\"\x1e\x82\xd3\xe4\x93\x02\x18:\x01*\x1a\x13/api/user/urls/{id}\x12l\n" +
+	"\n" +
+	"URLHistory\x12\x1b.shorturl.URLHistoryRequest\x1a\x1c.shorturl.URLHistoryResponse\"#\x82\xd3\xe4\x93\x02\x1d\x12\x1b/api/user/urls/{id}/history\x12c\n" +
+	"\vRollbackURL\x12\x1c.shorturl.RollbackURLRequest\x1a\r.shorturl.URL\"'\x82\xd3\xe4\x93\x02!:\x01*\"\x1c/api/user/urls/{id}/rollback\x12`\n" +
+	"\n" +
+	"RestoreURL\x12\x1b.shorturl.RestoreURLRequest\x1a\r.shorturl.URL\"&\x82\xd3\xe4\x93\x02 :\x01*\"\x1b/api/user/urls/{id}/restore\x12V\n" +
+	"\rInternalStats\x12\x0f.shorturl.Empty\x1a\x17.shorturl.StatsResponse\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/api/internal/stats\x12]\n" +
+	"\tLinkStats\x12\x1a.shorturl.LinkStatsRequest\x1a\x1b.shorturl.LinkStatsResponse\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/api/stats/{id}B\x10Z\x0eshorturl/protob\x06proto3"
 
 var (
 	file_shorturl_proto_rawDescOnce sync.Once
@@ -674,7 +1662,7 @@ func file_shorturl_proto_rawDescGZIP() []byte {
 	return file_shorturl_proto_rawDescData
 }
 
-var file_shorturl_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_shorturl_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
 var file_shorturl_proto_goTypes = []any{
 	(*ShortenRequest)(nil),           // 0: shorturl.ShortenRequest
 	(*ShortenResponse)(nil),          // 1: shorturl.ShortenResponse
@@ -683,34 +1671,64 @@ var file_shorturl_proto_goTypes = []any{
 	(*ShortenBatchRequest)(nil),      // 4: shorturl.ShortenBatchRequest
 	(*ShortenBatchResponse)(nil),     // 5: shorturl.ShortenBatchResponse
 	(*URL)(nil),                      // 6: shorturl.URL
-	(*UserUrlsResponse)(nil),         // 7: shorturl.UserUrlsResponse
-	(*DeleteUserUrlsRequest)(nil),    // 8: shorturl.DeleteUserUrlsRequest
-	(*Empty)(nil),                    // 9: shorturl.Empty
-	(*StatsResponse)(nil),            // 10: shorturl.StatsResponse
-	(*RedirectRequest)(nil),          // 11: shorturl.RedirectRequest
-	(*RedirectResponse)(nil),         // 12: shorturl.RedirectResponse
+	(*UserUrlsRequest)(nil),          // 7: shorturl.UserUrlsRequest
+	(*UserUrlsResponse)(nil),         // 8: shorturl.UserUrlsResponse
+	(*DeleteUserUrlsRequest)(nil),    // 9: shorturl.DeleteUserUrlsRequest
+	(*Empty)(nil),                    // 10: shorturl.Empty
+	(*StatsResponse)(nil),            // 11: shorturl.StatsResponse
+	(*RedirectRequest)(nil),          // 12: shorturl.RedirectRequest
+	(*RedirectResponse)(nil),         // 13: shorturl.RedirectResponse
+	(*UpdateURLRequest)(nil),         // 14: shorturl.UpdateURLRequest
+	(*URLHistoryRequest)(nil),        // 15: shorturl.URLHistoryRequest
+	(*URLHistoryItem)(nil),           // 16: shorturl.URLHistoryItem
+	(*URLHistoryResponse)(nil),       // 17: shorturl.URLHistoryResponse
+	(*RollbackURLRequest)(nil),       // 18: shorturl.RollbackURLRequest
+	(*RestoreURLRequest)(nil),        // 19: shorturl.RestoreURLRequest
+	(*LinkStatsRequest)(nil),         // 20: shorturl.LinkStatsRequest
+	(*StatsBucket)(nil),              // 21: shorturl.StatsBucket
+	(*LinkStatsResponse)(nil),        // 22: shorturl.LinkStatsResponse
+	(*ImportUrlRecord)(nil),          // 23: shorturl.ImportUrlRecord
+	(*ImportRowError)(nil),           // 24: shorturl.ImportRowError
+	(*ImportUrlsResponse)(nil),       // 25: shorturl.ImportUrlsResponse
 }
 var file_shorturl_proto_depIdxs = []int32{
 	2,  // 0: shorturl.ShortenBatchRequest.items:type_name -> shorturl.ShortenBatchItem
 	3,  // 1: shorturl.ShortenBatchResponse.items:type_name -> shorturl.ShortenBatchResponseItem
 	6,  // 2: shorturl.UserUrlsResponse.urls:type_name -> shorturl.URL
-	0,  // 3: shorturl.ShortenerService.PostURL:input_type -> shorturl.ShortenRequest
-	4,  // 4: shorturl.ShortenerService.ShortenBatchPost:input_type -> shorturl.ShortenBatchRequest
-	11, // 5: shorturl.ShortenerService.Redirect:input_type -> shorturl.RedirectRequest
-	9,  // 6: shorturl.ShortenerService.UserUrls:input_type -> shorturl.Empty
-	8,  // 7: shorturl.ShortenerService.DeleteUserUrls:input_type -> shorturl.DeleteUserUrlsRequest
-	9,  // 8: shorturl.ShortenerService.InternalStats:input_type -> shorturl.Empty
-	1,  // 9: shorturl.ShortenerService.PostURL:output_type -> shorturl.ShortenResponse
-	5,  // 10: shorturl.ShortenerService.ShortenBatchPost:output_type -> shorturl.ShortenBatchResponse
-	12, // 11: shorturl.ShortenerService.Redirect:output_type -> shorturl.RedirectResponse
-	7,  // 12: shorturl.ShortenerService.UserUrls:output_type -> shorturl.UserUrlsResponse
-	9,  // 13: shorturl.ShortenerService.DeleteUserUrls:output_type -> shorturl.Empty
-	10, // 14: shorturl.ShortenerService.InternalStats:output_type -> shorturl.StatsResponse
-	9,  // [9:15] is the sub-list for method output_type
-	3,  // [3:9] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	16, // 3: shorturl.URLHistoryResponse.items:type_name -> shorturl.URLHistoryItem
+	21, // 4: shorturl.LinkStatsResponse.series:type_name -> shorturl.StatsBucket
+	24, // 5: shorturl.ImportUrlsResponse.errors:type_name -> shorturl.ImportRowError
+	0,  // 6: shorturl.ShortenerService.PostURL:input_type -> shorturl.ShortenRequest
+	4,  // 7: shorturl.ShortenerService.ShortenBatchPost:input_type -> shorturl.ShortenBatchRequest
+	12, // 8: shorturl.ShortenerService.Redirect:input_type -> shorturl.RedirectRequest
+	7,  // 9: shorturl.ShortenerService.UserUrls:input_type -> shorturl.UserUrlsRequest
+	23, // 10: shorturl.ShortenerService.ImportUrls:input_type -> shorturl.ImportUrlRecord
+	10, // 11: shorturl.ShortenerService.ExportUserUrls:input_type -> shorturl.Empty
+	9,  // 12: shorturl.ShortenerService.DeleteUserUrls:input_type -> shorturl.DeleteUserUrlsRequest
+	14, // 13: shorturl.ShortenerService.UpdateURL:input_type -> shorturl.UpdateURLRequest
+	15, // 14: shorturl.ShortenerService.URLHistory:input_type -> shorturl.URLHistoryRequest
+	18, // 15: shorturl.ShortenerService.RollbackURL:input_type -> shorturl.RollbackURLRequest
+	19, // 16: shorturl.ShortenerService.RestoreURL:input_type -> shorturl.RestoreURLRequest
+	10, // 17: shorturl.ShortenerService.InternalStats:input_type -> shorturl.Empty
+	20, // 18: shorturl.ShortenerService.LinkStats:input_type -> shorturl.LinkStatsRequest
+	1,  // 19: shorturl.ShortenerService.PostURL:output_type -> shorturl.ShortenResponse
+	5,  // 20: shorturl.ShortenerService.ShortenBatchPost:output_type -> shorturl.ShortenBatchResponse
+	13, // 21: shorturl.ShortenerService.Redirect:output_type -> shorturl.RedirectResponse
+	8,  // 22: shorturl.ShortenerService.UserUrls:output_type -> shorturl.UserUrlsResponse
+	25, // 23: shorturl.ShortenerService.ImportUrls:output_type -> shorturl.ImportUrlsResponse
+	6,  // 24: shorturl.ShortenerService.ExportUserUrls:output_type -> shorturl.URL
+	10, // 25: shorturl.ShortenerService.DeleteUserUrls:output_type -> shorturl.Empty
+	6,  // 26: shorturl.ShortenerService.UpdateURL:output_type -> shorturl.URL
+	17, // 27: shorturl.ShortenerService.URLHistory:output_type -> shorturl.URLHistoryResponse
+	6,  // 28: shorturl.ShortenerService.RollbackURL:output_type -> shorturl.URL
+	6,  // 29: shorturl.ShortenerService.RestoreURL:output_type -> shorturl.URL
+	11, // 30: shorturl.ShortenerService.InternalStats:output_type -> shorturl.StatsResponse
+	22, // 31: shorturl.ShortenerService.LinkStats:output_type -> shorturl.LinkStatsResponse
+	19, // [19:32] is the sub-list for method output_type
+	6,  // [6:19] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_shorturl_proto_init() }
@@ -718,13 +1736,14 @@ func file_shorturl_proto_init() {
 	if File_shorturl_proto != nil {
 		return
 	}
+	file_shorturl_proto_msgTypes[6].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shorturl_proto_rawDesc), len(file_shorturl_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   13,
+			NumMessages:   26,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -24,8 +24,15 @@ const (
 	ShortenerService_ShortenBatchPost_FullMethodName = "/shorturl.ShortenerService/ShortenBatchPost"
 	ShortenerService_Redirect_FullMethodName         = "/shorturl.ShortenerService/Redirect"
 	ShortenerService_UserUrls_FullMethodName         = "/shorturl.ShortenerService/UserUrls"
+	ShortenerService_ImportUrls_FullMethodName       = "/shorturl.ShortenerService/ImportUrls"
+	ShortenerService_ExportUserUrls_FullMethodName   = "/shorturl.ShortenerService/ExportUserUrls"
 	ShortenerService_DeleteUserUrls_FullMethodName   = "/shorturl.ShortenerService/DeleteUserUrls"
+	ShortenerService_UpdateURL_FullMethodName        = "/shorturl.ShortenerService/UpdateURL"
+	ShortenerService_URLHistory_FullMethodName       = "/shorturl.ShortenerService/URLHistory"
+	ShortenerService_RollbackURL_FullMethodName      = "/shorturl.ShortenerService/RollbackURL"
+	ShortenerService_RestoreURL_FullMethodName       = "/shorturl.ShortenerService/RestoreURL"
 	ShortenerService_InternalStats_FullMethodName    = "/shorturl.ShortenerService/InternalStats"
+	ShortenerService_LinkStats_FullMethodName        = "/shorturl.ShortenerService/LinkStats"
 )
 
 // ShortenerServiceClient is the client API for ShortenerService service.
@@ -35,9 +42,16 @@ type ShortenerServiceClient interface {
 	PostURL(ctx context.Context, in *ShortenRequest, opts ...grpc.CallOption) (*ShortenResponse, error)
 	ShortenBatchPost(ctx context.Context, in *ShortenBatchRequest, opts ...grpc.CallOption) (*ShortenBatchResponse, error)
 	Redirect(ctx context.Context, in *RedirectRequest, opts ...grpc.CallOption) (*RedirectResponse, error)
-	UserUrls(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*UserUrlsResponse, error)
+	UserUrls(ctx context.Context, in *UserUrlsRequest, opts ...grpc.CallOption) (*UserUrlsResponse, error)
+	ImportUrls(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportUrlRecord, ImportUrlsResponse], error)
+	ExportUserUrls(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[URL], error)
 	DeleteUserUrls(ctx context.Context, in *DeleteUserUrlsRequest, opts ...grpc.CallOption) (*Empty, error)
+	UpdateURL(ctx context.Context, in *UpdateURLRequest, opts ...grpc.CallOption) (*URL, error)
+	URLHistory(ctx context.Context, in *URLHistoryRequest, opts ...grpc.CallOption) (*URLHistoryResponse, error)
+	RollbackURL(ctx context.Context, in *RollbackURLRequest, opts ...grpc.CallOption) (*URL, error)
+	RestoreURL(ctx context.Context, in *RestoreURLRequest, opts ...grpc.CallOption) (*URL, error)
 	InternalStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StatsResponse, error)
+	LinkStats(ctx context.Context, in *LinkStatsRequest, opts ...grpc.CallOption) (*LinkStatsResponse, error)
 }
 
 type shortenerServiceClient struct {
@@ -78,7 +92,7 @@ func (c *shortenerServiceClient) Redirect(ctx context.Context, in *RedirectReque
 	return out, nil
 }
 
-func (c *shortenerServiceClient) UserUrls(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*UserUrlsResponse, error) {
+func (c *shortenerServiceClient) UserUrls(ctx context.Context, in *UserUrlsRequest, opts ...grpc.CallOption) (*UserUrlsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(UserUrlsResponse)
 	err := c.cc.Invoke(ctx, ShortenerService_UserUrls_FullMethodName, in, out, cOpts...)
@@ -88,6 +102,38 @@ func (c *shortenerServiceClient) UserUrls(ctx context.Context, in *Empty, opts .
 	return out, nil
 }
 
+func (c *shortenerServiceClient) ImportUrls(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ImportUrlRecord, ImportUrlsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ShortenerService_ServiceDesc.Streams[0], ShortenerService_ImportUrls_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ImportUrlRecord, ImportUrlsResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_ImportUrlsClient = grpc.ClientStreamingClient[ImportUrlRecord, ImportUrlsResponse]
+
+func (c *shortenerServiceClient) ExportUserUrls(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[URL], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ShortenerService_ServiceDesc.Streams[1], ShortenerService_ExportUserUrls_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Empty, URL]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_ExportUserUrlsClient = grpc.ServerStreamingClient[URL]
+
 func (c *shortenerServiceClient) DeleteUserUrls(ctx context.Context, in *DeleteUserUrlsRequest, opts ...grpc.CallOption) (*Empty, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(Empty)
@@ -98,6 +144,46 @@ func (c *shortenerServiceClient) DeleteUserUrls(ctx context.Context, in *DeleteU
 	return out, nil
 }
 
+func (c *shortenerServiceClient) UpdateURL(ctx context.Context, in *UpdateURLRequest, opts ...grpc.CallOption) (*URL, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URL)
+	err := c.cc.Invoke(ctx, ShortenerService_UpdateURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerServiceClient) URLHistory(ctx context.Context, in *URLHistoryRequest, opts ...grpc.CallOption) (*URLHistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URLHistoryResponse)
+	err := c.cc.Invoke(ctx, ShortenerService_URLHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerServiceClient) RollbackURL(ctx context.Context, in *RollbackURLRequest, opts ...grpc.CallOption) (*URL, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URL)
+	err := c.cc.Invoke(ctx, ShortenerService_RollbackURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerServiceClient) RestoreURL(ctx context.Context, in *RestoreURLRequest, opts ...grpc.CallOption) (*URL, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(URL)
+	err := c.cc.Invoke(ctx, ShortenerService_RestoreURL_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shortenerServiceClient) InternalStats(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*StatsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(StatsResponse)
@@ -108,6 +194,16 @@ func (c *shortenerServiceClient) InternalStats(ctx context.Context, in *Empty, o
 	return out, nil
 }
 
+func (c *shortenerServiceClient) LinkStats(ctx context.Context, in *LinkStatsRequest, opts ...grpc.CallOption) (*LinkStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LinkStatsResponse)
+	err := c.cc.Invoke(ctx, ShortenerService_LinkStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShortenerServiceServer is the server API for ShortenerService service.
 // All implementations must embed UnimplementedShortenerServiceServer
 // for forward compatibility.
@@ -115,9 +211,16 @@ type ShortenerServiceServer interface {
 	PostURL(context.Context, *ShortenRequest) (*ShortenResponse, error)
 	ShortenBatchPost(context.Context, *ShortenBatchRequest) (*ShortenBatchResponse, error)
 	Redirect(context.Context, *RedirectRequest) (*RedirectResponse, error)
-	UserUrls(context.Context, *Empty) (*UserUrlsResponse, error)
+	UserUrls(context.Context, *UserUrlsRequest) (*UserUrlsResponse, error)
+	ImportUrls(grpc.ClientStreamingServer[ImportUrlRecord, ImportUrlsResponse]) error
+	ExportUserUrls(*Empty, grpc.ServerStreamingServer[URL]) error
 	DeleteUserUrls(context.Context, *DeleteUserUrlsRequest) (*Empty, error)
+	UpdateURL(context.Context, *UpdateURLRequest) (*URL, error)
+	URLHistory(context.Context, *URLHistoryRequest) (*URLHistoryResponse, error)
+	RollbackURL(context.Context, *RollbackURLRequest) (*URL, error)
+	RestoreURL(context.Context, *RestoreURLRequest) (*URL, error)
 	InternalStats(context.Context, *Empty) (*StatsResponse, error)
+	LinkStats(context.Context, *LinkStatsRequest) (*LinkStatsResponse, error)
 	mustEmbedUnimplementedShortenerServiceServer()
 }
 
@@ -137,15 +240,36 @@ func (UnimplementedShortenerServiceServer) ShortenBatchPost(context.Context, *Sh
 func (UnimplementedShortenerServiceServer) Redirect(context.Context, *RedirectRequest) (*RedirectResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Redirect not implemented")
 }
-func (UnimplementedShortenerServiceServer) UserUrls(context.Context, *Empty) (*UserUrlsResponse, error) {
+func (UnimplementedShortenerServiceServer) UserUrls(context.Context, *UserUrlsRequest) (*UserUrlsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UserUrls not implemented")
 }
+func (UnimplementedShortenerServiceServer) ImportUrls(grpc.ClientStreamingServer[ImportUrlRecord, ImportUrlsResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ImportUrls not implemented")
+}
+func (UnimplementedShortenerServiceServer) ExportUserUrls(*Empty, grpc.ServerStreamingServer[URL]) error {
+	return status.Errorf(codes.Unimplemented, "method ExportUserUrls not implemented")
+}
 func (UnimplementedShortenerServiceServer) DeleteUserUrls(context.Context, *DeleteUserUrlsRequest) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteUserUrls not implemented")
 }
+func (UnimplementedShortenerServiceServer) UpdateURL(context.Context, *UpdateURLRequest) (*URL, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateURL not implemented")
+}
+func (UnimplementedShortenerServiceServer) URLHistory(context.Context, *URLHistoryRequest) (*URLHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method URLHistory not implemented")
+}
+func (UnimplementedShortenerServiceServer) RollbackURL(context.Context, *RollbackURLRequest) (*URL, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RollbackURL not implemented")
+}
+func (UnimplementedShortenerServiceServer) RestoreURL(context.Context, *RestoreURLRequest) (*URL, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreURL not implemented")
+}
 func (UnimplementedShortenerServiceServer) InternalStats(context.Context, *Empty) (*StatsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method InternalStats not implemented")
 }
+func (UnimplementedShortenerServiceServer) LinkStats(context.Context, *LinkStatsRequest) (*LinkStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LinkStats not implemented")
+}
 func (UnimplementedShortenerServiceServer) mustEmbedUnimplementedShortenerServiceServer() {}
 func (UnimplementedShortenerServiceServer) testEmbeddedByValue()                          {}
 
@@ -222,7 +346,7 @@ func _ShortenerService_Redirect_Handler(srv interface{}, ctx context.Context, de
 }
 
 func _ShortenerService_UserUrls_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(Empty)
+	in := new(UserUrlsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -234,11 +358,29 @@ func _ShortenerService_UserUrls_Handler(srv interface{}, ctx context.Context, de
 		FullMethod: ShortenerService_UserUrls_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ShortenerServiceServer).UserUrls(ctx, req.(*Empty))
+		return srv.(ShortenerServiceServer).UserUrls(ctx, req.(*UserUrlsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShortenerService_ImportUrls_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ShortenerServiceServer).ImportUrls(&grpc.GenericServerStream[ImportUrlRecord, ImportUrlsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_ImportUrlsServer = grpc.ClientStreamingServer[ImportUrlRecord, ImportUrlsResponse]
+
+func _ShortenerService_ExportUserUrls_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShortenerServiceServer).ExportUserUrls(m, &grpc.GenericServerStream[Empty, URL]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ShortenerService_ExportUserUrlsServer = grpc.ServerStreamingServer[URL]
+
 func _ShortenerService_DeleteUserUrls_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeleteUserUrlsRequest)
 	if err := dec(in); err != nil {
@@ -257,6 +399,78 @@ func _ShortenerService_DeleteUserUrls_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShortenerService_UpdateURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServiceServer).UpdateURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortenerService_UpdateURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServiceServer).UpdateURL(ctx, req.(*UpdateURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerService_URLHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(URLHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServiceServer).URLHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortenerService_URLHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServiceServer).URLHistory(ctx, req.(*URLHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerService_RollbackURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RollbackURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServiceServer).RollbackURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortenerService_RollbackURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServiceServer).RollbackURL(ctx, req.(*RollbackURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShortenerService_RestoreURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServiceServer).RestoreURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortenerService_RestoreURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServiceServer).RestoreURL(ctx, req.(*RestoreURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ShortenerService_InternalStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Empty)
 	if err := dec(in); err != nil {
@@ -275,6 +489,24 @@ func _ShortenerService_InternalStats_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShortenerService_LinkStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LinkStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServiceServer).LinkStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShortenerService_LinkStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServiceServer).LinkStats(ctx, req.(*LinkStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ShortenerService_ServiceDesc is the grpc.ServiceDesc for ShortenerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -302,11 +534,42 @@ var ShortenerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteUserUrls",
 			Handler:    _ShortenerService_DeleteUserUrls_Handler,
 		},
+		{
+			MethodName: "UpdateURL",
+			Handler:    _ShortenerService_UpdateURL_Handler,
+		},
+		{
+			MethodName: "URLHistory",
+			Handler:    _ShortenerService_URLHistory_Handler,
+		},
+		{
+			MethodName: "RollbackURL",
+			Handler:    _ShortenerService_RollbackURL_Handler,
+		},
+		{
+			MethodName: "RestoreURL",
+			Handler:    _ShortenerService_RestoreURL_Handler,
+		},
 		{
 			MethodName: "InternalStats",
 			Handler:    _ShortenerService_InternalStats_Handler,
 		},
+		{
+			MethodName: "LinkStats",
+			Handler:    _ShortenerService_LinkStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ImportUrls",
+			Handler:       _ShortenerService_ImportUrls_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ExportUserUrls",
+			Handler:       _ShortenerService_ExportUserUrls_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "shorturl.proto",
 }